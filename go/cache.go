@@ -3,11 +3,12 @@ package examples
 import (
 	"context"
 	"fmt"
-	"log"
+	"io"
 	"os"
 	"path/filepath"
 	"time"
 
+	"gemini-api-examples/internal/caches"
 	"google.golang.org/genai"
 )
 
@@ -15,23 +16,21 @@ func CacheCreate() (*genai.GenerateContentResponse, error) {
 	// [START cache_create]
 	ctx := context.Background()
 	client, err := genai.NewClient(ctx, &genai.ClientConfig{
-		APIKey:  os.Getenv("GEMINI_API_KEY"), 
+		APIKey:  os.Getenv("GEMINI_API_KEY"),
 		Backend: genai.BackendGeminiAPI,
 	})
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
-
-	modelName := "gemini-3.5-flash"
 	document, err := client.Files.UploadFromPath(
-		ctx, 
-		filepath.Join(getMedia(), "a11.txt"), 
+		ctx,
+		filepath.Join(getMedia(), "a11.txt"),
 		&genai.UploadFileConfig{
-			MIMEType : "text/plain",
+			MIMEType: "text/plain",
 		},
 	)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 	parts := []*genai.Part{
 		genai.NewPartFromURI(document.URI, document.MIMEType),
@@ -39,14 +38,19 @@ func CacheCreate() (*genai.GenerateContentResponse, error) {
 	contents := []*genai.Content{
 		genai.NewContentFromParts(parts, genai.RoleUser),
 	}
-	cache, err := client.Caches.Create(ctx, modelName, &genai.CreateCachedContentConfig{
+	config := &genai.CreateCachedContentConfig{
 		Contents: contents,
 		SystemInstruction: genai.NewContentFromText(
 			"You are an expert analyzing transcripts.", genai.RoleUser,
 		),
-	})
+	}
+	// Check that the model can cache the contents before creating the cache.
+	if _, err := caches.Validate(ctx, client, "gemini-3.5-flash", config, nil); err != nil {
+		return nil, err
+	}
+	cache, err := client.Caches.Create(ctx, "gemini-3.5-flash", config)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 	fmt.Println("Cache created:")
 	fmt.Println(cache)
@@ -54,21 +58,22 @@ func CacheCreate() (*genai.GenerateContentResponse, error) {
 	// Use the cache for generating content.
 	response, err := client.Models.GenerateContent(
 		ctx,
-		modelName,
+		"gemini-3.5-flash",
 		genai.Text("Please summarize this transcript"),
 		&genai.GenerateContentConfig{
 			CachedContent: cache.Name,
 		},
 	)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 	printResponse(response)
 	// [END cache_create]
+	return response, nil
+}
 
-	// Delete the cache.
-	_, err = client.Caches.Delete(ctx, cache.Name, &genai.DeleteCachedContentConfig{})
-	return response, err
+func CacheCreateWithClient(ctx context.Context, client *genai.Client, w io.Writer) (*genai.GenerateContentResponse, error) {
+	return withClient(ctx, client, w, CacheCreate)
 }
 
 func CacheCreateFromName() (*genai.GenerateContentResponse, error) {
@@ -79,19 +84,17 @@ func CacheCreateFromName() (*genai.GenerateContentResponse, error) {
 		Backend: genai.BackendGeminiAPI,
 	})
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
-
-	modelName := "gemini-3.5-flash"
 	document, err := client.Files.UploadFromPath(
-		ctx, 
-		filepath.Join(getMedia(), "a11.txt"), 
+		ctx,
+		filepath.Join(getMedia(), "a11.txt"),
 		&genai.UploadFileConfig{
-			MIMEType : "text/plain",
+			MIMEType: "text/plain",
 		},
 	)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 	parts := []*genai.Part{
 		genai.NewPartFromURI(document.URI, document.MIMEType),
@@ -99,40 +102,47 @@ func CacheCreateFromName() (*genai.GenerateContentResponse, error) {
 	contents := []*genai.Content{
 		genai.NewContentFromParts(parts, genai.RoleUser),
 	}
-	cache, err := client.Caches.Create(ctx, modelName, &genai.CreateCachedContentConfig{
+	config := &genai.CreateCachedContentConfig{
 		Contents:          contents,
 		SystemInstruction: genai.NewContentFromText(
 			"You are an expert analyzing transcripts.", genai.RoleUser,
 		),
-	})
+	}
+	// Check that the model can cache the contents before creating the cache.
+	if _, err := caches.Validate(ctx, client, "gemini-3.5-flash", config, nil); err != nil {
+		return nil, err
+	}
+	cache, err := client.Caches.Create(ctx, "gemini-3.5-flash", config)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 	cacheName := cache.Name
 
 	// Later retrieve the cache.
 	cache, err = client.Caches.Get(ctx, cacheName, &genai.GetCachedContentConfig{})
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
 	response, err := client.Models.GenerateContent(
 		ctx,
-		modelName,
+		"gemini-3.5-flash",
 		genai.Text("Find a lighthearted moment from this transcript"),
 		&genai.GenerateContentConfig{
 			CachedContent: cache.Name,
 		},
 	)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 	fmt.Println("Response from cache (create from name):")
 	printResponse(response)
 	// [END cache_create_from_name]
+	return response, nil
+}
 
-	_, err = client.Caches.Delete(ctx, cache.Name, &genai.DeleteCachedContentConfig{})
-	return response, err
+func CacheCreateFromNameWithClient(ctx context.Context, client *genai.Client, w io.Writer) (*genai.GenerateContentResponse, error) {
+	return withClient(ctx, client, w, CacheCreateFromName)
 }
 
 func CacheCreateFromChat() (*genai.GenerateContentResponse, error) {
@@ -143,29 +153,27 @@ func CacheCreateFromChat() (*genai.GenerateContentResponse, error) {
 		Backend: genai.BackendGeminiAPI,
 	})
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
-
-	modelName := "gemini-3.5-flash"
 	systemInstruction := "You are an expert analyzing transcripts."
 
 	// Create initial chat with a system instruction.
-	chat, err := client.Chats.Create(ctx, modelName, &genai.GenerateContentConfig{
+	chat, err := client.Chats.Create(ctx, "gemini-3.5-flash", &genai.GenerateContentConfig{
 		SystemInstruction: genai.NewContentFromText(systemInstruction, genai.RoleUser),
 	}, nil)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
 	document, err := client.Files.UploadFromPath(
-		ctx, 
-		filepath.Join(getMedia(), "a11.txt"), 
+		ctx,
+		filepath.Join(getMedia(), "a11.txt"),
 		&genai.UploadFileConfig{
-			MIMEType : "text/plain",
+			MIMEType: "text/plain",
 		},
 	)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
 	// Send first message with the transcript.
@@ -181,7 +189,7 @@ func CacheCreateFromChat() (*genai.GenerateContentResponse, error) {
 	// Send chat message.
 	resp, err := chat.SendMessage(ctx, parts...)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 	fmt.Println("\n\nmodel: ", resp.Text())
 
@@ -192,25 +200,30 @@ func CacheCreateFromChat() (*genai.GenerateContentResponse, error) {
 		},
 	)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 	fmt.Println("\n\nmodel: ", resp.Text())
 
 	// To cache the conversation so far, pass the chat history as the list of contents.
-	cache, err := client.Caches.Create(ctx, modelName, &genai.CreateCachedContentConfig{
+	config := &genai.CreateCachedContentConfig{
 		Contents:          chat.History(false),
 		SystemInstruction: genai.NewContentFromText(systemInstruction, genai.RoleUser),
-	})
+	}
+	// Check that the model can cache the contents before creating the cache.
+	if _, err := caches.Validate(ctx, client, "gemini-3.5-flash", config, nil); err != nil {
+		return nil, err
+	}
+	cache, err := client.Caches.Create(ctx, "gemini-3.5-flash", config)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
 	// Continue the conversation using the cached history.
-	chat, err = client.Chats.Create(ctx, modelName, &genai.GenerateContentConfig{
+	chat, err = client.Chats.Create(ctx, "gemini-3.5-flash", &genai.GenerateContentConfig{
 		CachedContent: cache.Name,
 	}, nil)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
 	resp, err = chat.SendMessage(
@@ -220,18 +233,22 @@ func CacheCreateFromChat() (*genai.GenerateContentResponse, error) {
 		},
 	)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 	fmt.Println("\n\nmodel: ", resp.Text())
 	// [END cache_create_from_chat]
 
 	// Clean up the cache.
 	if _, err := client.Caches.Delete(ctx, cache.Name, nil); err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 	return resp, nil
 }
 
+func CacheCreateFromChatWithClient(ctx context.Context, client *genai.Client, w io.Writer) (*genai.GenerateContentResponse, error) {
+	return withClient(ctx, client, w, CacheCreateFromChat)
+}
+
 func CacheDelete() error {
 	// [START cache_delete]
 	ctx := context.Background()
@@ -240,19 +257,17 @@ func CacheDelete() error {
 		Backend: genai.BackendGeminiAPI,
 	})
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
-
-	modelName := "gemini-3.5-flash"
 	document, err := client.Files.UploadFromPath(
-		ctx, 
-		filepath.Join(getMedia(), "a11.txt"), 
+		ctx,
+		filepath.Join(getMedia(), "a11.txt"),
 		&genai.UploadFileConfig{
-			MIMEType : "text/plain",
+			MIMEType: "text/plain",
 		},
 	)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 	parts := []*genai.Part{
 		genai.NewPartFromURI(document.URI, document.MIMEType),
@@ -261,25 +276,34 @@ func CacheDelete() error {
 		genai.NewContentFromParts(parts, genai.RoleUser),
 	}
 
-	cache, err := client.Caches.Create(ctx, modelName, &genai.CreateCachedContentConfig{
+	config := &genai.CreateCachedContentConfig{
 		Contents:          contents,
 		SystemInstruction: genai.NewContentFromText(
 			"You are an expert analyzing transcripts.", genai.RoleUser,
 		),
-	})
+	}
+	// Check that the model can cache the contents before creating the cache.
+	if _, err := caches.Validate(ctx, client, "gemini-3.5-flash", config, nil); err != nil {
+		return err
+	}
+	cache, err := client.Caches.Create(ctx, "gemini-3.5-flash", config)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	_, err = client.Caches.Delete(ctx, cache.Name, &genai.DeleteCachedContentConfig{})
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 	fmt.Println("Cache deleted:", cache.Name)
 	// [END cache_delete]
 	return err
 }
 
+func CacheDeleteWithClient(ctx context.Context, client *genai.Client, w io.Writer) error {
+	return runWith(ctx, client, w, CacheDelete)
+}
+
 func CacheGet() error {
 	// [START cache_get]
 	ctx := context.Background()
@@ -288,19 +312,17 @@ func CacheGet() error {
 		Backend: genai.BackendGeminiAPI,
 	})
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
-
-	modelName := "gemini-3.5-flash"
 	document, err := client.Files.UploadFromPath(
-		ctx, 
-		filepath.Join(getMedia(), "a11.txt"), 
+		ctx,
+		filepath.Join(getMedia(), "a11.txt"),
 		&genai.UploadFileConfig{
-			MIMEType : "text/plain",
+			MIMEType: "text/plain",
 		},
 	)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 	parts := []*genai.Part{
 		genai.NewPartFromURI(document.URI, document.MIMEType),
@@ -309,26 +331,33 @@ func CacheGet() error {
 		genai.NewContentFromParts(parts, genai.RoleUser),
 	}
 
-	cache, err := client.Caches.Create(ctx, modelName, &genai.CreateCachedContentConfig{
+	config := &genai.CreateCachedContentConfig{
 		Contents:          contents,
 		SystemInstruction: genai.NewContentFromText(
 			"You are an expert analyzing transcripts.", genai.RoleUser,
 		),
-	})
+	}
+	// Check that the model can cache the contents before creating the cache.
+	if _, err := caches.Validate(ctx, client, "gemini-3.5-flash", config, nil); err != nil {
+		return err
+	}
+	cache, err := client.Caches.Create(ctx, "gemini-3.5-flash", config)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	cache, err = client.Caches.Get(ctx, cache.Name, &genai.GetCachedContentConfig{})
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 	fmt.Println("Retrieved cache:")
 	fmt.Println(cache)
 	// [END cache_get]
+	return nil
+}
 
-	_, err = client.Caches.Delete(ctx, cache.Name, &genai.DeleteCachedContentConfig{})
-	return err
+func CacheGetWithClient(ctx context.Context, client *genai.Client, w io.Writer) error {
+	return runWith(ctx, client, w, CacheGet)
 }
 
 func CacheList() error {
@@ -339,20 +368,18 @@ func CacheList() error {
 		Backend: genai.BackendGeminiAPI,
 	})
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
-
 	// For demonstration, create a cache first.
-	modelName := "gemini-3.5-flash"
 	document, err := client.Files.UploadFromPath(
-		ctx, 
-		filepath.Join(getMedia(), "a11.txt"), 
+		ctx,
+		filepath.Join(getMedia(), "a11.txt"),
 		&genai.UploadFileConfig{
-			MIMEType : "text/plain",
+			MIMEType: "text/plain",
 		},
 	)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 	parts := []*genai.Part{
 		genai.NewPartFromURI(document.URI, document.MIMEType),
@@ -360,20 +387,25 @@ func CacheList() error {
 	contents := []*genai.Content{
 		genai.NewContentFromParts(parts, genai.RoleUser),
 	}
-	cache, err := client.Caches.Create(ctx, modelName, &genai.CreateCachedContentConfig{
+	config := &genai.CreateCachedContentConfig{
 		Contents:          contents,
 		SystemInstruction: genai.NewContentFromText(
 			"You are an expert analyzing transcripts.", genai.RoleUser,
 		),
-	})
+	}
+	// Check that the model can cache the contents before creating the cache.
+	if _, err := caches.Validate(ctx, client, "gemini-3.5-flash", config, nil); err != nil {
+		return err
+	}
+	cache, err := client.Caches.Create(ctx, "gemini-3.5-flash", config)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	// List caches using the List method with a page size of 2.
 	page, err := client.Caches.List(ctx, &genai.ListCachedContentsConfig{PageSize: 2})
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	pageIndex := 1
@@ -393,10 +425,18 @@ func CacheList() error {
 		}
 		pageIndex++
 	}
-	// [END cache_list]
 
+	// Delete the cache created for the demonstration.
 	_, err = client.Caches.Delete(ctx, cache.Name, &genai.DeleteCachedContentConfig{})
-	return err
+	if err != nil {
+		return err
+	}
+	// [END cache_list]
+	return nil
+}
+
+func CacheListWithClient(ctx context.Context, client *genai.Client, w io.Writer) error {
+	return runWith(ctx, client, w, CacheList)
 }
 
 func CacheUpdate() error {
@@ -407,19 +447,17 @@ func CacheUpdate() error {
 		Backend: genai.BackendGeminiAPI,
 	})
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
-
-	modelName := "gemini-3.5-flash"
 	document, err := client.Files.UploadFromPath(
-		ctx, 
-		filepath.Join(getMedia(), "a11.txt"), 
+		ctx,
+		filepath.Join(getMedia(), "a11.txt"),
 		&genai.UploadFileConfig{
-			MIMEType : "text/plain",
+			MIMEType: "text/plain",
 		},
 	)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 	parts := []*genai.Part{
 		genai.NewPartFromURI(document.URI, document.MIMEType),
@@ -427,14 +465,19 @@ func CacheUpdate() error {
 	contents := []*genai.Content{
 		genai.NewContentFromParts(parts, genai.RoleUser),
 	}
-	cache, err := client.Caches.Create(ctx, modelName, &genai.CreateCachedContentConfig{
+	config := &genai.CreateCachedContentConfig{
 		Contents:          contents,
 		SystemInstruction: genai.NewContentFromText(
 			"You are an expert analyzing transcripts.", genai.RoleUser,
 		),
-	})
+	}
+	// Check that the model can cache the contents before creating the cache.
+	if _, err := caches.Validate(ctx, client, "gemini-3.5-flash", config, nil); err != nil {
+		return err
+	}
+	cache, err := client.Caches.Create(ctx, "gemini-3.5-flash", config)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	// Update the TTL (2 hours).
@@ -442,7 +485,7 @@ func CacheUpdate() error {
 		TTL: 7200 * time.Second,
 	})
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 	fmt.Println("After update:")
 	fmt.Println(cache)
@@ -453,7 +496,7 @@ func CacheUpdate() error {
 		ExpireTime: expire,
 	})
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 	fmt.Println("After expire_time update:")
 	fmt.Println(cache)
@@ -462,3 +505,7 @@ func CacheUpdate() error {
 	_, err = client.Caches.Delete(ctx, cache.Name, &genai.DeleteCachedContentConfig{})
 	return err
 }
+
+func CacheUpdateWithClient(ctx context.Context, client *genai.Client, w io.Writer) error {
+	return runWith(ctx, client, w, CacheUpdate)
+}
@@ -1,10 +1,18 @@
 package examples
 
 import (
+	"errors"
+	"io"
+	"net/http"
+	"slices"
 	"testing"
+
+	"gemini-api-examples/internal/caches"
+	"gemini-api-examples/internal/fakegemini"
 )
 
 func TestCacheCreate(t *testing.T) {
+	useCassette(t)
 	_, err := CacheCreate()
 	if err != nil {
 		t.Errorf("CacheCreate returned an error.")
@@ -12,6 +20,7 @@ func TestCacheCreate(t *testing.T) {
 }
 
 func TestCacheCreateFromName(t *testing.T) {
+	useCassette(t)
 	_, err := CacheCreateFromName()
 	if err != nil {
 		t.Errorf("CacheCreateFromName returned an error.")
@@ -19,6 +28,7 @@ func TestCacheCreateFromName(t *testing.T) {
 }
 
 func TestCacheCreateFromChat(t *testing.T) {
+	useCassette(t)
 	_, err := CacheCreateFromChat()
 	if err != nil {
 		t.Errorf("CacheCreateFromChat returned an error.")
@@ -26,6 +36,7 @@ func TestCacheCreateFromChat(t *testing.T) {
 }
 
 func TestCacheDelete(t *testing.T) {
+	useCassette(t)
 	err := CacheDelete()
 	if err != nil {
 		t.Errorf("CacheDelete returned an error.")
@@ -33,6 +44,7 @@ func TestCacheDelete(t *testing.T) {
 }
 
 func TestCacheGet(t *testing.T) {
+	useCassette(t)
 	err := CacheGet()
 	if err != nil {
 		t.Errorf("CacheGet returned an error.")
@@ -40,6 +52,7 @@ func TestCacheGet(t *testing.T) {
 }
 
 func TestCacheList(t *testing.T) {
+	useCassette(t)
 	err := CacheList()
 	if err != nil {
 		t.Errorf("CacheList returned an error.")
@@ -47,8 +60,33 @@ func TestCacheList(t *testing.T) {
 }
 
 func TestCacheUpdate(t *testing.T) {
+	useCassette(t)
 	err := CacheUpdate()
 	if err != nil {
 		t.Errorf("CacheUpdate returned an error.")
 	}
 }
+
+func TestFakeCacheSamplesValidate(t *testing.T) {
+	for _, s := range Samples {
+		if !slices.Contains(s.Creates, ResourceCache) {
+			continue
+		}
+		t.Run(s.Name, func(t *testing.T) {
+			srv, client := fakeClient(t)
+			srv.AddModel(&fakegemini.Model{
+				Name:                       "gemini-3.5-flash",
+				InputTokenLimit:            1048576,
+				SupportedGenerationMethods: []string{"generateContent", "countTokens"},
+			})
+			err := s.Run(t.Context(), client, io.Discard)
+			var verr *caches.ValidationError
+			if !errors.As(err, &verr) || verr.Field != "model" {
+				t.Errorf("err = %v, want the model rejected by caches.Validate", err)
+			}
+			if n := len(srv.RequestsTo(http.MethodPost, "/cachedContents")); n != 0 {
+				t.Errorf("made %d cache creation requests", n)
+			}
+		})
+	}
+}
@@ -3,8 +3,8 @@ package examples
 import (
 	"context"
 	"fmt"
+	"io"
 	"strings"
-	"log"
 	"os"
 	"path/filepath"
 
@@ -19,9 +19,8 @@ func Chat() error {
 		Backend: genai.BackendGeminiAPI,
 	})
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
-
 	// Pass initial history using the History field.
 	history := []*genai.Content{
 		genai.NewContentFromText("Hello", genai.RoleUser),
@@ -30,18 +29,18 @@ func Chat() error {
 
 	chat, err := client.Chats.Create(ctx, "gemini-3.5-flash", nil, history)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	firstResp, err := chat.SendMessage(ctx, genai.Part{Text: "I have 2 dogs in my house."})
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 	fmt.Println(firstResp.Text())
 
 	secondResp, err := chat.SendMessage(ctx, genai.Part{Text: "How many paws are in my house?"})
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 	fmt.Println(secondResp.Text())
 	// [END chat]
@@ -49,6 +48,10 @@ func Chat() error {
 	return nil
 }
 
+func ChatWithClient(ctx context.Context, client *genai.Client, w io.Writer) error {
+	return runWith(ctx, client, w, Chat)
+}
+
 func ChatStreaming() error {
 	// [START chat_streaming]
 	ctx := context.Background()
@@ -57,32 +60,41 @@ func ChatStreaming() error {
 		Backend: genai.BackendGeminiAPI,
 	})
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
-
 	history := []*genai.Content{
 		genai.NewContentFromText("Hello", genai.RoleUser),
 		genai.NewContentFromText("Great to meet you. What would you like to know?", genai.RoleModel),
 	}
 	chat, err := client.Chats.Create(ctx, "gemini-3.5-flash", nil, history)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
+	var last *genai.GenerateContentResponse
 	for chunk, err := range chat.SendMessageStream(ctx, genai.Part{Text: "I have 2 dogs in my house."}) {
 		if err != nil {
-			log.Fatal(err)
+			return err
 		}
 		fmt.Println(chunk.Text())
 		fmt.Println(strings.Repeat("_", 64))
+		last = chunk
+	}
+	if err := checkFinished(last); err != nil {
+		return err
 	}
 
+	last = nil
 	for chunk, err := range chat.SendMessageStream(ctx, genai.Part{Text: "How many paws are in my house?"}) {
 		if err != nil {
-			log.Fatal(err)
+			return err
 		}
 		fmt.Println(chunk.Text())
 		fmt.Println(strings.Repeat("_", 64))
+		last = chunk
+	}
+	if err := checkFinished(last); err != nil {
+		return err
 	}
 
 	fmt.Println(chat.History(false))
@@ -91,6 +103,10 @@ func ChatStreaming() error {
 	return nil
 }
 
+func ChatStreamingWithClient(ctx context.Context, client *genai.Client, w io.Writer) error {
+	return runWith(ctx, client, w, ChatStreaming)
+}
+
 func ChatStreamingWithImages() error {
 	// [START chat_streaming_with_images]
 	ctx := context.Background()
@@ -99,32 +115,36 @@ func ChatStreamingWithImages() error {
 		Backend: genai.BackendGeminiAPI,
 	})
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
-
 	chat, err := client.Chats.Create(ctx, "gemini-3.5-flash", nil, nil)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
+	var last *genai.GenerateContentResponse
 	for chunk, err := range chat.SendMessageStream(ctx, genai.Part{
 		Text: "Hello, I'm interested in learning about musical instruments. Can I show you one?"}) {
 		if err != nil {
-			log.Fatal(err)
+			return err
 		}
 		fmt.Println(chunk.Text())
 		fmt.Println(strings.Repeat("_", 64))
+		last = chunk
+	}
+	if err := checkFinished(last); err != nil {
+		return err
 	}
 
 	image, err := client.Files.UploadFromPath(
-		ctx, 
-		filepath.Join(getMedia(), "organ.jpg"), 
+		ctx,
+		filepath.Join(getMedia(), "organ.jpg"),
 		&genai.UploadFileConfig{
-			MIMEType : "image/jpeg",
+			MIMEType: "image/jpeg",
 		},
 	)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	// Upload image file
@@ -137,14 +157,23 @@ func ChatStreamingWithImages() error {
 		},
 	}
 
+	last = nil
 	for chunk, err := range chat.SendMessageStream(ctx, parts...) {
 		if err != nil {
-			log.Fatal(err)
+			return err
 		}
 		fmt.Println(chunk.Text())
 		fmt.Println(strings.Repeat("_", 64))
+		last = chunk
+	}
+	if err := checkFinished(last); err != nil {
+		return err
 	}
 	// [END chat_streaming_with_images]
 
 	return nil
 }
+
+func ChatStreamingWithImagesWithClient(ctx context.Context, client *genai.Client, w io.Writer) error {
+	return runWith(ctx, client, w, ChatStreamingWithImages)
+}
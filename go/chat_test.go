@@ -1,10 +1,14 @@
 package examples
 
 import (
+	"context"
+	"errors"
+	"io"
 	"testing"
 )
 
 func TestChat(t *testing.T) {
+	useCassette(t)
 	err := Chat()
 	if err != nil {
 		t.Errorf("Chat returned an error: %v", err)
@@ -12,6 +16,7 @@ func TestChat(t *testing.T) {
 }
 
 func TestChatStreaming(t *testing.T) {
+	useCassette(t)
 	err := ChatStreaming()
 	if err != nil {
 		t.Errorf("ChatStreaming returned an error: %v", err)
@@ -19,8 +24,21 @@ func TestChatStreaming(t *testing.T) {
 }
 
 func TestChatStreamingWithImages(t *testing.T) {
+	useCassette(t)
 	err := ChatStreamingWithImages()
 	if err != nil {
 		t.Errorf("ChatStreamingWithImages returned an error: %v", err)
 	}
 }
+
+func TestFakeChatWithClientCanceled(t *testing.T) {
+	srv, client := fakeClient(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := ChatWithClient(ctx, client, io.Discard); !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+	if n := len(srv.Requests()); n != 0 {
+		t.Errorf("server got %d requests after cancellation", n)
+	}
+}
@@ -0,0 +1,230 @@
+package examples
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"google.golang.org/genai"
+)
+
+// Each sample comes in two forms. Foo is the sample as the snippets show
+// it: it creates its own client from GEMINI_API_KEY, prints to standard
+// output and returns any error. FooWithClient runs Foo so that its client
+// talks to the API through the given client, honours ctx, and writes what
+// Foo prints to w; see runWith.
+
+// sampleModel is the model the samples generate content, count tokens and
+// create caches with, and sampleEmbeddingModel that of the embedding
+// samples. Their snippets name them literally; OverrideModels replaces
+// them in the requests of a client.
+const (
+	sampleModel          = "gemini-3.5-flash"
+	sampleEmbeddingModel = "gemini-embedding-001"
+)
+
+// OverrideModels returns a client like client whose requests name model,
+// and embeddingModel, instead of the models of the samples and of the
+// embedding samples, each when not empty, so that running the samples with
+// it exercises other models.
+func OverrideModels(ctx context.Context, client *genai.Client, model, embeddingModel string) (*genai.Client, error) {
+	var replace []string
+	if model != "" {
+		replace = append(replace, "models/"+sampleModel, "models/"+model)
+	}
+	if embeddingModel != "" {
+		replace = append(replace, "models/"+sampleEmbeddingModel, "models/"+embeddingModel)
+	}
+	if len(replace) == 0 {
+		return client, nil
+	}
+	cc := client.ClientConfig()
+	hc := &http.Client{}
+	if cc.HTTPClient != nil {
+		*hc = *cc.HTTPClient
+	}
+	hc.Transport = &modelOverride{replace: replace, base: hc.Transport}
+	cc.HTTPClient = hc
+	return genai.NewClient(ctx, &cc)
+}
+
+// A modelOverride renames models in the paths and JSON bodies of requests.
+type modelOverride struct {
+	// replace holds pairs of old and new model names.
+	replace []string
+	base    http.RoundTripper
+}
+
+func (m *modelOverride) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := m.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	out := req.Clone(req.Context())
+	for i := 0; i < len(m.replace); i += 2 {
+		from, to := m.replace[i], m.replace[i+1]
+		// The model ends the path, or is followed by the method, as in
+		// models/gemini-3.5-flash:generateContent.
+		if prefix, ok := strings.CutSuffix(out.URL.Path, from); ok {
+			out.URL.Path = prefix + to
+		} else if prefix, method, ok := strings.Cut(out.URL.Path, from+":"); ok {
+			out.URL.Path = prefix + to + ":" + method
+		}
+	}
+	out.URL.RawPath = ""
+	if req.Body != nil && req.Header.Get("Content-Type") == "application/json" {
+		body, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		for i := 0; i < len(m.replace); i += 2 {
+			body = bytes.ReplaceAll(body, []byte(strconv.Quote(m.replace[i])), []byte(strconv.Quote(m.replace[i+1])))
+		}
+		out.Body, out.ContentLength = io.NopCloser(bytes.NewReader(body)), int64(len(body))
+		out.GetBody = func() (io.ReadCloser, error) { return io.NopCloser(bytes.NewReader(body)), nil }
+	}
+	return base.RoundTrip(out)
+}
+
+// apiHost is the host of the Gemini API, which the clients of the samples
+// call.
+const apiHost = "generativelanguage.googleapis.com"
+
+// runMu serializes the runs of runWith, which change process-wide state.
+var runMu sync.Mutex
+
+// runWith runs sample, the Foo form of a sample, with client standing in
+// for the client it creates, and returns its error. For the time of the
+// run, it:
+//
+//   - sets GEMINI_API_KEY to the key of client;
+//   - replaces http.DefaultTransport, which the sample's client uses, with
+//     one sending each request through the HTTP client of client, with ctx,
+//     and the requests for the Gemini API to the base URL and API version
+//     of client;
+//   - points os.Stdout at a pipe copied to w, unless w is os.Stdout.
+//
+// client must use the Gemini API backend. Runs are serialized, and other
+// code in the process printing to os.Stdout or using http.DefaultTransport
+// meanwhile is affected as well.
+func runWith(ctx context.Context, client *genai.Client, w io.Writer, sample func() error) (err error) {
+	cc := client.ClientConfig()
+	if cc.Backend != genai.BackendGeminiAPI {
+		return errors.New("the samples only run against the Gemini API backend")
+	}
+	runMu.Lock()
+	defer runMu.Unlock()
+
+	if cc.APIKey != "" {
+		key, set := os.LookupEnv("GEMINI_API_KEY")
+		os.Setenv("GEMINI_API_KEY", cc.APIKey)
+		defer func() {
+			if set {
+				os.Setenv("GEMINI_API_KEY", key)
+			} else {
+				os.Unsetenv("GEMINI_API_KEY")
+			}
+		}()
+	}
+
+	previous := http.DefaultTransport
+	http.DefaultTransport = &redirect{
+		ctx:      ctx,
+		client:   cc.HTTPClient,
+		previous: previous,
+		baseURL:  cc.HTTPOptions.BaseURL,
+		version:  cc.HTTPOptions.APIVersion,
+	}
+	defer func() { http.DefaultTransport = previous }()
+
+	if f, ok := w.(*os.File); !ok || f != os.Stdout {
+		r, pw, err := os.Pipe()
+		if err != nil {
+			return err
+		}
+		stdout := os.Stdout
+		os.Stdout = pw
+		copied := make(chan error, 1)
+		go func() {
+			_, err := io.Copy(w, r)
+			r.Close()
+			copied <- err
+		}()
+		defer func() {
+			os.Stdout = stdout
+			pw.Close()
+			if cerr := <-copied; err == nil {
+				err = cerr
+			}
+		}()
+	}
+	return sample()
+}
+
+// withClient is runWith for the samples returning a result.
+func withClient[T any](ctx context.Context, client *genai.Client, w io.Writer, sample func() (T, error)) (T, error) {
+	var v T
+	err := runWith(ctx, client, w, func() (err error) {
+		v, err = sample()
+		return err
+	})
+	return v, err
+}
+
+// A redirect sends the requests of the clients the samples create through
+// the HTTP client of the client given to runWith. The requests it sends
+// come back to it when that client, or a transport it wraps, defaults to
+// http.DefaultTransport: it passes them on to the transport it replaced.
+type redirect struct {
+	ctx      context.Context
+	client   *http.Client
+	previous http.RoundTripper
+	baseURL  string
+	version  string
+}
+
+type redirectedKey struct{}
+
+func (r *redirect) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Context().Value(redirectedKey{}) != nil {
+		return r.previous.RoundTrip(req)
+	}
+	if err := r.ctx.Err(); err != nil {
+		return nil, err
+	}
+	out := req.Clone(context.WithValue(r.ctx, redirectedKey{}, true))
+	if req.URL.Host == apiHost {
+		u, err := r.rewrite(req.URL)
+		if err != nil {
+			return nil, err
+		}
+		out.URL, out.Host = u, u.Host
+	}
+	return r.client.Do(out)
+}
+
+// rewrite returns the URL the client given to runWith would have used for
+// u, a URL of the Gemini API: the client builds them from its base URL, its
+// API version and the path of the method, except for uploads, whose path
+// includes the version.
+func (r *redirect) rewrite(u *url.URL) (*url.URL, error) {
+	path := strings.TrimLeft(u.Path, "/")
+	if suffix, ok := strings.CutPrefix(path, "v1beta/"); ok {
+		path = r.version + "/" + suffix
+	}
+	out, err := url.Parse(fmt.Sprintf("%s/%s", r.baseURL, path))
+	if err != nil {
+		return nil, err
+	}
+	out.RawQuery = u.RawQuery
+	return out, nil
+}
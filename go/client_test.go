@@ -0,0 +1,63 @@
+package examples
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"cloud.google.com/go/auth"
+	"gemini-api-examples/internal/config"
+	"gemini-api-examples/internal/fakegemini"
+	"google.golang.org/genai"
+)
+
+func TestFakeWithClientAPIVersion(t *testing.T) {
+	srv := fakegemini.New()
+	t.Cleanup(srv.Close)
+	cfg := &config.Config{APIKey: "fake", BaseURL: srv.URL, APIVersion: "v1alpha"}
+	client, err := cfg.NewClient(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The fake only serves v1beta, so the call itself fails.
+	ModelsListWithClient(t.Context(), client, &bytes.Buffer{})
+	reqs := srv.Requests()
+	if len(reqs) != 1 || reqs[0].Path != "v1alpha/models" {
+		t.Errorf("requests = %+v, want one to v1alpha/models", reqs)
+	}
+}
+
+func TestFakeWithClientRestores(t *testing.T) {
+	_, client := fakeClient(t)
+	t.Setenv("GEMINI_API_KEY", "outside")
+	transport, stdout := http.DefaultTransport, os.Stdout
+	var out bytes.Buffer
+	if _, err := TextGenTextOnlyPromptWithClient(t.Context(), client, &out); err != nil {
+		t.Fatalf("TextGenTextOnlyPromptWithClient returned an error: %v", err)
+	}
+	if !strings.HasPrefix(out.String(), "You said:") {
+		t.Errorf("output = %q, want the response", out.String())
+	}
+	if http.DefaultTransport != transport || os.Stdout != stdout || os.Getenv("GEMINI_API_KEY") != "outside" {
+		t.Error("the run left http.DefaultTransport, os.Stdout or GEMINI_API_KEY changed")
+	}
+}
+
+func TestWithClientVertex(t *testing.T) {
+	client, err := genai.NewClient(context.Background(), &genai.ClientConfig{
+		Backend:     genai.BackendVertexAI,
+		Project:     "p",
+		Location:    "l",
+		Credentials: &auth.Credentials{},
+		HTTPClient:  &http.Client{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ModelsListWithClient(t.Context(), client, &bytes.Buffer{}); err == nil {
+		t.Error("ModelsListWithClient succeeded with a Vertex AI client")
+	}
+}
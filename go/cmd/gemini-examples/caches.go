@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"gemini-api-examples/internal/inventory"
+)
+
+// cachesOutput is the JSON form of the output of caches.
+type cachesOutput struct {
+	*inventory.CacheReport
+	// Action is "extend" or "delete", or empty if none was asked for.
+	Action string                `json:"action,omitempty"`
+	Result *inventory.BulkReport `json:"result,omitempty"`
+}
+
+// manageCaches implements the caches subcommand.
+func manageCaches(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	fl := flag.NewFlagSet("caches", flag.ContinueOnError)
+	fl.SetOutput(stderr)
+	models := fl.String("model", "", "only list caches of these comma-separated models, such as gemini-3.5-*")
+	name := fl.String("name", "", "only list caches whose display name matches this pattern, such as sample-*")
+	minTokens := fl.Int("min-tokens", 0, "only list caches of at least this many tokens")
+	maxTokens := fl.Int("max-tokens", 0, "only list caches of at most this many tokens")
+	createdAfter := fl.String("created-after", "", "only list caches created after this time, or this long ago, such as 36h")
+	createdBefore := fl.String("created-before", "", "only list caches created before this time, or this long ago")
+	expiresAfter := fl.String("expires-after", "", "only list caches expiring after this time, or this long from now, such as 1h")
+	expiresBefore := fl.String("expires-before", "", "only list caches expiring before this time, or this long from now, such as 10m")
+	sortKey := fl.String("sort", "", "sort by "+strings.Join(inventory.CacheSortKeys, ", ")+", or one of them after - for descending order (default: listing order)")
+	extend := fl.String("extend", "", "set the TTL of the listed caches to this duration, such as 2h")
+	del := fl.Bool("delete", false, "delete the listed caches")
+	all := fl.Bool("all", false, "allow -extend or -delete without any criterion")
+	dryRun := fl.Bool("dry-run", false, "only report what -extend or -delete would do")
+	parallel := fl.Int("parallel", 4, "number of concurrent updates or deletions")
+	pageSize := fl.Int("page-size", 0, "number of caches per list request (default: the API's)")
+	backend := fl.String("backend", "", "backend: gemini or vertex (default: from the configuration)")
+	format := fl.String("format", "text", "output format: text or json")
+	pos, err := parse(fl, args)
+	if err != nil {
+		return 2
+	}
+	if len(pos) > 0 {
+		fmt.Fprintf(stderr, "gemini-examples: unexpected arguments %q\n", pos)
+		return 2
+	}
+	if !checkFormat(*format, stderr) {
+		return 2
+	}
+	if *pageSize < 0 || *minTokens < 0 || *maxTokens < 0 {
+		fmt.Fprintln(stderr, "gemini-examples: -page-size, -min-tokens and -max-tokens must not be negative")
+		return 2
+	}
+	if err := inventory.SortCaches(nil, *sortKey); *sortKey != "" && err != nil {
+		fmt.Fprintf(stderr, "gemini-examples: %v\n", err)
+		return 2
+	}
+	var ttl time.Duration
+	if *extend != "" {
+		if ttl, err = parseAge(*extend); err != nil || ttl == 0 {
+			fmt.Fprintf(stderr, "gemini-examples: bad -extend: want a positive duration such as 2h, got %q\n", *extend)
+			return 2
+		}
+	}
+	if *extend != "" && *del {
+		fmt.Fprintln(stderr, "gemini-examples: -extend and -delete are exclusive")
+		return 2
+	}
+
+	f := inventory.CacheFilter{DisplayName: *name, MinTokens: int32(*minTokens), MaxTokens: int32(*maxTokens)}
+	if *models != "" {
+		f.Models = strings.Split(*models, ",")
+	}
+	now := time.Now()
+	for _, opt := range []struct {
+		flag, value string
+		sign        time.Duration
+		dst         *time.Time
+	}{
+		{"created-after", *createdAfter, -1, &f.CreatedAfter},
+		{"created-before", *createdBefore, -1, &f.CreatedBefore},
+		{"expires-after", *expiresAfter, 1, &f.ExpiresAfter},
+		{"expires-before", *expiresBefore, 1, &f.ExpiresBefore},
+	} {
+		if opt.value == "" {
+			continue
+		}
+		if *opt.dst, err = parseTime(opt.value, now, opt.sign); err != nil {
+			fmt.Fprintf(stderr, "gemini-examples: bad -%s: %v\n", opt.flag, err)
+			return 2
+		}
+	}
+	if err := f.Validate(); err != nil {
+		fmt.Fprintf(stderr, "gemini-examples: %v\n", err)
+		return 2
+	}
+	if (*extend != "" || *del) && f.Empty() && !*all {
+		fmt.Fprintln(stderr, "gemini-examples: -extend and -delete need a criterion such as -model or -expires-before, or -all to act on every cache")
+		return 2
+	}
+
+	client, err := newClient(ctx, *backend)
+	if err != nil {
+		fmt.Fprintf(stderr, "gemini-examples: %v\n", err)
+		return 1
+	}
+	r, err := inventory.Caches(ctx, client, f, int32(*pageSize))
+	if err != nil {
+		fmt.Fprintf(stderr, "gemini-examples: %v\n", err)
+		return 1
+	}
+	if *sortKey != "" {
+		inventory.SortCaches(r.Caches, *sortKey)
+	}
+	out := cachesOutput{CacheReport: r}
+	opts := inventory.BulkOptions{DryRun: *dryRun, Parallel: *parallel}
+	switch {
+	case *extend != "":
+		out.Action, out.Result = "extend", inventory.ExtendCaches(ctx, client, r.Caches, ttl, opts)
+	case *del:
+		out.Action, out.Result = "delete", inventory.DeleteCaches(ctx, client, r.Caches, opts)
+	}
+	if *format == "json" {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(out)
+	} else {
+		err = writeCaches(stdout, &out)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "gemini-examples: %v\n", err)
+		return 1
+	}
+	if out.Result != nil && len(out.Result.Failed) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// remaining formats the time left before c expires, to the second.
+func remaining(c *inventory.Cache, now time.Time) string {
+	d := c.Remaining(now).Round(time.Second)
+	if d == 0 {
+		return "expired"
+	}
+	return d.String()
+}
+
+func writeCaches(w io.Writer, out *cachesOutput) error {
+	r := out.CacheReport
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tDISPLAY NAME\tMODEL\tTOKENS\tCREATED\tEXPIRES\tREMAINING")
+	for _, c := range r.Caches {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s\t%s\t%s\n", c.Name, orDash([]string{c.DisplayName}), c.Model, c.Tokens,
+			c.CreateTime.Format(time.RFC3339), c.ExpireTime.Format(time.RFC3339), remaining(&c, r.Time))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	var byModel []string
+	for _, m := range slices.Sorted(maps.Keys(r.Totals.ByModel)) {
+		byModel = append(byModel, fmt.Sprintf("%s %d", m, r.Totals.ByModel[m]))
+	}
+	line := fmt.Sprintf("%d caches, %d tokens (%d listed)", r.Totals.Count, r.Totals.Tokens, r.Listed)
+	if len(byModel) > 0 {
+		line += "; " + strings.Join(byModel, ", ")
+	}
+	if _, err := fmt.Fprintln(w, line); err != nil || out.Result == nil {
+		return err
+	}
+
+	res := out.Result
+	verb := map[string]string{"extend": "extended", "delete": "deleted"}[out.Action]
+	if res.DryRun {
+		verb = "would " + out.Action
+	}
+	tw = tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	for _, c := range res.Done {
+		if out.Action == "extend" && !res.DryRun {
+			fmt.Fprintf(tw, "%s\t%s\tuntil %s\n", verb, c.Name, c.ExpireTime.Format(time.RFC3339))
+		} else {
+			fmt.Fprintf(tw, "%s\t%s\n", verb, c.Name)
+		}
+	}
+	for _, f := range res.Failed {
+		fmt.Fprintf(tw, "FAILED\t%s\t%v\n", f.Name, f.Err)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(w, "%d %s, %d failed\n", len(res.Done), verb, len(res.Failed))
+	return err
+}
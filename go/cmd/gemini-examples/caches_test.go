@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"gemini-api-examples/internal/fakegemini"
+
+	"google.golang.org/genai"
+)
+
+// seedCaches adds 25 caches to srv, expiring 5 minutes times one more than
+// their index from now, of 100 tokens times their index, every third one of
+// gemini-3.5-pro and the others of gemini-3.5-flash.
+func seedCaches(srv *fakegemini.Server) {
+	now := time.Now()
+	for i := range 25 {
+		model := "models/gemini-3.5-flash"
+		if i%3 == 0 {
+			model = "models/gemini-3.5-pro"
+		}
+		srv.AddCache(&genai.CachedContent{
+			Name:          fmt.Sprintf("cachedContents/c%02d", i),
+			DisplayName:   fmt.Sprintf("doc-%02d", i),
+			Model:         model,
+			CreateTime:    now.Add(-time.Hour),
+			ExpireTime:    now.Add(time.Duration(i+1) * 5 * time.Minute),
+			UsageMetadata: &genai.CachedContentUsageMetadata{TotalTokenCount: int32(100 * i)},
+		})
+	}
+}
+
+func TestCaches(t *testing.T) {
+	srv := useFakeServer(t)
+	seedCaches(srv)
+	var stdout, stderr bytes.Buffer
+	args := []string{"caches", "-model", "gemini-3.5-pro", "-sort", "-tokens", "-page-size", "4"}
+	if code := run(t.Context(), args, &stdout, &stderr); code != 0 {
+		t.Fatalf("exit code %d; stderr:\n%s", code, stderr.String())
+	}
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	// c24, 21, 18, 15, 12, 09, 06, 03 and 00, a header and the totals.
+	if len(lines) != 11 || !strings.HasPrefix(lines[0], "NAME") || !strings.HasPrefix(lines[1], "cachedContents/c24") {
+		t.Fatalf("output:\n%s", stdout.String())
+	}
+	if !strings.Contains(lines[0], "REMAINING") || !strings.Contains(lines[1], "gemini-3.5-pro") || !strings.Contains(lines[1], "2400") {
+		t.Errorf("first row = %q", lines[1])
+	}
+	want := fmt.Sprintf("9 caches, %d tokens (25 listed); gemini-3.5-pro 9", 100*(0+3+6+9+12+15+18+21+24))
+	if lines[len(lines)-1] != want {
+		t.Errorf("totals line = %q, want %q", lines[len(lines)-1], want)
+	}
+	if n := len(srv.RequestsTo("GET", "/cachedContents")); n != 7 {
+		t.Errorf("made %d list requests, want 7", n)
+	}
+}
+
+func TestCachesExtend(t *testing.T) {
+	srv := useFakeServer(t)
+	seedCaches(srv)
+	var stdout, stderr bytes.Buffer
+	args := []string{"caches", "-expires-before", "22m", "-extend", "3h", "-dry-run"}
+	if code := run(t.Context(), args, &stdout, &stderr); code != 0 {
+		t.Fatalf("exit code %d; stderr:\n%s", code, stderr.String())
+	}
+	if !strings.HasSuffix(stdout.String(), "4 would extend, 0 failed\n") {
+		t.Errorf("dry run output:\n%s", stdout.String())
+	}
+	if n := len(srv.RequestsTo("PATCH", "")); n != 0 {
+		t.Fatalf("dry run made %d updates", n)
+	}
+
+	stdout.Reset()
+	args = []string{"caches", "-expires-before", "22m", "-extend", "3h", "-format", "json"}
+	if code := run(t.Context(), args, &stdout, &stderr); code != 0 {
+		t.Fatalf("exit code %d; stderr:\n%s", code, stderr.String())
+	}
+	var out cachesOutput
+	if err := json.Unmarshal(stdout.Bytes(), &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Action != "extend" || out.Listed != 25 || len(out.Caches) != 4 || len(out.Result.Done) != 4 {
+		t.Fatalf("output: %s", stdout.String())
+	}
+	for _, c := range srv.Caches() {
+		extended := c.ExpireTime.After(time.Now().Add(150 * time.Minute))
+		if want := c.Name < "cachedContents/c04"; extended != want {
+			t.Errorf("%s expires at %v; extended = %v, want %v", c.Name, c.ExpireTime, extended, want)
+		}
+	}
+}
+
+func TestCachesDelete(t *testing.T) {
+	srv := useFakeServer(t)
+	seedCaches(srv)
+	srv.Inject("/cachedContents/c03", 0, fakegemini.ServerError(http.StatusInternalServerError))
+	var stdout, stderr bytes.Buffer
+	args := []string{"caches", "-model", "models/gemini-3.5-pro", "-max-tokens", "1000", "-delete", "-parallel", "2"}
+	if code := run(t.Context(), args, &stdout, &stderr); code != 1 {
+		t.Errorf("exit code %d, want 1", code)
+	}
+	// c00, c06 and c09 deleted; c03 failed.
+	if !strings.Contains(stdout.String(), "FAILED   cachedContents/c03") || !strings.HasSuffix(stdout.String(), "3 deleted, 1 failed\n") {
+		t.Errorf("output:\n%s", stdout.String())
+	}
+	if n := len(srv.Caches()); n != 22 {
+		t.Errorf("%d caches left, want 22", n)
+	}
+}
+
+func TestCachesUsage(t *testing.T) {
+	srv := useFakeServer(t)
+	seedCaches(srv)
+	for _, args := range [][]string{
+		{"caches", "-format", "csv"},
+		{"caches", "-sort", "size"},
+		{"caches", "-model", "gemini-["},
+		{"caches", "-expires-before", "soon"},
+		{"caches", "-min-tokens", "10", "-max-tokens", "5"},
+		{"caches", "-extend", "forever"},
+		{"caches", "-extend", "0s", "-all"},
+		{"caches", "-model", "gemini-3.5-pro", "-extend", "1h", "-delete"},
+		{"caches", "-delete"},
+		{"caches", "extra"},
+	} {
+		var stdout, stderr bytes.Buffer
+		if code := run(t.Context(), args, &stdout, &stderr); code != 2 {
+			t.Errorf("%q: exit code %d, want 2", args, code)
+		}
+		if stderr.Len() == 0 {
+			t.Errorf("%q: nothing on stderr", args)
+		}
+	}
+	if n := len(srv.Caches()); n != 25 {
+		t.Errorf("%d caches left, want 25", n)
+	}
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"gemini-api-examples/internal/inventory"
+
+	"google.golang.org/genai"
+)
+
+// files implements the files subcommand.
+func files(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	fl := flag.NewFlagSet("files", flag.ContinueOnError)
+	fl.SetOutput(stderr)
+	states := fl.String("state", "", "only list files in these comma-separated states, such as ACTIVE")
+	mimeTypes := fl.String("mime", "", "only list files of these comma-separated MIME types, such as image/*")
+	name := fl.String("name", "", "only list files whose display name matches this pattern, such as sample-*")
+	minSize := fl.String("min-size", "", "only list files of at least this size, such as 10MB")
+	maxSize := fl.String("max-size", "", "only list files of at most this size")
+	createdAfter := fl.String("created-after", "", "only list files created after this time, or this long ago, such as 7d")
+	createdBefore := fl.String("created-before", "", "only list files created before this time, or this long ago")
+	expiresAfter := fl.String("expires-after", "", "only list files expiring after this time, or this long from now, such as 6h")
+	expiresBefore := fl.String("expires-before", "", "only list files expiring before this time, or this long from now")
+	pageSize := fl.Int("page-size", 0, "number of files per list request (default: the API's)")
+	backend := fl.String("backend", "", "backend: gemini or vertex (default: from the configuration)")
+	format := fl.String("format", "text", "output format: text, json or csv")
+	pos, err := parse(fl, args)
+	if err != nil {
+		return 2
+	}
+	if len(pos) > 0 {
+		fmt.Fprintf(stderr, "gemini-examples: unexpected arguments %q\n", pos)
+		return 2
+	}
+	if *format != "csv" && !checkFormat(*format, stderr) {
+		return 2
+	}
+	if *pageSize < 0 {
+		fmt.Fprintln(stderr, "gemini-examples: -page-size must not be negative")
+		return 2
+	}
+
+	f := inventory.FileFilter{DisplayName: *name}
+	if *states != "" {
+		for _, s := range strings.Split(*states, ",") {
+			f.States = append(f.States, genai.FileState(strings.ToUpper(s)))
+		}
+	}
+	if *mimeTypes != "" {
+		f.MIMETypes = strings.Split(*mimeTypes, ",")
+	}
+	now := time.Now()
+	for _, opt := range []struct {
+		flag, value string
+		dst         *int64
+	}{
+		{"min-size", *minSize, &f.MinSize},
+		{"max-size", *maxSize, &f.MaxSize},
+	} {
+		if opt.value == "" {
+			continue
+		}
+		if *opt.dst, err = parseSize(opt.value); err != nil {
+			fmt.Fprintf(stderr, "gemini-examples: bad -%s: %v\n", opt.flag, err)
+			return 2
+		}
+	}
+	for _, opt := range []struct {
+		flag, value string
+		sign        time.Duration
+		dst         *time.Time
+	}{
+		{"created-after", *createdAfter, -1, &f.CreatedAfter},
+		{"created-before", *createdBefore, -1, &f.CreatedBefore},
+		{"expires-after", *expiresAfter, 1, &f.ExpiresAfter},
+		{"expires-before", *expiresBefore, 1, &f.ExpiresBefore},
+	} {
+		if opt.value == "" {
+			continue
+		}
+		if *opt.dst, err = parseTime(opt.value, now, opt.sign); err != nil {
+			fmt.Fprintf(stderr, "gemini-examples: bad -%s: %v\n", opt.flag, err)
+			return 2
+		}
+	}
+	if err := f.Validate(); err != nil {
+		fmt.Fprintf(stderr, "gemini-examples: %v\n", err)
+		return 2
+	}
+
+	client, err := newClient(ctx, *backend)
+	if err != nil {
+		fmt.Fprintf(stderr, "gemini-examples: %v\n", err)
+		return 1
+	}
+	r, err := inventory.Files(ctx, client, f, int32(*pageSize))
+	if err != nil {
+		fmt.Fprintf(stderr, "gemini-examples: %v\n", err)
+		return 1
+	}
+	switch *format {
+	case "json":
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(r)
+	case "csv":
+		err = writeFilesCSV(stdout, r)
+	default:
+		err = writeFiles(stdout, r)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "gemini-examples: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// parseSize parses a number of bytes with an optional unit: KB, MB or GB
+// for powers of 1000, or KiB, MiB or GiB for powers of 1024.
+func parseSize(s string) (int64, error) {
+	units := []struct {
+		suffix string
+		scale  int64
+	}{
+		{"KiB", 1 << 10}, {"MiB", 1 << 20}, {"GiB", 1 << 30},
+		{"KB", 1e3}, {"MB", 1e6}, {"GB", 1e9}, {"B", 1},
+	}
+	num, scale := s, int64(1)
+	for _, u := range units {
+		if n, ok := strings.CutSuffix(s, u.suffix); ok {
+			num, scale = strings.TrimSpace(n), u.scale
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(num, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return int64(n * float64(scale)), nil
+}
+
+// parseTime parses an RFC 3339 time, or a duration as parseAge does that is
+// counted from now, backward if sign is negative and forward otherwise.
+func parseTime(s string, now time.Time, sign time.Duration) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	d, err := parseAge(s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is neither an RFC 3339 time nor a duration", s)
+	}
+	return now.Add(sign * d), nil
+}
+
+func writeFiles(w io.Writer, r *inventory.FileReport) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tDISPLAY NAME\tMIME TYPE\tSIZE\tSTATE\tCREATED\tEXPIRES")
+	for _, f := range r.Files {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s\t%s\t%s\n", f.Name, orDash([]string{f.DisplayName}), f.MIMEType,
+			f.SizeBytes, f.State, f.CreateTime.Format(time.RFC3339), f.ExpirationTime.Format(time.RFC3339))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	var states []string
+	for _, s := range slices.Sorted(maps.Keys(r.Totals.ByState)) {
+		states = append(states, fmt.Sprintf("%s %d", s, r.Totals.ByState[s]))
+	}
+	line := fmt.Sprintf("%d files, %d bytes (%d listed)", r.Totals.Count, r.Totals.SizeBytes, r.Listed)
+	if len(states) > 0 {
+		line += "; " + strings.Join(states, ", ")
+	}
+	_, err := fmt.Fprintln(w, line)
+	return err
+}
+
+// writeFilesCSV writes one record per file after a header, and a last
+// record with the totals.
+func writeFilesCSV(w io.Writer, r *inventory.FileReport) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"name", "display_name", "mime_type", "size_bytes", "state", "create_time", "expiration_time", "uri"})
+	for _, f := range r.Files {
+		cw.Write([]string{f.Name, f.DisplayName, f.MIMEType, strconv.FormatInt(f.SizeBytes, 10), string(f.State),
+			f.CreateTime.Format(time.RFC3339), f.ExpirationTime.Format(time.RFC3339), f.URI})
+	}
+	cw.Write([]string{"TOTAL", strconv.Itoa(r.Totals.Count) + " files", "", strconv.FormatInt(r.Totals.SizeBytes, 10), "", "", "", ""})
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"gemini-api-examples/internal/fakegemini"
+	"gemini-api-examples/internal/inventory"
+
+	"google.golang.org/genai"
+)
+
+// seedInventory adds 30 files to srv: images of 1 KiB times their index,
+// created an hour apart from 2025-06-01, every third one a FAILED video.
+func seedInventory(srv *fakegemini.Server) {
+	start := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	for i := range 30 {
+		meta := &genai.File{
+			DisplayName: fmt.Sprintf("photo-%02d", i),
+			MIMEType:    "image/png",
+			CreateTime:  start.Add(time.Duration(i) * time.Hour),
+		}
+		if i%3 == 0 {
+			meta.DisplayName = fmt.Sprintf("clip-%02d", i)
+			meta.MIMEType = "video/mp4"
+			meta.State = genai.FileStateFailed
+		}
+		srv.AddFile(meta, make([]byte, i<<10))
+	}
+}
+
+func TestFiles(t *testing.T) {
+	srv := useFakeServer(t)
+	seedInventory(srv)
+	var stdout, stderr bytes.Buffer
+	args := []string{"files", "-mime", "image/*", "-min-size", "20KiB", "-page-size", "7"}
+	if code := run(t.Context(), args, &stdout, &stderr); code != 0 {
+		t.Fatalf("exit code %d; stderr:\n%s", code, stderr.String())
+	}
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	// 20, 22, 23, 25, 26, 28 and 29 KiB, a header and the totals.
+	if len(lines) != 9 || !strings.HasPrefix(lines[0], "NAME") || !strings.Contains(lines[1], "photo-20") {
+		t.Errorf("output:\n%s", stdout.String())
+	}
+	want := fmt.Sprintf("7 files, %d bytes (30 listed); ACTIVE 7", (20+22+23+25+26+28+29)<<10)
+	if lines[len(lines)-1] != want {
+		t.Errorf("totals line = %q, want %q", lines[len(lines)-1], want)
+	}
+	if n := len(srv.RequestsTo("GET", "/files")); n != 5 {
+		t.Errorf("made %d list requests, want 5", n)
+	}
+}
+
+func TestFilesJSON(t *testing.T) {
+	srv := useFakeServer(t)
+	seedInventory(srv)
+	var stdout, stderr bytes.Buffer
+	args := []string{"files", "-state", "failed", "-created-before", "2025-06-01T10:00:00Z", "-format", "json"}
+	if code := run(t.Context(), args, &stdout, &stderr); code != 0 {
+		t.Fatalf("exit code %d; stderr:\n%s", code, stderr.String())
+	}
+	var r inventory.FileReport
+	if err := json.Unmarshal(stdout.Bytes(), &r); err != nil {
+		t.Fatal(err)
+	}
+	if r.Listed != 30 || len(r.Files) != 4 || r.Totals.ByMIMEType["video/mp4"] != 4 {
+		t.Errorf("report: listed %d, %d files, by MIME type %v", r.Listed, len(r.Files), r.Totals.ByMIMEType)
+	}
+	for _, f := range r.Files {
+		if !strings.HasPrefix(f.DisplayName, "clip-") {
+			t.Errorf("file %s (%s) should not be listed", f.Name, f.DisplayName)
+		}
+	}
+}
+
+func TestFilesCSV(t *testing.T) {
+	srv := useFakeServer(t)
+	seedInventory(srv)
+	var stdout, stderr bytes.Buffer
+	args := []string{"files", "-name", "clip-*", "-max-size", "10KB", "-format", "csv"}
+	if code := run(t.Context(), args, &stdout, &stderr); code != 0 {
+		t.Fatalf("exit code %d; stderr:\n%s", code, stderr.String())
+	}
+	records, err := csv.NewReader(&stdout).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// clip-00, 03, 06 and 09, under 10000 bytes.
+	if len(records) != 6 || records[0][0] != "name" {
+		t.Fatalf("records:\n%q", records)
+	}
+	total := records[len(records)-1]
+	if want := []string{"TOTAL", "4 files", "", fmt.Sprint((0 + 3 + 6 + 9) << 10)}; len(total) < len(want) || !slices.Equal(total[:len(want)], want) {
+		t.Errorf("total record = %q, want it to start with %q", total, want)
+	}
+}
+
+func TestFilesUsage(t *testing.T) {
+	useFakeServer(t)
+	for _, args := range [][]string{
+		{"files", "-format", "yaml"},
+		{"files", "-min-size", "lots"},
+		{"files", "-created-after", "yesterday"},
+		{"files", "-min-size", "2MB", "-max-size", "1MB"},
+		{"files", "-mime", "image/["},
+		{"files", "extra"},
+	} {
+		var stdout, stderr bytes.Buffer
+		if code := run(t.Context(), args, &stdout, &stderr); code != 2 {
+			t.Errorf("%q: exit code %d, want 2", args, code)
+		}
+		if stderr.Len() == 0 {
+			t.Errorf("%q: nothing on stderr", args)
+		}
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	for in, want := range map[string]int64{
+		"0":      0,
+		"512":    512,
+		"100B":   100,
+		"10KB":   10000,
+		"1.5MB":  1500000,
+		"2GB":    2000000000,
+		"1KiB":   1024,
+		"20MiB":  20 << 20,
+		"1GiB":   1 << 30,
+		"3 MiB":  3 << 20,
+		"0.5KiB": 512,
+	} {
+		got, err := parseSize(in)
+		if err != nil || got != want {
+			t.Errorf("parseSize(%q) = %d, %v, want %d", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "MB", "-1KB", "ten"} {
+		if _, err := parseSize(in); err == nil {
+			t.Errorf("parseSize(%q) succeeded", in)
+		}
+	}
+}
+
+func TestParseTime(t *testing.T) {
+	now := time.Date(2025, 6, 10, 12, 0, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		in   string
+		sign time.Duration
+		want time.Time
+	}{
+		{"2025-06-01T00:00:00Z", -1, time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)},
+		{"7d", -1, now.Add(-7 * 24 * time.Hour)},
+		{"6h", 1, now.Add(6 * time.Hour)},
+	} {
+		got, err := parseTime(tc.in, now, tc.sign)
+		if err != nil || !got.Equal(tc.want) {
+			t.Errorf("parseTime(%q) = %v, %v, want %v", tc.in, got, err, tc.want)
+		}
+	}
+	if _, err := parseTime("June", now, 1); err == nil {
+		t.Error("parseTime(June) succeeded")
+	}
+}
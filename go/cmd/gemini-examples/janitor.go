@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"gemini-api-examples/internal/janitor"
+	"gemini-api-examples/internal/tracker"
+)
+
+// sweep implements the janitor subcommand.
+func sweep(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	fl := flag.NewFlagSet("janitor", flag.ContinueOnError)
+	fl.SetOutput(stderr)
+	kind := fl.String("kind", "", "only sweep this kind of resource: file or cache (default: both)")
+	prefix := fl.String("prefix", "", "only sweep resources whose display name has this prefix")
+	olderThan := fl.String("older-than", "", "only sweep resources older than this, such as 36h or 7d")
+	mimeTypes := fl.String("mime", "", "only sweep files of these comma-separated MIME types, such as image/*")
+	models := fl.String("model", "", "only sweep caches of these comma-separated models")
+	all := fl.Bool("all", false, "sweep everything of the selected kinds when no other criterion is given")
+	dryRun := fl.Bool("dry-run", false, "only report what would be deleted")
+	parallel := fl.Int("parallel", 4, "number of concurrent deletions")
+	rate := fl.Float64("rate", 10, "maximum deletions per second, or 0 for no limit")
+	backend := fl.String("backend", "", "backend: gemini or vertex (default: from the configuration)")
+	format := fl.String("format", "text", "output format: text or json")
+	pos, err := parse(fl, args)
+	if err != nil {
+		return 2
+	}
+	if len(pos) > 0 {
+		fmt.Fprintf(stderr, "gemini-examples: unexpected arguments %q\n", pos)
+		return 2
+	}
+	if !checkFormat(*format, stderr) {
+		return 2
+	}
+	f := janitor.Filter{Prefix: *prefix}
+	switch *kind {
+	case "":
+	case string(tracker.KindFile), string(tracker.KindCache):
+		f.Kinds = []tracker.Kind{tracker.Kind(*kind)}
+	default:
+		fmt.Fprintf(stderr, "gemini-examples: unknown kind %q; use file or cache\n", *kind)
+		return 2
+	}
+	if *olderThan != "" {
+		if f.OlderThan, err = parseAge(*olderThan); err != nil {
+			fmt.Fprintf(stderr, "gemini-examples: bad -older-than: %v\n", err)
+			return 2
+		}
+	}
+	if *mimeTypes != "" {
+		f.MIMETypes = strings.Split(*mimeTypes, ",")
+	}
+	if *models != "" {
+		f.Models = strings.Split(*models, ",")
+	}
+	if err := f.Validate(); err != nil {
+		fmt.Fprintf(stderr, "gemini-examples: %v\n", err)
+		return 2
+	}
+	if f.Empty() && !*all {
+		fmt.Fprintln(stderr, "gemini-examples: janitor needs -prefix, -older-than, -mime or -model, or -all to sweep everything")
+		return 2
+	}
+
+	client, err := newClient(ctx, *backend)
+	if err != nil {
+		fmt.Fprintf(stderr, "gemini-examples: %v\n", err)
+		return 1
+	}
+	r, err := janitor.Sweep(ctx, client, f, janitor.Options{DryRun: *dryRun, Parallel: *parallel, Rate: *rate})
+	if err != nil {
+		fmt.Fprintf(stderr, "gemini-examples: %v\n", err)
+		return 1
+	}
+	if *format == "json" {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(r)
+	} else {
+		err = writeSweep(stdout, r)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "gemini-examples: %v\n", err)
+		return 1
+	}
+	if len(r.Failed) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// parseAge parses a duration as time.ParseDuration does, also accepting a
+// whole number of days such as "7d".
+func parseAge(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err == nil && d < 0 {
+		err = fmt.Errorf("negative duration %q", s)
+	}
+	return d, err
+}
+
+func writeSweep(w io.Writer, r *janitor.Report) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	verb := "deleted"
+	if r.DryRun {
+		verb = "would delete"
+	}
+	for _, it := range r.Deleted {
+		display := it.DisplayName
+		if display == "" {
+			display = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", verb, it.Kind, it.Name, display, it.CreateTime.Format(time.RFC3339))
+	}
+	for _, f := range r.Failed {
+		fmt.Fprintf(tw, "FAILED\t%s\t%s\t%v\n", f.Kind, f.Name, f.Err)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(w, "%d listed, %d selected, %d %s, %d failed\n", r.Listed, len(r.Selected), len(r.Deleted), verb, len(r.Failed))
+	return err
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"gemini-api-examples/internal/fakegemini"
+	"gemini-api-examples/internal/janitor"
+
+	"google.golang.org/genai"
+)
+
+// seed adds 200 files and 100 caches to srv, half of them named "sample-"
+// and created 10 days ago, the other half named "keep-" and created now.
+func seed(srv *fakegemini.Server) {
+	now := time.Now()
+	for i := range 300 {
+		name, created := fmt.Sprintf("keep-%d", i), now
+		if i%2 == 0 {
+			name, created = fmt.Sprintf("sample-%d", i), now.Add(-10*24*time.Hour)
+		}
+		if i < 200 {
+			srv.AddFile(&genai.File{DisplayName: name, MIMEType: "text/plain", CreateTime: created}, nil)
+		} else {
+			srv.AddCache(&genai.CachedContent{DisplayName: name, Model: "models/gemini-3.5-flash", CreateTime: created, ExpireTime: now.Add(time.Hour)})
+		}
+	}
+}
+
+func TestJanitor(t *testing.T) {
+	srv := useFakeServer(t)
+	seed(srv)
+	var stdout, stderr bytes.Buffer
+	args := []string{"janitor", "-prefix", "sample-", "-older-than", "7d", "-dry-run", "-rate", "0"}
+	if code := run(t.Context(), args, &stdout, &stderr); code != 0 {
+		t.Fatalf("exit code %d; stderr:\n%s", code, stderr.String())
+	}
+	if !strings.HasSuffix(stdout.String(), "300 listed, 150 selected, 150 would delete, 0 failed\n") {
+		t.Errorf("dry run output ends with:\n%s", stdout.String()[max(0, stdout.Len()-200):])
+	}
+	if len(srv.Files()) != 200 || len(srv.Caches()) != 100 {
+		t.Fatal("dry run deleted resources")
+	}
+
+	stdout.Reset()
+	args = []string{"janitor", "-prefix", "sample-", "-older-than", "7d", "-parallel", "8", "-rate", "0", "-format", "json"}
+	if code := run(t.Context(), args, &stdout, &stderr); code != 0 {
+		t.Fatalf("exit code %d; stderr:\n%s", code, stderr.String())
+	}
+	var r janitor.Report
+	if err := json.Unmarshal(stdout.Bytes(), &r); err != nil {
+		t.Fatal(err)
+	}
+	if r.Listed != 300 || len(r.Deleted) != 150 || len(r.Failed) != 0 {
+		t.Errorf("report: listed %d, deleted %d, failed %d", r.Listed, len(r.Deleted), len(r.Failed))
+	}
+	if len(srv.Files()) != 100 || len(srv.Caches()) != 50 {
+		t.Errorf("%d files and %d caches left, want 100 and 50", len(srv.Files()), len(srv.Caches()))
+	}
+	for _, f := range srv.Files() {
+		if !strings.HasPrefix(f.DisplayName, "keep-") {
+			t.Errorf("file %s (%s) was not swept", f.Name, f.DisplayName)
+		}
+	}
+}
+
+func TestJanitorFailure(t *testing.T) {
+	srv := useFakeServer(t)
+	seed(srv)
+	victim := srv.Caches()[0]
+	srv.Inject(victim.Name, 0, fakegemini.ServerError(http.StatusInternalServerError))
+	var stdout, stderr bytes.Buffer
+	args := []string{"janitor", "-kind", "cache", "-all", "-rate", "0"}
+	if code := run(t.Context(), args, &stdout, &stderr); code != 1 {
+		t.Errorf("exit code %d, want 1", code)
+	}
+	failed := false
+	for _, line := range strings.Split(stdout.String(), "\n") {
+		f := strings.Fields(line)
+		failed = failed || len(f) > 2 && f[0] == "FAILED" && f[2] == victim.Name
+	}
+	if !failed || len(srv.Caches()) != 1 {
+		t.Errorf("output:\n%s", stdout.String())
+	}
+}
+
+func TestJanitorUsage(t *testing.T) {
+	for _, args := range [][]string{
+		{"janitor"},
+		{"janitor", "-kind", "model", "-all"},
+		{"janitor", "-older-than", "soon"},
+		{"janitor", "-mime", "image/["},
+		{"janitor", "-all", "extra"},
+	} {
+		var stdout, stderr bytes.Buffer
+		if code := run(t.Context(), args, &stdout, &stderr); code != 2 {
+			t.Errorf("run(%q): exit code %d, want 2", args, code)
+		}
+	}
+}
+
+func TestParseAge(t *testing.T) {
+	for in, want := range map[string]time.Duration{"7d": 7 * 24 * time.Hour, "36h": 36 * time.Hour, "90m": 90 * time.Minute} {
+		if got, err := parseAge(in); err != nil || got != want {
+			t.Errorf("parseAge(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "d", "-1d", "-1h", "1w"} {
+		if _, err := parseAge(in); err == nil {
+			t.Errorf("parseAge(%q) succeeded", in)
+		}
+	}
+}
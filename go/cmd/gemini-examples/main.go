@@ -0,0 +1,472 @@
+// Gemini-examples lists and runs the Go samples by region tag, takes stock
+// of the files and caches of an account, and sweeps the files and caches the
+// samples leave in it.
+//
+// Usage:
+//
+//	go run ./cmd/gemini-examples list [flags]
+//	go run ./cmd/gemini-examples run [flags] tag...
+//	go run ./cmd/gemini-examples run [flags] -all
+//	go run ./cmd/gemini-examples files [flags]
+//	go run ./cmd/gemini-examples caches [flags]
+//	go run ./cmd/gemini-examples janitor [flags]
+//
+// The list subcommand prints the tag, file, required media and labels of
+// each sample: its modalities, tools and features, "streaming" if it
+// streams, and "creates" and "creates-file" or "creates-cache" if it
+// creates remote resources.
+//
+// The run subcommand runs the samples with the given tags, or with -all
+// every sample, against the API configured as described in the README, and
+// prints their output. Samples whose media are missing from third_party
+// fail without being run. The files and caches the samples create and do
+// not delete are deleted at the end, even when the samples fail or the
+// command is interrupted; anything that cannot be deleted is reported. The
+// exit status is 1 if any sample fails or anything is left behind.
+//
+// The flags of list are:
+//
+//	-tag labels
+//		Only list the samples having all the comma-separated labels, such
+//		as video, cache or streaming. A label may list alternatives
+//		separated by "|", as in "image|video".
+//	-format text|json
+//		Output format (default text).
+//
+// The flags of run are:
+//
+//	-all
+//		Run every sample, or with -tag every sample having some labels.
+//	-tag labels
+//		With -all, only run the samples having all the labels, as for list.
+//	-model name
+//		Use model name instead of the samples' own model.
+//	-embedding-model name
+//		Use model name instead of the embedding samples' own model.
+//	-backend gemini|vertex
+//		Backend to use, overriding GEMINI_BACKEND. The samples only run
+//		against the Gemini API, and fail with vertex.
+//	-format text|json
+//		Output format (default text).
+//	-keep
+//		Keep the files and caches the samples leave behind.
+//
+// Flags may follow the tags.
+//
+// The files subcommand lists every file of the account, page by page, and
+// prints those matching all the criteria given, with their count, total
+// size and count by state. Its flags are:
+//
+//	-state list
+//		Only list files in the comma-separated states, such as ACTIVE or
+//		PROCESSING,FAILED.
+//	-mime list
+//		Only list files of the comma-separated MIME types, which may be
+//		patterns such as image/*.
+//	-name pattern
+//		Only list files whose display name matches pattern, as in
+//		path.Match, such as sample-*.
+//	-min-size size, -max-size size
+//		Only list files of at least or at most size bytes. Sizes may have
+//		a unit: KB, MB, GB, or KiB, MiB, GiB.
+//	-created-after time, -created-before time
+//		Only list files created after or before time, given in RFC 3339
+//		or as an age such as 36h or 7d.
+//	-expires-after time, -expires-before time
+//		Only list files expiring after or before time, given in RFC 3339
+//		or as a duration from now such as 6h.
+//	-page-size n
+//		Number of files per list request (default: the API's).
+//	-backend gemini|vertex
+//		Backend to use, overriding GEMINI_BACKEND.
+//	-format text|json|csv
+//		Output format (default text). The CSV output ends with a TOTAL
+//		record.
+//
+// The caches subcommand lists every cache of the account, page by page, and
+// prints those matching all the criteria given, with their remaining TTL,
+// their count, total tokens and count by model. It can then extend the TTL
+// of the caches listed or delete them, in parallel. Its flags are:
+//
+//	-model list
+//		Only list caches of the comma-separated models, with or without
+//		"models/", which may be patterns such as gemini-3.5-*.
+//	-name pattern
+//		Only list caches whose display name matches pattern, as in
+//		path.Match.
+//	-min-tokens n, -max-tokens n
+//		Only list caches of at least or at most n tokens.
+//	-created-after time, -created-before time
+//		Only list caches created after or before time, given in RFC 3339
+//		or as an age such as 36h or 7d.
+//	-expires-after time, -expires-before time
+//		Only list caches expiring after or before time, given in RFC 3339
+//		or as a duration from now such as 10m.
+//	-sort key
+//		Sort by name, display-name, model, created, expires or tokens,
+//		descending if key starts with "-" (default: listing order).
+//	-extend ttl
+//		Set the TTL of the caches listed to ttl, such as 2h.
+//	-delete
+//		Delete the caches listed.
+//	-all
+//		Allow -extend or -delete without any criterion. Without it, one of
+//		the criteria above is required for them.
+//	-dry-run
+//		Only report what -extend or -delete would do.
+//	-parallel n
+//		Number of concurrent updates or deletions (default 4).
+//	-page-size n
+//		Number of caches per list request (default: the API's).
+//	-backend gemini|vertex
+//		Backend to use, overriding GEMINI_BACKEND.
+//	-format text|json
+//		Output format (default text).
+//
+// The exit status of caches is 1 if any cache could not be extended or
+// deleted.
+//
+// The janitor subcommand lists every file and cache of the account, page
+// by page, and deletes those matching all the criteria given, in parallel
+// under a rate limit. Its flags are:
+//
+//	-kind file|cache
+//		Only sweep files or caches (default both).
+//	-prefix prefix
+//		Only sweep resources whose display name starts with prefix.
+//	-older-than age
+//		Only sweep resources created more than age ago, such as 36h or 7d.
+//	-mime list
+//		Only sweep files of the comma-separated MIME types, which may be
+//		patterns such as image/*.
+//	-model list
+//		Only sweep caches of the comma-separated models.
+//	-all
+//		Sweep everything of the selected kinds. Without it, one of the
+//		criteria above is required.
+//	-dry-run
+//		Only report what would be deleted.
+//	-parallel n
+//		Number of concurrent deletions (default 4).
+//	-rate n
+//		Maximum deletions per second (default 10), or 0 for no limit.
+//	-backend gemini|vertex
+//		Backend to use, overriding GEMINI_BACKEND.
+//	-format text|json
+//		Output format (default text).
+//
+// The exit status of janitor is 1 if anything could not be deleted.
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	examples "gemini-api-examples"
+	"gemini-api-examples/internal/config"
+	"gemini-api-examples/internal/tracker"
+
+	"google.golang.org/genai"
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	code := run(ctx, os.Args[1:], os.Stdout, os.Stderr)
+	stop()
+	os.Exit(code)
+}
+
+const usage = `usage: gemini-examples list [-tag labels] [-format text|json]
+       gemini-examples run [-model name] [-embedding-model name] [-backend gemini|vertex] [-format text|json] [-keep] [-all [-tag labels]] [tag...]
+       gemini-examples files [-state list] [-mime list] [-name pattern] [-min-size size] [-max-size size] [-created-after time] [-created-before time] [-expires-after time] [-expires-before time] [-page-size n] [-backend gemini|vertex] [-format text|json|csv]
+       gemini-examples caches [-model list] [-name pattern] [-min-tokens n] [-max-tokens n] [-created-after time] [-created-before time] [-expires-after time] [-expires-before time] [-sort key] [-extend ttl | -delete] [-all] [-dry-run] [-parallel n] [-page-size n] [-backend gemini|vertex] [-format text|json]
+       gemini-examples janitor [-kind file|cache] [-prefix p] [-older-than age] [-mime list] [-model list] [-all] [-dry-run] [-parallel n] [-rate n] [-backend gemini|vertex] [-format text|json]
+`
+
+func run(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+	switch args[0] {
+	case "list":
+		return list(args[1:], stdout, stderr)
+	case "run":
+		return runSamples(ctx, args[1:], stdout, stderr)
+	case "files":
+		return files(ctx, args[1:], stdout, stderr)
+	case "caches":
+		return manageCaches(ctx, args[1:], stdout, stderr)
+	case "janitor":
+		return sweep(ctx, args[1:], stdout, stderr)
+	case "help", "-h", "-help", "--help":
+		fmt.Fprint(stdout, usage)
+		return 0
+	}
+	fmt.Fprintf(stderr, "gemini-examples: unknown subcommand %q\n%s", args[0], usage)
+	return 2
+}
+
+// parse parses args with fl, allowing flags to follow positional arguments,
+// and returns the positional arguments.
+func parse(fl *flag.FlagSet, args []string) ([]string, error) {
+	var pos []string
+	for {
+		if err := fl.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fl.Args()
+		if len(args) == 0 {
+			return pos, nil
+		}
+		pos = append(pos, args[0])
+		args = args[1:]
+	}
+}
+
+func checkFormat(format string, stderr io.Writer) bool {
+	if format != "text" && format != "json" {
+		fmt.Fprintf(stderr, "gemini-examples: unknown format %q; use text or json\n", format)
+		return false
+	}
+	return true
+}
+
+// filter returns the samples having all the labels in list, separated by
+// commas, or all of them if list is empty.
+func filter(list string) []*examples.Sample {
+	if list == "" {
+		return examples.Select()
+	}
+	return examples.Select(strings.Split(list, ",")...)
+}
+
+// listEntry is the JSON form of a sample in the output of list.
+type listEntry struct {
+	Tag        string   `json:"tag"`
+	Name       string   `json:"name"`
+	File       string   `json:"file"`
+	Modalities []string `json:"modalities"`
+	Media      []string `json:"media"`
+	Tools      []string `json:"tools"`
+	Creates    []string `json:"creates"`
+	Streams    bool     `json:"streams"`
+	Features   []string `json:"features"`
+}
+
+// strs returns the strings of list, never nil.
+func strs[T ~string](list []T) []string {
+	out := []string{}
+	for _, v := range list {
+		out = append(out, string(v))
+	}
+	return out
+}
+
+func list(args []string, stdout, stderr io.Writer) int {
+	fl := flag.NewFlagSet("list", flag.ContinueOnError)
+	fl.SetOutput(stderr)
+	labels := fl.String("tag", "", "only list the samples having these comma-separated labels")
+	format := fl.String("format", "text", "output format: text or json")
+	pos, err := parse(fl, args)
+	if err != nil {
+		return 2
+	}
+	if len(pos) > 0 {
+		fmt.Fprintf(stderr, "gemini-examples: unexpected arguments %q\n", pos)
+		return 2
+	}
+	if !checkFormat(*format, stderr) {
+		return 2
+	}
+	samples := filter(*labels)
+	if *format == "json" {
+		entries := []listEntry{}
+		for _, s := range samples {
+			entries = append(entries, listEntry{
+				Tag:        s.Tag,
+				Name:       s.Name,
+				File:       s.File,
+				Modalities: strs(s.Modalities),
+				Media:      strs(s.Media),
+				Tools:      strs(s.Tools),
+				Creates:    strs(s.Creates),
+				Streams:    s.Streams,
+				Features:   strs(s.Features),
+			})
+		}
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(entries)
+	} else {
+		tw := tabwriter.NewWriter(stdout, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(tw, "TAG\tFILE\tMEDIA\tLABELS")
+		for _, s := range samples {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", s.Tag, s.File, orDash(s.Media), orDash(s.Labels()))
+		}
+		err = tw.Flush()
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "gemini-examples: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func orDash(list []string) string {
+	if len(list) == 0 {
+		return "-"
+	}
+	return strings.Join(list, ",")
+}
+
+// result is the outcome of running one sample.
+type result struct {
+	Tag        string `json:"tag"`
+	Name       string `json:"name"`
+	OK         bool   `json:"ok"`
+	Error      string `json:"error,omitempty"`
+	Output     string `json:"output"`
+	DurationMS int64  `json:"durationMs"`
+}
+
+func runSamples(ctx context.Context, args []string, stdout, stderr io.Writer) (code int) {
+	fl := flag.NewFlagSet("run", flag.ContinueOnError)
+	fl.SetOutput(stderr)
+	all := fl.Bool("all", false, "run every sample, or every sample having the -tag labels")
+	labels := fl.String("tag", "", "with -all, only run the samples having these comma-separated labels")
+	model := fl.String("model", "", "model to use instead of the samples' own")
+	embeddingModel := fl.String("embedding-model", "", "model to use instead of that of the embedding samples")
+	backend := fl.String("backend", "", "backend: gemini or vertex (default: from the configuration)")
+	format := fl.String("format", "text", "output format: text or json")
+	keep := fl.Bool("keep", false, "keep the files and caches the samples leave behind")
+	tags, err := parse(fl, args)
+	if err != nil {
+		return 2
+	}
+	if !checkFormat(*format, stderr) {
+		return 2
+	}
+	var samples []*examples.Sample
+	switch {
+	case *all && len(tags) > 0:
+		fmt.Fprintln(stderr, "gemini-examples: -all takes no tags")
+		return 2
+	case *all:
+		samples = filter(*labels)
+	case *labels != "":
+		fmt.Fprintln(stderr, "gemini-examples: -tag needs -all")
+		return 2
+	case len(tags) == 0:
+		fmt.Fprintf(stderr, "gemini-examples: no samples to run\n%s", usage)
+		return 2
+	}
+	for _, tag := range tags {
+		s := examples.SampleByTag(tag)
+		if s == nil {
+			fmt.Fprintf(stderr, "gemini-examples: unknown sample %q; see gemini-examples list\n", tag)
+			return 2
+		}
+		samples = append(samples, s)
+	}
+
+	client, err := newClient(ctx, *backend)
+	if err == nil {
+		client, err = examples.OverrideModels(ctx, client, *model, *embeddingModel)
+	}
+	// resources records the files and caches the samples create.
+	resources := tracker.New()
+	if err == nil {
+		client, err = resources.Client(ctx, client)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "gemini-examples: %v\n", err)
+		return 1
+	}
+	if !*keep {
+		defer func() {
+			// Clean up even if ctx was canceled by an interrupt.
+			ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), time.Minute)
+			defer cancel()
+			if err := resources.Cleanup(ctx, client); err != nil {
+				fmt.Fprintf(stderr, "gemini-examples: %v\n", err)
+				code = 1
+			}
+		}()
+	}
+	results := []result{}
+	failed := 0
+	for _, s := range samples {
+		var out bytes.Buffer
+		w := io.Writer(&out)
+		if *format == "text" {
+			fmt.Fprintf(stdout, "=== %s\n", s.Tag)
+			w = stdout
+		}
+		start := time.Now()
+		err := runSample(ctx, client, s, w)
+		r := result{
+			Tag:        s.Tag,
+			Name:       s.Name,
+			OK:         err == nil,
+			Output:     out.String(),
+			DurationMS: time.Since(start).Milliseconds(),
+		}
+		if err != nil {
+			r.Error = err.Error()
+			failed++
+		}
+		results = append(results, r)
+		if *format == "text" {
+			if err != nil {
+				fmt.Fprintf(stdout, "--- FAIL: %s (%dms): %v\n", s.Tag, r.DurationMS, err)
+			} else {
+				fmt.Fprintf(stdout, "--- ok: %s (%dms)\n", s.Tag, r.DurationMS)
+			}
+		}
+	}
+	if *format == "json" {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(results); err != nil {
+			fmt.Fprintf(stderr, "gemini-examples: %v\n", err)
+			return 1
+		}
+	} else if len(samples) > 1 {
+		fmt.Fprintf(stdout, "%d passed, %d failed\n", len(samples)-failed, failed)
+	}
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// runSample runs s unless media it needs are missing.
+func runSample(ctx context.Context, client *genai.Client, s *examples.Sample, w io.Writer) error {
+	if missing := s.MissingMedia(); len(missing) > 0 {
+		return fmt.Errorf("missing media in third_party: %s", strings.Join(missing, ", "))
+	}
+	return s.Run(ctx, client, w)
+}
+
+// newClient returns a client for the configuration from the environment,
+// with the given backend override when not empty.
+func newClient(ctx context.Context, backend string) (*genai.Client, error) {
+	cfg, err := config.FromEnv()
+	if err != nil {
+		return nil, err
+	}
+	if backend != "" {
+		cfg.Backend = backend
+	}
+	return cfg.NewClient(ctx)
+}
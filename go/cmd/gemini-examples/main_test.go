@@ -0,0 +1,246 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+
+	examples "gemini-api-examples"
+	"gemini-api-examples/internal/config"
+	"gemini-api-examples/internal/fakegemini"
+
+	"google.golang.org/genai"
+)
+
+// useFakeServer points the client of the command at a fake server, which
+// also serves gemini-test and embedding-test models.
+func useFakeServer(t *testing.T) *fakegemini.Server {
+	t.Helper()
+	srv := fakegemini.New()
+	srv.AddModel(&fakegemini.Model{
+		Name:                       "models/gemini-test",
+		BaseModelID:                "gemini-test",
+		SupportedGenerationMethods: []string{"generateContent", "countTokens", "createCachedContent"},
+	})
+	srv.AddModel(&fakegemini.Model{
+		Name:                       "models/embedding-test",
+		BaseModelID:                "embedding-test",
+		SupportedGenerationMethods: []string{"embedContent"},
+	})
+	t.Cleanup(srv.Close)
+	t.Setenv("GEMINI_CONFIG", "")
+	t.Setenv("GEMINI_BACKEND", config.BackendGeminiAPI)
+	t.Setenv("GEMINI_API_KEY", "fake")
+	t.Setenv("GEMINI_BASE_URL", srv.URL)
+	return srv
+}
+
+func TestList(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := run(t.Context(), []string{"list"}, &stdout, &stderr); code != 0 {
+		t.Fatalf("exit code %d; stderr:\n%s", code, stderr.String())
+	}
+	lines := strings.Split(strings.TrimSuffix(stdout.String(), "\n"), "\n")
+	if got := strings.Fields(lines[0]); strings.Join(got, " ") != "TAG FILE MEDIA LABELS" {
+		t.Errorf("header = %q", lines[0])
+	}
+	if len(lines) != len(examples.Samples)+1 {
+		t.Errorf("got %d samples, want %d", len(lines)-1, len(examples.Samples))
+	}
+	want := "text_gen_multimodal_video_prompt text_generation.go Big_Buck_Bunny.mp4 text,video,creates,creates-file,files"
+	found := false
+	for _, l := range lines {
+		found = found || strings.Join(strings.Fields(l), " ") == want
+	}
+	if !found {
+		t.Errorf("no line %q in:\n%s", want, stdout.String())
+	}
+}
+
+func TestListFeatureJSON(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := run(t.Context(), []string{"list", "-format", "json", "-tag", "video,streaming"}, &stdout, &stderr); code != 0 {
+		t.Fatalf("exit code %d; stderr:\n%s", code, stderr.String())
+	}
+	var entries []listEntry
+	if err := json.Unmarshal(stdout.Bytes(), &entries); err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) == 0 {
+		t.Fatal("no streaming video samples listed")
+	}
+	for _, e := range entries {
+		if !slices.Contains(e.Modalities, "video") || !e.Streams {
+			t.Errorf("%s listed, with modalities %q and streams %v", e.Tag, e.Modalities, e.Streams)
+		}
+	}
+}
+
+func TestRunModel(t *testing.T) {
+	srv := useFakeServer(t)
+	var stdout, stderr bytes.Buffer
+	args := []string{"run", "text_gen_text_only_prompt", "-model", "gemini-test"}
+	if code := run(t.Context(), args, &stdout, &stderr); code != 0 {
+		t.Fatalf("exit code %d; stderr:\n%s\nstdout:\n%s", code, stderr.String(), stdout.String())
+	}
+	out := stdout.String()
+	if !strings.HasPrefix(out, "=== text_gen_text_only_prompt\n") || !strings.Contains(out, "--- ok: text_gen_text_only_prompt") {
+		t.Errorf("output:\n%s", out)
+	}
+	reqs := srv.RequestsTo(http.MethodPost, "models/gemini-test:generateContent")
+	if len(reqs) != 1 {
+		t.Errorf("got %d requests to the model given with -model, want 1", len(reqs))
+	}
+}
+
+func TestRunCacheModel(t *testing.T) {
+	srv := useFakeServer(t)
+	var stdout, stderr bytes.Buffer
+	args := []string{"run", "-model", "gemini-test", "cache_create"}
+	if code := run(t.Context(), args, &stdout, &stderr); code != 0 {
+		t.Fatalf("exit code %d; stderr:\n%s\nstdout:\n%s", code, stderr.String(), stdout.String())
+	}
+	reqs := srv.RequestsTo(http.MethodPost, "cachedContents")
+	if len(reqs) != 1 {
+		t.Fatalf("got %d cache creations, want 1", len(reqs))
+	}
+	var body struct{ Model string }
+	if err := json.Unmarshal(reqs[0].Body, &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Model != "models/gemini-test" {
+		t.Errorf("cache created for model %q, want models/gemini-test", body.Model)
+	}
+}
+
+func TestRunEmbeddingModel(t *testing.T) {
+	srv := useFakeServer(t)
+	var stdout, stderr bytes.Buffer
+	args := []string{"run", "-model", "gemini-test", "-embedding-model", "embedding-test", "embed_content", "batch_embed_contents"}
+	if code := run(t.Context(), args, &stdout, &stderr); code != 0 {
+		t.Fatalf("exit code %d; stderr:\n%s\nstdout:\n%s", code, stderr.String(), stdout.String())
+	}
+	// The SDK sends both samples' requests to batchEmbedContents.
+	if n := len(srv.RequestsTo(http.MethodPost, "models/embedding-test:batchEmbedContents")); n != 2 {
+		t.Errorf("got %d requests to the model given with -embedding-model, want 2", n)
+	}
+	if n := len(srv.RequestsTo(http.MethodPost, "models/gemini-test:")); n != 0 {
+		t.Errorf("%d embedding requests to the model given with -model", n)
+	}
+}
+
+func TestRunAllFeature(t *testing.T) {
+	useFakeServer(t)
+	var stdout, stderr bytes.Buffer
+	args := []string{"run", "-all", "-tag", "cache", "-format", "json"}
+	if code := run(t.Context(), args, &stdout, &stderr); code != 0 {
+		t.Errorf("exit code %d; stderr:\n%s\nstdout:\n%s", code, stderr.String(), stdout.String())
+	}
+	var results []result
+	if err := json.Unmarshal(stdout.Bytes(), &results); err != nil {
+		t.Fatal(err)
+	}
+	var want []string
+	for _, s := range examples.Samples {
+		if s.HasLabel("cache") {
+			want = append(want, s.Tag)
+		}
+	}
+	if len(results) != len(want) {
+		t.Fatalf("ran %d samples, want %d", len(results), len(want))
+	}
+	for i, r := range results {
+		if r.Tag != want[i] || !r.OK || r.Output == "" {
+			t.Errorf("result %d = %+v, want an ok run of %s with output", i, r, want[i])
+		}
+	}
+}
+
+func TestRunMissingMedia(t *testing.T) {
+	srv := useFakeServer(t)
+	var s *examples.Sample
+	for i := range examples.Samples {
+		if len(examples.Samples[i].MissingMedia()) > 0 {
+			s = &examples.Samples[i]
+			break
+		}
+	}
+	if s == nil {
+		t.Skip("no sample lacks its media")
+	}
+	var stdout, stderr bytes.Buffer
+	if code := run(t.Context(), []string{"run", s.Tag}, &stdout, &stderr); code != 1 {
+		t.Errorf("exit code %d, want 1", code)
+	}
+	if !strings.Contains(stdout.String(), "--- FAIL: "+s.Tag) || !strings.Contains(stdout.String(), "missing media") {
+		t.Errorf("output:\n%s", stdout.String())
+	}
+	if n := len(srv.Requests()); n != 0 {
+		t.Errorf("sample sent %d requests without its media", n)
+	}
+}
+
+func TestUsage(t *testing.T) {
+	for _, args := range [][]string{
+		nil,
+		{"frobnicate"},
+		{"run"},
+		{"run", "no_such_tag"},
+		{"run", "-all", "cache_create"},
+		{"run", "-tag", "video"},
+		{"run", "-format", "yaml", "cache_create"},
+		{"list", "extra"},
+	} {
+		var stdout, stderr bytes.Buffer
+		if code := run(t.Context(), args, &stdout, &stderr); code != 2 {
+			t.Errorf("run(%q): exit code %d, want 2", args, code)
+		}
+		if stderr.Len() == 0 {
+			t.Errorf("run(%q): nothing on standard error", args)
+		}
+	}
+}
+
+func TestRunCleanup(t *testing.T) {
+	srv := useFakeServer(t)
+	// files_create_text keeps its file; cache_create fails before deleting
+	// its cache and file.
+	srv.Inject(":generateContent", 2, fakegemini.ServerError(http.StatusInternalServerError))
+	var stdout, stderr bytes.Buffer
+	args := []string{"run", "files_create_text", "cache_create"}
+	if code := run(t.Context(), args, &stdout, &stderr); code != 1 {
+		t.Errorf("exit code %d, want 1", code)
+	}
+	if files, caches := srv.Files(), srv.Caches(); len(files) != 0 || len(caches) != 0 {
+		t.Errorf("left on the server: %d files, %d caches", len(files), len(caches))
+	}
+
+	srv.Inject(":generateContent", 1, fakegemini.ServerError(http.StatusInternalServerError))
+	stdout.Reset()
+	if code := run(t.Context(), []string{"run", "-keep", "files_create_text"}, &stdout, &stderr); code != 1 {
+		t.Errorf("exit code %d, want 1", code)
+	}
+	if n := len(srv.Files()); n != 1 {
+		t.Errorf("%d files on the server with -keep, want 1", n)
+	}
+}
+
+func TestRunLeak(t *testing.T) {
+	srv := useFakeServer(t)
+	srv.OnGenerate(func(req *fakegemini.GenerateRequest) (*genai.GenerateContentResponse, error) {
+		// Make the deletion of the uploaded file fail.
+		files := srv.Files()
+		srv.Inject(files[len(files)-1].Name, 0, fakegemini.ServerError(http.StatusServiceUnavailable))
+		return fakegemini.TextResponse("ok"), nil
+	})
+	var stdout, stderr bytes.Buffer
+	if code := run(t.Context(), []string{"run", "files_create_text"}, &stdout, &stderr); code != 1 {
+		t.Errorf("exit code %d, want 1 when a file cannot be deleted", code)
+	}
+	if !strings.Contains(stderr.String(), "could not delete 1 resources") {
+		t.Errorf("stderr:\n%s", stderr.String())
+	}
+}
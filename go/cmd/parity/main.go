@@ -0,0 +1,235 @@
+// Parity compares the region tags of the samples across languages, and fails
+// when the Go samples fall behind the others.
+//
+// Usage:
+//
+//	go run ./cmd/parity [flags]
+//
+// It builds the inventory of tags in each language and reports, for the base
+// language (Go by default):
+//
+//   - missing tags: tags other languages have that the base language lacks;
+//   - extra tags: tags only the base language has;
+//   - mismatched tags: tags whose markers are malformed in some language,
+//     such as a START closed by an END naming another tag.
+//
+// The exit status is 1 when tags are missing from the base language, other
+// than those listed with -allow, and 0 otherwise.
+//
+// The flags are:
+//
+//	-root dir
+//		Repository root. By default, the nearest directory at or above the
+//		current one that has a go/go.mod file.
+//	-base lang
+//		Language checked against the others (default go).
+//	-lang list
+//		Comma-separated languages to compare: go, python, javascript, java
+//		and rest. By default, all of them.
+//	-allow list
+//		Comma-separated tags the base language may lack.
+//	-format text|json
+//		Output format (default text).
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"gemini-api-examples/internal/region"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// Report is the result of a comparison.
+type Report struct {
+	Base string `json:"base"`
+	// Counts holds the number of tags of each language.
+	Counts map[string]int `json:"counts"`
+	// Missing holds the tags the base language lacks, with the languages
+	// that have them.
+	Missing []Tag `json:"missing"`
+	// Allowed holds the missing tags excused with -allow.
+	Allowed []Tag `json:"allowed"`
+	// Extra holds the tags only the base language has.
+	Extra []string `json:"extra"`
+	// Mismatched holds the malformed regions found.
+	Mismatched []Mismatch `json:"mismatched"`
+}
+
+// Tag is a tag and the languages that have it.
+type Tag struct {
+	Tag       string   `json:"tag"`
+	Languages []string `json:"languages"`
+}
+
+// Mismatch is a malformed region in one language.
+type Mismatch struct {
+	Tag      string `json:"tag"`
+	Language string `json:"language"`
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+	Problem  string `json:"problem"`
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	fl := flag.NewFlagSet("parity", flag.ContinueOnError)
+	fl.SetOutput(stderr)
+	root := fl.String("root", "", "repository root (default: found from the current directory)")
+	base := fl.String("base", "go", "language checked against the others")
+	langList := fl.String("lang", "", "comma-separated languages (default: all)")
+	allow := fl.String("allow", "", "comma-separated tags the base language may lack")
+	format := fl.String("format", "text", "output format: text or json")
+	if err := fl.Parse(args); err != nil {
+		return 2
+	}
+	if fl.NArg() > 0 {
+		fmt.Fprintf(stderr, "parity: unexpected arguments %q\n", fl.Args())
+		return 2
+	}
+	if *format != "text" && *format != "json" {
+		fmt.Fprintf(stderr, "parity: unknown format %q; use text or json\n", *format)
+		return 2
+	}
+	langs, err := region.ParseLanguages(*langList)
+	if err != nil {
+		fmt.Fprintf(stderr, "parity: %v\n", err)
+		return 2
+	}
+	if !slices.ContainsFunc(langs, func(l region.Language) bool { return l.Name == *base }) {
+		fmt.Fprintf(stderr, "parity: base language %q is not among those compared\n", *base)
+		return 2
+	}
+	if *root == "" {
+		if *root, err = region.FindRoot(); err != nil {
+			fmt.Fprintf(stderr, "parity: %v\n", err)
+			return 1
+		}
+	}
+
+	regions, problems, err := region.Extract(*root, langs)
+	if err != nil {
+		fmt.Fprintf(stderr, "parity: %v\n", err)
+		return 1
+	}
+	var allowed []string
+	if *allow != "" {
+		allowed = strings.Split(*allow, ",")
+	}
+	r := compare(*base, langs, regions, problems, allowed)
+	if *format == "json" {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(r)
+	} else {
+		err = r.writeText(stdout)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "parity: %v\n", err)
+		return 1
+	}
+	if len(r.Missing) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// compare builds the report for base from the regions and problems found in
+// langs.
+func compare(base string, langs []region.Language, regions []region.Region, problems []region.Problem, allow []string) *Report {
+	r := &Report{
+		Base:       base,
+		Counts:     map[string]int{},
+		Missing:    []Tag{},
+		Allowed:    []Tag{},
+		Extra:      []string{},
+		Mismatched: []Mismatch{},
+	}
+	for _, l := range langs {
+		r.Counts[l.Name] = 0
+	}
+	have := map[string][]string{}
+	var tags []string
+	for _, reg := range regions {
+		if have[reg.Tag] == nil {
+			tags = append(tags, reg.Tag)
+		}
+		have[reg.Tag] = append(have[reg.Tag], reg.Language)
+		r.Counts[reg.Language]++
+	}
+	// Regions are sorted by tag, and then by language.
+	for _, tag := range tags {
+		ls := have[tag]
+		switch {
+		case !slices.Contains(ls, base):
+			t := Tag{Tag: tag, Languages: ls}
+			if slices.Contains(allow, tag) {
+				r.Allowed = append(r.Allowed, t)
+			} else {
+				r.Missing = append(r.Missing, t)
+			}
+		case len(ls) == 1 && len(langs) > 1:
+			r.Extra = append(r.Extra, tag)
+		}
+	}
+	langOf := func(file string) string {
+		for _, l := range langs {
+			if strings.HasPrefix(file, l.Dir+"/") {
+				return l.Name
+			}
+		}
+		return ""
+	}
+	for _, p := range problems {
+		r.Mismatched = append(r.Mismatched, Mismatch{
+			Tag:      p.Tag,
+			Language: langOf(p.File),
+			File:     p.File,
+			Line:     p.Line,
+			Problem:  p.Msg,
+		})
+	}
+	slices.SortStableFunc(r.Mismatched, func(a, b Mismatch) int { return strings.Compare(a.Tag, b.Tag) })
+	return r
+}
+
+func (r *Report) writeText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	var counts []string
+	for _, l := range region.Languages {
+		if n, ok := r.Counts[l.Name]; ok {
+			counts = append(counts, fmt.Sprintf("%s %d", l.Name, n))
+		}
+	}
+	fmt.Fprintf(tw, "Tags per language: %s\n", strings.Join(counts, ", "))
+	section := func(title string, n int) {
+		fmt.Fprintf(tw, "\n%s: %d\n", title, n)
+	}
+	section(fmt.Sprintf("Missing from %s", r.Base), len(r.Missing))
+	for _, t := range r.Missing {
+		fmt.Fprintf(tw, "  %s\t%s\n", t.Tag, strings.Join(t.Languages, " "))
+	}
+	if len(r.Allowed) > 0 {
+		section(fmt.Sprintf("Missing from %s, allowed", r.Base), len(r.Allowed))
+		for _, t := range r.Allowed {
+			fmt.Fprintf(tw, "  %s\t%s\n", t.Tag, strings.Join(t.Languages, " "))
+		}
+	}
+	section(fmt.Sprintf("Only in %s", r.Base), len(r.Extra))
+	for _, tag := range r.Extra {
+		fmt.Fprintf(tw, "  %s\n", tag)
+	}
+	section("Mismatched", len(r.Mismatched))
+	for _, m := range r.Mismatched {
+		fmt.Fprintf(tw, "  %s\t%s\t%s:%d: %s\n", m.Tag, m.Language, m.File, m.Line, m.Problem)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestBehind(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"-root", "testdata/behind"}, &stdout, &stderr); code != 1 {
+		t.Errorf("exit code %d, want 1 with tags missing from go; stderr:\n%s", code, stderr.String())
+	}
+	want := `Tags per language: go 2, python 3, javascript 1, java 0, rest 1
+
+Missing from go: 2
+  code_execution_chat  python javascript
+  grounding_maps       python rest
+
+Only in go: 1
+  go_only
+
+Mismatched: 2
+  code_execution_chat_return          javascript  javascript/samples.js:5: region code_execution_chat_return has no END
+  code_execution_request_chat_return  javascript  javascript/samples.js:7: END code_execution_request_chat_return without a START
+`
+	if got := stdout.String(); got != want {
+		t.Errorf("report:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestAllow(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	args := []string{"-root", "testdata/behind", "-allow", "grounding_maps,code_execution_chat", "-format", "json"}
+	if code := run(args, &stdout, &stderr); code != 0 {
+		t.Errorf("exit code %d, want 0 with every missing tag allowed; stderr:\n%s", code, stderr.String())
+	}
+	var r Report
+	if err := json.Unmarshal(stdout.Bytes(), &r); err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Missing) != 0 || len(r.Allowed) != 2 || r.Allowed[1].Tag != "grounding_maps" {
+		t.Errorf("report = %+v", r)
+	}
+}
+
+func TestOtherBase(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	args := []string{"-root", "testdata/behind", "-base", "rest", "-lang", "rest,python", "-format", "json"}
+	if code := run(args, &stdout, &stderr); code != 1 {
+		t.Errorf("exit code %d, want 1", code)
+	}
+	var r Report
+	if err := json.Unmarshal(stdout.Bytes(), &r); err != nil {
+		t.Fatal(err)
+	}
+	var missing []string
+	for _, m := range r.Missing {
+		missing = append(missing, m.Tag)
+	}
+	if len(missing) != 2 || missing[0] != "code_execution_chat" || missing[1] != "embed_content" {
+		t.Errorf("missing = %v, want code_execution_chat and embed_content", missing)
+	}
+	if len(r.Extra) != 0 || len(r.Counts) != 2 {
+		t.Errorf("report = %+v", r)
+	}
+}
+
+func TestEven(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"-root", "testdata/even"}, &stdout, &stderr); code != 0 {
+		t.Errorf("exit code %d, want 0; output:\n%s%s", code, stdout.String(), stderr.String())
+	}
+}
+
+func TestBadFlags(t *testing.T) {
+	for _, args := range [][]string{
+		{"-format", "yaml"},
+		{"-lang", "cobol"},
+		{"-base", "java", "-lang", "go,python"},
+		{"extra"},
+	} {
+		var stdout, stderr bytes.Buffer
+		if code := run(args, &stdout, &stderr); code != 2 {
+			t.Errorf("run(%q) = %d, want 2", args, code)
+		}
+	}
+}
@@ -0,0 +1,10 @@
+package samples
+
+func Samples() {
+	// [START embed_content]
+	embed()
+	// [END embed_content]
+	// [START go_only]
+	goOnly()
+	// [END go_only]
+}
@@ -0,0 +1,8 @@
+export async function samples() {
+  // [START code_execution_chat]
+  chat();
+  // [END code_execution_chat]
+  // [START code_execution_chat_return]
+  return chat();
+  // [END code_execution_request_chat_return]
+}
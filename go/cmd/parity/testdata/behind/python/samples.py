@@ -0,0 +1,10 @@
+def test_samples(self):
+    # [START embed_content]
+    embed()
+    # [END embed_content]
+    # [START grounding_maps]
+    ground()
+    # [END grounding_maps]
+    # [START code_execution_chat]
+    chat()
+    # [END code_execution_chat]
@@ -0,0 +1,3 @@
+# [START grounding_maps]
+curl "$BASE_URL/models/gemini-3.5-flash:generateContent"
+# [END grounding_maps]
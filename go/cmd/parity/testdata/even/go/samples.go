@@ -0,0 +1,7 @@
+package samples
+
+func Samples() {
+	// [START embed_content]
+	embed()
+	// [END embed_content]
+}
@@ -0,0 +1,4 @@
+def test_samples(self):
+    # [START embed_content]
+    embed()
+    # [END embed_content]
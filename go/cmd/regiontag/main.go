@@ -0,0 +1,61 @@
+// Regiontag checks the region tags of the Go samples; see the regiontag
+// analyzer in internal/regiontag for the rules. Run it through go vet:
+//
+//	go build -o /tmp/regiontag ./cmd/regiontag
+//	go vet -vettool=/tmp/regiontag ./...
+//
+// or on its own, which runs go vet with itself as the tool:
+//
+//	go run ./cmd/regiontag ./...
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+
+	"gemini-api-examples/internal/regiontag"
+	"golang.org/x/tools/go/analysis/singlechecker"
+)
+
+func main() {
+	if args := os.Args[1:]; !fromVet(args) {
+		os.Exit(vet(args))
+	}
+	singlechecker.Main(regiontag.Analyzer)
+}
+
+// fromVet reports whether go vet is running the command, with the
+// configuration file of a package or one of the flags it queries the tool
+// with. Loading the packages itself, the command would read the export data
+// of their dependencies, which the toolchain may write in a format the
+// analysis framework cannot read, whereas go vet hands them over already
+// type-checked.
+func fromVet(args []string) bool {
+	return slices.ContainsFunc(args, func(a string) bool {
+		return strings.HasSuffix(a, ".cfg") || a == "-flags" || strings.HasPrefix(a, "-V")
+	})
+}
+
+// vet runs go vet on args with the command as the tool and returns its exit
+// code.
+func vet(args []string) int {
+	self, err := os.Executable()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "regiontag: %v\n", err)
+		return 1
+	}
+	cmd := exec.Command("go", append([]string{"vet", "-vettool=" + self}, args...)...)
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	var exitErr *exec.ExitError
+	if err := cmd.Run(); errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	} else if err != nil {
+		fmt.Fprintf(os.Stderr, "regiontag: %v\n", err)
+		return 1
+	}
+	return 0
+}
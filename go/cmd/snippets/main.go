@@ -0,0 +1,204 @@
+// Snippets extracts the region-tagged snippets of the samples in every
+// language of the repository, so that documentation can embed the exact
+// code that is tested.
+//
+// Usage:
+//
+//	go run ./cmd/snippets [flags]
+//
+// The output maps each region tag to its snippet in each language that has
+// it, as JSON:
+//
+//	{"cache_create": {"go": {"file": "go/cache.go", "startLine": 24, "endLine": 67, "code": "..."}}}
+//
+// or, with -format markdown, as one section per tag with a fenced code block
+// per language. Malformed regions are reported on standard error and left
+// out; with -strict they also make the command fail.
+//
+// The flags are:
+//
+//	-root dir
+//		Repository root. By default, the nearest directory at or above the
+//		current one that has a go/go.mod file.
+//	-format json|markdown
+//		Output format (default json).
+//	-lang list
+//		Comma-separated languages to extract: go, python, javascript, java
+//		and rest. By default, all of them.
+//	-tag pattern
+//		Only extract tags matching the pattern, as in path.Match, for
+//		example "cache_*".
+//	-label list
+//		Only extract the tags of the Go samples having all the
+//		comma-separated labels of the sample registry, for example
+//		"streaming" or "creates-file,video".
+//	-o file
+//		Write the output to file instead of standard output.
+//	-strict
+//		Exit with status 1 if any region is malformed.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	examples "gemini-api-examples"
+	"gemini-api-examples/internal/region"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	fl := flag.NewFlagSet("snippets", flag.ContinueOnError)
+	fl.SetOutput(stderr)
+	root := fl.String("root", "", "repository root (default: found from the current directory)")
+	format := fl.String("format", "json", "output format: json or markdown")
+	langList := fl.String("lang", "", "comma-separated languages (default: all)")
+	tag := fl.String("tag", "", "only extract tags matching this pattern")
+	labels := fl.String("label", "", "only extract the tags of Go samples having these comma-separated labels")
+	out := fl.String("o", "", "output file (default: standard output)")
+	strict := fl.Bool("strict", false, "fail if any region is malformed")
+	if err := fl.Parse(args); err != nil {
+		return 2
+	}
+	if fl.NArg() > 0 {
+		fmt.Fprintf(stderr, "snippets: unexpected arguments %q\n", fl.Args())
+		return 2
+	}
+	if *format != "json" && *format != "markdown" {
+		fmt.Fprintf(stderr, "snippets: unknown format %q; use json or markdown\n", *format)
+		return 2
+	}
+	if _, err := path.Match(*tag, ""); err != nil {
+		fmt.Fprintf(stderr, "snippets: bad -tag pattern %q: %v\n", *tag, err)
+		return 2
+	}
+	langs, err := region.ParseLanguages(*langList)
+	if err != nil {
+		fmt.Fprintf(stderr, "snippets: %v\n", err)
+		return 2
+	}
+	if *root == "" {
+		if *root, err = region.FindRoot(); err != nil {
+			fmt.Fprintf(stderr, "snippets: %v\n", err)
+			return 1
+		}
+	}
+
+	regions, problems, err := region.Extract(*root, langs)
+	if err != nil {
+		fmt.Fprintf(stderr, "snippets: %v\n", err)
+		return 1
+	}
+	for _, p := range problems {
+		fmt.Fprintln(stderr, p)
+	}
+	if *tag != "" {
+		var kept []region.Region
+		for _, r := range regions {
+			if ok, _ := path.Match(*tag, r.Tag); ok {
+				kept = append(kept, r)
+			}
+		}
+		regions = kept
+	}
+	if *labels != "" {
+		tags := map[string]bool{}
+		for _, s := range examples.Select(strings.Split(*labels, ",")...) {
+			tags[s.Tag] = true
+		}
+		var kept []region.Region
+		for _, r := range regions {
+			if tags[r.Tag] {
+				kept = append(kept, r)
+			}
+		}
+		regions = kept
+	}
+
+	write := writeJSON
+	if *format == "markdown" {
+		write = writeMarkdown
+	}
+	if *out == "" {
+		err = write(stdout, regions)
+	} else {
+		err = writeFile(*out, write, regions)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "snippets: %v\n", err)
+		return 1
+	}
+	if *strict && len(problems) > 0 {
+		fmt.Fprintf(stderr, "snippets: %d malformed regions\n", len(problems))
+		return 1
+	}
+	return 0
+}
+
+func writeFile(name string, write func(io.Writer, []region.Region) error, regions []region.Region) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	if err := write(f, regions); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// writeJSON writes regions keyed by tag and language. Two regions with the
+// same tag and language, which region.Extract reports as a problem and
+// leaves out, are an error rather than one overwriting the other.
+func writeJSON(w io.Writer, regions []region.Region) error {
+	byTag := map[string]map[string]region.Region{}
+	for _, r := range regions {
+		if byTag[r.Tag] == nil {
+			byTag[r.Tag] = map[string]region.Region{}
+		}
+		if prev, ok := byTag[r.Tag][r.Language]; ok {
+			return fmt.Errorf("%s:%d: duplicate %s region %s; first defined at %s:%d", r.File, r.StartLine, r.Language, r.Tag, prev.File, prev.StartLine)
+		}
+		byTag[r.Tag][r.Language] = r
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(byTag)
+}
+
+func writeMarkdown(w io.Writer, regions []region.Region) error {
+	var b strings.Builder
+	for i, r := range regions {
+		if i == 0 || regions[i-1].Tag != r.Tag {
+			fmt.Fprintf(&b, "## %s\n\n", r.Tag)
+		}
+		lang, _ := region.LanguageByName(r.Language)
+		fmt.Fprintf(&b, "### %s\n\n", lang.Title)
+		if first, last := r.StartLine+1, r.EndLine-1; first == last {
+			fmt.Fprintf(&b, "From `%s`, line %d:\n\n", r.File, first)
+		} else {
+			fmt.Fprintf(&b, "From `%s`, lines %d-%d:\n\n", r.File, first, last)
+		}
+		fence := fenceFor(r.Code)
+		fmt.Fprintf(&b, "%s%s\n%s%s\n\n", fence, lang.Fence, r.Code, fence)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// fenceFor returns a code fence longer than any run of backticks in code.
+func fenceFor(code string) string {
+	fence := "```"
+	for strings.Contains(code, fence) {
+		fence += "`"
+	}
+	return fence
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"gemini-api-examples/internal/region"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+func TestGolden(t *testing.T) {
+	for _, format := range []string{"json", "markdown"} {
+		t.Run(format, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			code := run([]string{"-root", "testdata/repo", "-format", format}, &stdout, &stderr)
+			if code != 0 {
+				t.Fatalf("exit code %d; stderr:\n%s", code, stderr.String())
+			}
+			golden := filepath.Join("testdata", "want."+format)
+			if *update {
+				if err := os.WriteFile(golden, stdout.Bytes(), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := stdout.String(); got != string(want) {
+				t.Errorf("output differs from %s; run with -update to see the diff\n%s", golden, got)
+			}
+		})
+	}
+}
+
+func TestProblems(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"-root", "testdata/repo", "-lang", "javascript"}, &stdout, &stderr); code != 0 {
+		t.Fatalf("exit code %d, want 0 without -strict", code)
+	}
+	want := "javascript/sample.js:6: region unclosed has no END\n" +
+		"javascript/sample.js:8: START unclosed while the region started at line 6 is open; should this be END?\n" +
+		"javascript/sample.ts:2: duplicate region hello; first defined at javascript/sample.js:2\n"
+	if got := stderr.String(); got != want {
+		t.Errorf("stderr = %q, want %q", got, want)
+	}
+
+	stderr.Reset()
+	if code := run([]string{"-root", "testdata/repo", "-strict"}, &stdout, &stderr); code != 1 {
+		t.Errorf("exit code %d with -strict, want 1", code)
+	}
+}
+
+func TestWriteJSONDuplicate(t *testing.T) {
+	regions := []region.Region{
+		{Tag: "hello", Language: "javascript", File: "javascript/sample.js", StartLine: 2},
+		{Tag: "hello", Language: "javascript", File: "javascript/sample.ts", StartLine: 2},
+	}
+	var out bytes.Buffer
+	err := writeJSON(&out, regions)
+	if err == nil || !strings.Contains(err.Error(), "javascript/sample.ts:2: duplicate javascript region hello") {
+		t.Errorf("writeJSON = %v, want a duplicate region error", err)
+	}
+}
+
+func TestFilters(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"-root", "testdata/repo", "-lang", "go,java", "-tag", "*_print", "-format", "markdown"}, &stdout, &stderr)
+	if code != 0 {
+		t.Fatalf("exit code %d; stderr:\n%s", code, stderr.String())
+	}
+	got := stdout.String()
+	if strings.Count("\n"+got, "\n## ") != 1 || !strings.Contains(got, "### Go") || !strings.Contains(got, "### Java") {
+		t.Errorf("output does not hold just hello_print in Go and Java:\n%s", got)
+	}
+	if strings.Contains(got, "Python") {
+		t.Errorf("output includes an unselected language:\n%s", got)
+	}
+}
+
+func TestLabels(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"-root", "../../..", "-lang", "go", "-label", "streaming,google-search"}, &stdout, &stderr)
+	if code != 0 {
+		t.Fatalf("exit code %d; stderr:\n%s", code, stderr.String())
+	}
+	var got map[string]any
+	if err := json.Unmarshal(stdout.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := got["thinking_with_search_tool_streaming"]; !ok || len(got) != 1 {
+		t.Errorf("got tags %v, want just thinking_with_search_tool_streaming", slices.Collect(maps.Keys(got)))
+	}
+}
+
+func TestOutputFile(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "snippets.json")
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"-root", "testdata/repo", "-lang", "rest", "-o", out}, &stdout, &stderr); code != 0 {
+		t.Fatalf("exit code %d; stderr:\n%s", code, stderr.String())
+	}
+	if stdout.Len() != 0 {
+		t.Errorf("wrote %d bytes to stdout with -o", stdout.Len())
+	}
+	b, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), `"rest/sample.sh"`) {
+		t.Errorf("output file = %s", b)
+	}
+}
+
+func TestBadFlags(t *testing.T) {
+	for _, args := range [][]string{
+		{"-format", "yaml"},
+		{"-lang", "cobol"},
+		{"-tag", "["},
+		{"extra"},
+	} {
+		var stdout, stderr bytes.Buffer
+		if code := run(args, &stdout, &stderr); code != 2 {
+			t.Errorf("run(%q) = %d, want 2", args, code)
+		}
+	}
+}
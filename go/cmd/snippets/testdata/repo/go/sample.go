@@ -0,0 +1,11 @@
+package sample
+
+func Hello(w io.Writer) error {
+	// [START hello]
+	greeting := "Hello"
+	// [START hello_print]
+	fmt.Fprintln(w, greeting)
+	// [END hello_print]
+	// [END hello]
+	return nil
+}
@@ -0,0 +1,7 @@
+public class Sample {
+    public static void hello() {
+        // [START hello_print]
+        System.out.println("```Hello```");
+        // [END hello_print]
+    }
+}
@@ -0,0 +1,9 @@
+export async function hello() {
+  // [START hello]
+  const greeting = "Hello";
+  console.log(`${greeting}`);
+  // [END hello]
+  // [START unclosed]
+  return greeting;
+  // [START unclosed]
+}
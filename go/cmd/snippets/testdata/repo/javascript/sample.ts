@@ -0,0 +1,7 @@
+export async function hello(): Promise<string> {
+  // [START hello]
+  const greeting: string = "Hello";
+  console.log(`${greeting}`);
+  // [END hello]
+  return greeting;
+}
@@ -0,0 +1,9 @@
+class UnitTests(absltest.TestCase):
+
+    def test_hello(self):
+        # [START hello]
+        greeting = "Hello"
+        if greeting:
+
+            print(greeting)
+        # [END hello]
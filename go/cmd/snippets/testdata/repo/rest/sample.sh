@@ -0,0 +1,7 @@
+set -eu
+
+echo "[START hello]"
+# [START hello]
+curl "https://generativelanguage.googleapis.com/v1beta/models" \
+    -H "x-goog-api-key: $GEMINI_API_KEY"
+# [END hello]
@@ -3,8 +3,8 @@ package examples
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
-	"log"
 
 	"google.golang.org/genai"
 )
@@ -17,9 +17,8 @@ func CodeExecutionBasic() (*genai.GenerateContentResponse, error) {
 		Backend: genai.BackendGeminiAPI,
 	})
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
-
 	response, err := client.Models.GenerateContent(
 		ctx,
 		"gemini-3.5-flash",
@@ -30,7 +29,7 @@ func CodeExecutionBasic() (*genai.GenerateContentResponse, error) {
 		&genai.GenerateContentConfig{},
 	)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
 	// Print the response.
@@ -73,6 +72,10 @@ func CodeExecutionBasic() (*genai.GenerateContentResponse, error) {
 	return response, err
 }
 
+func CodeExecutionBasicWithClient(ctx context.Context, client *genai.Client, w io.Writer) (*genai.GenerateContentResponse, error) {
+	return withClient(ctx, client, w, CodeExecutionBasic)
+}
+
 func CodeExecutionRequestOverride() (*genai.GenerateContentResponse, error) {
 	// [START code_execution_request_override]
 	ctx := context.Background()
@@ -81,9 +84,8 @@ func CodeExecutionRequestOverride() (*genai.GenerateContentResponse, error) {
 		Backend: genai.BackendGeminiAPI,
 	})
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
-
 	response, err := client.Models.GenerateContent(
 		ctx,
 		"gemini-3.5-flash",
@@ -98,7 +100,7 @@ Generate and run code for the calculation, and make sure you get all 50.`,
 		},
 	)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
 	// Print the response.
@@ -144,3 +146,7 @@ Generate and run code for the calculation, and make sure you get all 50.`,
 
 	return response, err
 }
+
+func CodeExecutionRequestOverrideWithClient(ctx context.Context, client *genai.Client, w io.Writer) (*genai.GenerateContentResponse, error) {
+	return withClient(ctx, client, w, CodeExecutionRequestOverride)
+}
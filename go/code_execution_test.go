@@ -5,6 +5,7 @@ import (
 )
 
 func TestCodeExecutionBasic(t *testing.T) {
+	useCassette(t)
 	_, err := CodeExecutionBasic()
 	if err != nil {
 		t.Errorf("CodeExecutionBasic returned an error.")
@@ -12,6 +13,7 @@ func TestCodeExecutionBasic(t *testing.T) {
 }
 
 func TestCodeExecutionRequestOverride(t *testing.T) {
+	useCassette(t)
 	_, err := CodeExecutionRequestOverride()
 	if err != nil {
 		t.Errorf("CodeExecutionRequestOverride returned an error.")
@@ -3,7 +3,7 @@ package examples
 import (
 	"context"
 	"os"
-	"log"
+	"io"
 	
 	"google.golang.org/genai"
 )
@@ -16,9 +16,8 @@ func ConfigureModelParameters() (*genai.GenerateContentResponse, error) {
 		Backend: genai.BackendGeminiAPI,
 	})
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
-
 	// Create local variables for parameters.
 	candidateCount := int32(1)
 	maxOutputTokens := int32(20)
@@ -36,10 +35,14 @@ func ConfigureModelParameters() (*genai.GenerateContentResponse, error) {
 		},
 	)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
 	printResponse(response)
 	// [END configure_model_parameters]
 	return response, err
 }
+
+func ConfigureModelParametersWithClient(ctx context.Context, client *genai.Client, w io.Writer) (*genai.GenerateContentResponse, error) {
+	return withClient(ctx, client, w, ConfigureModelParameters)
+}
@@ -5,6 +5,7 @@ import (
 )
 
 func TestConfigureModelParameters(t *testing.T) {
+	useCassette(t)
 	_, err := ConfigureModelParameters()
 	if err != nil {
 		t.Errorf("ConfigureModelParameters returned an error.")
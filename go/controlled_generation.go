@@ -4,7 +4,7 @@ import (
 	"context"
 	"path/filepath"
 	"os"
-	"log"
+	"io"
 
 	"google.golang.org/genai"
 )
@@ -13,13 +13,12 @@ func JsonControlledGeneration() (*genai.GenerateContentResponse, error) {
 	// [START json_controlled_generation]
 	ctx := context.Background()
 	client, err := genai.NewClient(ctx, &genai.ClientConfig{
-		APIKey:  os.Getenv("GEMINI_API_KEY"), 
+		APIKey:  os.Getenv("GEMINI_API_KEY"),
 		Backend: genai.BackendGeminiAPI,
 	})
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
-
 	schema := &genai.Schema{
 		Type: genai.TypeArray,
 		Items: &genai.Schema{
@@ -47,22 +46,26 @@ func JsonControlledGeneration() (*genai.GenerateContentResponse, error) {
 		config,
 	)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 	printResponse(response)
 	// [END json_controlled_generation]
 	return response, err
 }
 
+func JsonControlledGenerationWithClient(ctx context.Context, client *genai.Client, w io.Writer) (*genai.GenerateContentResponse, error) {
+	return withClient(ctx, client, w, JsonControlledGeneration)
+}
+
 func JsonNoSchema() (*genai.GenerateContentResponse, error) {
 	// [START json_no_schema]
 	ctx := context.Background()
 	client, err := genai.NewClient(ctx, &genai.ClientConfig{
-		APIKey:  os.Getenv("GEMINI_API_KEY"), 
+		APIKey:  os.Getenv("GEMINI_API_KEY"),
 		Backend: genai.BackendGeminiAPI,
 	})
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 	prompt := "List a few popular cookie recipes in JSON format.\n\n" +
 			  "Use this JSON schema:\n\n" +
@@ -70,24 +73,27 @@ func JsonNoSchema() (*genai.GenerateContentResponse, error) {
 		      "Return: list[Recipe]"
 	response, err := client.Models.GenerateContent(ctx, "gemini-3.5-flash", genai.Text(prompt), nil)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 	printResponse(response)
 	// [END json_no_schema]
 	return response, err
 }
 
+func JsonNoSchemaWithClient(ctx context.Context, client *genai.Client, w io.Writer) (*genai.GenerateContentResponse, error) {
+	return withClient(ctx, client, w, JsonNoSchema)
+}
+
 func JsonEnum() (*genai.GenerateContentResponse, error) {
 	// [START json_enum]
 	ctx := context.Background()
 	client, err := genai.NewClient(ctx, &genai.ClientConfig{
-		APIKey:  os.Getenv("GEMINI_API_KEY"), 
+		APIKey:  os.Getenv("GEMINI_API_KEY"),
 		Backend: genai.BackendGeminiAPI,
 	})
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
-
 	// Choice is a custom type representing a musical instrument category.
 	type Choice string
 
@@ -117,14 +123,14 @@ func JsonEnum() (*genai.GenerateContentResponse, error) {
 	}
 
 	file, err := client.Files.UploadFromPath(
-		ctx, 
-		filepath.Join(getMedia(), "organ.jpg"), 
+		ctx,
+		filepath.Join(getMedia(), "organ.jpg"),
 		&genai.UploadFileConfig{
-			MIMEType : "image/jpeg",
+			MIMEType: "image/jpeg",
 		},
 	)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 	parts := []*genai.Part{
 		genai.NewPartFromText("What kind of instrument is this:"),
@@ -139,24 +145,27 @@ func JsonEnum() (*genai.GenerateContentResponse, error) {
 		config,
 	)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 	printResponse(response)
 	// [END json_enum]
 	return response, err
 }
 
+func JsonEnumWithClient(ctx context.Context, client *genai.Client, w io.Writer) (*genai.GenerateContentResponse, error) {
+	return withClient(ctx, client, w, JsonEnum)
+}
+
 func EnumInJson() (*genai.GenerateContentResponse, error) {
 	// [START enum_in_json]
 	ctx := context.Background()
 	client, err := genai.NewClient(ctx, &genai.ClientConfig{
-		APIKey:  os.Getenv("GEMINI_API_KEY"), 
+		APIKey:  os.Getenv("GEMINI_API_KEY"),
 		Backend: genai.BackendGeminiAPI,
 	})
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
-
 	// We use a schema representing an array of objects.
 	schema := &genai.Schema{
 		Type: genai.TypeArray,
@@ -178,7 +187,7 @@ func EnumInJson() (*genai.GenerateContentResponse, error) {
 		config,
 	)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 	// Expected output: a JSON-parsed list with recipe names and grades (e.g., "a+")
 	printResponse(response)
@@ -186,17 +195,20 @@ func EnumInJson() (*genai.GenerateContentResponse, error) {
 	return response, err
 }
 
+func EnumInJsonWithClient(ctx context.Context, client *genai.Client, w io.Writer) (*genai.GenerateContentResponse, error) {
+	return withClient(ctx, client, w, EnumInJson)
+}
+
 func JsonEnumRaw() (*genai.GenerateContentResponse, error) {
 	// [START json_enum_raw]
 	ctx := context.Background()
 	client, err := genai.NewClient(ctx, &genai.ClientConfig{
-		APIKey:  os.Getenv("GEMINI_API_KEY"), 
+		APIKey:  os.Getenv("GEMINI_API_KEY"),
 		Backend: genai.BackendGeminiAPI,
 	})
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
-
 	config := &genai.GenerateContentConfig{
 		ResponseMIMEType: "application/json",
 		ResponseSchema: &genai.Schema{
@@ -206,14 +218,14 @@ func JsonEnumRaw() (*genai.GenerateContentResponse, error) {
 	}
 
 	file, err := client.Files.UploadFromPath(
-		ctx, 
-		filepath.Join(getMedia(), "organ.jpg"), 
+		ctx,
+		filepath.Join(getMedia(), "organ.jpg"),
 		&genai.UploadFileConfig{
-			MIMEType : "image/jpeg",
+			MIMEType: "image/jpeg",
 		},
 	)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 	parts := []*genai.Part{
 		genai.NewPartFromText("What kind of instrument is this:"),
@@ -228,7 +240,7 @@ func JsonEnumRaw() (*genai.GenerateContentResponse, error) {
 		config,
 	)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
 	printResponse(response)
@@ -236,6 +248,10 @@ func JsonEnumRaw() (*genai.GenerateContentResponse, error) {
 	return response, err
 }
 
+func JsonEnumRawWithClient(ctx context.Context, client *genai.Client, w io.Writer) (*genai.GenerateContentResponse, error) {
+	return withClient(ctx, client, w, JsonEnumRaw)
+}
+
 func XEnum() (*genai.GenerateContentResponse, error) {
 	// [START x_enum]
 	ctx := context.Background()
@@ -244,9 +260,8 @@ func XEnum() (*genai.GenerateContentResponse, error) {
 		Backend: genai.BackendGeminiAPI,
 	})
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
-
 	// Choice is a custom type representing a musical instrument category.
 	type Choice string
 
@@ -276,14 +291,14 @@ func XEnum() (*genai.GenerateContentResponse, error) {
 	}
 
 	file, err := client.Files.UploadFromPath(
-		ctx, 
-		filepath.Join(getMedia(), "organ.jpg"), 
+		ctx,
+		filepath.Join(getMedia(), "organ.jpg"),
 		&genai.UploadFileConfig{
-			MIMEType : "image/jpeg",
+			MIMEType: "image/jpeg",
 		},
 	)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 	parts := []*genai.Part{
 		genai.NewPartFromText("What kind of instrument is this:"),
@@ -297,13 +312,17 @@ func XEnum() (*genai.GenerateContentResponse, error) {
 		config,
 	)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 	printResponse(response)
 	// [END x_enum]
 	return response, err
 }
 
+func XEnumWithClient(ctx context.Context, client *genai.Client, w io.Writer) (*genai.GenerateContentResponse, error) {
+	return withClient(ctx, client, w, XEnum)
+}
+
 func XEnumRaw() (*genai.GenerateContentResponse, error) {
 	// [START x_enum_raw]
 	ctx := context.Background()
@@ -312,9 +331,8 @@ func XEnumRaw() (*genai.GenerateContentResponse, error) {
 		Backend: genai.BackendGeminiAPI,
 	})
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
-
 	rawSchema := &genai.Schema{
 		Type: genai.TypeString,
 		Enum: []string{"Percussion", "String", "Woodwind", "Brass", "Keyboard"},
@@ -325,14 +343,14 @@ func XEnumRaw() (*genai.GenerateContentResponse, error) {
 	}
 
 	file, err := client.Files.UploadFromPath(
-		ctx, 
-		filepath.Join(getMedia(), "organ.jpg"), 
+		ctx,
+		filepath.Join(getMedia(), "organ.jpg"),
 		&genai.UploadFileConfig{
-			MIMEType : "image/jpeg",
+			MIMEType: "image/jpeg",
 		},
 	)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 	parts := []*genai.Part{
 		genai.NewPartFromText("What kind of instrument is this:"),
@@ -346,10 +364,14 @@ func XEnumRaw() (*genai.GenerateContentResponse, error) {
 		config,
 	)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 	printResponse(response)
 	// Expected output: "Keyboard"
 	// [END x_enum_raw]
 	return response, err
 }
+
+func XEnumRawWithClient(ctx context.Context, client *genai.Client, w io.Writer) (*genai.GenerateContentResponse, error) {
+	return withClient(ctx, client, w, XEnumRaw)
+}
@@ -5,6 +5,7 @@ import (
 )
 
 func TestJsonControlledGeneration(t *testing.T) {
+	useCassette(t)
 	_, err := JsonControlledGeneration()
 	if err != nil {
 		t.Errorf("JsonControlledGeneration returned an error.")
@@ -12,6 +13,7 @@ func TestJsonControlledGeneration(t *testing.T) {
 }
 
 func TestJsonNoSchema(t *testing.T) {
+	useCassette(t)
 	_, err := JsonNoSchema()
 	if err != nil {
 		t.Errorf("JsonNoSchema returned an error.")
@@ -19,6 +21,7 @@ func TestJsonNoSchema(t *testing.T) {
 }
 
 func TestJsonEnum(t *testing.T) {
+	useCassette(t)
 	_, err := JsonEnum()
 	if err != nil {
 		t.Errorf("JsonEnum returned an error.")
@@ -26,6 +29,7 @@ func TestJsonEnum(t *testing.T) {
 }
 
 func TestEnumInJson(t *testing.T) {
+	useCassette(t)
 	_, err := EnumInJson()
 	if err != nil {
 		t.Errorf("EnumInJson returned an error.")
@@ -33,6 +37,7 @@ func TestEnumInJson(t *testing.T) {
 }
 
 func TestJsonEnumRaw(t *testing.T) {
+	useCassette(t)
 	_, err := JsonEnumRaw()
 	if err != nil {
 		t.Errorf("JsonEnumRaw returned an error.")
@@ -40,6 +45,7 @@ func TestJsonEnumRaw(t *testing.T) {
 }
 
 func TestXEnum(t *testing.T) {
+	useCassette(t)
 	_, err := XEnum()
 	if err != nil {
 		t.Errorf("XEnum returned an error.")
@@ -47,6 +53,7 @@ func TestXEnum(t *testing.T) {
 }
 
 func TestXEnumRaw(t *testing.T) {
+	useCassette(t)
 	_, err := XEnumRaw()
 	if err != nil {
 		t.Errorf("XEnumRaw returned an error.")
@@ -4,11 +4,13 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"time"
-	"log"
 	"os"
 	"path/filepath"
 
+	"gemini-api-examples/internal/caches"
+	"gemini-api-examples/internal/filewait"
 	"google.golang.org/genai"
 )
 
@@ -20,12 +22,11 @@ func TokensContextWindow() error {
 		Backend: genai.BackendGeminiAPI,
 	})
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
-
 	modelInfo, err := client.Models.Get(ctx, "gemini-3.5-flash", &genai.GetModelConfig{})
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 	fmt.Printf("input_token_limit=%d\n", modelInfo.InputTokenLimit)
 	fmt.Printf("output_token_limit=%d\n", modelInfo.OutputTokenLimit)
@@ -33,6 +34,10 @@ func TokensContextWindow() error {
 	return err
 }
 
+func TokensContextWindowWithClient(ctx context.Context, client *genai.Client, w io.Writer) error {
+	return runWith(ctx, client, w, TokensContextWindow)
+}
+
 func TokensTextOnly() error {
 	// [START tokens_text_only]
 	ctx := context.Background()
@@ -41,7 +46,7 @@ func TokensTextOnly() error {
 		Backend: genai.BackendGeminiAPI,
 	})
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 	prompt := "The quick brown fox jumps over the lazy dog."
 
@@ -57,17 +62,21 @@ func TokensTextOnly() error {
 
 	response, err := client.Models.GenerateContent(ctx, "gemini-3.5-flash", contents, nil)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 	usageMetadata, err := json.MarshalIndent(response.UsageMetadata, "", "  ")
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 	fmt.Println(string(usageMetadata))
 	// [END tokens_text_only]
 	return err
 }
 
+func TokensTextOnlyWithClient(ctx context.Context, client *genai.Client, w io.Writer) error {
+	return runWith(ctx, client, w, TokensTextOnly)
+}
+
 func TokensChat() error {
 	// [START tokens_chat]
 	ctx := context.Background()
@@ -76,9 +85,8 @@ func TokensChat() error {
 		Backend: genai.BackendGeminiAPI,
 	})
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
-
 	// Initialize chat with some history.
 	history := []*genai.Content{
 		{Role: genai.RoleUser, Parts: []*genai.Part{{Text: "Hi my name is Bob"}}},
@@ -86,12 +94,12 @@ func TokensChat() error {
 	}
 	chat, err := client.Chats.Create(ctx, "gemini-3.5-flash", nil, history)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	firstTokenResp, err := client.Models.CountTokens(ctx, "gemini-3.5-flash", chat.History(false), nil)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 	fmt.Println(firstTokenResp.TotalTokens)
 
@@ -99,7 +107,7 @@ func TokensChat() error {
 		Text: "In one sentence, explain how a computer works to a young child."},
 	)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 	fmt.Printf("%#v\n", resp.UsageMetadata)
 
@@ -110,7 +118,7 @@ func TokensChat() error {
 
 	secondTokenResp, err := client.Models.CountTokens(ctx, "gemini-3.5-flash", hist, nil)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 	fmt.Println(secondTokenResp.TotalTokens)
 	// [END tokens_chat]
@@ -118,6 +126,56 @@ func TokensChat() error {
 	return nil
 }
 
+func TokensChatWithClient(ctx context.Context, client *genai.Client, w io.Writer) error {
+	return runWith(ctx, client, w, TokensChat)
+}
+
+func TokensMultimodalImageInline() error {
+	// [START tokens_multimodal_image_inline]
+	ctx := context.Background()
+	client, err := genai.NewClient(ctx, &genai.ClientConfig{
+		APIKey:  os.Getenv("GEMINI_API_KEY"),
+		Backend: genai.BackendGeminiAPI,
+	})
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(filepath.Join(getMedia(), "organ.jpg"))
+	if err != nil {
+		return err
+	}
+	parts := []*genai.Part{
+		genai.NewPartFromText("Tell me about this image"),
+		genai.NewPartFromBytes(data, "image/jpeg"),
+	}
+	contents := []*genai.Content{
+		genai.NewContentFromParts(parts, genai.RoleUser),
+	}
+
+	// Count tokens for combined text and inline image.
+	tokenResp, err := client.Models.CountTokens(ctx, "gemini-3.5-flash", contents, nil)
+	if err != nil {
+		return err
+	}
+	fmt.Println("Multimodal image token count:", tokenResp.TotalTokens)
+
+	response, err := client.Models.GenerateContent(ctx, "gemini-3.5-flash", contents, nil)
+	if err != nil {
+		return err
+	}
+	usageMetadata, err := json.MarshalIndent(response.UsageMetadata, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(usageMetadata))
+	// [END tokens_multimodal_image_inline]
+	return err
+}
+
+func TokensMultimodalImageInlineWithClient(ctx context.Context, client *genai.Client, w io.Writer) error {
+	return runWith(ctx, client, w, TokensMultimodalImageInline)
+}
+
 func TokensMultimodalImageFileApi() error {
 	// [START tokens_multimodal_image_file_api]
 	ctx := context.Background()
@@ -126,18 +184,17 @@ func TokensMultimodalImageFileApi() error {
 		Backend: genai.BackendGeminiAPI,
 	})
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
-
 	file, err := client.Files.UploadFromPath(
-		ctx, 
-		filepath.Join(getMedia(), "organ.jpg"), 
+		ctx,
+		filepath.Join(getMedia(), "organ.jpg"),
 		&genai.UploadFileConfig{
-			MIMEType : "image/jpeg",
+			MIMEType: "image/jpeg",
 		},
 	)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 	parts := []*genai.Part{
 		genai.NewPartFromText("Tell me about this image"),
@@ -149,23 +206,27 @@ func TokensMultimodalImageFileApi() error {
 
 	tokenResp, err := client.Models.CountTokens(ctx, "gemini-3.5-flash", contents, nil)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 	fmt.Println("Multimodal image token count:", tokenResp.TotalTokens)
 
 	response, err := client.Models.GenerateContent(ctx, "gemini-3.5-flash", contents, nil)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 	usageMetadata, err := json.MarshalIndent(response.UsageMetadata, "", "  ")
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 	fmt.Println(string(usageMetadata))
 	// [END tokens_multimodal_image_file_api]
 	return err
 }
 
+func TokensMultimodalImageFileApiWithClient(ctx context.Context, client *genai.Client, w io.Writer) error {
+	return runWith(ctx, client, w, TokensMultimodalImageFileApi)
+}
+
 func TokensMultimodalVideoAudioFileApi() error {
 	// [START tokens_multimodal_video_audio_file_api]
 	ctx := context.Background()
@@ -174,30 +235,32 @@ func TokensMultimodalVideoAudioFileApi() error {
 		Backend: genai.BackendGeminiAPI,
 	})
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
-
 	file, err := client.Files.UploadFromPath(
-		ctx, 
-		filepath.Join(getMedia(), "Big_Buck_Bunny.mp4"), 
+		ctx,
+		filepath.Join(getMedia(), "Big_Buck_Bunny.mp4"),
 		&genai.UploadFileConfig{
-			MIMEType : "video/mp4",
+			MIMEType: "video/mp4",
 		},
 	)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
-	// Poll until the video file is completely processed (state becomes ACTIVE).
-	for file.State == genai.FileStateUnspecified || file.State != genai.FileStateActive {
-		fmt.Println("Processing video...")
-		fmt.Println("File state:", file.State)
-		time.Sleep(5 * time.Second)
-
-		file, err = client.Files.Get(ctx, file.Name, nil)
-		if err != nil {
-			log.Fatal(err)
-		}
+	// Wait until the video file is completely processed (state becomes
+	// ACTIVE), polling less and less often, and give up if processing fails
+	// or takes more than five minutes.
+	waitCtx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+	defer cancel()
+	file, err = filewait.Wait(waitCtx, client, file, &filewait.Options{
+		Progress: func(f *genai.File, next time.Duration) {
+			fmt.Println("Processing video...")
+			fmt.Println("File state:", f.State)
+		},
+	})
+	if err != nil {
+		return err
 	}
 
 	parts := []*genai.Part{
@@ -210,22 +273,26 @@ func TokensMultimodalVideoAudioFileApi() error {
 
 	tokenResp, err := client.Models.CountTokens(ctx, "gemini-3.5-flash", contents, nil)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 	fmt.Println("Multimodal video/audio token count:", tokenResp.TotalTokens)
 	response, err := client.Models.GenerateContent(ctx, "gemini-3.5-flash", contents, nil)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 	usageMetadata, err := json.MarshalIndent(response.UsageMetadata, "", "  ")
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 	fmt.Println(string(usageMetadata))
 	// [END tokens_multimodal_video_audio_file_api]
 	return err
 }
 
+func TokensMultimodalVideoAudioFileApiWithClient(ctx context.Context, client *genai.Client, w io.Writer) error {
+	return runWith(ctx, client, w, TokensMultimodalVideoAudioFileApi)
+}
+
 func TokensMultimodalPdfFileApi() error {
 	// [START tokens_multimodal_pdf_file_api]
 	ctx := context.Background()
@@ -234,18 +301,17 @@ func TokensMultimodalPdfFileApi() error {
 		Backend: genai.BackendGeminiAPI,
 	})
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
-
 	file, err := client.Files.UploadFromPath(
-		ctx, 
-		filepath.Join(getMedia(), "test.pdf"), 
+		ctx,
+		filepath.Join(getMedia(), "test.pdf"),
 		&genai.UploadFileConfig{
-			MIMEType : "application/pdf",
+			MIMEType: "application/pdf",
 		},
 	)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 	parts := []*genai.Part{
 		genai.NewPartFromText("Give me a summary of this document."),
@@ -257,22 +323,26 @@ func TokensMultimodalPdfFileApi() error {
 
 	tokenResp, err := client.Models.CountTokens(ctx, "gemini-3.5-flash", contents, nil)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 	fmt.Printf("Multimodal PDF token count: %d\n", tokenResp.TotalTokens)
 	response, err := client.Models.GenerateContent(ctx, "gemini-3.5-flash", contents, nil)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 	usageMetadata, err := json.MarshalIndent(response.UsageMetadata, "", "  ")
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 	fmt.Println(string(usageMetadata))
 	// [END tokens_multimodal_pdf_file_api]
 	return err
 }
 
+func TokensMultimodalPdfFileApiWithClient(ctx context.Context, client *genai.Client, w io.Writer) error {
+	return runWith(ctx, client, w, TokensMultimodalPdfFileApi)
+}
+
 func TokensCachedContent() error {
 	// [START tokens_cached_content]
 	ctx := context.Background()
@@ -281,18 +351,17 @@ func TokensCachedContent() error {
 		Backend: genai.BackendGeminiAPI,
 	})
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
-
 	file, err := client.Files.UploadFromPath(
-		ctx, 
-		filepath.Join(getMedia(), "a11.txt"), 
+		ctx,
+		filepath.Join(getMedia(), "a11.txt"),
 		&genai.UploadFileConfig{
-			MIMEType : "text/plain",
+			MIMEType: "text/plain",
 		},
 	)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 	parts := []*genai.Part{
 		genai.NewPartFromText("Here the Apollo 11 transcript:"),
@@ -303,11 +372,16 @@ func TokensCachedContent() error {
 	}
 
 	// Create cached content using a simple slice with text and a file.
-	cache, err := client.Caches.Create(ctx, "gemini-3.5-flash", &genai.CreateCachedContentConfig{
+	config := &genai.CreateCachedContentConfig{
 		Contents: contents,
-	})
+	}
+	// Check that the model can cache the contents before creating the cache.
+	if _, err := caches.Validate(ctx, client, "gemini-3.5-flash", config, nil); err != nil {
+		return err
+	}
+	cache, err := client.Caches.Create(ctx, "gemini-3.5-flash", config)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	prompt := "Please give a short summary of this file."
@@ -315,7 +389,7 @@ func TokensCachedContent() error {
 		genai.NewContentFromText(prompt, genai.RoleUser),
 	}, nil)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 	fmt.Printf("%d", countResp.TotalTokens)
 	response, err := client.Models.GenerateContent(ctx, "gemini-3.5-flash", []*genai.Content{
@@ -324,16 +398,20 @@ func TokensCachedContent() error {
 		CachedContent: cache.Name,
 	})
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	usageMetadata, err := json.MarshalIndent(response.UsageMetadata, "", "  ")
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 	// Returns `nil` for some reason
 	fmt.Println(string(usageMetadata))
 	_, err = client.Caches.Delete(ctx, cache.Name, &genai.DeleteCachedContentConfig{})
 	// [END tokens_cached_content]
-	return err
+	return nil
+}
+
+func TokensCachedContentWithClient(ctx context.Context, client *genai.Client, w io.Writer) error {
+	return runWith(ctx, client, w, TokensCachedContent)
 }
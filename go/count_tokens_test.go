@@ -5,6 +5,7 @@ import (
 )
 
 func TestTokensContextWindow(t *testing.T) {
+	useCassette(t)
 	err := TokensContextWindow()
 	if err != nil {
 		t.Errorf("TokensContextWindow returned an error.")
@@ -12,6 +13,7 @@ func TestTokensContextWindow(t *testing.T) {
 }
 
 func TestTokensTextOnly(t *testing.T) {
+	useCassette(t)
 	err := TokensTextOnly()
 	if err != nil {
 		t.Errorf("TokensTextOnly returned an error.")
@@ -19,12 +21,21 @@ func TestTokensTextOnly(t *testing.T) {
 }
 
 func TestTokensChat(t *testing.T) {
+	useCassette(t)
 	if err := TokensChat(); err != nil {
 		t.Errorf("TokensChat returned an error: %v", err)
 	}
 }
 
+func TestTokensMultimodalImageInline(t *testing.T) {
+	useCassette(t)
+	if err := TokensMultimodalImageInline(); err != nil {
+		t.Errorf("TokensMultimodalImageInline returned an error: %v", err)
+	}
+}
+
 func TestTokensMultimodalImageFileApi(t *testing.T) {
+	useCassette(t)
 	err := TokensMultimodalImageFileApi()
 	if err != nil {
 		t.Errorf("TokensMultimodalImageFileApi returned an error.")
@@ -32,6 +43,7 @@ func TestTokensMultimodalImageFileApi(t *testing.T) {
 }
 
 func TestTokensMultimodalVideoAudioFileApi(t *testing.T) {
+	useCassette(t)
 	err := TokensMultimodalVideoAudioFileApi()
 	if err != nil {
 		t.Errorf("TokensMultimodalVideoAudioFileApi returned an error.")
@@ -39,6 +51,7 @@ func TestTokensMultimodalVideoAudioFileApi(t *testing.T) {
 }
 
 func TestTokensMultimodalPdfFileApi(t *testing.T) {
+	useCassette(t)
 	err := TokensMultimodalPdfFileApi()
 	if err != nil {
 		t.Errorf("TokensMultimodalPdfFileApi returned an error.")
@@ -46,6 +59,7 @@ func TestTokensMultimodalPdfFileApi(t *testing.T) {
 }
 
 func TestTokensCachedContent(t *testing.T) {
+	useCassette(t)
 	err := TokensCachedContent()
 	if err != nil {
 		t.Errorf("TokensCachedContent returned an error.")
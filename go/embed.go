@@ -3,8 +3,8 @@ package examples
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
-	"log"
 	"encoding/json"
 
 	"google.golang.org/genai"
@@ -18,9 +18,8 @@ func EmbedContent() error {
 		Backend: genai.BackendGeminiAPI,
 	})
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
-
 	text := "Hello World!"
 	outputDim := int32(10)
 	contents := []*genai.Content{
@@ -31,18 +30,22 @@ func EmbedContent() error {
 			OutputDimensionality: &outputDim,
 	})
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	embeddings, err := json.MarshalIndent(result.Embeddings, "", "  ")
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 	fmt.Println(string(embeddings))
 	// [END embed_content]
 	return err
 }
 
+func EmbedContentWithClient(ctx context.Context, client *genai.Client, w io.Writer) error {
+	return runWith(ctx, client, w, EmbedContent)
+}
+
 func BatchEmbedContents() error {
 	// [START batch_embed_contents]
 	ctx := context.Background()
@@ -51,9 +54,8 @@ func BatchEmbedContents() error {
 		Backend: genai.BackendGeminiAPI,
 	})
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
-
 	contents := []*genai.Content{
 		genai.NewContentFromText("What is the meaning of life?", genai.RoleUser),
 		genai.NewContentFromText("How much wood would a woodchuck chuck?", genai.RoleUser),
@@ -65,14 +67,18 @@ func BatchEmbedContents() error {
 		OutputDimensionality: &outputDim,
 	})
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 	
 	embeddings, err := json.MarshalIndent(result.Embeddings, "", "  ")
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 	fmt.Println(string(embeddings))
 	// [END batch_embed_contents]
 	return err
 }
+
+func BatchEmbedContentsWithClient(ctx context.Context, client *genai.Client, w io.Writer) error {
+	return runWith(ctx, client, w, BatchEmbedContents)
+}
@@ -1,10 +1,14 @@
 package examples
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"testing"
 )
 
 func TestEmbedContent(t *testing.T) {
+	useCassette(t)
 	err := EmbedContent()
 	if err != nil {
 		t.Errorf("EmbedContent returned an error.")
@@ -12,8 +16,26 @@ func TestEmbedContent(t *testing.T) {
 }
 
 func TestBatchEmbedContents(t *testing.T) {
+	useCassette(t)
 	err := BatchEmbedContents()
 	if err != nil {
 		t.Errorf("BatchEmbedContents returned an error.")
 	}
 }
+
+func TestFakeEmbedContentWithClient(t *testing.T) {
+	_, client := fakeClient(t)
+	var out bytes.Buffer
+	if err := EmbedContentWithClient(context.Background(), client, &out); err != nil {
+		t.Fatalf("EmbedContentWithClient returned an error: %v", err)
+	}
+	var embeddings []struct {
+		Values []float32 `json:"values"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &embeddings); err != nil {
+		t.Fatalf("output is not the JSON embeddings: %v", err)
+	}
+	if len(embeddings) != 1 || len(embeddings[0].Values) != 10 {
+		t.Errorf("got %d embeddings, want one of 10 values", len(embeddings))
+	}
+}
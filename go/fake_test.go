@@ -0,0 +1,251 @@
+package examples
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"gemini-api-examples/internal/config"
+	"gemini-api-examples/internal/fakegemini"
+	"gemini-api-examples/internal/tracker"
+	"google.golang.org/genai"
+)
+
+// fakeClient starts a fake Gemini API server and returns a client talking
+// to it, for calling the WithClient variants of the samples. The files and
+// caches created through the client are deleted when the test ends.
+func fakeClient(t *testing.T) (*fakegemini.Server, *genai.Client) {
+	t.Helper()
+	srv := fakegemini.New()
+	t.Cleanup(srv.Close)
+	cfg := &config.Config{APIKey: "fake", BaseURL: srv.URL}
+	client, err := cfg.NewClient(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return srv, trackResources(t, client)
+}
+
+// trackResources returns a client like client whose files and caches are
+// deleted when t and its subtests complete. Anything that cannot be
+// deleted fails t.
+func trackResources(t *testing.T, client *genai.Client) *genai.Client {
+	t.Helper()
+	tr := tracker.New()
+	tracked, err := tr.Client(context.Background(), client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		if err := tr.Cleanup(ctx, tracked); err != nil {
+			t.Error(err)
+		}
+	})
+	return tracked
+}
+
+func TestFakeFilesCreateText(t *testing.T) {
+	srv, client := fakeClient(t)
+	resp, err := FilesCreateTextWithClient(t.Context(), client, io.Discard)
+	if err != nil {
+		t.Fatalf("FilesCreateText returned an error: %v", err)
+	}
+	if !strings.HasPrefix(resp.Text(), "You said:") {
+		t.Errorf("Text() = %q", resp.Text())
+	}
+	files := srv.Files()
+	if len(files) != 1 || files[0].MIMEType != "text/plain" {
+		t.Fatalf("files on server = %+v, want the uploaded poem", files)
+	}
+	reqs := srv.GenerateRequests()
+	if len(reqs) != 1 || reqs[0].Contents[0].Parts[0].FileData.FileURI != files[0].URI {
+		t.Errorf("generate request does not reference the uploaded file")
+	}
+}
+
+func TestFakeWithClientTracked(t *testing.T) {
+	srv, client := fakeClient(t)
+	tr := tracker.New()
+	tracked, err := tr.Client(t.Context(), client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The sample fails after creating its cache.
+	srv.Inject(":generateContent", 1, fakegemini.ServerError(http.StatusInternalServerError))
+	if _, err := CacheCreateWithClient(t.Context(), tracked, io.Discard); err == nil {
+		t.Fatal("CacheCreateWithClient succeeded despite the server error")
+	}
+	if live := tr.Live(); len(live) != 2 {
+		t.Fatalf("tracked %v, want the file and the cache left behind", live)
+	}
+	if err := tr.Cleanup(t.Context(), tracked); err != nil {
+		t.Fatal(err)
+	}
+	if files, caches := srv.Files(), srv.Caches(); len(files) != 0 || len(caches) != 0 {
+		t.Errorf("left on the server after Cleanup: %d files, %d caches", len(files), len(caches))
+	}
+}
+
+func TestFakeFilesDelete(t *testing.T) {
+	srv, client := fakeClient(t)
+	if err := FilesDeleteWithClient(t.Context(), client, io.Discard); err != nil {
+		t.Fatalf("FilesDelete returned an error: %v", err)
+	}
+	if n := len(srv.Files()); n != 0 {
+		t.Errorf("%d files left on the server", n)
+	}
+}
+
+func TestFakeFunctionCalling(t *testing.T) {
+	srv, client := fakeClient(t)
+	srv.OnGenerate(func(req *fakegemini.GenerateRequest) (*genai.GenerateContentResponse, error) {
+		if len(req.FunctionDeclarations()) > 0 {
+			return fakegemini.FunctionCallResponse("multiplyNumbers", map[string]any{
+				"firstParam":  57,
+				"secondParam": 44,
+			}), nil
+		}
+		return fakegemini.TextResponse("That is 2508 mittens."), nil
+	})
+	if err := FunctionCallingWithClient(t.Context(), client, io.Discard); err != nil {
+		t.Fatalf("FunctionCalling returned an error: %v", err)
+	}
+	reqs := srv.GenerateRequests()
+	if len(reqs) != 2 {
+		t.Fatalf("got %d generate requests, want 2", len(reqs))
+	}
+	if got := reqs[1].LastUserText(); got != "The final result is 2508" {
+		t.Errorf("follow-up prompt = %q, want the computed product", got)
+	}
+}
+
+func TestFakeCacheList(t *testing.T) {
+	srv, client := fakeClient(t)
+	for range 3 {
+		srv.AddCache(&genai.CachedContent{Model: "models/gemini-3.5-flash"})
+	}
+	if err := CacheListWithClient(t.Context(), client, io.Discard); err != nil {
+		t.Fatalf("CacheList returned an error: %v", err)
+	}
+	// Four caches at two per page.
+	if n := len(srv.RequestsTo(http.MethodGet, "cachedContents")); n != 2 {
+		t.Errorf("got %d list requests, want 2", n)
+	}
+	if n := len(srv.Caches()); n != 3 {
+		t.Errorf("%d caches left, want the 3 seeded ones", n)
+	}
+}
+
+func TestFakeModelsList(t *testing.T) {
+	srv, client := fakeClient(t)
+	if err := ModelsListWithClient(t.Context(), client, io.Discard); err != nil {
+		t.Fatalf("ModelsList returned an error: %v", err)
+	}
+	if n := len(srv.RequestsTo(http.MethodGet, "models")); n != 1 {
+		t.Errorf("got %d list requests, want 1", n)
+	}
+}
+
+func TestFakeBatchEmbedContents(t *testing.T) {
+	srv, client := fakeClient(t)
+	if err := BatchEmbedContentsWithClient(t.Context(), client, io.Discard); err != nil {
+		t.Fatalf("BatchEmbedContents returned an error: %v", err)
+	}
+	reqs := srv.RequestsTo(http.MethodPost, ":batchEmbedContents")
+	if len(reqs) != 1 {
+		t.Fatalf("got %d batch requests, want 1", len(reqs))
+	}
+	var body struct {
+		Requests []json.RawMessage `json:"requests"`
+	}
+	if err := json.Unmarshal(reqs[0].Body, &body); err != nil {
+		t.Fatal(err)
+	}
+	if len(body.Requests) != 3 {
+		t.Errorf("batch has %d requests, want 3", len(body.Requests))
+	}
+}
+
+// generateMethods lists the methods of the models service that ask the
+// model for content, whose faults every sample calling them must report.
+var generateMethods = []string{":generateContent", ":streamGenerateContent", ":embedContent", ":batchEmbedContents", ":countTokens"}
+
+// TestFakeSampleFaults runs every sample against each fault of the fake
+// server and checks that it fails cleanly: with an error for faults that
+// leave it without a usable response, and without panicking for those that
+// answer with an empty or blocked one.
+func TestFakeSampleFaults(t *testing.T) {
+	for _, s := range Samples {
+		t.Run(s.Name, func(t *testing.T) {
+			if missing := s.MissingMedia(); len(missing) > 0 {
+				t.Skipf("missing media in third_party: %s", strings.Join(missing, ", "))
+			}
+			// A clean run tells which methods the sample calls.
+			srv, client := fakeClient(t)
+			srv.SetProcessingPolls(0)
+			if err := runSample(t, &s, client); err != nil {
+				t.Fatalf("clean run returned an error: %v", err)
+			}
+			type fault struct {
+				name    string
+				suffix  string
+				fault   fakegemini.Fault
+				wantErr bool
+			}
+			faults := []fault{
+				{"unavailable", "", fakegemini.ServerError(http.StatusServiceUnavailable), true},
+				{"disconnect", "", fakegemini.Disconnect(0), true},
+			}
+			for _, m := range generateMethods {
+				// FilesDelete expects its generation request, about a
+				// deleted file, to fail.
+				if len(srv.RequestsTo(http.MethodPost, m)) == 0 || s.Name == "FilesDelete" {
+					continue
+				}
+				faults = append(faults,
+					fault{m + "/rate limited", m, fakegemini.RateLimited(30 * time.Second), true},
+					fault{m + "/malformed", m, fakegemini.Malformed(), true},
+				)
+				if m == ":generateContent" || m == ":streamGenerateContent" {
+					faults = append(faults,
+						fault{m + "/empty candidates", m, fakegemini.EmptyCandidates(), m == ":streamGenerateContent"},
+						fault{m + "/blocked prompt", m, fakegemini.BlockedPrompt(genai.BlockedReasonSafety), m == ":streamGenerateContent"},
+					)
+				}
+				if m == ":streamGenerateContent" {
+					faults = append(faults, fault{m + "/cut short", m, fakegemini.Disconnect(1), true})
+				}
+			}
+			for _, f := range faults {
+				t.Run(f.name, func(t *testing.T) {
+					srv, client := fakeClient(t)
+					srv.SetProcessingPolls(0)
+					srv.Inject(f.suffix, 0, f.fault)
+					if err := runSample(t, &s, client); (err != nil) != f.wantErr {
+						t.Errorf("err = %v, want error: %v", err, f.wantErr)
+					}
+				})
+			}
+		})
+	}
+}
+
+// runSample runs s with client, discarding its output, and fails the test if
+// it panics.
+func runSample(t *testing.T, s *Sample, client *genai.Client) (err error) {
+	t.Helper()
+	defer func() {
+		if p := recover(); p != nil {
+			t.Fatalf("%s panicked: %v", s.Name, p)
+		}
+	}()
+	ctx, cancel := context.WithTimeout(t.Context(), time.Minute)
+	defer cancel()
+	return s.Run(ctx, client, io.Discard)
+}
@@ -3,11 +3,12 @@ package examples
 import (
 	"context"
 	"fmt"
-	"log"
+	"io"
 	"os"
 	"path/filepath"
 	"time"
 
+	"gemini-api-examples/internal/filewait"
 	"google.golang.org/genai"
 )
 
@@ -19,18 +20,18 @@ func FilesCreateText() (*genai.GenerateContentResponse, error) {
 		Backend: genai.BackendGeminiAPI,
 	})
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 	
 	myfile, err := client.Files.UploadFromPath(
-		ctx, 
-		filepath.Join(getMedia(), "poem.txt"), 
+		ctx,
+		filepath.Join(getMedia(), "poem.txt"),
 		&genai.UploadFileConfig{
-			MIMEType : "text/plain",
+			MIMEType: "text/plain",
 		},
 	)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 	fmt.Printf("myfile=%+v\n", myfile)
 
@@ -46,7 +47,7 @@ func FilesCreateText() (*genai.GenerateContentResponse, error) {
 
 	response, err := client.Models.GenerateContent(ctx, "gemini-3.5-flash", contents, nil)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 	text := response.Text()
 	fmt.Printf("result.text=%s\n", text)
@@ -54,6 +55,10 @@ func FilesCreateText() (*genai.GenerateContentResponse, error) {
 	return response, err
 }
 
+func FilesCreateTextWithClient(ctx context.Context, client *genai.Client, w io.Writer) (*genai.GenerateContentResponse, error) {
+	return withClient(ctx, client, w, FilesCreateText)
+}
+
 func FilesCreateImage() (*genai.GenerateContentResponse, error) {
 	// [START files_create_image]
 	ctx := context.Background()
@@ -62,17 +67,17 @@ func FilesCreateImage() (*genai.GenerateContentResponse, error) {
 		Backend: genai.BackendGeminiAPI,
 	})
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 	myfile, err := client.Files.UploadFromPath(
-		ctx, 
-		filepath.Join(getMedia(), "Cajun_instruments.jpg"), 
+		ctx,
+		filepath.Join(getMedia(), "Cajun_instruments.jpg"),
 		&genai.UploadFileConfig{
-			MIMEType : "image/jpeg",
+			MIMEType: "image/jpeg",
 		},
 	)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 	fmt.Printf("myfile=%+v\n", myfile)
 
@@ -88,7 +93,7 @@ func FilesCreateImage() (*genai.GenerateContentResponse, error) {
 
 	response, err := client.Models.GenerateContent(ctx, "gemini-3.5-flash", contents, nil)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 	text := response.Text()
 	fmt.Printf("result.text=%s\n", text)
@@ -96,6 +101,10 @@ func FilesCreateImage() (*genai.GenerateContentResponse, error) {
 	return response, err
 }
 
+func FilesCreateImageWithClient(ctx context.Context, client *genai.Client, w io.Writer) (*genai.GenerateContentResponse, error) {
+	return withClient(ctx, client, w, FilesCreateImage)
+}
+
 func FilesCreateAudio() (*genai.GenerateContentResponse, error) {
 	// [START files_create_audio]
 	ctx := context.Background()
@@ -104,17 +113,17 @@ func FilesCreateAudio() (*genai.GenerateContentResponse, error) {
 		Backend: genai.BackendGeminiAPI,
 	})
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 	myfile, err := client.Files.UploadFromPath(
-		ctx, 
-		filepath.Join(getMedia(), "sample.mp3"), 
+		ctx,
+		filepath.Join(getMedia(), "sample.mp3"),
 		&genai.UploadFileConfig{
-			MIMEType : "audio/mpeg",
+			MIMEType: "audio/mpeg",
 		},
 	)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 	fmt.Printf("myfile=%+v\n", myfile)
 
@@ -129,7 +138,7 @@ func FilesCreateAudio() (*genai.GenerateContentResponse, error) {
 
 	response, err := client.Models.GenerateContent(ctx, "gemini-3.5-flash", contents, nil)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 	text := response.Text()
 	fmt.Printf("result.text=%s\n", text)
@@ -137,6 +146,10 @@ func FilesCreateAudio() (*genai.GenerateContentResponse, error) {
 	return response, err
 }
 
+func FilesCreateAudioWithClient(ctx context.Context, client *genai.Client, w io.Writer) (*genai.GenerateContentResponse, error) {
+	return withClient(ctx, client, w, FilesCreateAudio)
+}
+
 func FilesCreateVideo() (*genai.GenerateContentResponse, error) {
 	// [START files_create_video]
 	ctx := context.Background()
@@ -145,30 +158,33 @@ func FilesCreateVideo() (*genai.GenerateContentResponse, error) {
 		Backend: genai.BackendGeminiAPI,
 	})
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 	myfile, err := client.Files.UploadFromPath(
-		ctx, 
-		filepath.Join(getMedia(), "Big_Buck_Bunny.mp4"), 
+		ctx,
+		filepath.Join(getMedia(), "Big_Buck_Bunny.mp4"),
 		&genai.UploadFileConfig{
-			MIMEType : "video/mp4",
+			MIMEType: "video/mp4",
 		},
 	)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 	fmt.Printf("myfile=%+v\n", myfile)
 
-	// Poll until the video file is completely processed (state becomes ACTIVE).
-	for myfile.State == genai.FileStateUnspecified || myfile.State != genai.FileStateActive {
-		fmt.Println("Processing video...")
-		fmt.Println("File state:", myfile.State)
-		time.Sleep(5 * time.Second)
-
-		myfile, err = client.Files.Get(ctx, myfile.Name, nil)
-		if err != nil {
-			log.Fatal(err)
-		}
+	// Wait until the video file is completely processed (state becomes
+	// ACTIVE), polling less and less often, and give up if processing fails
+	// or takes more than five minutes.
+	waitCtx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+	defer cancel()
+	myfile, err = filewait.Wait(waitCtx, client, myfile, &filewait.Options{
+		Progress: func(f *genai.File, next time.Duration) {
+			fmt.Println("Processing video...")
+			fmt.Println("File state:", f.State)
+		},
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	parts := []*genai.Part{
@@ -182,7 +198,7 @@ func FilesCreateVideo() (*genai.GenerateContentResponse, error) {
 
 	response, err := client.Models.GenerateContent(ctx, "gemini-3.5-flash", contents, nil)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 	text := response.Text()
 	fmt.Printf("result.text=%s\n", text)
@@ -190,6 +206,10 @@ func FilesCreateVideo() (*genai.GenerateContentResponse, error) {
 	return response, err
 }
 
+func FilesCreateVideoWithClient(ctx context.Context, client *genai.Client, w io.Writer) (*genai.GenerateContentResponse, error) {
+	return withClient(ctx, client, w, FilesCreateVideo)
+}
+
 func FilesCreatePdf() (*genai.GenerateContentResponse, error) {
 	// [START files_create_pdf]
 	ctx := context.Background()
@@ -198,7 +218,7 @@ func FilesCreatePdf() (*genai.GenerateContentResponse, error) {
 		Backend: genai.BackendGeminiAPI,
 	})
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 	samplePdf, err := client.Files.UploadFromPath(
 		ctx,
@@ -208,7 +228,7 @@ func FilesCreatePdf() (*genai.GenerateContentResponse, error) {
 		},
 	)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
 	parts := []*genai.Part{
@@ -222,7 +242,7 @@ func FilesCreatePdf() (*genai.GenerateContentResponse, error) {
 
 	response, err := client.Models.GenerateContent(ctx, "gemini-3.5-flash", contents, nil)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 	text := response.Text()
 	fmt.Println(text)
@@ -230,6 +250,10 @@ func FilesCreatePdf() (*genai.GenerateContentResponse, error) {
 	return response, err
 }
 
+func FilesCreatePdfWithClient(ctx context.Context, client *genai.Client, w io.Writer) (*genai.GenerateContentResponse, error) {
+	return withClient(ctx, client, w, FilesCreatePdf)
+}
+
 func FilesCreateFromIO() (*genai.GenerateContentResponse, error) {
 	// [START files_create_io]
 	ctx := context.Background()
@@ -238,18 +262,18 @@ func FilesCreateFromIO() (*genai.GenerateContentResponse, error) {
 		Backend: genai.BackendGeminiAPI,
 	})
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 	f, err := os.Open(filepath.Join(getMedia(), "test.pdf"))
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 	defer f.Close()
 	samplePdf, err := client.Files.Upload(ctx, f, &genai.UploadFileConfig{
-		MIMEType : "application/pdf",
+		MIMEType: "application/pdf",
 	})
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
 	parts := []*genai.Part{
@@ -263,7 +287,7 @@ func FilesCreateFromIO() (*genai.GenerateContentResponse, error) {
 
 	response, err := client.Models.GenerateContent(ctx, "gemini-3.5-flash", contents, nil)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 	text := response.Text()
 	fmt.Println(text)
@@ -271,6 +295,10 @@ func FilesCreateFromIO() (*genai.GenerateContentResponse, error) {
 	return response, err
 }
 
+func FilesCreateFromIOWithClient(ctx context.Context, client *genai.Client, w io.Writer) (*genai.GenerateContentResponse, error) {
+	return withClient(ctx, client, w, FilesCreateFromIO)
+}
+
 func FilesList() error {
 	// [START files_list]
 	ctx := context.Background()
@@ -279,20 +307,24 @@ func FilesList() error {
 		Backend: genai.BackendGeminiAPI,
 	})
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 	fmt.Println("My files:")
-	page, err := client.Files.List(ctx, nil)
-	if err != nil {
-		log.Fatal(err)
-	}
-	for _, f := range page.Items {
+	// All goes through every page of the listing.
+	for f, err := range client.Files.All(ctx) {
+		if err != nil {
+			return err
+		}
 		fmt.Println("  ", f.Name)
 	}
 	// [END files_list]
 	return nil
 }
 
+func FilesListWithClient(ctx context.Context, client *genai.Client, w io.Writer) error {
+	return runWith(ctx, client, w, FilesList)
+}
+
 func FilesGet() (*genai.File, error) {
 	// [START files_get]
 	ctx := context.Background()
@@ -301,29 +333,33 @@ func FilesGet() (*genai.File, error) {
 		Backend: genai.BackendGeminiAPI,
 	})
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 	myfile, err := client.Files.UploadFromPath(
 		ctx,
-		filepath.Join(getMedia(), "poem.txt"), 
+		filepath.Join(getMedia(), "poem.txt"),
 		&genai.UploadFileConfig{
 			MIMEType: "text/plain",
 		},
 	)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 	fileName := myfile.Name
 	fmt.Println(fileName)
 	file, err := client.Files.Get(ctx, fileName, nil)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 	fmt.Println(file)
 	// [END files_get]
 	return file, err
 }
 
+func FilesGetWithClient(ctx context.Context, client *genai.Client, w io.Writer) (*genai.File, error) {
+	return withClient(ctx, client, w, FilesGet)
+}
+
 func FilesDelete() error {
 	// [START files_delete]
 	ctx := context.Background()
@@ -332,22 +368,22 @@ func FilesDelete() error {
 		Backend: genai.BackendGeminiAPI,
 	})
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 	myfile, err := client.Files.UploadFromPath(
-		ctx, 
-		filepath.Join(getMedia(), "poem.txt"), 
+		ctx,
+		filepath.Join(getMedia(), "poem.txt"),
 		&genai.UploadFileConfig{
 			MIMEType: "text/plain",
 		},
 	)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 	// Delete the file.
 	_, err = client.Files.Delete(ctx, myfile.Name, nil)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 	// Attempt to use the deleted file.
 	parts := []*genai.Part{
@@ -361,9 +397,13 @@ func FilesDelete() error {
 	
 	_, err = client.Models.GenerateContent(ctx, "gemini-3.5-flash", contents, nil)
 	// Expect an error when using a deleted file.
-	if err != nil {
-		return nil
+	if err == nil {
+		return fmt.Errorf("expected an error when using deleted file")
 	}
-	return fmt.Errorf("expected an error when using deleted file")
 	// [END files_delete]
+	return nil
+}
+
+func FilesDeleteWithClient(ctx context.Context, client *genai.Client, w io.Writer) error {
+	return runWith(ctx, client, w, FilesDelete)
 }
@@ -1,10 +1,29 @@
 package examples
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
+
+	"gemini-api-examples/internal/fakegemini"
+	"gemini-api-examples/internal/filewait"
+	"gemini-api-examples/internal/upload"
+
+	"google.golang.org/genai"
 )
 
 func TestFilesCreateText(t *testing.T) {
+	useCassette(t)
 	_, err := FilesCreateText()
 	if err != nil {
 		t.Errorf("FilesCreateText returned an error: %v", err)
@@ -12,6 +31,7 @@ func TestFilesCreateText(t *testing.T) {
 }
 
 func TestFilesCreateImage(t *testing.T) {
+	useCassette(t)
 	_, err := FilesCreateImage()
 	if err != nil {
 		t.Errorf("FilesCreateImage returned an error: %v", err)
@@ -19,6 +39,7 @@ func TestFilesCreateImage(t *testing.T) {
 }
 
 func TestFilesCreateAudio(t *testing.T) {
+	useCassette(t)
 	_, err := FilesCreateAudio()
 	if err != nil {
 		t.Errorf("FilesCreateAudio returned an error: %v", err)
@@ -26,6 +47,7 @@ func TestFilesCreateAudio(t *testing.T) {
 }
 
 func TestFilesCreateVideo(t *testing.T) {
+	useCassette(t)
 	_, err := FilesCreateVideo()
 	if err != nil {
 		t.Errorf("FilesCreateVideo returned an error: %v", err)
@@ -33,6 +55,7 @@ func TestFilesCreateVideo(t *testing.T) {
 }
 
 func TestFilesCreatePdf(t *testing.T) {
+	useCassette(t)
 	_, err := FilesCreatePdf()
 	if err != nil {
 		t.Errorf("FilesCreatePdf returned an error: %v", err)
@@ -40,6 +63,7 @@ func TestFilesCreatePdf(t *testing.T) {
 }
 
 func TestFilesCreateFromIO(t *testing.T) {
+	useCassette(t)
 	_, err := FilesCreateFromIO()
 	if err != nil {
 		t.Errorf("FilesCreateFromIO returned an error: %v", err)
@@ -47,6 +71,7 @@ func TestFilesCreateFromIO(t *testing.T) {
 }
 
 func TestFilesList(t *testing.T) {
+	useCassette(t)
 	err := FilesList()
 	if err != nil {
 		t.Errorf("FilesList returned an error: %v", err)
@@ -54,6 +79,7 @@ func TestFilesList(t *testing.T) {
 }
 
 func TestFilesGet(t *testing.T) {
+	useCassette(t)
 	_, err := FilesGet()
 	if err != nil {
 		t.Errorf("FilesGet returned an error: %v", err)
@@ -61,8 +87,186 @@ func TestFilesGet(t *testing.T) {
 }
 
 func TestFilesDelete(t *testing.T) {
+	useCassette(t)
 	err := FilesDelete()
 	if err != nil {
 		t.Errorf("FilesDelete returned an error: %v", err)
 	}
 }
+
+func TestFakeFilesCreateVideoProcessingFailed(t *testing.T) {
+	srv, client := fakeClient(t)
+	srv.Inject("files", 1, fakegemini.ProcessingFailed("unsupported codec"))
+	_, err := FilesCreateVideoWithClient(t.Context(), client, io.Discard)
+	if err == nil || !strings.Contains(err.Error(), "unsupported codec") {
+		t.Errorf("err = %v, want an error with the server's message", err)
+	}
+	if n := len(srv.GenerateRequests()); n != 0 {
+		t.Errorf("got %d generate requests, want none", n)
+	}
+}
+
+// TestFakeVideoProcessingFailedWithoutDetails checks that the samples
+// waiting for a video report its failure when the server gives no details.
+func TestFakeVideoProcessingFailedWithoutDetails(t *testing.T) {
+	for _, s := range Select("video") {
+		t.Run(s.Name, func(t *testing.T) {
+			if missing := s.MissingMedia(); len(missing) > 0 {
+				t.Skipf("missing media in third_party: %s", strings.Join(missing, ", "))
+			}
+			srv, client := fakeClient(t)
+			srv.Inject("files", 1, fakegemini.ProcessingFailed(""))
+			var failed *filewait.FailedError
+			if err := runSample(t, s, client); !errors.As(err, &failed) {
+				t.Errorf("err = %v, want a *filewait.FailedError", err)
+			}
+		})
+	}
+}
+
+func TestFakeFilesCreateFromIO(t *testing.T) {
+	srv, client := fakeClient(t)
+	if _, err := FilesCreateFromIOWithClient(t.Context(), client, io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	files := srv.Files()
+	if len(files) != 1 || files[0].MIMEType != "application/pdf" {
+		t.Fatalf("files on server = %+v, want the uploaded PDF", files)
+	}
+	if n := len(srv.GenerateRequests()); n != 1 {
+		t.Errorf("got %d generate requests, want 1", n)
+	}
+}
+
+func TestFakeFilesListAllPages(t *testing.T) {
+	srv, client := fakeClient(t)
+	for i := range 25 {
+		srv.AddFile(&genai.File{Name: fmt.Sprintf("files/f%02d", i), MIMEType: "text/plain"}, nil)
+	}
+	var out bytes.Buffer
+	if err := FilesListWithClient(t.Context(), client, &out); err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(out.String(), "files/f"); n != 25 {
+		t.Errorf("listed %d files, want 25:\n%s", n, out.String())
+	}
+	if n := len(srv.RequestsTo("GET", "/files")); n != 3 {
+		t.Errorf("made %d list requests, want 3", n)
+	}
+}
+
+func TestFakeFilesCreateVideoWithClientDeadline(t *testing.T) {
+	srv, client := fakeClient(t)
+	srv.SetProcessingPolls(-1)
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	var out bytes.Buffer
+	_, err := FilesCreateVideoWithClient(ctx, client, &out)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want context.DeadlineExceeded", err)
+	}
+	if !strings.Contains(out.String(), "File state: PROCESSING") {
+		t.Errorf("output = %q, want progress lines", out.String())
+	}
+}
+
+// TestUploadMIMETypes checks the MIME type each sample gives
+// Files.UploadFromPath for a file of third_party against the type
+// upload.DetectFile finds for it, so that the snippets can show the plain SDK
+// call and still upload every file with a type the Gemini API accepts.
+func TestUploadMIMETypes(t *testing.T) {
+	names, err := filepath.Glob("*.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fset := token.NewFileSet()
+	checked := 0
+	for _, name := range names {
+		if strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		f, err := parser.ParseFile(fset, name, nil, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ast.Inspect(f, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok || len(call.Args) != 3 {
+				return true
+			}
+			if fun, ok := call.Fun.(*ast.SelectorExpr); !ok || fun.Sel.Name != "UploadFromPath" {
+				return true
+			}
+			pos := fset.Position(call.Pos())
+			file, ok := mediaArg(call.Args[1])
+			if !ok {
+				t.Errorf("%s: UploadFromPath of a path other than filepath.Join(getMedia(), \"name\")", pos)
+				return true
+			}
+			mimeType, ok := mimeTypeArg(call.Args[2])
+			if !ok {
+				t.Errorf("%s: UploadFromPath of %s without a literal MIMEType", pos, file)
+				return true
+			}
+			want, err := upload.DetectFile(filepath.Join(getMedia(), file))
+			if err != nil {
+				t.Logf("%s: %v", pos, err)
+				return true
+			}
+			if mimeType != want {
+				t.Errorf("%s: %s uploaded as %s, want %s", pos, file, mimeType, want)
+			}
+			checked++
+			return true
+		})
+	}
+	if checked == 0 {
+		t.Error("found no UploadFromPath call to check")
+	}
+}
+
+// mediaArg returns the name of the file of third_party that e, of the form
+// filepath.Join(getMedia(), "name"), refers to.
+func mediaArg(e ast.Expr) (string, bool) {
+	call, ok := e.(*ast.CallExpr)
+	if !ok || len(call.Args) != 2 {
+		return "", false
+	}
+	if c, ok := call.Args[0].(*ast.CallExpr); !ok {
+		return "", false
+	} else if id, ok := c.Fun.(*ast.Ident); !ok || id.Name != "getMedia" {
+		return "", false
+	}
+	lit, ok := call.Args[1].(*ast.BasicLit)
+	if !ok || lit.Kind != token.STRING {
+		return "", false
+	}
+	name, err := strconv.Unquote(lit.Value)
+	return name, err == nil
+}
+
+// mimeTypeArg returns the MIMEType of e, of the form
+// &genai.UploadFileConfig{MIMEType: "type", ...}.
+func mimeTypeArg(e ast.Expr) (string, bool) {
+	u, ok := e.(*ast.UnaryExpr)
+	if !ok {
+		return "", false
+	}
+	lit, ok := u.X.(*ast.CompositeLit)
+	if !ok {
+		return "", false
+	}
+	for _, elt := range lit.Elts {
+		kv, ok := elt.(*ast.KeyValueExpr)
+		if !ok {
+			continue
+		}
+		if key, ok := kv.Key.(*ast.Ident); ok && key.Name == "MIMEType" {
+			if v, ok := kv.Value.(*ast.BasicLit); ok && v.Kind == token.STRING {
+				s, err := strconv.Unquote(v.Value)
+				return s, err == nil
+			}
+		}
+	}
+	return "", false
+}
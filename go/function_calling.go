@@ -4,60 +4,12 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"log"
+	"io"
 	"os"
 
 	"google.golang.org/genai"
 )
 
-// Arithmetic functions.
-func add(a, b float64) float64 {
-	return a + b
-}
-
-func subtract(a, b float64) float64 {
-	return a - b
-}
-
-func multiply(a, b float64) float64 {
-	return a * b
-}
-
-func divide(a, b float64) float64 {
-	return a / b
-}
-
-// ArithmeticArgs represents the expected arguments for our arithmetic operations.
-type ArithmeticArgs struct {
-	FirstParam  float64 `json:"firstParam"`
-	SecondParam float64 `json:"secondParam"`
-}
-
-// createArithmeticToolDeclaration creates a function declaration with the given name and description.
-// The parameters schema includes "firstParam" and "secondParam" as required numbers.
-func createArithmeticToolDeclaration(name, description string) *genai.FunctionDeclaration {
-	paramSchema := &genai.Schema{
-		Type: genai.TypeObject,
-		Description: "The result of the arithmetic operation.",
-		Properties: map[string]*genai.Schema{
-			"firstParam": {
-				Type:        genai.TypeNumber,
-				Description: "The first parameter which can be an integer or a floating point number.",
-			},
-			"secondParam": {
-				Type:        genai.TypeNumber,
-				Description: "The second parameter which can be an integer or a floating point number.",
-			},
-		},
-		Required: []string{"firstParam", "secondParam"},
-	}
-	return &genai.FunctionDeclaration{
-		Name:        name,
-		Description: description,
-		Parameters:  paramSchema,
-	}
-}
-
 func FunctionCalling() error {
 	// [START function_calling]
 	ctx := context.Background()
@@ -66,9 +18,45 @@ func FunctionCalling() error {
 		Backend: genai.BackendGeminiAPI,
 	})
 	if err != nil {
-		log.Fatal(err)
+		return err
+	}
+
+	// Arithmetic functions.
+	add := func(a, b float64) float64 { return a + b }
+	subtract := func(a, b float64) float64 { return a - b }
+	multiply := func(a, b float64) float64 { return a * b }
+	divide := func(a, b float64) float64 { return a / b }
+
+	// ArithmeticArgs represents the expected arguments for our arithmetic operations.
+	type ArithmeticArgs struct {
+		FirstParam  float64 `json:"firstParam"`
+		SecondParam float64 `json:"secondParam"`
+	}
+
+	// createArithmeticToolDeclaration creates a function declaration with the given name and description.
+	// The parameters schema includes "firstParam" and "secondParam" as required numbers.
+	createArithmeticToolDeclaration := func(name, description string) *genai.FunctionDeclaration {
+		paramSchema := &genai.Schema{
+			Type: genai.TypeObject,
+			Description: "The result of the arithmetic operation.",
+			Properties: map[string]*genai.Schema{
+				"firstParam": {
+					Type:        genai.TypeNumber,
+					Description: "The first parameter which can be an integer or a floating point number.",
+				},
+				"secondParam": {
+					Type:        genai.TypeNumber,
+					Description: "The second parameter which can be an integer or a floating point number.",
+				},
+			},
+			Required: []string{"firstParam", "secondParam"},
+		}
+		return &genai.FunctionDeclaration{
+			Name:        name,
+			Description: description,
+			Parameters:  paramSchema,
+		}
 	}
-	modelName := "gemini-3.5-flash"
 
 	// Create the function declarations for arithmetic operations.
 	addDeclaration := createArithmeticToolDeclaration("addNumbers", "Return the result of adding two numbers.")
@@ -106,29 +94,29 @@ func FunctionCalling() error {
 		},
 	}
 
-	genContentResp, err := client.Models.GenerateContent(ctx, modelName, contents, config)
+	genContentResp, err := client.Models.GenerateContent(ctx, "gemini-3.5-flash", contents, config)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	// Assume the response includes a list of function calls.
 	if len(genContentResp.FunctionCalls()) == 0 {
-		log.Println("No function call returned from the AI.")
+		fmt.Println("No function call returned from the AI.")
 		return nil
 	}
 	functionCall := genContentResp.FunctionCalls()[0]
-	log.Printf("Function call: %+v\n", functionCall)
+	fmt.Printf("Function call: %+v\n", functionCall)
 
 	// Marshal the Args map into JSON bytes.
 	argsMap, err := json.Marshal(functionCall.Args)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	// Unmarshal the JSON bytes into the ArithmeticArgs struct.
 	var args ArithmeticArgs
 	if err := json.Unmarshal(argsMap, &args); err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	// Map the function name to the actual arithmetic function.
@@ -145,7 +133,7 @@ func FunctionCalling() error {
 		default:
 			return fmt.Errorf("unimplemented function: %s", functionCall.Name)
 	}
-	log.Printf("Function result: %v\n", result)
+	fmt.Printf("Function result: %v\n", result)
 
 	// Prepare the final result message as content.
 	resultContents := []*genai.Content{
@@ -153,12 +141,16 @@ func FunctionCalling() error {
 	}
 
 	// Use GenerateContent to send the final result.
-	finalResponse, err := client.Models.GenerateContent(ctx, modelName, resultContents, &genai.GenerateContentConfig{})
+	finalResponse, err := client.Models.GenerateContent(ctx, "gemini-3.5-flash", resultContents, &genai.GenerateContentConfig{})
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	printResponse(finalResponse)
 	// [END function_calling]
 	return err
 }
+
+func FunctionCallingWithClient(ctx context.Context, client *genai.Client, w io.Writer) error {
+	return runWith(ctx, client, w, FunctionCalling)
+}
@@ -1,12 +1,41 @@
 package examples
 
 import (
+	"bytes"
+	"context"
+	"strings"
 	"testing"
+
+	"gemini-api-examples/internal/fakegemini"
+	"google.golang.org/genai"
 )
 
 func TestFunctionCalling(t *testing.T) {
+	useCassette(t)
 	err := FunctionCalling()
 	if err != nil {
 		t.Errorf("FunctionCalling returned an error.")
 	}
 }
+
+func TestFakeFunctionCallingWithClient(t *testing.T) {
+	srv, client := fakeClient(t)
+	srv.OnGenerate(func(req *fakegemini.GenerateRequest) (*genai.GenerateContentResponse, error) {
+		if len(req.FunctionDeclarations()) > 0 {
+			return fakegemini.FunctionCallResponse("divideNumbers", map[string]any{
+				"firstParam":  10,
+				"secondParam": 4,
+			}), nil
+		}
+		return fakegemini.TextResponse("The answer is 2.5."), nil
+	})
+	var out bytes.Buffer
+	if err := FunctionCallingWithClient(context.Background(), client, &out); err != nil {
+		t.Fatalf("FunctionCallingWithClient returned an error: %v", err)
+	}
+	for _, want := range []string{"Function result: 2.5\n", "The answer is 2.5.\n"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output = %q, want it to contain %q", out.String(), want)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+// Package caches creates cached contents once and reuses them.
+//
+// A Manager fingerprints what goes into a cache, the model, system
+// instruction, contents and tools, and returns a live cache with that
+// fingerprint instead of creating another one:
+//
+//	cache, reused, err := caches.GetOrCreate(ctx, client, "gemini-3.5-flash", &genai.CreateCachedContentConfig{
+//		Contents:          contents,
+//		SystemInstruction: genai.NewContentFromText("You are an expert analyzing transcripts.", genai.RoleUser),
+//	})
+//
+// Before creating a cache, GetOrCreate checks with Validate that the model
+// supports caching and that the contents are within its limits.
+//
+// GetOrCreate uses the index file named by the GEMINI_CACHE_INDEX
+// environment variable, if set, and otherwise a Manager that lives as long
+// as the program.
+//
+// A Keepalive extends the TTL of the caches a long job holds, so that they
+// do not expire while in use, and Savings works out whether caching paid
+// off from the usage metadata of the calls that used them. A Chat moves
+// its history into a cache as the conversation grows.
+package caches
+
+import (
+	"context"
+	"os"
+	"sync"
+
+	"google.golang.org/genai"
+)
+
+// GetOrCreate returns a live cache for model and config, as
+// Manager.GetOrCreate does, through the Manager of the index file named by
+// GEMINI_CACHE_INDEX or, if it is not set, one shared by the program.
+func GetOrCreate(ctx context.Context, client *genai.Client, model string, config *genai.CreateCachedContentConfig) (*genai.CachedContent, bool, error) {
+	m, err := envManager()
+	if err != nil {
+		return nil, false, err
+	}
+	return m.GetOrCreate(ctx, client, model, config)
+}
+
+// envManager returns the Manager of GetOrCreate, opened once.
+var envManager = sync.OnceValues(func() (*Manager, error) {
+	return OpenManager(os.Getenv("GEMINI_CACHE_INDEX"))
+})
@@ -0,0 +1,59 @@
+package caches
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"gemini-api-examples/internal/config"
+	"gemini-api-examples/internal/fakegemini"
+	"gemini-api-examples/internal/upload"
+
+	"google.golang.org/genai"
+)
+
+const thirdParty = "../../../third_party"
+
+func newFake(t *testing.T) (*fakegemini.Server, *genai.Client) {
+	t.Helper()
+	srv := fakegemini.New()
+	t.Cleanup(srv.Close)
+	cfg := &config.Config{APIKey: "fake", BaseURL: srv.URL}
+	client, err := cfg.NewClient(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return srv, client
+}
+
+// transcript uploads the Apollo 11 transcript and returns the config of a
+// cache of it.
+func transcript(t *testing.T, client *genai.Client) *genai.CreateCachedContentConfig {
+	t.Helper()
+	f, err := upload.FromPath(t.Context(), client, filepath.Join(thirdParty, "a11.txt"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &genai.CreateCachedContentConfig{
+		Contents: []*genai.Content{
+			genai.NewContentFromParts([]*genai.Part{genai.NewPartFromURI(f.URI, f.MIMEType)}, genai.RoleUser),
+		},
+		SystemInstruction: genai.NewContentFromText("You are an expert analyzing transcripts.", genai.RoleUser),
+	}
+}
+
+func TestGetOrCreate(t *testing.T) {
+	srv, client := newFake(t)
+	config := transcript(t, client)
+	first, reused, err := GetOrCreate(t.Context(), client, "gemini-3.5-flash", config)
+	if err != nil || reused {
+		t.Fatalf("GetOrCreate = %v, %v, want a new cache", reused, err)
+	}
+	second, reused, err := GetOrCreate(t.Context(), client, "gemini-3.5-flash", config)
+	if err != nil || !reused || second.Name != first.Name {
+		t.Errorf("GetOrCreate again = %v, %v, %v, want %s reused", second, reused, err, first.Name)
+	}
+	if n := len(srv.Caches()); n != 1 {
+		t.Errorf("%d caches, want 1", n)
+	}
+}
@@ -0,0 +1,191 @@
+package caches
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gemini-api-examples/internal/tracker"
+
+	"google.golang.org/genai"
+)
+
+// DefaultCheckpointTokens is the default ChatOptions.Threshold.
+const DefaultCheckpointTokens = 32768
+
+// ChatOptions controls a Chat.
+type ChatOptions struct {
+	// Threshold is the number of tokens, as counted by Models.CountTokens,
+	// that the history not yet cached must reach to be moved into a cache
+	// before the next message. It defaults to DefaultCheckpointTokens and
+	// must not be less than DefaultMinTokens for the model, the smallest
+	// cache it can have.
+	Threshold int32
+	// TTL is the TTL of the caches, the API's default if zero.
+	TTL time.Duration
+}
+
+// A Chat is a conversation, like a genai.Chat, that keeps its history in a
+// cache once it grows large, so that each message does not send it again.
+//
+// Before sending a message, the Chat counts the tokens of the history
+// beyond its cache. Past the threshold, it caches the whole history, with
+// the system instruction and tools of the chat, continues on top of the new
+// cache and deletes the previous one. If that cache expired or was deleted
+// meanwhile, which the API reports as NOT_FOUND, the Chat caches the history
+// again and sends the message on top of the new cache; other errors, such
+// as PERMISSION_DENIED, go back to the caller. Call Close to delete the last cache. A Chat is not
+// safe for concurrent use.
+type Chat struct {
+	client  *genai.Client
+	model   string
+	config  genai.GenerateContentConfig
+	opts    ChatOptions
+	history []*genai.Content
+	// cached is the number of entries at the start of history that are in
+	// cache.
+	cached int
+	cache  *genai.CachedContent
+}
+
+// NewChat starts a chat with model. The system instruction, tools and tool
+// configuration of config go into the caches; it must not name a cache
+// itself. NewChat fails if opts sets a threshold below the minimum size of a
+// cache for model, which no checkpoint could reach.
+func NewChat(client *genai.Client, model string, config *genai.GenerateContentConfig, opts *ChatOptions) (*Chat, error) {
+	c := &Chat{client: client, model: model}
+	if config != nil {
+		if config.CachedContent != "" {
+			return nil, errors.New("caches.NewChat: the config names a cache")
+		}
+		c.config = *config
+	}
+	if opts != nil {
+		c.opts = *opts
+	}
+	if c.opts.Threshold <= 0 {
+		c.opts.Threshold = DefaultCheckpointTokens
+	}
+	if minTokens := DefaultMinTokens(model); c.opts.Threshold < minTokens {
+		return nil, fmt.Errorf("caches.NewChat: threshold of %d tokens is below the minimum of %d for a cache of %s",
+			c.opts.Threshold, minTokens, model)
+	}
+	return c, nil
+}
+
+// SendMessage checkpoints the history if it is due and sends parts as the
+// next user message. The message and the reply are added to the history
+// when the response has a candidate.
+func (c *Chat) SendMessage(ctx context.Context, parts ...genai.Part) (*genai.GenerateContentResponse, error) {
+	if err := c.checkpointIfDue(ctx); err != nil {
+		return nil, err
+	}
+	input := &genai.Content{Role: genai.RoleUser}
+	for i := range parts {
+		input.Parts = append(input.Parts, &parts[i])
+	}
+	resp, err := c.send(ctx, input)
+	if err != nil && c.cache != nil && tracker.IsGone(tracker.KindCache, err) {
+		// The cache expired or was deleted: cache the whole history again.
+		c.cache, c.cached = nil, 0
+		if err := c.Checkpoint(ctx); err != nil {
+			return nil, err
+		}
+		resp, err = c.send(ctx, input)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Candidates) > 0 && resp.Candidates[0].Content != nil {
+		output := resp.Candidates[0].Content
+		if output.Role == "" {
+			output.Role = genai.RoleModel
+		}
+		c.history = append(c.history, input, output)
+	}
+	return resp, nil
+}
+
+// send sends input on top of the cache and the history beyond it.
+func (c *Chat) send(ctx context.Context, input *genai.Content) (*genai.GenerateContentResponse, error) {
+	cfg := c.config
+	if c.cache != nil {
+		cfg.CachedContent = c.cache.Name
+		cfg.SystemInstruction, cfg.Tools, cfg.ToolConfig = nil, nil, nil
+	}
+	contents := append(c.history[c.cached:len(c.history):len(c.history)], input)
+	return c.client.Models.GenerateContent(ctx, c.model, contents, &cfg)
+}
+
+// checkpointIfDue checkpoints the history if the part beyond the cache has
+// reached the threshold.
+func (c *Chat) checkpointIfDue(ctx context.Context) error {
+	tail := c.history[c.cached:]
+	if len(tail) == 0 {
+		return nil
+	}
+	count, err := c.client.Models.CountTokens(ctx, c.model, tail, nil)
+	if err != nil {
+		return fmt.Errorf("counting the tokens of the history: %w", err)
+	}
+	if count.TotalTokens < c.opts.Threshold {
+		return nil
+	}
+	return c.Checkpoint(ctx)
+}
+
+// Checkpoint moves the whole history into a new cache, on top of which the
+// chat continues, and deletes the previous cache. The cache is checked with
+// Validate before it is created.
+func (c *Chat) Checkpoint(ctx context.Context) error {
+	if c.cached == len(c.history) {
+		return nil
+	}
+	cfg := &genai.CreateCachedContentConfig{
+		TTL:               c.opts.TTL,
+		Contents:          c.history,
+		SystemInstruction: c.config.SystemInstruction,
+		Tools:             c.config.Tools,
+		ToolConfig:        c.config.ToolConfig,
+	}
+	if _, err := Validate(ctx, c.client, c.model, cfg, nil); err != nil {
+		return fmt.Errorf("caching the history: %w", err)
+	}
+	cache, err := c.client.Caches.Create(ctx, c.model, cfg)
+	if err != nil {
+		return fmt.Errorf("caching the history: %w", err)
+	}
+	previous := c.cache
+	c.cache, c.cached = cache, len(c.history)
+	return c.delete(ctx, previous)
+}
+
+// History returns the messages of the chat, cached or not.
+func (c *Chat) History() []*genai.Content {
+	return c.history[:len(c.history):len(c.history)]
+}
+
+// Cache returns the cache holding the start of the history, or nil if there
+// is none yet.
+func (c *Chat) Cache() *genai.CachedContent {
+	return c.cache
+}
+
+// Close deletes the cache of the chat, after which the chat starts again
+// from its whole history.
+func (c *Chat) Close(ctx context.Context) error {
+	cache := c.cache
+	c.cache, c.cached = nil, 0
+	return c.delete(ctx, cache)
+}
+
+func (c *Chat) delete(ctx context.Context, cache *genai.CachedContent) error {
+	if cache == nil {
+		return nil
+	}
+	if _, err := c.client.Caches.Delete(ctx, cache.Name, nil); err != nil && !tracker.IsGone(tracker.KindCache, err) {
+		return fmt.Errorf("deleting %s: %w", cache.Name, err)
+	}
+	return nil
+}
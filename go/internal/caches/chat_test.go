@@ -0,0 +1,246 @@
+package caches
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"gemini-api-examples/internal/fakegemini"
+
+	"google.golang.org/genai"
+)
+
+// message returns a message of about n tokens for the fake server, which
+// counts one token per four characters.
+func message(text string, n int) genai.Part {
+	return genai.Part{Text: text + strings.Repeat(" talk", n*4/5)}
+}
+
+func TestChatCheckpoints(t *testing.T) {
+	srv, client := newFake(t)
+	tools := []*genai.Tool{{CodeExecution: &genai.ToolCodeExecution{}}}
+	chat, err := NewChat(client, "gemini-3.5-flash", &genai.GenerateContentConfig{
+		SystemInstruction: genai.NewContentFromText("You are an expert analyzing transcripts.", genai.RoleUser),
+		Tools:             tools,
+	}, &ChatOptions{Threshold: 2000})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A short exchange stays uncached.
+	if _, err := chat.SendMessage(t.Context(), message("hello", 200)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := chat.SendMessage(t.Context(), message("tell me more", 2000)); err != nil {
+		t.Fatal(err)
+	}
+	if chat.Cache() != nil || len(srv.Caches()) != 0 {
+		t.Fatal("cached a history under the threshold")
+	}
+
+	// Past the threshold, the history moves into a cache before the next
+	// message, which is sent on top of it.
+	if _, err := chat.SendMessage(t.Context(), message("and then?", 200)); err != nil {
+		t.Fatal(err)
+	}
+	first := chat.Cache()
+	if first == nil {
+		t.Fatal("history not cached past the threshold")
+	}
+	reqs := srv.GenerateRequests()
+	last := reqs[len(reqs)-1]
+	if last.CachedContent != first.Name || len(last.Contents) != 1 || last.SystemInstruction != nil || len(last.Tools) != 0 {
+		t.Errorf("request on the cache: cache %q, %d contents, system instruction %v, %d tools; want only the new message",
+			last.CachedContent, len(last.Contents), last.SystemInstruction, len(last.Tools))
+	}
+	var created struct {
+		Contents          []*genai.Content
+		SystemInstruction *genai.Content
+		Tools             []*genai.Tool
+	}
+	creates := srv.RequestsTo(http.MethodPost, "cachedContents")
+	if err := json.Unmarshal(creates[0].Body, &created); err != nil {
+		t.Fatal(err)
+	}
+	if len(created.Contents) != 4 || created.SystemInstruction == nil || len(created.Tools) != 1 {
+		t.Errorf("cache of %d contents, system instruction %v, %d tools; want the 4 of the history, the instruction and the tool",
+			len(created.Contents), created.SystemInstruction, len(created.Tools))
+	}
+
+	// The cache rolls forward once the history grows again, and the
+	// previous one is deleted.
+	if _, err := chat.SendMessage(t.Context(), message("again", 2000)); err != nil {
+		t.Fatal(err)
+	}
+	if chat.Cache() != first {
+		t.Error("rolled the cache forward under the threshold")
+	}
+	if _, err := chat.SendMessage(t.Context(), message("last", 200)); err != nil {
+		t.Fatal(err)
+	}
+	second := chat.Cache()
+	if second == first {
+		t.Fatal("cache not rolled forward")
+	}
+	if caches := srv.Caches(); len(caches) != 1 || caches[0].Name != second.Name {
+		t.Errorf("caches = %v, want only %s", caches, second.Name)
+	}
+	creates = srv.RequestsTo(http.MethodPost, "cachedContents")
+	if err := json.Unmarshal(creates[1].Body, &created); err != nil {
+		t.Fatal(err)
+	}
+	if len(created.Contents) != 8 {
+		t.Errorf("rolled cache of %d contents, want the 8 of the history", len(created.Contents))
+	}
+	if n := len(chat.History()); n != 10 {
+		t.Errorf("history of %d contents, want 10", n)
+	}
+
+	if err := chat.Close(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(srv.Caches()); n != 0 {
+		t.Errorf("%d caches after Close", n)
+	}
+	// Without a cache, the chat sends its whole history again.
+	if _, err := chat.SendMessage(t.Context(), message("bye", 1)); err != nil {
+		t.Fatal(err)
+	}
+	if chat.Cache() == nil {
+		t.Error("history not cached again after Close")
+	}
+}
+
+func TestChatCheckpointFailure(t *testing.T) {
+	srv, client := newFake(t)
+	chat, err := NewChat(client, "gemini-3.5-flash", nil, &ChatOptions{Threshold: 1200})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := chat.SendMessage(t.Context(), message("hello", 1500)); err != nil {
+		t.Fatal(err)
+	}
+	srv.Inject("cachedContents", 1, fakegemini.ServerError(http.StatusInternalServerError))
+	if _, err := chat.SendMessage(t.Context(), message("more", 1)); err == nil {
+		t.Fatal("SendMessage succeeded despite the failure to cache the history")
+	}
+	if len(chat.History()) != 2 || chat.Cache() != nil {
+		t.Errorf("history of %d contents, cache %v, want them unchanged", len(chat.History()), chat.Cache())
+	}
+	// The next message tries again.
+	if _, err := chat.SendMessage(t.Context(), message("more", 1)); err != nil {
+		t.Fatal(err)
+	}
+	if chat.Cache() == nil {
+		t.Error("history not cached on the second try")
+	}
+	if err := chat.Close(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewChatWithCache(t *testing.T) {
+	_, client := newFake(t)
+	if _, err := NewChat(client, "gemini-3.5-flash", &genai.GenerateContentConfig{CachedContent: "cachedContents/x"}, nil); err == nil {
+		t.Error("NewChat succeeded with a cache in the config")
+	}
+}
+
+func TestNewChatThreshold(t *testing.T) {
+	_, client := newFake(t)
+	for _, tc := range []struct {
+		model     string
+		threshold int32
+		ok        bool
+	}{
+		{"gemini-3.5-flash", 0, true},
+		{"gemini-3.5-flash", 1024, true},
+		{"gemini-3.5-flash", 1000, false},
+		{"gemini-3.5-pro", 2000, false},
+		{"gemini-3.5-pro", 4096, true},
+	} {
+		_, err := NewChat(client, tc.model, nil, &ChatOptions{Threshold: tc.threshold})
+		if (err == nil) != tc.ok {
+			t.Errorf("NewChat(%s, threshold %d) = %v, want success %v", tc.model, tc.threshold, err, tc.ok)
+		}
+	}
+}
+
+func TestChatCacheGone(t *testing.T) {
+	srv, client := newFake(t)
+	chat, err := NewChat(client, "gemini-3.5-flash", nil, &ChatOptions{Threshold: 1200})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := chat.SendMessage(t.Context(), message("hello", 1500)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := chat.SendMessage(t.Context(), message("more", 1)); err != nil {
+		t.Fatal(err)
+	}
+	first := chat.Cache()
+	if first == nil {
+		t.Fatal("history not cached past the threshold")
+	}
+	// The cache expires behind the chat's back.
+	if _, err := client.Caches.Delete(t.Context(), first.Name, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := chat.SendMessage(t.Context(), message("still there?", 1)); err != nil {
+		t.Fatalf("SendMessage after the cache expired: %v", err)
+	}
+	second := chat.Cache()
+	if second == nil || second.Name == first.Name {
+		t.Fatalf("cache = %v, want a new one", second)
+	}
+	if caches := srv.Caches(); len(caches) != 1 || caches[0].Name != second.Name {
+		t.Errorf("caches = %v, want only %s", caches, second.Name)
+	}
+	reqs := srv.GenerateRequests()
+	last := reqs[len(reqs)-1]
+	if last.CachedContent != second.Name || len(last.Contents) != 1 {
+		t.Errorf("retried with cache %q and %d contents, want %s and the new message", last.CachedContent, len(last.Contents), second.Name)
+	}
+	if n := len(chat.History()); n != 6 {
+		t.Errorf("history of %d contents, want 6", n)
+	}
+	if err := chat.Close(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestChatCacheForbidden(t *testing.T) {
+	srv, client := newFake(t)
+	chat, err := NewChat(client, "gemini-3.5-flash", nil, &ChatOptions{Threshold: 1200})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := chat.SendMessage(t.Context(), message("hello", 1500)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := chat.SendMessage(t.Context(), message("more", 1)); err != nil {
+		t.Fatal(err)
+	}
+	first := chat.Cache()
+	if first == nil {
+		t.Fatal("history not cached past the threshold")
+	}
+	// A cache the key may not use is no reason to cache the history again.
+	srv.Inject(":generateContent", 0, fakegemini.ServerError(http.StatusForbidden))
+	_, err = chat.SendMessage(t.Context(), message("still there?", 1))
+	var apiErr genai.APIError
+	if !errors.As(err, &apiErr) || apiErr.Code != http.StatusForbidden {
+		t.Fatalf("err = %v, want the 403 error", err)
+	}
+	if chat.Cache() != first || len(srv.Caches()) != 1 {
+		t.Errorf("cache = %v and %d caches, want %s kept", chat.Cache(), len(srv.Caches()), first.Name)
+	}
+	if n := len(chat.History()); n != 4 {
+		t.Errorf("history of %d contents, want 4", n)
+	}
+	if err := chat.Close(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,53 @@
+package caches_test
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path/filepath"
+
+	"gemini-api-examples/internal/caches"
+	"gemini-api-examples/internal/config"
+	"gemini-api-examples/internal/fakegemini"
+
+	"google.golang.org/genai"
+)
+
+// The caching samples each create a cache of the Apollo 11 transcript with
+// Caches.Create, as the documentation shows. A program caching the same
+// content over and over gets one cache instead through a Manager.
+func ExampleManager_GetOrCreate() {
+	srv := fakegemini.New()
+	defer srv.Close()
+	ctx := context.Background()
+	client, err := (&config.Config{APIKey: "fake", BaseURL: srv.URL}).NewClient(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	m, err := caches.OpenManager("")
+	if err != nil {
+		log.Fatal(err)
+	}
+	for range 3 {
+		document, err := client.Files.UploadFromPath(ctx, filepath.Join("..", "..", "..", "third_party", "a11.txt"),
+			&genai.UploadFileConfig{MIMEType: "text/plain"})
+		if err != nil {
+			log.Fatal(err)
+		}
+		cache, reused, err := m.GetOrCreate(ctx, client, "gemini-3.5-flash", &genai.CreateCachedContentConfig{
+			Contents: []*genai.Content{
+				genai.NewContentFromParts([]*genai.Part{genai.NewPartFromURI(document.URI, document.MIMEType)}, genai.RoleUser),
+			},
+			SystemInstruction: genai.NewContentFromText("You are an expert analyzing transcripts.", genai.RoleUser),
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println(cache.Name, reused)
+	}
+	// Output:
+	// cachedContents/cache-3 false
+	// cachedContents/cache-3 true
+	// cachedContents/cache-3 true
+}
@@ -0,0 +1,248 @@
+package caches
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"gemini-api-examples/internal/tracker"
+
+	"google.golang.org/genai"
+)
+
+// KeepaliveOptions controls a Keepalive. The zero value, like a nil
+// *KeepaliveOptions, extends caches by an hour when they have less than ten
+// minutes left.
+type KeepaliveOptions struct {
+	// TTL is the time left a cache gets when it is extended.
+	TTL time.Duration
+	// Margin is how long before its expiry a cache is extended. It must be
+	// less than TTL.
+	Margin time.Duration
+	// RetryDelay is the wait before trying again to extend a cache after
+	// a failure. It defaults to 30 seconds.
+	RetryDelay time.Duration
+	// Now returns the current time. It defaults to time.Now.
+	Now func() time.Time
+	// After waits for the duration to elapse and then sends the current
+	// time on the returned channel. It defaults to time.After.
+	After func(time.Duration) <-chan time.Time
+}
+
+// A KeepaliveError reports a failure to extend a cache.
+type KeepaliveError struct {
+	// Name is the name of the cache.
+	Name string
+	// Gone is set when the cache no longer exists, so that it is no longer
+	// watched.
+	Gone bool
+	Err  error
+}
+
+func (e *KeepaliveError) Error() string {
+	return fmt.Sprintf("extending %s: %v", e.Name, e.Err)
+}
+
+func (e *KeepaliveError) Unwrap() error {
+	return e.Err
+}
+
+// A Keepalive extends the TTL of the caches it holds before they expire, so
+// that they outlive a job that uses them for longer than their TTL:
+//
+//	k, err := caches.StartKeepalive(ctx, client, nil)
+//	if err != nil {
+//		return err
+//	}
+//	defer k.Stop()
+//	k.Hold(cache)
+//
+// Failures are reported on the Errors channel; a cache that cannot be
+// extended is tried again after RetryDelay, unless it is gone.
+type Keepalive struct {
+	client *genai.Client
+	opts   KeepaliveOptions
+	errs   chan error
+	wake   chan struct{}
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	mu     sync.Mutex
+	caches map[string]*held
+}
+
+// held is the state of a cache a Keepalive holds.
+type held struct {
+	expire time.Time
+	// due is when to extend the cache next.
+	due time.Time
+}
+
+// StartKeepalive starts a Keepalive that runs until Stop is called or ctx is
+// done.
+func StartKeepalive(ctx context.Context, client *genai.Client, opts *KeepaliveOptions) (*Keepalive, error) {
+	var o KeepaliveOptions
+	if opts != nil {
+		o = *opts
+	}
+	if o.TTL <= 0 {
+		o.TTL = time.Hour
+	}
+	if o.Margin <= 0 {
+		o.Margin = 10 * time.Minute
+	}
+	if o.Margin >= o.TTL {
+		return nil, fmt.Errorf("keepalive margin %v is not less than the TTL %v", o.Margin, o.TTL)
+	}
+	if o.RetryDelay <= 0 {
+		o.RetryDelay = 30 * time.Second
+	}
+	if o.Now == nil {
+		o.Now = time.Now
+	}
+	if o.After == nil {
+		o.After = time.After
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	k := &Keepalive{
+		client: client,
+		opts:   o,
+		errs:   make(chan error, 16),
+		wake:   make(chan struct{}, 1),
+		cancel: cancel,
+		done:   make(chan struct{}),
+		caches: map[string]*held{},
+	}
+	go k.run(ctx)
+	return k, nil
+}
+
+// Hold starts extending cache, which is extended at once if it expires
+// within the margin. Holding a cache again updates its expiry.
+func (k *Keepalive) Hold(cache *genai.CachedContent) {
+	k.mu.Lock()
+	k.caches[cache.Name] = &held{expire: cache.ExpireTime, due: cache.ExpireTime.Add(-k.opts.Margin)}
+	k.mu.Unlock()
+	k.poke()
+}
+
+// Release stops extending the named cache, which then expires with its
+// current TTL.
+func (k *Keepalive) Release(name string) {
+	k.mu.Lock()
+	delete(k.caches, name)
+	k.mu.Unlock()
+	k.poke()
+}
+
+// Expiry returns the expiry time of the named cache as last known, and
+// whether it is held.
+func (k *Keepalive) Expiry(name string) (time.Time, bool) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	h, ok := k.caches[name]
+	if !ok {
+		return time.Time{}, false
+	}
+	return h.expire, true
+}
+
+// Errors returns the channel on which failures are reported, as
+// *KeepaliveError values. It is closed once the Keepalive stops. Failures
+// are dropped while 16 of them are waiting to be received.
+func (k *Keepalive) Errors() <-chan error {
+	return k.errs
+}
+
+// Stop stops extending the caches and waits for a request in flight to end.
+func (k *Keepalive) Stop() {
+	k.cancel()
+	<-k.done
+}
+
+func (k *Keepalive) poke() {
+	select {
+	case k.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (k *Keepalive) run(ctx context.Context) {
+	defer close(k.done)
+	defer close(k.errs)
+	// timer fires at timerDue, when the cache due first is due. Without
+	// caches to hold, it is nil until one is held.
+	var timer <-chan time.Time
+	var timerDue time.Time
+	for {
+		name, due := k.next()
+		switch {
+		case name == "":
+			timer = nil
+		case !due.After(k.opts.Now()):
+			k.extend(ctx, name)
+			continue
+		case timer == nil || !due.Equal(timerDue):
+			timer, timerDue = k.opts.After(due.Sub(k.opts.Now())), due
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-k.wake:
+		case <-timer:
+			timer = nil
+		}
+	}
+}
+
+// next returns the name of the cache due first and when it is, or "" if
+// none is held.
+func (k *Keepalive) next() (string, time.Time) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	var name string
+	var due time.Time
+	for n, h := range k.caches {
+		if name == "" || h.due.Before(due) || h.due.Equal(due) && n < name {
+			name, due = n, h.due
+		}
+	}
+	return name, due
+}
+
+// extend extends the named cache and schedules its next extension.
+func (k *Keepalive) extend(ctx context.Context, name string) {
+	c, err := k.client.Caches.Update(ctx, name, &genai.UpdateCachedContentConfig{TTL: k.opts.TTL})
+	if ctx.Err() != nil {
+		return
+	}
+	now, gone := k.opts.Now(), tracker.IsGone(tracker.KindCache, err)
+	k.mu.Lock()
+	h, ok := k.caches[name]
+	switch {
+	case !ok:
+		// Released during the request.
+	case err == nil:
+		h.expire = c.ExpireTime
+		if h.expire.IsZero() {
+			h.expire = now.Add(k.opts.TTL)
+		}
+		h.due = h.expire.Add(-k.opts.Margin)
+	case gone:
+		delete(k.caches, name)
+	default:
+		h.due = now.Add(k.opts.RetryDelay)
+	}
+	k.mu.Unlock()
+	if err != nil && ok {
+		k.report(&KeepaliveError{Name: name, Gone: gone, Err: err})
+	}
+}
+
+func (k *Keepalive) report(err error) {
+	select {
+	case k.errs <- err:
+	default:
+	}
+}
@@ -0,0 +1,230 @@
+package caches
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"gemini-api-examples/internal/fakegemini"
+	"gemini-api-examples/internal/tracker"
+
+	"google.golang.org/genai"
+)
+
+// A fakeClock only moves when told to. Each call to After is reported on
+// sleeps, so that tests know when the keepalive is idle.
+type fakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []waiter
+	sleeps  chan time.Duration
+}
+
+type waiter struct {
+	at time.Time
+	ch chan time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{
+		now:    time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC),
+		sleeps: make(chan time.Duration, 64),
+	}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	ch := make(chan time.Time, 1)
+	c.waiters = append(c.waiters, waiter{c.now.Add(d), ch})
+	c.mu.Unlock()
+	c.sleeps <- d
+	return ch
+}
+
+// Advance moves the clock forward by d, firing the waits that end by then.
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	waiters := c.waiters[:0]
+	for _, w := range c.waiters {
+		if w.at.After(c.now) {
+			waiters = append(waiters, w)
+			continue
+		}
+		w.ch <- c.now
+	}
+	c.waiters = waiters
+}
+
+// sleep returns the duration of the next wait the keepalive starts.
+func (c *fakeClock) sleep(t *testing.T) time.Duration {
+	t.Helper()
+	select {
+	case d := <-c.sleeps:
+		return d
+	case <-time.After(5 * time.Second):
+		t.Fatal("the keepalive did not wait")
+		return 0
+	}
+}
+
+// nextError returns the next failure the keepalive reports.
+func nextError(t *testing.T, k *Keepalive) *KeepaliveError {
+	t.Helper()
+	select {
+	case err := <-k.Errors():
+		var kerr *KeepaliveError
+		if !errors.As(err, &kerr) {
+			t.Fatalf("error %v is not a *KeepaliveError", err)
+		}
+		return kerr
+	case <-time.After(5 * time.Second):
+		t.Fatal("no error reported")
+		return nil
+	}
+}
+
+func startKeepalive(t *testing.T, srv *fakegemini.Server, client *genai.Client) (*Keepalive, *fakeClock) {
+	t.Helper()
+	clock := newFakeClock()
+	srv.SetClock(clock.Now)
+	k, err := StartKeepalive(t.Context(), client, &KeepaliveOptions{
+		TTL:    time.Hour,
+		Margin: 10 * time.Minute,
+		Now:    clock.Now,
+		After:  clock.After,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(k.Stop)
+	return k, clock
+}
+
+func TestKeepalive(t *testing.T) {
+	srv, client := newFake(t)
+	k, clock := startKeepalive(t, srv, client)
+	start := clock.Now()
+	c := srv.AddCache(&genai.CachedContent{Model: "models/gemini-3.5-flash", ExpireTime: start.Add(30 * time.Minute)})
+
+	k.Hold(c)
+	if d := clock.sleep(t); d != 20*time.Minute {
+		t.Errorf("first wait = %v, want 20m, until 10m before expiry", d)
+	}
+	clock.Advance(20 * time.Minute)
+	if d := clock.sleep(t); d != 50*time.Minute {
+		t.Errorf("wait after extending = %v, want 50m", d)
+	}
+	if got, _ := k.Expiry(c.Name); !got.Equal(start.Add(80 * time.Minute)) {
+		t.Errorf("expiry = %v, want an hour after the extension", got)
+	}
+	clock.Advance(50 * time.Minute)
+	clock.sleep(t)
+
+	// Well past its first expiry, the cache is alive.
+	caches := srv.Caches()
+	if len(caches) != 1 || !caches[0].ExpireTime.Equal(start.Add(130*time.Minute)) {
+		t.Fatalf("caches = %+v, want the cache extended to %v", caches, start.Add(130*time.Minute))
+	}
+	if n := len(srv.RequestsTo(http.MethodPatch, c.Name)); n != 2 {
+		t.Errorf("%d updates, want 2", n)
+	}
+
+	// Once released, it expires.
+	k.Release(c.Name)
+	if _, ok := k.Expiry(c.Name); ok {
+		t.Error("released cache is still held")
+	}
+	clock.Advance(2 * time.Hour)
+	if _, err := client.Caches.Get(t.Context(), c.Name, nil); !tracker.IsGone(tracker.KindCache, err) {
+		t.Errorf("Get of the released cache = %v, want it expired", err)
+	}
+	k.Stop()
+	for err := range k.Errors() {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestKeepaliveFailures(t *testing.T) {
+	srv, client := newFake(t)
+	k, clock := startKeepalive(t, srv, client)
+	c := srv.AddCache(&genai.CachedContent{Model: "models/gemini-3.5-flash", ExpireTime: clock.Now().Add(5 * time.Minute)})
+
+	// Within the margin, the cache is extended at once; the failure is
+	// retried after RetryDelay.
+	srv.Inject(c.Name, 1, fakegemini.ServerError(http.StatusServiceUnavailable))
+	k.Hold(c)
+	kerr := nextError(t, k)
+	var apiErr genai.APIError
+	if kerr.Name != c.Name || kerr.Gone || !errors.As(kerr, &apiErr) || apiErr.Code != http.StatusServiceUnavailable {
+		t.Errorf("error = %+v, want the 503 for %s", kerr, c.Name)
+	}
+	if d := clock.sleep(t); d != 30*time.Second {
+		t.Errorf("wait after the failure = %v, want the 30s retry delay", d)
+	}
+	clock.Advance(30 * time.Second)
+	if d := clock.sleep(t); d != 50*time.Minute {
+		t.Errorf("wait after the retry = %v, want 50m", d)
+	}
+
+	// A cache deleted meanwhile is no longer held.
+	if _, err := client.Caches.Delete(t.Context(), c.Name, nil); err != nil {
+		t.Fatal(err)
+	}
+	clock.Advance(50 * time.Minute)
+	if kerr := nextError(t, k); !kerr.Gone {
+		t.Errorf("error = %+v, want the cache gone", kerr)
+	}
+	if _, ok := k.Expiry(c.Name); ok {
+		t.Error("deleted cache is still held")
+	}
+}
+
+func TestKeepaliveStopsWithContext(t *testing.T) {
+	srv, client := newFake(t)
+	ctx, cancel := context.WithCancel(t.Context())
+	clock := newFakeClock()
+	srv.SetClock(clock.Now)
+	k, err := StartKeepalive(ctx, client, &KeepaliveOptions{Now: clock.Now, After: clock.After})
+	if err != nil {
+		t.Fatal(err)
+	}
+	k.Hold(srv.AddCache(&genai.CachedContent{Model: "models/gemini-3.5-flash", ExpireTime: clock.Now().Add(time.Hour)}))
+	clock.sleep(t)
+	cancel()
+	select {
+	case _, ok := <-k.Errors():
+		if ok {
+			t.Error("unexpected error")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the keepalive did not stop")
+	}
+	clock.Advance(2 * time.Hour)
+	if n := len(srv.RequestsTo(http.MethodPatch, "cachedContents")); n != 0 {
+		t.Errorf("%d updates after the context was canceled", n)
+	}
+}
+
+func TestKeepaliveOptions(t *testing.T) {
+	_, client := newFake(t)
+	if _, err := StartKeepalive(t.Context(), client, &KeepaliveOptions{TTL: 5 * time.Minute}); err == nil {
+		t.Error("StartKeepalive succeeded with the default 10m margin over a 5m TTL")
+	}
+	k, err := StartKeepalive(t.Context(), client, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	k.Stop()
+	k.Stop()
+}
@@ -0,0 +1,236 @@
+package caches
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"gemini-api-examples/internal/index"
+	"gemini-api-examples/internal/tracker"
+
+	"google.golang.org/genai"
+)
+
+// A Manager remembers the caches it creates by fingerprint, so that the
+// same content is cached once and reused until the cache expires.
+//
+// It finds caches in its index, which may be kept in a file across runs,
+// and otherwise by their display name, which holds the fingerprint, so
+// that caches created by other programs with the same prefix are found
+// too. Caches recorded in an index file are meant to outlive the program:
+// they are not recorded by trackers and expire with their TTL. A Manager
+// is safe for concurrent use; concurrent calls for the same content create
+// one cache.
+type Manager struct {
+	// Prefix starts the display names of the caches, followed by a dash
+	// and the fingerprint. It defaults to "gemini-examples".
+	Prefix string
+	// MinRemaining is the minimum time a cache must have left before it
+	// expires to be reused, so that it does not expire while in use. It
+	// defaults to five minutes.
+	MinRemaining time.Duration
+	// Now returns the current time, against which expiry is checked. It
+	// defaults to time.Now.
+	Now func() time.Time
+	// MinTokens is the smallest size of a cache for the model, checked by
+	// Validate before a cache is created. It defaults to
+	// DefaultMinTokens(model).
+	MinTokens int32
+
+	path  string
+	index *index.Index[Entry]
+}
+
+// An Entry is a cache recorded by a Manager.
+type Entry struct {
+	// Name is the resource name, such as "cachedContents/abc".
+	Name       string    `json:"name"`
+	Model      string    `json:"model"`
+	ExpireTime time.Time `json:"expireTime"`
+}
+
+// OpenManager returns a Manager whose index is stored in the JSON file at
+// path, which is created when the first cache is recorded. With an empty
+// path, the index only lives in memory.
+func OpenManager(path string) (*Manager, error) {
+	ix, err := index.Open(path, "caches", func(e Entry) time.Time { return e.ExpireTime })
+	if err != nil {
+		return nil, err
+	}
+	return &Manager{path: path, index: ix}, nil
+}
+
+// Entries returns a copy of the entries, keyed by scope and fingerprint.
+func (m *Manager) Entries() map[string]Entry {
+	return m.index.Entries()
+}
+
+// DisplayName returns the display name of the caches with fingerprint fp.
+func (m *Manager) DisplayName(fp string) string {
+	prefix := m.Prefix
+	if prefix == "" {
+		prefix = "gemini-examples"
+	}
+	return prefix + "-" + fp
+}
+
+// GetOrCreate returns a cache of config's contents for model, and whether it
+// existed. A cache with the same fingerprint that is recorded in the index
+// or has the display name for it is reused if it does not expire within
+// MinRemaining; otherwise one is created with config, under that display
+// name, and recorded. Before creating it, GetOrCreate checks with Validate
+// that it can be created.
+func (m *Manager) GetOrCreate(ctx context.Context, client *genai.Client, model string, config *genai.CreateCachedContentConfig) (*genai.CachedContent, bool, error) {
+	fp, err := Fingerprint(ctx, client, model, config)
+	if err != nil {
+		return nil, false, err
+	}
+	key := index.Key(client, fp)
+	entry, ok, done, err := m.index.Acquire(ctx, key)
+	if err != nil {
+		return nil, false, err
+	}
+	defer done()
+
+	if ok {
+		c, err := client.Caches.Get(ctx, entry.Name, nil)
+		switch {
+		case err == nil && m.fresh(c):
+			return c, true, m.record(key, c)
+		case err != nil && !tracker.IsGone(tracker.KindCache, err):
+			return nil, false, err
+		}
+		// Gone or about to expire: look further.
+	}
+	c, err := m.find(ctx, client, m.DisplayName(fp))
+	if err != nil {
+		return nil, false, err
+	}
+	if c != nil {
+		return c, true, m.record(key, c)
+	}
+
+	var cfg genai.CreateCachedContentConfig
+	if config != nil {
+		cfg = *config
+	}
+	cfg.DisplayName = m.DisplayName(fp)
+	if _, err := Validate(ctx, client, model, &cfg, &ValidateOptions{MinTokens: m.MinTokens, Now: m.Now}); err != nil {
+		return nil, false, err
+	}
+	createCtx := ctx
+	if m.path != "" {
+		createCtx = tracker.Untracked(ctx)
+	}
+	c, err = client.Caches.Create(createCtx, model, &cfg)
+	if err != nil {
+		return nil, false, err
+	}
+	return c, false, m.record(key, c)
+}
+
+// find returns the live cache named displayName that expires last, if it
+// does not expire within MinRemaining, or nil.
+func (m *Manager) find(ctx context.Context, client *genai.Client, displayName string) (*genai.CachedContent, error) {
+	var found *genai.CachedContent
+	for c, err := range client.Caches.All(ctx) {
+		if err != nil {
+			return nil, fmt.Errorf("listing caches: %w", err)
+		}
+		if c.DisplayName == displayName && m.fresh(c) && (found == nil || c.ExpireTime.After(found.ExpireTime)) {
+			found = c
+		}
+	}
+	return found, nil
+}
+
+func (m *Manager) fresh(c *genai.CachedContent) bool {
+	return index.Fresh(c.ExpireTime, m.Now, m.MinRemaining, 5*time.Minute)
+}
+
+// record stores c in the index under key.
+func (m *Manager) record(key string, c *genai.CachedContent) error {
+	return m.index.Record(key, Entry{Name: c.Name, Model: c.Model, ExpireTime: c.ExpireTime}, m.Now)
+}
+
+// Fingerprint returns a hex SHA-256 hash of what a cache created for model
+// with config holds: the model, system instruction, contents, tools and
+// tool configuration. Files of the Files API are identified by the hash of
+// their content rather than their URI, so that uploading the same file
+// again gives the same fingerprint.
+func Fingerprint(ctx context.Context, client *genai.Client, model string, config *genai.CreateCachedContentConfig) (string, error) {
+	var cfg genai.CreateCachedContentConfig
+	if config != nil {
+		cfg = *config
+	}
+	c := struct {
+		Model             string            `json:"model"`
+		SystemInstruction *genai.Content    `json:"systemInstruction,omitempty"`
+		Contents          []*genai.Content  `json:"contents,omitempty"`
+		Tools             []*genai.Tool     `json:"tools,omitempty"`
+		ToolConfig        *genai.ToolConfig `json:"toolConfig,omitempty"`
+	}{
+		Model:      strings.TrimPrefix(model, "models/"),
+		Tools:      cfg.Tools,
+		ToolConfig: cfg.ToolConfig,
+	}
+	// Work on copies, whose file references can be replaced.
+	data, err := json.Marshal(append([]*genai.Content{cfg.SystemInstruction}, cfg.Contents...))
+	if err != nil {
+		return "", err
+	}
+	var contents []*genai.Content
+	if err := json.Unmarshal(data, &contents); err != nil {
+		return "", err
+	}
+	hashes := map[string]string{}
+	for _, content := range contents {
+		if content == nil {
+			continue
+		}
+		for _, p := range content.Parts {
+			if p == nil || p.FileData == nil {
+				continue
+			}
+			uri := p.FileData.FileURI
+			if _, ok := hashes[uri]; !ok {
+				h, err := fileHash(ctx, client, uri)
+				if err != nil {
+					return "", err
+				}
+				hashes[uri] = h
+			}
+			if h := hashes[uri]; h != "" {
+				p.FileData.FileURI = "sha256:" + h
+			}
+		}
+	}
+	c.SystemInstruction, c.Contents = contents[0], contents[1:]
+	data, err = json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// fileHash returns the content hash of the Files API file at uri, or "" if
+// uri is not such a file or it cannot be found.
+func fileHash(ctx context.Context, client *genai.Client, uri string) (string, error) {
+	i := strings.LastIndex(uri, "/files/")
+	if i < 0 || client.ClientConfig().Backend == genai.BackendVertexAI {
+		return "", nil
+	}
+	f, err := client.Files.Get(ctx, uri[i+1:], nil)
+	if err != nil {
+		if ctx.Err() != nil {
+			return "", err
+		}
+		return "", nil
+	}
+	return f.Sha256Hash, nil
+}
@@ -0,0 +1,297 @@
+package caches
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"gemini-api-examples/internal/tracker"
+
+	"google.golang.org/genai"
+)
+
+func TestManagerReuse(t *testing.T) {
+	srv, client := newFake(t)
+	m, err := OpenManager("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := transcript(t, client)
+	first, reused, err := m.GetOrCreate(t.Context(), client, "gemini-3.5-flash", config)
+	if err != nil || reused {
+		t.Fatalf("GetOrCreate = %v, %v, want a new cache", reused, err)
+	}
+	fp, err := Fingerprint(t.Context(), client, "gemini-3.5-flash", config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.DisplayName != "gemini-examples-"+fp {
+		t.Errorf("display name = %q, want the prefix and fingerprint", first.DisplayName)
+	}
+	if config.DisplayName != "" {
+		t.Errorf("config display name set to %q", config.DisplayName)
+	}
+	for range 3 {
+		c, reused, err := m.GetOrCreate(t.Context(), client, "models/gemini-3.5-flash", config)
+		if err != nil || !reused || c.Name != first.Name {
+			t.Errorf("GetOrCreate = %v, %v, %v, want %s reused", c, reused, err, first.Name)
+		}
+	}
+	if n := len(srv.RequestsTo(http.MethodPost, "/cachedContents")); n != 1 {
+		t.Errorf("%d caches created, want 1", n)
+	}
+	// Another system instruction is another cache.
+	other := *config
+	other.SystemInstruction = genai.NewContentFromText("You are a flight controller.", genai.RoleUser)
+	if _, reused, err := m.GetOrCreate(t.Context(), client, "gemini-3.5-flash", &other); err != nil || reused {
+		t.Errorf("GetOrCreate with another instruction = %v, %v, want a new cache", reused, err)
+	}
+	if n := len(srv.Caches()); n != 2 {
+		t.Errorf("%d caches, want 2", n)
+	}
+}
+
+func TestManagerExpiry(t *testing.T) {
+	srv, client := newFake(t)
+	now := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+	srv.SetClock(clock)
+	m, err := OpenManager("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Now = clock
+	config := transcript(t, client)
+	config.TTL = time.Hour
+	first, _, err := m.GetOrCreate(t.Context(), client, "gemini-3.5-flash", config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now = now.Add(50 * time.Minute)
+	if c, reused, err := m.GetOrCreate(t.Context(), client, "gemini-3.5-flash", config); err != nil || !reused || c.Name != first.Name {
+		t.Errorf("with 10 minutes left: %v, %v, %v, want %s reused", c, reused, err, first.Name)
+	}
+	// A cache about to expire is not reused.
+	now = now.Add(6 * time.Minute)
+	second, reused, err := m.GetOrCreate(t.Context(), client, "gemini-3.5-flash", config)
+	if err != nil || reused || second.Name == first.Name {
+		t.Errorf("with 4 minutes left: %v, %v, %v, want a new cache", second, reused, err)
+	}
+	// Nor is one that expired.
+	now = now.Add(2 * time.Hour)
+	if c, reused, err := m.GetOrCreate(t.Context(), client, "gemini-3.5-flash", config); err != nil || reused || c.Name == second.Name {
+		t.Errorf("after expiry: %v, %v, %v, want a new cache", c, reused, err)
+	}
+}
+
+func TestManagerDeleted(t *testing.T) {
+	_, client := newFake(t)
+	m, err := OpenManager("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := transcript(t, client)
+	first, _, err := m.GetOrCreate(t.Context(), client, "gemini-3.5-flash", config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Caches.Delete(t.Context(), first.Name, nil); err != nil {
+		t.Fatal(err)
+	}
+	c, reused, err := m.GetOrCreate(t.Context(), client, "gemini-3.5-flash", config)
+	if err != nil || reused || c.Name == first.Name {
+		t.Errorf("GetOrCreate = %v, %v, %v, want a new cache", c, reused, err)
+	}
+}
+
+func TestManagerFindsByDisplayName(t *testing.T) {
+	srv, client := newFake(t)
+	config := transcript(t, client)
+	// Fill more than a page of the list with other caches.
+	for range 12 {
+		srv.AddCache(&genai.CachedContent{DisplayName: "other", Model: "models/gemini-3.5-flash"})
+	}
+	a, err := OpenManager("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, _, err := a.GetOrCreate(t.Context(), client, "gemini-3.5-flash", config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A manager that did not create it finds it.
+	b, err := OpenManager("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c, reused, err := b.GetOrCreate(t.Context(), client, "gemini-3.5-flash", config); err != nil || !reused || c.Name != first.Name {
+		t.Errorf("GetOrCreate = %v, %v, %v, want %s reused", c, reused, err, first.Name)
+	}
+	// One with another prefix does not.
+	c, err := OpenManager("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Prefix = "mine"
+	if _, reused, err := c.GetOrCreate(t.Context(), client, "gemini-3.5-flash", config); err != nil || reused {
+		t.Errorf("GetOrCreate with another prefix = %v, %v, want a new cache", reused, err)
+	}
+}
+
+func TestManagerIndexFile(t *testing.T) {
+	srv, client := newFake(t)
+	path := filepath.Join(t.TempDir(), "caches.json")
+	m, err := OpenManager(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := transcript(t, client)
+	first, _, err := m.GetOrCreate(t.Context(), client, "gemini-3.5-flash", config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stored struct {
+		Caches map[string]Entry `json:"caches"`
+	}
+	if err := json.Unmarshal(data, &stored); err != nil {
+		t.Fatal(err)
+	}
+	if len(stored.Caches) != 1 {
+		t.Fatalf("index file:\n%s", data)
+	}
+	for _, e := range stored.Caches {
+		if e.Name != first.Name || !e.ExpireTime.Equal(first.ExpireTime) {
+			t.Errorf("entry = %+v, want %s", e, first.Name)
+		}
+	}
+
+	// Another run gets the cache through the index, without listing.
+	again, err := OpenManager(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lists := len(srv.RequestsTo(http.MethodGet, "/cachedContents"))
+	if c, reused, err := again.GetOrCreate(t.Context(), client, "gemini-3.5-flash", config); err != nil || !reused || c.Name != first.Name {
+		t.Errorf("GetOrCreate = %v, %v, %v, want %s reused", c, reused, err, first.Name)
+	}
+	if n := len(srv.RequestsTo(http.MethodGet, "/cachedContents")); n != lists {
+		t.Errorf("listed caches %d times, want the index used", n-lists)
+	}
+	if len(again.Entries()) != 1 {
+		t.Errorf("entries = %v, want 1", again.Entries())
+	}
+
+	if err := os.WriteFile(path, []byte("{"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenManager(path); err == nil {
+		t.Error("OpenManager succeeded with a corrupt index")
+	}
+}
+
+func TestManagerIndexUntracked(t *testing.T) {
+	srv, client := newFake(t)
+	tr := tracker.New()
+	tracked, err := tr.Client(t.Context(), client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := transcript(t, client)
+	m, err := OpenManager(filepath.Join(t.TempDir(), "caches.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := m.GetOrCreate(t.Context(), tracked, "gemini-3.5-flash", config); err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.Cleanup(t.Context(), tracked); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(srv.Caches()); n != 1 {
+		t.Errorf("%d caches after Cleanup, want the indexed cache kept", n)
+	}
+}
+
+func TestManagerConcurrent(t *testing.T) {
+	srv, client := newFake(t)
+	m, err := OpenManager("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := transcript(t, client)
+	names := make([]string, 8)
+	var wg sync.WaitGroup
+	for i := range names {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c, _, err := m.GetOrCreate(t.Context(), client, "gemini-3.5-flash", config)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			names[i] = c.Name
+		}()
+	}
+	wg.Wait()
+	if n := len(srv.Caches()); n != 1 {
+		t.Errorf("%d caches, want 1", n)
+	}
+	for _, name := range names {
+		if name != names[0] {
+			t.Errorf("got caches %v, want the same one", names)
+			break
+		}
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	_, client := newFake(t)
+	base := transcript(t, client)
+	fp := func(model string, config *genai.CreateCachedContentConfig) string {
+		t.Helper()
+		s, err := Fingerprint(t.Context(), client, model, config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+	want := fp("gemini-3.5-flash", base)
+
+	// Uploading the transcript again gives the same fingerprint, as do the
+	// settings that do not change the contents.
+	same := transcript(t, client)
+	same.TTL = time.Minute
+	same.DisplayName = "mine"
+	if got := fp("models/gemini-3.5-flash", same); got != want {
+		t.Errorf("fingerprint after upload again = %s, want %s", got, want)
+	}
+	if base.Contents[0].Parts[0].FileData.FileURI == "" || base.Contents[0].Parts[0].FileData.FileURI[:7] == "sha256:" {
+		t.Error("Fingerprint changed the config's file URI")
+	}
+
+	other := *base
+	other.SystemInstruction = genai.NewContentFromText("You are a poet.", genai.RoleUser)
+	withTools := *base
+	withTools.Tools = []*genai.Tool{{CodeExecution: &genai.ToolCodeExecution{}}}
+	text := *base
+	text.Contents = []*genai.Content{genai.NewContentFromText("Houston, Tranquility Base here.", genai.RoleUser)}
+	for name, got := range map[string]string{
+		"model":              fp("gemini-3.5-pro", base),
+		"system instruction": fp("gemini-3.5-flash", &other),
+		"tools":              fp("gemini-3.5-flash", &withTools),
+		"contents":           fp("gemini-3.5-flash", &text),
+	} {
+		if got == want {
+			t.Errorf("another %s gives the same fingerprint", name)
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package caches
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"google.golang.org/genai"
+)
+
+// A Price is what a model charges for input tokens, in US dollars per
+// million tokens.
+type Price struct {
+	// Input is the price of prompt tokens that are not cached.
+	Input float64 `json:"input"`
+	// CachedInput is the price of prompt tokens read from a cache.
+	CachedInput float64 `json:"cachedInput"`
+	// StoragePerHour is the price of keeping tokens in a cache, per hour.
+	StoragePerHour float64 `json:"storagePerHour"`
+}
+
+// Prices maps model names, without "models/", to their prices. A model
+// without an entry of its own has the price of the longest name it starts
+// with followed by a dash, so that "gemini-3.5-flash" covers
+// "gemini-3.5-flash-001".
+type Prices map[string]Price
+
+// ReadPrices reads a price table in JSON, an object mapping model names to
+// objects with the fields input, cachedInput and storagePerHour.
+func ReadPrices(r io.Reader) (Prices, error) {
+	var p Prices
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&p); err != nil {
+		return nil, fmt.Errorf("reading prices: %w", err)
+	}
+	for model, price := range p {
+		if price.Input < 0 || price.CachedInput < 0 || price.StoragePerHour < 0 {
+			return nil, fmt.Errorf("reading prices: negative price for %s", model)
+		}
+	}
+	return p, nil
+}
+
+// Lookup returns the price of model.
+func (p Prices) Lookup(model string) (Price, bool) {
+	model = strings.TrimPrefix(model, "models/")
+	if price, ok := p[model]; ok {
+		return price, true
+	}
+	best := ""
+	for name := range p {
+		if strings.HasPrefix(model, name+"-") && len(name) > len(best) {
+			best = name
+		}
+	}
+	price, ok := p[best]
+	return price, ok && best != ""
+}
+
+// A Call is the usage of one generation request.
+type Call struct {
+	// CachedContent is the name of the cache the request used, or "".
+	CachedContent string                                      `json:"cachedContent,omitempty"`
+	Usage         *genai.GenerateContentResponseUsageMetadata `json:"usageMetadata"`
+}
+
+// CacheSavings is what a cache cost and saved over the calls that used it.
+// Costs are in US dollars.
+type CacheSavings struct {
+	Name  string `json:"name"`
+	Model string `json:"model"`
+	// Tokens is the size of the cache.
+	Tokens int64 `json:"tokens"`
+	// StorageHours is how long the cache is kept, from its creation to its
+	// expiry.
+	StorageHours float64 `json:"storageHours"`
+	Calls        int     `json:"calls"`
+	// PromptTokens is the number of prompt tokens of the calls, cached
+	// ones included, and CachedTokens the number read from the cache.
+	PromptTokens int64 `json:"promptTokens"`
+	CachedTokens int64 `json:"cachedTokens"`
+	// UncachedCost is what the calls' prompts would cost without a cache,
+	// and CachedCost what they cost with it.
+	UncachedCost float64 `json:"uncachedCost"`
+	CachedCost   float64 `json:"cachedCost"`
+	StorageCost  float64 `json:"storageCost"`
+	// Net is UncachedCost less CachedCost and StorageCost: the savings, or
+	// if negative the loss.
+	Net float64 `json:"net"`
+	// BreakEven is the number of calls like these after which the cache
+	// pays for its storage, or -1 if it never does.
+	BreakEven int `json:"breakEven"`
+}
+
+// A SavingsReport lists the savings of each cache, and their sum.
+type SavingsReport struct {
+	Caches []CacheSavings `json:"caches"`
+	Total  CacheSavings   `json:"total"`
+}
+
+// Savings works out what caching saved on calls, which may be any
+// generation requests: those that used none of caches are ignored. The
+// caches must be as last updated, so that their expiry time includes the
+// extensions of their TTL.
+func Savings(caches []*genai.CachedContent, calls []Call, prices Prices) (*SavingsReport, error) {
+	byName := map[string]*CacheSavings{}
+	priceOf := map[string]Price{}
+	var errs []error
+	r := &SavingsReport{Caches: make([]CacheSavings, 0, len(caches))}
+	for _, c := range caches {
+		price, ok := prices.Lookup(c.Model)
+		if !ok {
+			errs = append(errs, fmt.Errorf("no price for %s, the model of %s", c.Model, c.Name))
+			continue
+		}
+		s := CacheSavings{
+			Name:      c.Name,
+			Model:     strings.TrimPrefix(c.Model, "models/"),
+			BreakEven: -1,
+		}
+		if c.UsageMetadata != nil {
+			s.Tokens = int64(c.UsageMetadata.TotalTokenCount)
+		}
+		if !c.CreateTime.IsZero() && c.ExpireTime.After(c.CreateTime) {
+			s.StorageHours = c.ExpireTime.Sub(c.CreateTime).Hours()
+		}
+		s.StorageCost = float64(s.Tokens) * s.StorageHours * price.StoragePerHour / 1e6
+		r.Caches = append(r.Caches, s)
+		priceOf[c.Name] = price
+	}
+	for i := range r.Caches {
+		byName[r.Caches[i].Name] = &r.Caches[i]
+	}
+	for _, call := range calls {
+		if call.CachedContent == "" || call.Usage == nil {
+			continue
+		}
+		s, ok := byName[call.CachedContent]
+		if !ok {
+			continue
+		}
+		price := priceOf[s.Name]
+		prompt, cached := int64(call.Usage.PromptTokenCount), int64(call.Usage.CachedContentTokenCount)
+		s.Calls++
+		s.PromptTokens += prompt
+		s.CachedTokens += cached
+		s.UncachedCost += float64(prompt) * price.Input / 1e6
+		s.CachedCost += (float64(prompt-cached)*price.Input + float64(cached)*price.CachedInput) / 1e6
+	}
+	for i := range r.Caches {
+		s := &r.Caches[i]
+		s.Net = s.UncachedCost - s.CachedCost - s.StorageCost
+		s.BreakEven = breakEven(s)
+		t := &r.Total
+		t.Tokens += s.Tokens
+		t.StorageHours += s.StorageHours
+		t.Calls += s.Calls
+		t.PromptTokens += s.PromptTokens
+		t.CachedTokens += s.CachedTokens
+		t.UncachedCost += s.UncachedCost
+		t.CachedCost += s.CachedCost
+		t.StorageCost += s.StorageCost
+		t.Net += s.Net
+	}
+	r.Total.Name = "TOTAL"
+	r.Total.BreakEven = breakEven(&r.Total)
+	slices.SortStableFunc(r.Caches, func(a, b CacheSavings) int { return strings.Compare(a.Name, b.Name) })
+	return r, errors.Join(errs...)
+}
+
+// breakEven returns the number of calls saving as much as s's do on
+// average that pay for its storage, or -1 if they save nothing.
+func breakEven(s *CacheSavings) int {
+	if s.StorageCost == 0 {
+		return 0
+	}
+	saved := s.UncachedCost - s.CachedCost
+	if s.Calls == 0 || saved <= 0 {
+		return -1
+	}
+	// Round away the error of the float division before rounding up, so
+	// that an exact number of calls is not one too many.
+	return int(math.Ceil(s.StorageCost/(saved/float64(s.Calls)) - 1e-9))
+}
+
+// WriteText writes r as a table, one row per cache and a TOTAL row.
+func (r *SavingsReport) WriteText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "CACHE\tMODEL\tCALLS\tCACHED TOKENS\tPROMPT TOKENS\tSTORAGE HOURS\tSTORAGE\tSAVED\tNET\tBREAK-EVEN")
+	for _, s := range append(slices.Clip(r.Caches), r.Total) {
+		breakEven := "never"
+		if s.BreakEven >= 0 {
+			breakEven = fmt.Sprintf("%d calls", s.BreakEven)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%d\t%.2f\t$%.4f\t$%.4f\t%s\t%s\n",
+			s.Name, s.Model, s.Calls, s.CachedTokens, s.PromptTokens, s.StorageHours,
+			s.StorageCost, s.UncachedCost-s.CachedCost, dollars(s.Net), breakEven)
+	}
+	return tw.Flush()
+}
+
+// dollars formats an amount that may be negative, as in -$0.0100.
+func dollars(v float64) string {
+	if v < 0 {
+		return fmt.Sprintf("-$%.4f", -v)
+	}
+	return fmt.Sprintf("$%.4f", v)
+}
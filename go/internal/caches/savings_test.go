@@ -0,0 +1,138 @@
+package caches
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"os"
+	"strings"
+	"testing"
+
+	"google.golang.org/genai"
+)
+
+// recordedUsage reads the prices, caches and calls of testdata/savings.json.
+func recordedUsage(t *testing.T) (Prices, []*genai.CachedContent, []Call) {
+	t.Helper()
+	data, err := os.ReadFile("testdata/savings.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var recorded struct {
+		Prices json.RawMessage
+		Caches []*genai.CachedContent
+		Calls  []Call
+	}
+	if err := json.Unmarshal(data, &recorded); err != nil {
+		t.Fatal(err)
+	}
+	prices, err := ReadPrices(bytes.NewReader(recorded.Prices))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return prices, recorded.Caches, recorded.Calls
+}
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestSavings(t *testing.T) {
+	prices, caches, calls := recordedUsage(t)
+	r, err := Savings(caches, calls, prices)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Caches) != 3 {
+		t.Fatalf("report of %d caches, want 3", len(r.Caches))
+	}
+	byName := map[string]CacheSavings{}
+	for _, s := range r.Caches {
+		byName[s.Name] = s
+	}
+
+	// Five calls each read 100000 tokens at $0.03 instead of $0.30 per
+	// million, saving $0.027, while an hour of storage costs $0.10.
+	s := byName["cachedContents/transcript"]
+	if s.Calls != 5 || s.PromptTokens != 500250 || s.CachedTokens != 500000 || s.StorageHours != 1 {
+		t.Errorf("transcript: %+v", s)
+	}
+	if !near(s.StorageCost, 0.10) || !near(s.UncachedCost-s.CachedCost, 0.135) || !near(s.Net, 0.035) || s.BreakEven != 4 {
+		t.Errorf("transcript: storage $%v, net $%v, break-even %d calls, want $0.10, $0.035 and 4", s.StorageCost, s.Net, s.BreakEven)
+	}
+
+	// Two hours of 200000 tokens at $4.50 per million per hour for a
+	// single call saving $0.225.
+	s = byName["cachedContents/manual"]
+	if s.Calls != 1 || s.StorageHours != 2 || !near(s.StorageCost, 1.80) || !near(s.Net, -1.575) || s.BreakEven != 8 {
+		t.Errorf("manual: %+v", s)
+	}
+
+	// An unused cache only costs its storage, at the price of the model
+	// it is a version of.
+	s = byName["cachedContents/unused"]
+	if s.Calls != 0 || !near(s.StorageCost, 0.032768) || !near(s.Net, -0.032768) || s.BreakEven != -1 {
+		t.Errorf("unused: %+v", s)
+	}
+
+	if r.Total.Calls != 6 || !near(r.Total.Net, 0.035-1.575-0.032768) {
+		t.Errorf("total: %+v", r.Total)
+	}
+}
+
+func TestSavingsMissingPrice(t *testing.T) {
+	_, caches, calls := recordedUsage(t)
+	r, err := Savings(caches, calls, Prices{"gemini-3.5-flash": {Input: 0.30, CachedInput: 0.03, StoragePerHour: 1}})
+	if err == nil || !strings.Contains(err.Error(), "no price for models/gemini-3.5-pro") {
+		t.Errorf("err = %v, want the pro model missing", err)
+	}
+	if len(r.Caches) != 2 {
+		t.Errorf("report of %d caches, want the 2 with a price", len(r.Caches))
+	}
+}
+
+func TestSavingsWriteText(t *testing.T) {
+	prices, caches, calls := recordedUsage(t)
+	r, err := Savings(caches, calls, prices)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	if err := r.WriteText(&out); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 5 || !strings.HasPrefix(lines[0], "CACHE") || !strings.HasPrefix(lines[4], "TOTAL") {
+		t.Fatalf("output:\n%s", out.String())
+	}
+	for i, want := range []string{"-$1.5750  8 calls", "$0.0350   4 calls", "-$0.0328  never"} {
+		if !strings.Contains(lines[i+1], want) {
+			t.Errorf("line %q does not contain %q", lines[i+1], want)
+		}
+	}
+}
+
+func TestReadPrices(t *testing.T) {
+	prices, err := ReadPrices(strings.NewReader(`{"gemini-3.5-flash": {"input": 0.3}, "gemini-3.5-flash-lite": {"input": 0.1}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for model, want := range map[string]float64{
+		"gemini-3.5-flash":              0.3,
+		"models/gemini-3.5-flash-001":   0.3,
+		"gemini-3.5-flash-lite-preview": 0.1,
+		"models/gemini-3.5-flash-lite":  0.1,
+	} {
+		if p, ok := prices.Lookup(model); !ok || p.Input != want {
+			t.Errorf("Lookup(%q) = %v, %v, want %v", model, p, ok, want)
+		}
+	}
+	if _, ok := prices.Lookup("gemini-3.5-flashy"); ok {
+		t.Error("Lookup(gemini-3.5-flashy) found a price")
+	}
+	for _, in := range []string{`{"m": {"input": -1}}`, `{"m": {"price": 1}}`, `[]`} {
+		if _, err := ReadPrices(strings.NewReader(in)); err == nil {
+			t.Errorf("ReadPrices(%s) succeeded", in)
+		}
+	}
+}
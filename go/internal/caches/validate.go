@@ -0,0 +1,151 @@
+package caches
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"google.golang.org/genai"
+)
+
+// A ValidationError explains why a cache cannot be created.
+type ValidationError struct {
+	// Model is the model of the cache, without "models/".
+	Model string
+	// Field is what is wrong: "model", "contents", "ttl" or "expireTime".
+	Field string
+	Msg   string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("cache for %s: %s: %s", e.Model, e.Field, e.Msg)
+}
+
+// ValidateOptions controls Validate.
+type ValidateOptions struct {
+	// MinTokens is the smallest size of a cache for the model, which the
+	// API does not report. It defaults to DefaultMinTokens(model).
+	MinTokens int32
+	// Now returns the current time, against which an expiry time is
+	// checked. It defaults to time.Now.
+	Now func() time.Time
+}
+
+// DefaultMinTokens returns the documented minimum size of a cache for model:
+// 4096 tokens for the Pro models and 1024 for the others.
+func DefaultMinTokens(model string) int32 {
+	if strings.Contains(strings.TrimPrefix(model, "models/"), "-pro") {
+		return 4096
+	}
+	return 1024
+}
+
+// An Estimate describes the cache that config would create.
+type Estimate struct {
+	// Model is the model of the cache, without "models/".
+	Model string
+	// Tokens is the size of the contents and system instruction, as
+	// counted by Models.CountTokens.
+	Tokens int32
+	// MinTokens and MaxTokens are the smallest and largest sizes of a cache
+	// for the model.
+	MinTokens int32
+	MaxTokens int32
+	// TTL is how long the cache would be stored.
+	TTL time.Duration
+}
+
+// StorageCost returns what storing the cache for its TTL costs at prices, in
+// US dollars, and false if prices has no price for the model.
+func (e *Estimate) StorageCost(prices Prices) (float64, bool) {
+	price, ok := prices.Lookup(e.Model)
+	if !ok {
+		return 0, false
+	}
+	return float64(e.Tokens) * e.TTL.Hours() * price.StoragePerHour / 1e6, true
+}
+
+// Validate checks, before any cache call is made, that a cache can be
+// created for model with config, and estimates its size and storage time.
+// It gets the model with Models.Get to check that it supports caching and
+// its input limit, and counts the tokens of the contents and system
+// instruction with Models.CountTokens. Every problem found is reported as a
+// *ValidationError; other errors come from the API calls.
+func Validate(ctx context.Context, client *genai.Client, model string, config *genai.CreateCachedContentConfig, opts *ValidateOptions) (*Estimate, error) {
+	var cfg genai.CreateCachedContentConfig
+	if config != nil {
+		cfg = *config
+	}
+	var o ValidateOptions
+	if opts != nil {
+		o = *opts
+	}
+	if o.Now == nil {
+		o.Now = time.Now
+	}
+	e := &Estimate{Model: strings.TrimPrefix(model, "models/"), MinTokens: o.MinTokens, TTL: time.Hour}
+	if e.MinTokens <= 0 {
+		e.MinTokens = DefaultMinTokens(model)
+	}
+	var errs []error
+	add := func(field, format string, args ...any) {
+		errs = append(errs, &ValidationError{Model: e.Model, Field: field, Msg: fmt.Sprintf(format, args...)})
+	}
+
+	m, err := client.Models.Get(ctx, model, nil)
+	var apiErr genai.APIError
+	if errors.As(err, &apiErr) && apiErr.Code == http.StatusNotFound {
+		add("model", "not found")
+		return e, errors.Join(errs...)
+	}
+	if err != nil {
+		return nil, err
+	}
+	// Only the Gemini API lists the supported actions.
+	if len(m.SupportedActions) > 0 && !slices.Contains(m.SupportedActions, "createCachedContent") {
+		add("model", "does not support caching; it supports %s", strings.Join(m.SupportedActions, ", "))
+		return e, errors.Join(errs...)
+	}
+	e.MaxTokens = m.InputTokenLimit
+
+	switch {
+	case cfg.TTL < 0:
+		add("ttl", "negative TTL %v", cfg.TTL)
+	case cfg.TTL > 0 && !cfg.ExpireTime.IsZero():
+		add("ttl", "set along with the expiry time; set one of them")
+	case cfg.TTL > 0:
+		e.TTL = cfg.TTL
+	case !cfg.ExpireTime.IsZero():
+		e.TTL = cfg.ExpireTime.Sub(o.Now())
+		if e.TTL <= 0 {
+			add("expireTime", "%v is in the past", cfg.ExpireTime.Format(time.RFC3339))
+		}
+	}
+
+	// The Gemini API does not count system instructions in the config, so
+	// the instruction is counted as one more content.
+	contents := slices.Clone(cfg.Contents)
+	if cfg.SystemInstruction != nil {
+		contents = append(contents, cfg.SystemInstruction)
+	}
+	if len(contents) == 0 {
+		add("contents", "empty; a cache needs contents or a system instruction")
+		return e, errors.Join(errs...)
+	}
+	count, err := client.Models.CountTokens(ctx, model, contents, nil)
+	if err != nil {
+		return nil, fmt.Errorf("counting the tokens to cache: %w", err)
+	}
+	e.Tokens = count.TotalTokens
+	switch {
+	case e.Tokens < e.MinTokens:
+		add("contents", "%d tokens, fewer than the minimum of %d for a cache", e.Tokens, e.MinTokens)
+	case e.MaxTokens > 0 && e.Tokens > e.MaxTokens:
+		add("contents", "%d tokens, more than the input limit of %d", e.Tokens, e.MaxTokens)
+	}
+	return e, errors.Join(errs...)
+}
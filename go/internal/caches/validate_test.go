@@ -0,0 +1,132 @@
+package caches
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"gemini-api-examples/internal/fakegemini"
+
+	"google.golang.org/genai"
+)
+
+// problems returns the fields of the *ValidationError values in err.
+func problems(t *testing.T, err error) []string {
+	t.Helper()
+	var fields []string
+	var errs interface{ Unwrap() []error }
+	if !errors.As(err, &errs) {
+		t.Fatalf("err = %v, want joined validation errors", err)
+	}
+	for _, err := range errs.Unwrap() {
+		var verr *ValidationError
+		if !errors.As(err, &verr) {
+			t.Fatalf("error %v is not a *ValidationError", err)
+		}
+		fields = append(fields, verr.Field)
+	}
+	return fields
+}
+
+func TestValidate(t *testing.T) {
+	srv, client := newFake(t)
+	config := transcript(t, client)
+	config.TTL = 2 * time.Hour
+	e, err := Validate(t.Context(), client, "gemini-3.5-flash", config, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Model != "gemini-3.5-flash" || e.Tokens < e.MinTokens || e.MinTokens != 1024 || e.MaxTokens != 1048576 || e.TTL != 2*time.Hour {
+		t.Errorf("estimate = %+v", e)
+	}
+	cost, ok := e.StorageCost(Prices{"gemini-3.5-flash": {StoragePerHour: 1}})
+	if want := float64(e.Tokens) * 2 / 1e6; !ok || !near(cost, want) {
+		t.Errorf("StorageCost = %v, %v, want %v", cost, ok, want)
+	}
+	if n := len(srv.RequestsTo(http.MethodPost, "cachedContents")); n != 0 {
+		t.Errorf("made %d cache calls", n)
+	}
+}
+
+func TestValidateProblems(t *testing.T) {
+	srv, client := newFake(t)
+	now := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	srv.AddModel(&fakegemini.Model{
+		Name:                       "gemini-3.5-tiny",
+		InputTokenLimit:            2000,
+		SupportedGenerationMethods: []string{"generateContent", "countTokens", "createCachedContent"},
+	})
+	large := transcript(t, client)
+	small := &genai.CreateCachedContentConfig{Contents: genai.Text("Houston, Tranquility Base here.")}
+	for _, tt := range []struct {
+		name   string
+		model  string
+		config *genai.CreateCachedContentConfig
+		want   []string
+	}{
+		{"unknown model", "gemini-0-flash", small, []string{"model"}},
+		{"model without caching", "gemini-embedding-001", small, []string{"model"}},
+		{"too small", "gemini-3.5-flash", small, []string{"contents"}},
+		{"too large", "gemini-3.5-tiny", large, []string{"contents"}},
+		{"empty", "gemini-3.5-flash", &genai.CreateCachedContentConfig{}, []string{"contents"}},
+		{"TTL and expiry", "gemini-3.5-flash", &genai.CreateCachedContentConfig{
+			Contents: small.Contents, TTL: time.Hour, ExpireTime: now.Add(time.Hour),
+		}, []string{"ttl", "contents"}},
+		{"past expiry", "gemini-3.5-flash", &genai.CreateCachedContentConfig{
+			Contents: large.Contents, ExpireTime: now.Add(-time.Minute),
+		}, []string{"expireTime"}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Validate(t.Context(), client, tt.model, tt.config, &ValidateOptions{Now: func() time.Time { return now }})
+			if got := problems(t, err); !slices.Equal(got, tt.want) {
+				t.Errorf("problems with %v, want %v: %v", got, tt.want, err)
+			}
+		})
+	}
+	if n := len(srv.RequestsTo(http.MethodPost, "cachedContents")); n != 0 {
+		t.Errorf("made %d cache calls", n)
+	}
+}
+
+func TestValidateMatchesServer(t *testing.T) {
+	srv, client := newFake(t)
+	srv.AddModel(&fakegemini.Model{
+		Name:                       "gemini-3.5-flash",
+		InputTokenLimit:            1048576,
+		SupportedGenerationMethods: []string{"generateContent", "countTokens", "createCachedContent"},
+		MinCacheTokens:             2048,
+	})
+	config := &genai.CreateCachedContentConfig{Contents: genai.Text(string(make([]byte, 6000)))}
+	// 1500 tokens are enough by default, but not for this server.
+	if _, err := Validate(t.Context(), client, "gemini-3.5-flash", config, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Caches.Create(t.Context(), "gemini-3.5-flash", config); err == nil {
+		t.Fatal("the server accepted a cache under its minimum")
+	}
+	_, err := Validate(t.Context(), client, "gemini-3.5-flash", config, &ValidateOptions{MinTokens: 2048})
+	var verr *ValidationError
+	if !errors.As(err, &verr) || verr.Field != "contents" {
+		t.Errorf("err = %v, want the contents too small", err)
+	}
+}
+
+func TestManagerValidates(t *testing.T) {
+	srv, client := newFake(t)
+	m, err := OpenManager("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, err = m.GetOrCreate(t.Context(), client, "gemini-3.5-flash", &genai.CreateCachedContentConfig{
+		Contents: genai.Text("Houston, Tranquility Base here."),
+	})
+	var verr *ValidationError
+	if !errors.As(err, &verr) || verr.Field != "contents" {
+		t.Errorf("err = %v, want the contents too small", err)
+	}
+	if n := len(srv.RequestsTo(http.MethodPost, "cachedContents")); n != 0 {
+		t.Errorf("made %d cache calls", n)
+	}
+}
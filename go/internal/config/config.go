@@ -0,0 +1,256 @@
+// Package config builds Gemini API clients from settings read from
+// environment variables and an optional JSON config file.
+//
+// Settings are resolved in order of increasing precedence: defaults, the
+// file named by GEMINI_CONFIG, then the environment:
+//
+//	GEMINI_API_KEY         API key (GOOGLE_API_KEY is used when unset)
+//	GEMINI_BACKEND         "gemini" (the default) or "vertex"
+//	GOOGLE_CLOUD_PROJECT   Vertex AI project
+//	GOOGLE_CLOUD_LOCATION  Vertex AI location
+//	GEMINI_API_VERSION     API version, for example "v1beta"
+//	GEMINI_BASE_URL        base URL, for example a local fake server
+//	GEMINI_TIMEOUT         per-request timeout, for example "30s"
+//
+// GOOGLE_GENAI_USE_VERTEXAI=true selects the Vertex AI backend as well, for
+// compatibility with the SDK's own variables. The config file holds the same
+// settings as a JSON object:
+//
+//	{"backend": "vertex", "project": "my-project", "location": "us-central1", "timeout": "2m"}
+package config
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/genai"
+)
+
+// Backend names accepted in Config.Backend.
+const (
+	BackendGeminiAPI = "gemini"
+	BackendVertexAI  = "vertex"
+)
+
+// Config holds the settings used to build a client.
+type Config struct {
+	APIKey     string   `json:"apiKey,omitempty"`
+	Backend    string   `json:"backend,omitempty"`
+	Project    string   `json:"project,omitempty"`
+	Location   string   `json:"location,omitempty"`
+	APIVersion string   `json:"apiVersion,omitempty"`
+	BaseURL    string   `json:"baseUrl,omitempty"`
+	Timeout    Duration `json:"timeout,omitempty"`
+}
+
+// Duration is a time.Duration written as a string such as "30s" in the
+// config file.
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("duration must be a string such as \"30s\": %w", err)
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// Error describes a missing or invalid setting.
+type Error struct {
+	// Field is the setting's name in the config file.
+	Field string
+	// Env is the environment variable that sets it.
+	Env string
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("config: %s (%s): %s", e.Field, e.Env, e.Msg)
+}
+
+// FromEnv returns the configuration given by the environment, on top of the
+// config file named by GEMINI_CONFIG if that is set. It does not validate
+// the result.
+func FromEnv() (*Config, error) {
+	return fromEnv(os.Getenv)
+}
+
+func fromEnv(getenv func(string) string) (*Config, error) {
+	c := &Config{}
+	if path := getenv("GEMINI_CONFIG"); path != "" {
+		var err error
+		if c, err = Load(path); err != nil {
+			return nil, err
+		}
+	}
+	if err := c.applyEnv(getenv); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Load reads a JSON config file. Unknown fields are rejected, so that a
+// misspelt setting is not silently ignored.
+func Load(path string) (*Config, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("config: %w", err)
+	}
+	defer f.Close()
+	dec := json.NewDecoder(f)
+	dec.DisallowUnknownFields()
+	c := &Config{}
+	if err := dec.Decode(c); err != nil {
+		return nil, fmt.Errorf("config: parsing %s: %w", path, err)
+	}
+	return c, nil
+}
+
+func (c *Config) applyEnv(getenv func(string) string) error {
+	set := func(dst *string, names ...string) {
+		for _, name := range names {
+			if v := getenv(name); v != "" {
+				*dst = v
+				return
+			}
+		}
+	}
+	set(&c.APIKey, "GEMINI_API_KEY", "GOOGLE_API_KEY")
+	if v, err := strconv.ParseBool(getenv("GOOGLE_GENAI_USE_VERTEXAI")); err == nil && v {
+		c.Backend = BackendVertexAI
+	}
+	set(&c.Backend, "GEMINI_BACKEND")
+	set(&c.Project, "GOOGLE_CLOUD_PROJECT")
+	set(&c.Location, "GOOGLE_CLOUD_LOCATION", "GOOGLE_CLOUD_REGION")
+	set(&c.APIVersion, "GEMINI_API_VERSION")
+	set(&c.BaseURL, "GEMINI_BASE_URL")
+	if v := getenv("GEMINI_TIMEOUT"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return &Error{Field: "timeout", Env: "GEMINI_TIMEOUT", Msg: err.Error()}
+		}
+		c.Timeout = Duration(d)
+	}
+	return nil
+}
+
+var apiVersionRE = regexp.MustCompile(`^v\d+((alpha|beta)\d*)?$`)
+
+// Validate checks that the configuration is complete and consistent. It
+// reports every problem found, each as an *Error.
+func (c *Config) Validate() error {
+	var errs []error
+	add := func(field, env, format string, args ...any) {
+		errs = append(errs, &Error{Field: field, Env: env, Msg: fmt.Sprintf(format, args...)})
+	}
+	switch c.backend() {
+	case BackendGeminiAPI:
+		if c.APIKey == "" {
+			add("apiKey", "GEMINI_API_KEY", "required for the Gemini API backend; get one at https://aistudio.google.com/apikey")
+		}
+	case BackendVertexAI:
+		if c.Project == "" {
+			add("project", "GOOGLE_CLOUD_PROJECT", "required for the Vertex AI backend")
+		}
+		if c.Location == "" {
+			add("location", "GOOGLE_CLOUD_LOCATION", "required for the Vertex AI backend")
+		}
+	default:
+		add("backend", "GEMINI_BACKEND", "unknown backend %q; use %q or %q", c.Backend, BackendGeminiAPI, BackendVertexAI)
+	}
+	if c.APIVersion != "" && !apiVersionRE.MatchString(c.APIVersion) {
+		add("apiVersion", "GEMINI_API_VERSION", "%q is not an API version such as v1 or v1beta", c.APIVersion)
+	}
+	if c.BaseURL != "" {
+		u, err := url.Parse(c.BaseURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			add("baseUrl", "GEMINI_BASE_URL", "%q is not an absolute http or https URL", c.BaseURL)
+		}
+	}
+	if c.Timeout < 0 {
+		add("timeout", "GEMINI_TIMEOUT", "must not be negative")
+	}
+	return errors.Join(errs...)
+}
+
+func (c *Config) backend() string {
+	if c.Backend == "" {
+		return BackendGeminiAPI
+	}
+	return strings.ToLower(c.Backend)
+}
+
+// ClientConfig returns the SDK configuration for c. The HTTP client is only
+// set for the Gemini API backend with a timeout; see NewClient for Vertex AI.
+func (c *Config) ClientConfig() *genai.ClientConfig {
+	cc := &genai.ClientConfig{
+		HTTPOptions: genai.HTTPOptions{
+			BaseURL:    c.BaseURL,
+			APIVersion: c.APIVersion,
+		},
+	}
+	if c.backend() == BackendVertexAI {
+		cc.Backend = genai.BackendVertexAI
+		cc.Project = c.Project
+		cc.Location = c.Location
+		return cc
+	}
+	cc.Backend = genai.BackendGeminiAPI
+	cc.APIKey = c.APIKey
+	if c.Timeout > 0 {
+		cc.HTTPClient = &http.Client{Timeout: time.Duration(c.Timeout)}
+	}
+	return cc
+}
+
+// NewClient validates c and returns a client for it. The timeout bounds each
+// HTTP exchange, including reading a streamed response.
+func (c *Config) NewClient(ctx context.Context) (*genai.Client, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	client, err := genai.NewClient(ctx, c.ClientConfig())
+	if err != nil {
+		return nil, err
+	}
+	if c.backend() == BackendVertexAI && c.Timeout > 0 {
+		// The SDK builds the authenticated HTTP client for Vertex AI
+		// itself; rebuild the client around a copy with the timeout.
+		cc := client.ClientConfig()
+		hc := *cc.HTTPClient
+		hc.Timeout = time.Duration(c.Timeout)
+		cc.HTTPClient = &hc
+		return genai.NewClient(ctx, &cc)
+	}
+	return client, nil
+}
+
+// Scope identifies the account and endpoint of client, whose files and
+// caches other clients cannot see, without revealing its credentials. It
+// keys records of remote resources kept across runs.
+func Scope(client *genai.Client) string {
+	cc := client.ClientConfig()
+	id := strings.Join([]string{cc.Backend.String(), cc.HTTPOptions.BaseURL, cc.APIKey, cc.Project, cc.Location}, "\x00")
+	sum := sha256.Sum256([]byte(id))
+	return hex.EncodeToString(sum[:8])
+}
@@ -0,0 +1,227 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/genai"
+)
+
+func env(vars map[string]string) func(string) string {
+	return func(name string) string { return vars[name] }
+}
+
+func writeFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestFromEnv(t *testing.T) {
+	c, err := fromEnv(env(map[string]string{
+		"GOOGLE_API_KEY":     "google-key",
+		"GEMINI_API_VERSION": "v1",
+		"GEMINI_BASE_URL":    "http://localhost:8080",
+		"GEMINI_TIMEOUT":     "90s",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Config{
+		APIKey:     "google-key",
+		APIVersion: "v1",
+		BaseURL:    "http://localhost:8080",
+		Timeout:    Duration(90 * time.Second),
+	}
+	if *c != want {
+		t.Errorf("config = %+v, want %+v", *c, want)
+	}
+	if err := c.Validate(); err != nil {
+		t.Errorf("Validate() = %v", err)
+	}
+}
+
+func TestFromEnvOverridesFile(t *testing.T) {
+	path := writeFile(t, `{
+		"apiKey": "file-key",
+		"backend": "vertex",
+		"project": "file-project",
+		"location": "us-central1",
+		"timeout": "2m"
+	}`)
+	c, err := fromEnv(env(map[string]string{
+		"GEMINI_CONFIG":        path,
+		"GOOGLE_CLOUD_PROJECT": "env-project",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Config{
+		APIKey:   "file-key",
+		Backend:  BackendVertexAI,
+		Project:  "env-project",
+		Location: "us-central1",
+		Timeout:  Duration(2 * time.Minute),
+	}
+	if *c != want {
+		t.Errorf("config = %+v, want %+v", *c, want)
+	}
+}
+
+func TestFromEnvVertexSwitch(t *testing.T) {
+	c, err := fromEnv(env(map[string]string{"GOOGLE_GENAI_USE_VERTEXAI": "true"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Backend != BackendVertexAI {
+		t.Errorf("Backend = %q, want %q", c.Backend, BackendVertexAI)
+	}
+	c, err = fromEnv(env(map[string]string{"GOOGLE_GENAI_USE_VERTEXAI": "true", "GEMINI_BACKEND": "gemini"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Backend != BackendGeminiAPI {
+		t.Errorf("Backend = %q, want GEMINI_BACKEND to win", c.Backend)
+	}
+}
+
+func TestFromEnvErrors(t *testing.T) {
+	if _, err := fromEnv(env(map[string]string{"GEMINI_TIMEOUT": "soon"})); err == nil {
+		t.Error("invalid GEMINI_TIMEOUT accepted")
+	}
+	if _, err := fromEnv(env(map[string]string{"GEMINI_CONFIG": "does-not-exist.json"})); err == nil {
+		t.Error("missing config file accepted")
+	}
+	path := writeFile(t, `{"api_key": "typo"}`)
+	if _, err := fromEnv(env(map[string]string{"GEMINI_CONFIG": path})); err == nil || !strings.Contains(err.Error(), "api_key") {
+		t.Errorf("err = %v, want the unknown field named", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		config Config
+		fields []string
+	}{
+		{"gemini ok", Config{APIKey: "k"}, nil},
+		{"vertex ok", Config{Backend: "Vertex", Project: "p", Location: "global"}, nil},
+		{"gemini without key", Config{}, []string{"apiKey"}},
+		{"vertex without project", Config{Backend: BackendVertexAI, Location: "l"}, []string{"project"}},
+		{"unknown backend", Config{Backend: "openai"}, []string{"backend"}},
+		{"everything wrong", Config{
+			Backend:    BackendVertexAI,
+			APIVersion: "beta",
+			BaseURL:    "localhost:8080",
+			Timeout:    -1,
+		}, []string{"project", "location", "apiVersion", "baseUrl", "timeout"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			var fields []string
+			for _, e := range unwrap(err) {
+				var cerr *Error
+				if !errors.As(e, &cerr) {
+					t.Fatalf("error %v is not a *config.Error", e)
+				}
+				fields = append(fields, cerr.Field)
+			}
+			if strings.Join(fields, ",") != strings.Join(tt.fields, ",") {
+				t.Errorf("Validate() = %v, want errors for %v", err, tt.fields)
+			}
+		})
+	}
+}
+
+func unwrap(err error) []error {
+	if err == nil {
+		return nil
+	}
+	if j, ok := err.(interface{ Unwrap() []error }); ok {
+		return j.Unwrap()
+	}
+	return []error{err}
+}
+
+func TestClientConfig(t *testing.T) {
+	c := &Config{Backend: BackendVertexAI, Project: "p", Location: "europe-west4", APIVersion: "v1"}
+	cc := c.ClientConfig()
+	if cc.Backend != genai.BackendVertexAI || cc.Project != "p" || cc.Location != "europe-west4" ||
+		cc.HTTPOptions.APIVersion != "v1" || cc.APIKey != "" {
+		t.Errorf("ClientConfig() = %+v", cc)
+	}
+}
+
+func TestNewClientBaseURLAndTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("x-goog-api-key") != "k" {
+			http.Error(w, "no key", http.StatusForbidden)
+			return
+		}
+		if strings.HasSuffix(r.URL.Path, "/slow-model") {
+			time.Sleep(200 * time.Millisecond)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"name": "models/fast-model"}`))
+	}))
+	defer srv.Close()
+
+	c := &Config{APIKey: "k", BaseURL: srv.URL, Timeout: Duration(50 * time.Millisecond)}
+	client, err := c.NewClient(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	m, err := client.Models.Get(ctx, "fast-model", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Name != "models/fast-model" {
+		t.Errorf("Name = %q", m.Name)
+	}
+	if _, err := client.Models.Get(ctx, "slow-model", nil); err == nil {
+		t.Error("request slower than the timeout succeeded")
+	}
+}
+
+func TestNewClientValidates(t *testing.T) {
+	_, err := (&Config{}).NewClient(context.Background())
+	var cerr *Error
+	if !errors.As(err, &cerr) || cerr.Env != "GEMINI_API_KEY" {
+		t.Errorf("err = %v, want a missing GEMINI_API_KEY error", err)
+	}
+}
+
+func TestScope(t *testing.T) {
+	ctx := context.Background()
+	scopeOf := func(c *Config) string {
+		t.Helper()
+		client, err := c.NewClient(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return Scope(client)
+	}
+	a := scopeOf(&Config{APIKey: "secret-a", BaseURL: "http://localhost:1"})
+	if a != scopeOf(&Config{APIKey: "secret-a", BaseURL: "http://localhost:1"}) {
+		t.Error("same settings, different scopes")
+	}
+	if a == scopeOf(&Config{APIKey: "secret-b", BaseURL: "http://localhost:1"}) ||
+		a == scopeOf(&Config{APIKey: "secret-a", BaseURL: "http://localhost:2"}) {
+		t.Error("different accounts or endpoints, same scope")
+	}
+	if strings.Contains(a, "secret") {
+		t.Errorf("Scope = %q reveals the API key", a)
+	}
+}
@@ -0,0 +1,218 @@
+package fakegemini
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"google.golang.org/genai"
+)
+
+// defaultCacheTTL is the TTL the API applies when neither ttl nor expireTime
+// is given.
+const defaultCacheTTL = time.Hour
+
+type cacheRequest struct {
+	Model             string           `json:"model"`
+	DisplayName       string           `json:"displayName"`
+	Contents          []*genai.Content `json:"contents"`
+	SystemInstruction *genai.Content   `json:"systemInstruction"`
+	Tools             []*genai.Tool    `json:"tools"`
+	TTL               string           `json:"ttl"`
+	ExpireTime        time.Time        `json:"expireTime"`
+}
+
+// expiry returns the expiry time requested by r, or the zero time if none.
+func (r *cacheRequest) expiry(now time.Time) (time.Time, error) {
+	if r.TTL != "" {
+		ttl, err := time.ParseDuration(r.TTL)
+		if err != nil || ttl <= 0 {
+			return time.Time{}, errorf(http.StatusBadRequest, "INVALID_ARGUMENT", "invalid ttl %q", r.TTL)
+		}
+		return now.Add(ttl), nil
+	}
+	return r.ExpireTime, nil
+}
+
+// AddCache stores a cached content entry and returns it. Name and timestamps
+// are filled in when empty.
+func (s *Server) AddCache(c *genai.CachedContent) *genai.CachedContent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return copyCache(s.newCache(c))
+}
+
+// Caches returns the stored cache entries in creation order, including
+// expired ones that have not been listed or fetched since they expired.
+func (s *Server) Caches() []*genai.CachedContent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []*genai.CachedContent
+	for _, name := range s.cacheOrder {
+		out = append(out, copyCache(s.caches[name]))
+	}
+	return out
+}
+
+// newCache registers a cache entry. Callers must hold s.mu.
+func (s *Server) newCache(c *genai.CachedContent) *genai.CachedContent {
+	cc := copyCache(c)
+	if cc.Name == "" {
+		cc.Name = "cachedContents/" + s.newID("cache")
+	}
+	now := s.now().UTC()
+	if cc.CreateTime.IsZero() {
+		cc.CreateTime = now
+	}
+	if cc.UpdateTime.IsZero() {
+		cc.UpdateTime = cc.CreateTime
+	}
+	if cc.ExpireTime.IsZero() {
+		cc.ExpireTime = cc.CreateTime.Add(defaultCacheTTL)
+	}
+	if _, exists := s.caches[cc.Name]; !exists {
+		s.cacheOrder = append(s.cacheOrder, cc.Name)
+	}
+	s.caches[cc.Name] = cc
+	return cc
+}
+
+// expireCaches drops entries past their expiry time, as the API does.
+// Callers must hold s.mu.
+func (s *Server) expireCaches() {
+	now := s.now()
+	for _, name := range append([]string(nil), s.cacheOrder...) {
+		if !s.caches[name].ExpireTime.After(now) {
+			delete(s.caches, name)
+			s.cacheOrder = remove(s.cacheOrder, name)
+		}
+	}
+}
+
+// liveCache returns the named cache if it exists and has not expired.
+// Callers must hold s.mu.
+func (s *Server) liveCache(name string) (*genai.CachedContent, error) {
+	s.expireCaches()
+	c, ok := s.caches[name]
+	if !ok {
+		return nil, errorf(http.StatusNotFound, "NOT_FOUND", "CachedContent not found")
+	}
+	return c, nil
+}
+
+func (s *Server) serveCaches(w http.ResponseWriter, req *Request, resource string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if resource == "cachedContents" {
+		switch req.Method {
+		case http.MethodPost:
+			s.createCache(w, req)
+		case http.MethodGet:
+			s.expireCaches()
+			names, next, err := page(s.cacheOrder, req.Query, 10)
+			if err != nil {
+				writeError(w, err)
+				return
+			}
+			caches := make([]*genai.CachedContent, 0, len(names))
+			for _, name := range names {
+				caches = append(caches, s.caches[name])
+			}
+			resp := map[string]any{"cachedContents": caches}
+			if next != "" {
+				resp["nextPageToken"] = next
+			}
+			writeJSON(w, resp)
+		default:
+			writeError(w, errorf(http.StatusMethodNotAllowed, "INVALID_ARGUMENT", "cachedContents: unsupported method %s", req.Method))
+		}
+		return
+	}
+
+	c, err := s.liveCache(resource)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	switch req.Method {
+	case http.MethodGet:
+		writeJSON(w, c)
+	case http.MethodPatch:
+		var body cacheRequest
+		if err := decode(req.Body, &body); err != nil {
+			writeError(w, err)
+			return
+		}
+		now := s.now().UTC()
+		expire, err := body.expiry(now)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		if expire.IsZero() {
+			writeError(w, errorf(http.StatusBadRequest, "INVALID_ARGUMENT", "update requires ttl or expireTime"))
+			return
+		}
+		c.ExpireTime = expire.UTC()
+		c.UpdateTime = now
+		writeJSON(w, c)
+	case http.MethodDelete:
+		delete(s.caches, resource)
+		s.cacheOrder = remove(s.cacheOrder, resource)
+		writeJSON(w, map[string]any{})
+	default:
+		writeError(w, errorf(http.StatusMethodNotAllowed, "INVALID_ARGUMENT", "%s: unsupported method %s", resource, req.Method))
+	}
+}
+
+func (s *Server) createCache(w http.ResponseWriter, req *Request) {
+	var body cacheRequest
+	if err := decode(req.Body, &body); err != nil {
+		writeError(w, err)
+		return
+	}
+	model := strings.TrimPrefix(body.Model, "models/")
+	m := s.findModel(model)
+	if m == nil {
+		writeError(w, errorf(http.StatusNotFound, "NOT_FOUND", "models/%s is not found", model))
+		return
+	}
+	if !m.supports("createCachedContent") {
+		writeError(w, errorf(http.StatusBadRequest, "INVALID_ARGUMENT", "models/%s does not support createCachedContent", model))
+		return
+	}
+	if err := s.checkFileRefs(body.Contents); err != nil {
+		writeError(w, err)
+		return
+	}
+	now := s.now().UTC()
+	expire, err := body.expiry(now)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	tokens := s.countTokens(body.Contents) + s.countTokens([]*genai.Content{body.SystemInstruction})
+	if tokens < m.MinCacheTokens {
+		writeError(w, errorf(http.StatusBadRequest, "INVALID_ARGUMENT",
+			"Cached content is too small. total_token_count=%d, min_total_token_count=%d", tokens, m.MinCacheTokens))
+		return
+	}
+	c := s.newCache(&genai.CachedContent{
+		DisplayName:   body.DisplayName,
+		Model:         "models/" + model,
+		CreateTime:    now,
+		ExpireTime:    expire,
+		UsageMetadata: &genai.CachedContentUsageMetadata{TotalTokenCount: tokens},
+	})
+	writeJSON(w, c)
+}
+
+func copyCache(c *genai.CachedContent) *genai.CachedContent {
+	cc := *c
+	if c.UsageMetadata != nil {
+		u := *c.UsageMetadata
+		cc.UsageMetadata = &u
+	}
+	return &cc
+}
@@ -0,0 +1,196 @@
+package fakegemini
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"google.golang.org/genai"
+)
+
+type faultKind int
+
+const (
+	faultStatus faultKind = iota
+	faultDisconnect
+	faultMalformed
+	faultEmpty
+	faultBlocked
+	faultProcessingFailed
+	faultDropUpload
+)
+
+// A Fault is a failure the server can be told to produce with Inject.
+type Fault struct {
+	kind    faultKind
+	err     *Error
+	chunks  int
+	reason  genai.BlockedReason
+	message string
+	keep    int
+}
+
+// RateLimited fails the request with 429 RESOURCE_EXHAUSTED and a RetryInfo
+// detail asking the client to wait retryDelay.
+func RateLimited(retryDelay time.Duration) Fault {
+	err := errorf(http.StatusTooManyRequests, "RESOURCE_EXHAUSTED",
+		"You exceeded your current quota, please check your plan and billing details.")
+	err.Details = []map[string]any{{
+		"@type":      "type.googleapis.com/google.rpc.RetryInfo",
+		"retryDelay": fmt.Sprintf("%gs", retryDelay.Seconds()),
+	}}
+	return Fault{kind: faultStatus, err: err}
+}
+
+// ServerError fails the request with the given 5xx status code.
+func ServerError(code int) Fault {
+	var err *Error
+	switch code {
+	case http.StatusInternalServerError:
+		err = errorf(code, "INTERNAL", "An internal error has occurred. Please retry or report in https://developers.generativeai.google/guide/troubleshooting")
+	case http.StatusServiceUnavailable:
+		err = errorf(code, "UNAVAILABLE", "The model is overloaded. Please try again later.")
+	case http.StatusGatewayTimeout:
+		err = errorf(code, "DEADLINE_EXCEEDED", "Deadline expired before operation could complete.")
+	default:
+		err = errorf(code, strings.ToUpper(strings.ReplaceAll(http.StatusText(code), " ", "_")), "%s", http.StatusText(code))
+	}
+	return Fault{kind: faultStatus, err: err}
+}
+
+// Disconnect drops the connection. Streaming generation requests get
+// afterChunks events first; any other request is dropped before a response
+// is written. Note that the SDK ends a stream cut short this way without an
+// error: the last chunk received simply has no finish reason.
+func Disconnect(afterChunks int) Fault {
+	return Fault{kind: faultDisconnect, chunks: afterChunks}
+}
+
+// Malformed answers with a body that is not valid JSON. Streaming generation
+// requests get one valid event followed by a malformed one.
+func Malformed() Fault {
+	return Fault{kind: faultMalformed}
+}
+
+// EmptyCandidates makes a generation request succeed with no candidates.
+func EmptyCandidates() Fault {
+	return Fault{kind: faultEmpty}
+}
+
+// BlockedPrompt makes a generation request succeed with no candidates and
+// prompt feedback giving reason as the block reason.
+func BlockedPrompt(reason genai.BlockedReason) Fault {
+	return Fault{kind: faultBlocked, reason: reason}
+}
+
+// ProcessingFailed makes the file created by an upload end up FAILED, with
+// message as its error, or without error details if message is empty.
+func ProcessingFailed(message string) Fault {
+	return Fault{kind: faultProcessingFailed, message: message}
+}
+
+// DropUpload drops the connection of a request carrying bytes of a
+// resumable upload once the server has received keep bytes of it. The
+// server keeps those bytes, as a real one would, so a client that queries
+// the upload can resume from there.
+func DropUpload(keep int) Fault {
+	return Fault{kind: faultDropUpload, keep: keep}
+}
+
+// appliesTo reports whether f can be applied to req.
+func (f *Fault) appliesTo(req *Request) bool {
+	switch f.kind {
+	case faultEmpty, faultBlocked:
+		return isGenerate(req)
+	case faultProcessingFailed:
+		return strings.HasPrefix(req.Path, "upload/") &&
+			strings.Contains(req.Header.Get("X-Goog-Upload-Command"), "finalize")
+	case faultDropUpload:
+		return strings.HasPrefix(req.Path, "upload/") &&
+			strings.HasPrefix(req.Header.Get("X-Goog-Upload-Command"), "upload")
+	}
+	return true
+}
+
+func isGenerate(req *Request) bool {
+	return strings.HasSuffix(req.Path, ":generateContent") || isStream(req)
+}
+
+func isStream(req *Request) bool {
+	return strings.HasSuffix(req.Path, ":streamGenerateContent")
+}
+
+type injected struct {
+	suffix string
+	left   int
+	fault  Fault
+}
+
+// Inject makes the server apply f to the next n requests whose path ends
+// with suffix, for example ":streamGenerateContent" or "files". An empty
+// suffix matches every request and n <= 0 applies f to every match. Faults
+// are tried in the order they were injected; requests a fault does not apply
+// to, such as an upload start for ProcessingFailed, do not consume it.
+func (s *Server) Inject(suffix string, n int, f Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = append(s.faults, &injected{suffix: suffix, left: n, fault: f})
+}
+
+// takeFault returns the first injected fault matching req, or nil.
+func (s *Server) takeFault(req *Request) *Fault {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	path := strings.TrimSuffix(req.Path, "/")
+	for i, in := range s.faults {
+		if !strings.HasSuffix(path, in.suffix) || !in.fault.appliesTo(req) {
+			continue
+		}
+		f := in.fault
+		if in.left > 0 {
+			in.left--
+			if in.left == 0 {
+				s.faults = append(s.faults[:i], s.faults[i+1:]...)
+			}
+		}
+		return &f
+	}
+	return nil
+}
+
+// serveFault writes the response for faults that replace the whole
+// exchange, and reports whether it did. Faults that alter a normal response
+// are left to the handlers through req.fault.
+func serveFault(w http.ResponseWriter, req *Request) bool {
+	f := req.fault
+	switch {
+	case f == nil:
+		return false
+	case f.kind == faultStatus:
+		writeError(w, f.err)
+		return true
+	case f.kind == faultDisconnect && !isStream(req):
+		hangUp(w)
+		return true
+	case f.kind == faultMalformed && !isStream(req):
+		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+		fmt.Fprint(w, `{"candidates": [{"content": {"parts": [{"text": "trunc`)
+		return true
+	}
+	return false
+}
+
+// hangUp closes the connection under w without completing the response.
+func hangUp(w http.ResponseWriter) {
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		panic("fakegemini: connection cannot be hijacked")
+	}
+	conn, buf, err := hj.Hijack()
+	if err != nil {
+		return
+	}
+	buf.Flush()
+	conn.Close()
+}
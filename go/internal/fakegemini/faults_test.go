@@ -0,0 +1,191 @@
+package fakegemini
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/genai"
+)
+
+func TestRateLimited(t *testing.T) {
+	srv, client := newClient(t)
+	srv.Inject(":generateContent", 1, RateLimited(2*time.Second))
+	ctx := context.Background()
+
+	_, err := client.Models.GenerateContent(ctx, "gemini-3.5-flash", genai.Text("hi"), nil)
+	var apiErr genai.APIError
+	if !errors.As(err, &apiErr) || apiErr.Code != http.StatusTooManyRequests || apiErr.Status != "RESOURCE_EXHAUSTED" {
+		t.Fatalf("err = %v, want 429 RESOURCE_EXHAUSTED", err)
+	}
+	if len(apiErr.Details) != 1 || apiErr.Details[0]["retryDelay"] != "2s" {
+		t.Errorf("Details = %v, want a RetryInfo with retryDelay 2s", apiErr.Details)
+	}
+
+	if _, err := client.Models.GenerateContent(ctx, "gemini-3.5-flash", genai.Text("hi"), nil); err != nil {
+		t.Errorf("second call: %v, want the fault to be used up", err)
+	}
+}
+
+func TestServerErrorEveryRequest(t *testing.T) {
+	srv, client := newClient(t)
+	srv.Inject("", 0, ServerError(http.StatusServiceUnavailable))
+	ctx := context.Background()
+	for range 3 {
+		_, err := client.Models.Get(ctx, "gemini-3.5-flash", nil)
+		var apiErr genai.APIError
+		if !errors.As(err, &apiErr) || apiErr.Code != http.StatusServiceUnavailable {
+			t.Fatalf("err = %v, want 503", err)
+		}
+	}
+}
+
+func TestDisconnect(t *testing.T) {
+	srv, client := newClient(t)
+	srv.OnGenerate(func(*GenerateRequest) (*genai.GenerateContentResponse, error) {
+		return TextResponse("one two three four five six seven"), nil
+	})
+	srv.Inject(":streamGenerateContent", 1, Disconnect(2))
+	srv.Inject(":generateContent", 1, Disconnect(0))
+	ctx := context.Background()
+
+	// The SDK logs the read error and ends the stream without reporting
+	// it, so a missing finish reason is the only sign of the disconnect.
+	var chunks []*genai.GenerateContentResponse
+	for resp, err := range client.Models.GenerateContentStream(ctx, "gemini-3.5-flash", genai.Text("hi"), nil) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		chunks = append(chunks, resp)
+	}
+	if len(chunks) != 2 || chunks[1].Candidates[0].FinishReason != "" {
+		t.Errorf("got %d chunks, want 2 without a finish reason", len(chunks))
+	}
+
+	if _, err := client.Models.GenerateContent(ctx, "gemini-3.5-flash", genai.Text("hi"), nil); err == nil {
+		t.Error("unary call on a dropped connection succeeded")
+	}
+}
+
+func TestMalformed(t *testing.T) {
+	srv, client := newClient(t)
+	srv.Inject(":streamGenerateContent", 1, Malformed())
+	srv.Inject(":generateContent", 1, Malformed())
+	ctx := context.Background()
+
+	var chunks int
+	var streamErr error
+	for _, err := range client.Models.GenerateContentStream(ctx, "gemini-3.5-flash", genai.Text("hi"), nil) {
+		if err != nil {
+			streamErr = err
+			break
+		}
+		chunks++
+	}
+	if chunks != 1 || streamErr == nil {
+		t.Errorf("got %d chunks and err %v, want 1 chunk then an error", chunks, streamErr)
+	}
+
+	if _, err := client.Models.GenerateContent(ctx, "gemini-3.5-flash", genai.Text("hi"), nil); err == nil {
+		t.Error("unary call with a malformed body succeeded")
+	}
+}
+
+func TestEmptyAndBlocked(t *testing.T) {
+	srv, client := newClient(t)
+	srv.Inject(":generateContent", 1, EmptyCandidates())
+	srv.Inject(":generateContent", 1, BlockedPrompt(genai.BlockedReasonSafety))
+	ctx := context.Background()
+
+	resp, err := client.Models.GenerateContent(ctx, "gemini-3.5-flash", genai.Text("hi"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Candidates) != 0 || resp.PromptFeedback != nil {
+		t.Errorf("response = %+v, want no candidates and no feedback", resp)
+	}
+
+	resp, err = client.Models.GenerateContent(ctx, "gemini-3.5-flash", genai.Text("hi"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Candidates) != 0 || resp.PromptFeedback == nil || resp.PromptFeedback.BlockReason != genai.BlockedReasonSafety {
+		t.Errorf("response = %+v, want a prompt blocked for SAFETY", resp)
+	}
+}
+
+func TestProcessingFailed(t *testing.T) {
+	srv, client := newClient(t)
+	srv.Inject("files", 1, ProcessingFailed("unsupported codec"))
+	f, err := client.Files.Upload(context.Background(), strings.NewReader("video"), &genai.UploadFileConfig{MIMEType: "video/mp4"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.State != genai.FileStateFailed || f.Error == nil || f.Error.Message != "unsupported codec" {
+		t.Errorf("file = %+v, want FAILED with the injected message", f)
+	}
+}
+
+func TestProcessingForever(t *testing.T) {
+	srv, client := newClient(t)
+	srv.SetProcessingPolls(-1)
+	ctx := context.Background()
+	f, err := client.Files.Upload(ctx, strings.NewReader("video"), &genai.UploadFileConfig{MIMEType: "video/mp4"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range 10 {
+		if f, err = client.Files.Get(ctx, f.Name, nil); err != nil {
+			t.Fatal(err)
+		}
+		if f.State != genai.FileStateProcessing {
+			t.Fatalf("State = %s, want PROCESSING", f.State)
+		}
+	}
+}
+
+func TestDropUpload(t *testing.T) {
+	srv, client := newClient(t)
+	srv.Inject("files", 1, DropUpload(4))
+	ctx := context.Background()
+	if _, err := client.Files.Upload(ctx, strings.NewReader("0123456789"), &genai.UploadFileConfig{MIMEType: "text/plain"}); err == nil {
+		t.Fatal("upload succeeded despite the dropped connection")
+	}
+	reqs := srv.RequestsTo(http.MethodPost, "upload/v1beta/files")
+	url := srv.URL + "/upload/v1beta/files?upload_id=" + reqs[len(reqs)-1].Query.Get("upload_id")
+	send := func(command string, offset int, body string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodPost, url, strings.NewReader(body))
+		req.Header.Set("x-goog-api-key", "fake")
+		req.Header.Set("X-Goog-Upload-Command", command)
+		req.Header.Set("X-Goog-Upload-Offset", strconv.Itoa(offset))
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	resp := send("query", 0, "")
+	if got, status := resp.Header.Get("X-Goog-Upload-Size-Received"), resp.Header.Get("X-Goog-Upload-Status"); got != "4" || status != "active" {
+		t.Fatalf("query: %s bytes received, status %q, want 4 and active", got, status)
+	}
+	if resp := send("upload, finalize", 4, "456789"); resp.Header.Get("X-Goog-Upload-Status") != "final" {
+		t.Fatalf("resumed upload: status %d %q, want final", resp.StatusCode, resp.Header.Get("X-Goog-Upload-Status"))
+	}
+	if resp := send("query", 0, ""); resp.Header.Get("X-Goog-Upload-Status") != "final" {
+		t.Errorf("query after finalizing: status %q, want final", resp.Header.Get("X-Goog-Upload-Status"))
+	}
+	files := srv.Files()
+	if len(files) != 1 {
+		t.Fatalf("%d files, want 1", len(files))
+	}
+	if data, _ := srv.FileData(files[0].Name); string(data) != "0123456789" {
+		t.Errorf("file data = %q, want all the bytes once", data)
+	}
+}
@@ -0,0 +1,338 @@
+package fakegemini
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/genai"
+)
+
+// fileTTL is how long the Files API keeps an upload.
+const fileTTL = 48 * time.Hour
+
+type file struct {
+	meta *genai.File
+	data []byte
+	// polls is the number of Files.Get calls left before a PROCESSING file
+	// becomes ACTIVE. A negative value keeps it PROCESSING for good.
+	polls int
+}
+
+func (f *file) tokens() int32 {
+	return mediaTokens(f.meta.MIMEType, int64(len(f.data)))
+}
+
+type upload struct {
+	meta *genai.File
+	size int64
+	data []byte
+	// file is the file created when the upload was finalized.
+	file *genai.File
+}
+
+// AddFile stores a file as if it had been uploaded and returns its metadata.
+// Name, URI, size, hash and timestamps are filled in when empty; the state
+// defaults to ACTIVE.
+func (s *Server) AddFile(meta *genai.File, data []byte) *genai.File {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f := s.newFile(meta, data)
+	if meta.State == "" {
+		f.meta.State = genai.FileStateActive
+	}
+	return copyFile(f.meta)
+}
+
+// Files returns the stored files in upload order.
+func (s *Server) Files() []*genai.File {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []*genai.File
+	for _, name := range s.fileOrder {
+		out = append(out, copyFile(s.files[name].meta))
+	}
+	return out
+}
+
+// FileData returns the uploaded bytes of the named file.
+func (s *Server) FileData(name string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f, ok := s.files[name]
+	if !ok {
+		return nil, false
+	}
+	return append([]byte(nil), f.data...), true
+}
+
+// SetFileState changes the state of the named file.
+func (s *Server) SetFileState(name string, state genai.FileState) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if f, ok := s.files[name]; ok {
+		f.meta.State = state
+		f.polls = 0
+	}
+}
+
+// newFile registers a file. Callers must hold s.mu.
+func (s *Server) newFile(meta *genai.File, data []byte) *file {
+	m := copyFile(meta)
+	if m.Name == "" {
+		m.Name = "files/" + s.newID("file")
+	}
+	if !strings.HasPrefix(m.Name, "files/") {
+		m.Name = "files/" + m.Name
+	}
+	if m.URI == "" {
+		m.URI = s.URL + "/v1beta/" + m.Name
+	}
+	size := int64(len(data))
+	if m.SizeBytes == nil {
+		m.SizeBytes = &size
+	}
+	if m.Sha256Hash == "" {
+		sum := sha256.Sum256(data)
+		m.Sha256Hash = base64.StdEncoding.EncodeToString(sum[:])
+	}
+	now := s.now().UTC()
+	if m.CreateTime.IsZero() {
+		m.CreateTime = now
+	}
+	if m.UpdateTime.IsZero() {
+		m.UpdateTime = m.CreateTime
+	}
+	if m.ExpirationTime.IsZero() {
+		m.ExpirationTime = m.CreateTime.Add(fileTTL)
+	}
+	if m.Source == "" {
+		m.Source = genai.FileSourceUploaded
+	}
+	f := &file{meta: m, data: data}
+	if _, exists := s.files[m.Name]; !exists {
+		s.fileOrder = append(s.fileOrder, m.Name)
+	}
+	s.files[m.Name] = f
+	return f
+}
+
+// fileByURI returns the file a FileData part refers to. Callers must hold
+// s.mu.
+func (s *Server) fileByURI(uri string) *file {
+	i := strings.Index(uri, "files/")
+	if i < 0 {
+		return nil
+	}
+	return s.files[uri[i:]]
+}
+
+// checkFileRefs fails if a part refers to a file that does not exist or is
+// not ACTIVE. Callers must hold s.mu.
+func (s *Server) checkFileRefs(contents []*genai.Content) error {
+	for _, c := range contents {
+		if c == nil {
+			continue
+		}
+		for _, p := range c.Parts {
+			if p == nil || p.FileData == nil {
+				continue
+			}
+			f := s.fileByURI(p.FileData.FileURI)
+			if f == nil {
+				return errorf(http.StatusForbidden, "PERMISSION_DENIED",
+					"You do not have permission to access the File %s or it may not exist.", p.FileData.FileURI)
+			}
+			if f.meta.State != genai.FileStateActive {
+				return errorf(http.StatusBadRequest, "FAILED_PRECONDITION",
+					"The File %s is not in an ACTIVE state and usage is not allowed.", f.meta.Name)
+			}
+		}
+	}
+	return nil
+}
+
+func (s *Server) serveFiles(w http.ResponseWriter, req *Request, resource string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if resource == "files" {
+		if req.Method != http.MethodGet {
+			writeError(w, errorf(http.StatusMethodNotAllowed, "INVALID_ARGUMENT", "files: unsupported method %s", req.Method))
+			return
+		}
+		names, next, err := page(s.fileOrder, req.Query, 10)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		files := make([]*genai.File, 0, len(names))
+		for _, name := range names {
+			files = append(files, s.files[name].meta)
+		}
+		resp := map[string]any{"files": files}
+		if next != "" {
+			resp["nextPageToken"] = next
+		}
+		writeJSON(w, resp)
+		return
+	}
+
+	f, ok := s.files[resource]
+	if !ok {
+		writeError(w, errorf(http.StatusForbidden, "PERMISSION_DENIED",
+			"You do not have permission to access the File %s or it may not exist.", strings.TrimPrefix(resource, "files/")))
+		return
+	}
+	switch req.Method {
+	case http.MethodGet:
+		m := copyFile(f.meta)
+		if f.meta.State == genai.FileStateProcessing && f.polls > 0 {
+			// Report PROCESSING this time; the transition becomes visible
+			// on the next call.
+			f.polls--
+			if f.polls == 0 {
+				f.meta.State = genai.FileStateActive
+				f.meta.UpdateTime = s.now().UTC()
+			}
+		}
+		writeJSON(w, m)
+	case http.MethodDelete:
+		delete(s.files, resource)
+		s.fileOrder = remove(s.fileOrder, resource)
+		writeJSON(w, map[string]any{})
+	default:
+		writeError(w, errorf(http.StatusMethodNotAllowed, "INVALID_ARGUMENT", "%s: unsupported method %s", resource, req.Method))
+	}
+}
+
+// serveUpload implements the resumable upload protocol used by
+// Files.Upload: a "start" request that returns an upload URL, followed by
+// "upload" and "upload, finalize" requests carrying the bytes. A "query"
+// request reports how many bytes were received, and the file once the
+// upload is finalized.
+func (s *Server) serveUpload(w http.ResponseWriter, req *Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := req.Query.Get("upload_id")
+	command := req.Header.Get("X-Goog-Upload-Command")
+	if id == "" {
+		if command != "start" {
+			writeError(w, errorf(http.StatusBadRequest, "INVALID_ARGUMENT", "expected upload command start, got %q", command))
+			return
+		}
+		var body struct {
+			File *genai.File `json:"file"`
+		}
+		if err := decode(req.Body, &body); err != nil {
+			writeError(w, err)
+			return
+		}
+		meta := body.File
+		if meta == nil {
+			meta = &genai.File{}
+		}
+		if meta.MIMEType == "" {
+			meta.MIMEType = req.Header.Get("X-Goog-Upload-Header-Content-Type")
+		}
+		if meta.MIMEType == "" {
+			writeError(w, errorf(http.StatusBadRequest, "INVALID_ARGUMENT", "missing MIME type"))
+			return
+		}
+		size, _ := strconv.ParseInt(req.Header.Get("X-Goog-Upload-Header-Content-Length"), 10, 64)
+		id = s.newID("upload")
+		s.uploads[id] = &upload{meta: meta, size: size}
+		w.Header().Set("X-Goog-Upload-Url", s.URL+"/upload/v1beta/files?upload_id="+id)
+		w.Header().Set("X-Goog-Upload-Status", "active")
+		writeJSON(w, map[string]any{})
+		return
+	}
+
+	u, ok := s.uploads[id]
+	if !ok {
+		writeError(w, errorf(http.StatusNotFound, "NOT_FOUND", "unknown upload %q", id))
+		return
+	}
+	w.Header().Set("X-Goog-Upload-Size-Received", strconv.Itoa(len(u.data)))
+	switch {
+	case command == "query" && u.file != nil:
+		w.Header().Set("X-Goog-Upload-Status", "final")
+		writeJSON(w, map[string]any{"file": u.file})
+		return
+	case command == "query":
+		w.Header().Set("X-Goog-Upload-Status", "active")
+		writeJSON(w, map[string]any{})
+		return
+	case u.file != nil:
+		writeError(w, errorf(http.StatusBadRequest, "INVALID_ARGUMENT", "upload %q is already finalized", id))
+		return
+	}
+	offset, err := strconv.ParseInt(req.Header.Get("X-Goog-Upload-Offset"), 10, 64)
+	if err != nil || offset != int64(len(u.data)) {
+		writeError(w, errorf(http.StatusBadRequest, "INVALID_ARGUMENT",
+			"upload offset %q does not match received size %d", req.Header.Get("X-Goog-Upload-Offset"), len(u.data)))
+		return
+	}
+	if req.fault != nil && req.fault.kind == faultDropUpload {
+		// Keep what arrived before the connection broke.
+		u.data = append(u.data, req.Body[:min(req.fault.keep, len(req.Body))]...)
+		hangUp(w)
+		return
+	}
+	u.data = append(u.data, req.Body...)
+	w.Header().Set("X-Goog-Upload-Size-Received", strconv.Itoa(len(u.data)))
+	if !strings.Contains(command, "finalize") {
+		w.Header().Set("X-Goog-Upload-Status", "active")
+		writeJSON(w, map[string]any{})
+		return
+	}
+	if u.size > 0 && u.size != int64(len(u.data)) {
+		delete(s.uploads, id)
+		writeError(w, errorf(http.StatusBadRequest, "INVALID_ARGUMENT",
+			"declared size %d does not match received size %d", u.size, len(u.data)))
+		return
+	}
+	f := s.newFile(u.meta, u.data)
+	f.meta.State = genai.FileStateActive
+	switch {
+	case req.fault != nil && req.fault.kind == faultProcessingFailed:
+		f.meta.State = genai.FileStateFailed
+		if req.fault.message != "" {
+			code := int32(3)
+			f.meta.Error = &genai.FileStatus{Code: &code, Message: req.fault.message}
+		}
+	case needsProcessing(f.meta.MIMEType) && s.processing != 0:
+		f.meta.State = genai.FileStateProcessing
+		f.polls = s.processing
+	}
+	// Keep the upload so that a client that lost the response can query it.
+	u.file = f.meta
+	w.Header().Set("X-Goog-Upload-Status", "final")
+	writeJSON(w, map[string]any{"file": f.meta})
+}
+
+func needsProcessing(mimeType string) bool {
+	return strings.HasPrefix(mimeType, "video/") || strings.HasPrefix(mimeType, "audio/")
+}
+
+func copyFile(f *genai.File) *genai.File {
+	c := *f
+	if f.SizeBytes != nil {
+		size := *f.SizeBytes
+		c.SizeBytes = &size
+	}
+	return &c
+}
+
+func remove(names []string, name string) []string {
+	for i, n := range names {
+		if n == name {
+			return append(names[:i:i], names[i+1:]...)
+		}
+	}
+	return names
+}
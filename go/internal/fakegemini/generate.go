@@ -0,0 +1,419 @@
+package fakegemini
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"google.golang.org/genai"
+)
+
+// GenerateRequest is the decoded body of a generateContent or
+// streamGenerateContent call.
+type GenerateRequest struct {
+	// Model is the model ID from the URL, for example "gemini-3.5-flash".
+	Model string `json:"-"`
+	// Stream reports whether the call was streamGenerateContent.
+	Stream bool `json:"-"`
+
+	Contents          []*genai.Content       `json:"contents,omitempty"`
+	SystemInstruction *genai.Content         `json:"systemInstruction,omitempty"`
+	Tools             []*genai.Tool          `json:"tools,omitempty"`
+	ToolConfig        *genai.ToolConfig      `json:"toolConfig,omitempty"`
+	SafetySettings    []*genai.SafetySetting `json:"safetySettings,omitempty"`
+	GenerationConfig  map[string]any         `json:"generationConfig,omitempty"`
+	CachedContent     string                 `json:"cachedContent,omitempty"`
+}
+
+// LastUserText returns the text of the last user turn in the request.
+func (r *GenerateRequest) LastUserText() string {
+	for i := len(r.Contents) - 1; i >= 0; i-- {
+		c := r.Contents[i]
+		if c == nil || (c.Role != "" && c.Role != genai.RoleUser) {
+			continue
+		}
+		var parts []string
+		for _, p := range c.Parts {
+			if p != nil && p.Text != "" {
+				parts = append(parts, p.Text)
+			}
+		}
+		return strings.Join(parts, "")
+	}
+	return ""
+}
+
+// FunctionDeclarations returns all function declarations offered in the
+// request's tools.
+func (r *GenerateRequest) FunctionDeclarations() []*genai.FunctionDeclaration {
+	var decls []*genai.FunctionDeclaration
+	for _, t := range r.Tools {
+		if t != nil {
+			decls = append(decls, t.FunctionDeclarations...)
+		}
+	}
+	return decls
+}
+
+// GenerateFunc produces the response to a generate call. Returning an *Error
+// sends an API error instead. For streaming calls the response is split into
+// several chunks.
+type GenerateFunc func(req *GenerateRequest) (*genai.GenerateContentResponse, error)
+
+// OnGenerate installs fn to answer generateContent and streamGenerateContent
+// calls. A nil fn restores the default, which echoes the last user message,
+// as a JSON object when the request asks for JSON.
+func (s *Server) OnGenerate(fn GenerateFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.generate = fn
+}
+
+// GenerateRequests returns the decoded bodies of the generate calls received
+// so far.
+func (s *Server) GenerateRequests() []*GenerateRequest {
+	var out []*GenerateRequest
+	for _, r := range s.Requests() {
+		if r.Method != http.MethodPost || !strings.HasPrefix(r.Path, "v1beta/models/") {
+			continue
+		}
+		model, method, _ := strings.Cut(strings.TrimPrefix(r.Path, "v1beta/models/"), ":")
+		if method != "generateContent" && method != "streamGenerateContent" {
+			continue
+		}
+		req := &GenerateRequest{Model: model, Stream: method == "streamGenerateContent"}
+		if json.Unmarshal(r.Body, req) == nil {
+			out = append(out, req)
+		}
+	}
+	return out
+}
+
+// TextResponse returns a response with a single candidate holding text.
+func TextResponse(text string) *genai.GenerateContentResponse {
+	return &genai.GenerateContentResponse{
+		Candidates: []*genai.Candidate{{
+			Content:      genai.NewContentFromText(text, genai.RoleModel),
+			FinishReason: genai.FinishReasonStop,
+		}},
+	}
+}
+
+// FunctionCallResponse returns a response whose candidate calls the named
+// function with args.
+func FunctionCallResponse(name string, args map[string]any) *genai.GenerateContentResponse {
+	return &genai.GenerateContentResponse{
+		Candidates: []*genai.Candidate{{
+			Content:      genai.NewContentFromFunctionCall(name, args, genai.RoleModel),
+			FinishReason: genai.FinishReasonStop,
+		}},
+	}
+}
+
+func defaultGenerate(req *GenerateRequest) (*genai.GenerateContentResponse, error) {
+	text := req.LastUserText()
+	// The request asks for JSON through its response MIME type, or in the
+	// prompt.
+	if req.GenerationConfig["responseMimeType"] == "application/json" || strings.Contains(text, "JSON") {
+		data, err := json.Marshal(map[string]string{"youSaid": text})
+		if err != nil {
+			return nil, err
+		}
+		return TextResponse(string(data)), nil
+	}
+	return TextResponse("You said: " + text), nil
+}
+
+func (s *Server) serveModelMethod(w http.ResponseWriter, req *Request, resource, method string) {
+	model := strings.TrimPrefix(resource, "models/")
+	s.mu.Lock()
+	m := s.findModel(model)
+	s.mu.Unlock()
+	if m == nil {
+		writeError(w, errorf(http.StatusNotFound, "NOT_FOUND", "models/%s is not found for API version v1beta", model))
+		return
+	}
+	if req.Method != http.MethodPost {
+		writeError(w, errorf(http.StatusMethodNotAllowed, "INVALID_ARGUMENT", "%s requires POST", method))
+		return
+	}
+	action := method
+	if method == "streamGenerateContent" {
+		action = "generateContent"
+	}
+	if method == "batchEmbedContents" {
+		action = "embedContent"
+	}
+	if !m.supports(action) {
+		writeError(w, errorf(http.StatusBadRequest, "INVALID_ARGUMENT", "models/%s does not support %s", model, method))
+		return
+	}
+	switch method {
+	case "generateContent", "streamGenerateContent":
+		s.serveGenerate(w, req, model, method == "streamGenerateContent")
+	case "countTokens":
+		s.serveCountTokens(w, req)
+	case "embedContent", "batchEmbedContents":
+		s.serveEmbed(w, req, method == "batchEmbedContents")
+	default:
+		writeError(w, errorf(http.StatusNotFound, "NOT_FOUND", "unknown method %q", method))
+	}
+}
+
+func (s *Server) serveGenerate(w http.ResponseWriter, req *Request, model string, stream bool) {
+	greq := &GenerateRequest{Model: model, Stream: stream}
+	if err := decode(req.Body, greq); err != nil {
+		writeError(w, err)
+		return
+	}
+	if len(greq.Contents) == 0 {
+		writeError(w, errorf(http.StatusBadRequest, "INVALID_ARGUMENT", "contents is not specified"))
+		return
+	}
+	if greq.CachedContent != "" && (greq.SystemInstruction != nil || len(greq.Tools) > 0 || greq.ToolConfig != nil) {
+		writeError(w, errorf(http.StatusBadRequest, "INVALID_ARGUMENT",
+			"CachedContent can not be used with GenerateContent request setting system_instruction, tools or tool_config."))
+		return
+	}
+
+	s.mu.Lock()
+	var cached *genai.CachedContent
+	err := s.checkFileRefs(greq.Contents)
+	if err == nil && greq.CachedContent != "" {
+		cached, err = s.liveCache(greq.CachedContent)
+	}
+	gen := s.generate
+	s.mu.Unlock()
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if gen == nil {
+		gen = defaultGenerate
+	}
+	var resp *genai.GenerateContentResponse
+	switch f := req.fault; {
+	case f != nil && f.kind == faultEmpty:
+		resp = &genai.GenerateContentResponse{}
+	case f != nil && f.kind == faultBlocked:
+		resp = &genai.GenerateContentResponse{
+			PromptFeedback: &genai.GenerateContentResponsePromptFeedback{BlockReason: f.reason},
+		}
+	default:
+		resp, err = gen(greq)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+	}
+	if resp == nil {
+		resp = &genai.GenerateContentResponse{}
+	}
+	s.fillResponse(greq, resp, cached)
+
+	if !stream {
+		writeJSON(w, resp)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	flusher, _ := w.(http.Flusher)
+	for i, chunk := range splitResponse(resp) {
+		if f := req.fault; f != nil && f.kind == faultDisconnect && i == f.chunks {
+			hangUp(w)
+			return
+		}
+		data, err := json.Marshal(chunk)
+		if err != nil {
+			return
+		}
+		fmt.Fprintf(w, "data: %s\r\n\r\n", data)
+		if f := req.fault; f != nil && f.kind == faultMalformed {
+			fmt.Fprint(w, "data: {\"candidates\": [{\"content\r\n\r\n")
+			return
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+}
+
+// fillResponse sets the fields a real response always carries.
+func (s *Server) fillResponse(req *GenerateRequest, resp *genai.GenerateContentResponse, cached *genai.CachedContent) {
+	if resp.ModelVersion == "" {
+		resp.ModelVersion = req.Model
+	}
+	for _, c := range resp.Candidates {
+		if c.Content != nil && c.Content.Role == "" {
+			c.Content.Role = genai.RoleModel
+		}
+	}
+	if resp.UsageMetadata != nil {
+		return
+	}
+	s.mu.Lock()
+	prompt := s.countTokens(req.Contents) + s.countTokens([]*genai.Content{req.SystemInstruction})
+	s.mu.Unlock()
+	usage := &genai.GenerateContentResponseUsageMetadata{PromptTokenCount: prompt}
+	if cached != nil && cached.UsageMetadata != nil {
+		usage.CachedContentTokenCount = cached.UsageMetadata.TotalTokenCount
+		usage.PromptTokenCount += cached.UsageMetadata.TotalTokenCount
+	}
+	if len(resp.Candidates) > 0 && resp.Candidates[0].Content != nil {
+		usage.CandidatesTokenCount = textTokens(resp.Text())
+	}
+	usage.TotalTokenCount = usage.PromptTokenCount + usage.CandidatesTokenCount
+	resp.UsageMetadata = usage
+}
+
+// splitResponse splits the text of the first candidate into chunks of a few
+// words each, the way the streaming endpoint delivers it. The finish reason
+// and usage metadata are sent with the last chunk only.
+func splitResponse(resp *genai.GenerateContentResponse) []*genai.GenerateContentResponse {
+	if len(resp.Candidates) == 0 || resp.Candidates[0].Content == nil ||
+		len(resp.Candidates[0].Content.Parts) != 1 || resp.Candidates[0].Content.Parts[0].Text == "" {
+		return []*genai.GenerateContentResponse{resp}
+	}
+	cand := resp.Candidates[0]
+	words := strings.SplitAfter(cand.Content.Parts[0].Text, " ")
+	var chunks []*genai.GenerateContentResponse
+	for i := 0; i < len(words); i += 3 {
+		text := strings.Join(words[i:min(i+3, len(words))], "")
+		chunks = append(chunks, &genai.GenerateContentResponse{
+			ModelVersion: resp.ModelVersion,
+			Candidates: []*genai.Candidate{{
+				Content: genai.NewContentFromText(text, genai.Role(cand.Content.Role)),
+			}},
+		})
+	}
+	last := chunks[len(chunks)-1]
+	last.Candidates[0].FinishReason = cand.FinishReason
+	last.Candidates[0].SafetyRatings = cand.SafetyRatings
+	last.UsageMetadata = resp.UsageMetadata
+	return chunks
+}
+
+func (s *Server) serveCountTokens(w http.ResponseWriter, req *Request) {
+	var body struct {
+		Contents []*genai.Content `json:"contents"`
+	}
+	if err := decode(req.Body, &body); err != nil {
+		writeError(w, err)
+		return
+	}
+	s.mu.Lock()
+	err := s.checkFileRefs(body.Contents)
+	n := s.countTokens(body.Contents)
+	s.mu.Unlock()
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, map[string]any{"totalTokens": n})
+}
+
+type embedRequest struct {
+	Content              *genai.Content `json:"content"`
+	OutputDimensionality int            `json:"outputDimensionality,omitempty"`
+}
+
+func (s *Server) serveEmbed(w http.ResponseWriter, req *Request, batch bool) {
+	var reqs []embedRequest
+	if batch {
+		var body struct {
+			Requests []embedRequest `json:"requests"`
+		}
+		if err := decode(req.Body, &body); err != nil {
+			writeError(w, err)
+			return
+		}
+		reqs = body.Requests
+	} else {
+		var body embedRequest
+		if err := decode(req.Body, &body); err != nil {
+			writeError(w, err)
+			return
+		}
+		reqs = []embedRequest{body}
+	}
+	var embeddings []map[string]any
+	for _, r := range reqs {
+		dim := r.OutputDimensionality
+		if dim == 0 {
+			dim = 768
+		}
+		var text strings.Builder
+		if r.Content != nil {
+			for _, p := range r.Content.Parts {
+				text.WriteString(p.Text)
+			}
+		}
+		embeddings = append(embeddings, map[string]any{"values": fakeEmbedding(text.String(), dim)})
+	}
+	if batch {
+		writeJSON(w, map[string]any{"embeddings": embeddings})
+	} else {
+		writeJSON(w, map[string]any{"embedding": embeddings[0]})
+	}
+}
+
+// fakeEmbedding returns a deterministic vector derived from text.
+func fakeEmbedding(text string, dim int) []float32 {
+	h := fnv.New64a()
+	h.Write([]byte(text))
+	seed := h.Sum64()
+	values := make([]float32, dim)
+	for i := range values {
+		seed = seed*6364136223846793005 + 1442695040888963407
+		values[i] = float32(int64(seed>>33)%2000-1000) / 1000
+	}
+	return values
+}
+
+// countTokens estimates the token count of contents. The estimate is
+// deterministic but only loosely follows the real tokenizer: four characters
+// per text token, a flat 258 tokens per image or PDF page, and a size-based
+// figure for other files. Callers must hold s.mu.
+func (s *Server) countTokens(contents []*genai.Content) int32 {
+	var n int32
+	for _, c := range contents {
+		if c == nil {
+			continue
+		}
+		for _, p := range c.Parts {
+			switch {
+			case p == nil:
+			case p.Text != "":
+				n += textTokens(p.Text)
+			case p.InlineData != nil:
+				n += mediaTokens(p.InlineData.MIMEType, int64(len(p.InlineData.Data)))
+			case p.FileData != nil:
+				if f := s.fileByURI(p.FileData.FileURI); f != nil {
+					n += f.tokens()
+				}
+			case p.FunctionCall != nil || p.FunctionResponse != nil:
+				n += 16
+			}
+		}
+	}
+	return n
+}
+
+func textTokens(text string) int32 {
+	return int32((utf8.RuneCountInString(text) + 3) / 4)
+}
+
+func mediaTokens(mimeType string, size int64) int32 {
+	switch {
+	case strings.HasPrefix(mimeType, "image/"):
+		return 258
+	case mimeType == "application/pdf":
+		return 258 * int32(max(1, size/50_000))
+	case strings.HasPrefix(mimeType, "text/"):
+		return int32((size + 3) / 4)
+	default:
+		return int32(max(1, size/1000))
+	}
+}
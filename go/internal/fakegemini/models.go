@@ -0,0 +1,120 @@
+package fakegemini
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// Model describes a model served by the fake, in the API's wire format.
+type Model struct {
+	Name                       string   `json:"name"`
+	BaseModelID                string   `json:"baseModelId,omitempty"`
+	Version                    string   `json:"version,omitempty"`
+	DisplayName                string   `json:"displayName,omitempty"`
+	Description                string   `json:"description,omitempty"`
+	InputTokenLimit            int32    `json:"inputTokenLimit,omitempty"`
+	OutputTokenLimit           int32    `json:"outputTokenLimit,omitempty"`
+	SupportedGenerationMethods []string `json:"supportedGenerationMethods,omitempty"`
+
+	// MinCacheTokens is the smallest content, in tokens, that
+	// cachedContents.create accepts for this model. It is not part of the
+	// wire format.
+	MinCacheTokens int32 `json:"-"`
+}
+
+func (m *Model) supports(action string) bool {
+	return slices.Contains(m.SupportedGenerationMethods, action)
+}
+
+func defaultModels() []*Model {
+	return []*Model{
+		{
+			Name:             "models/gemini-3.5-flash",
+			BaseModelID:      "gemini-3.5-flash",
+			Version:          "001",
+			DisplayName:      "Gemini 3.5 Flash",
+			InputTokenLimit:  1048576,
+			OutputTokenLimit: 65536,
+			SupportedGenerationMethods: []string{
+				"generateContent", "countTokens", "createCachedContent", "batchGenerateContent",
+			},
+		},
+		{
+			Name:                       "models/gemini-embedding-001",
+			BaseModelID:                "gemini-embedding-001",
+			Version:                    "001",
+			DisplayName:                "Gemini Embedding 001",
+			InputTokenLimit:            2048,
+			OutputTokenLimit:           1,
+			SupportedGenerationMethods: []string{"embedContent", "countTextTokens", "countTokens"},
+		},
+	}
+}
+
+// AddModel adds a model to the catalog, replacing any model with the same
+// name.
+func (s *Server) AddModel(m *Model) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	mm := *m
+	if !strings.HasPrefix(mm.Name, "models/") {
+		mm.Name = "models/" + mm.Name
+	}
+	for i, old := range s.models {
+		if old.Name == mm.Name {
+			s.models[i] = &mm
+			return
+		}
+	}
+	s.models = append(s.models, &mm)
+}
+
+// findModel returns the model with the given ID. Callers must hold s.mu.
+func (s *Server) findModel(id string) *Model {
+	id = strings.TrimPrefix(id, "models/")
+	for _, m := range s.models {
+		if m.Name == "models/"+id {
+			return m
+		}
+	}
+	return nil
+}
+
+func (s *Server) serveModels(w http.ResponseWriter, req *Request, resource string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if req.Method != http.MethodGet {
+		writeError(w, errorf(http.StatusMethodNotAllowed, "INVALID_ARGUMENT", "%s: unsupported method %s", resource, req.Method))
+		return
+	}
+	if resource != "models" {
+		m := s.findModel(resource)
+		if m == nil {
+			writeError(w, errorf(http.StatusNotFound, "NOT_FOUND", "%s is not found for API version v1beta", resource))
+			return
+		}
+		writeJSON(w, m)
+		return
+	}
+	names := make([]string, len(s.models))
+	byName := make(map[string]*Model, len(s.models))
+	for i, m := range s.models {
+		names[i] = m.Name
+		byName[m.Name] = m
+	}
+	selected, next, err := page(names, req.Query, 50)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	models := make([]*Model, 0, len(selected))
+	for _, name := range selected {
+		models = append(models, byName[name])
+	}
+	resp := map[string]any{"models": models}
+	if next != "" {
+		resp["nextPageToken"] = next
+	}
+	writeJSON(w, resp)
+}
@@ -0,0 +1,281 @@
+// Package fakegemini implements an in-process stand-in for the parts of the
+// Gemini API used by the examples: content generation (unary and SSE
+// streaming), token counting, embeddings, the Files API including resumable
+// uploads, cached contents and models.
+//
+// Point a client at it through the base URL:
+//
+//	srv := fakegemini.New()
+//	defer srv.Close()
+//	client, err := genai.NewClient(ctx, &genai.ClientConfig{
+//		APIKey:      "fake",
+//		Backend:     genai.BackendGeminiAPI,
+//		HTTPOptions: genai.HTTPOptions{BaseURL: srv.URL},
+//	})
+//
+// Responses are scriptable through OnGenerate, and the server state can be
+// seeded and inspected through the Add* and accessor methods.
+package fakegemini
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/genai"
+)
+
+// Server is a fake Gemini API server.
+type Server struct {
+	// URL is the base URL of the server, suitable for
+	// genai.HTTPOptions.BaseURL.
+	URL string
+
+	srv *httptest.Server
+
+	mu         sync.Mutex
+	now        func() time.Time
+	generate   GenerateFunc
+	processing int
+	nextID     int
+	requests   []*Request
+	files      map[string]*file
+	fileOrder  []string
+	uploads    map[string]*upload
+	caches     map[string]*genai.CachedContent
+	cacheOrder []string
+	models     []*Model
+	faults     []*injected
+}
+
+// Request is a request received by the server.
+type Request struct {
+	Method string
+	// Path is the request path without leading slashes, for example
+	// "v1beta/models/gemini-3.5-flash:generateContent".
+	Path   string
+	Query  url.Values
+	Header http.Header
+	Body   []byte
+
+	fault *Fault
+}
+
+// Error is an API error returned by the server. Returning an *Error from a
+// GenerateFunc sends it to the client as the standard JSON error payload.
+type Error struct {
+	Code    int              `json:"code"`
+	Message string           `json:"message"`
+	Status  string           `json:"status"`
+	Details []map[string]any `json:"details,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%d %s: %s", e.Code, e.Status, e.Message)
+}
+
+func errorf(code int, status, format string, args ...any) *Error {
+	return &Error{Code: code, Status: status, Message: fmt.Sprintf(format, args...)}
+}
+
+// New starts a server. Call Close when done.
+func New() *Server {
+	s := &Server{
+		now:        time.Now,
+		processing: 1,
+		files:      make(map[string]*file),
+		uploads:    make(map[string]*upload),
+		caches:     make(map[string]*genai.CachedContent),
+		models:     defaultModels(),
+	}
+	// Paths such as "//v1beta/files" are routed by hand: http.ServeMux would
+	// redirect them, and the SDK joins its base URL with a double slash.
+	s.srv = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	s.URL = s.srv.URL
+	return s
+}
+
+// Transport returns a transport that serves every request in process with
+// the server, whatever its URL, so that clients keeping the default base
+// URL talk to it. The URLs the server hands out, of uploads and files,
+// remain its own.
+func (s *Server) Transport() http.RoundTripper {
+	return transport{s}
+}
+
+type transport struct{ s *Server }
+
+func (t transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	in := req.Clone(req.Context())
+	if in.Body == nil {
+		in.Body = http.NoBody
+	}
+	w := httptest.NewRecorder()
+	t.s.serveHTTP(w, in)
+	resp := w.Result()
+	resp.Request = req
+	return resp, nil
+}
+
+// Close shuts the server down.
+func (s *Server) Close() {
+	s.srv.Close()
+}
+
+// SetClock replaces the clock used for timestamps and expiry.
+func (s *Server) SetClock(now func() time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.now = now
+}
+
+// SetProcessingPolls sets how many times a newly uploaded video or audio file
+// reports PROCESSING from Files.Get before it becomes ACTIVE. The default is 1;
+// 0 makes such files ACTIVE at once and a negative n keeps them PROCESSING
+// forever.
+func (s *Server) SetProcessingPolls(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.processing = n
+}
+
+// Requests returns the requests received so far.
+func (s *Server) Requests() []*Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*Request(nil), s.requests...)
+}
+
+// RequestsTo returns the requests whose path ends with suffix, for example
+// ":generateContent".
+func (s *Server) RequestsTo(method, suffix string) []*Request {
+	var out []*Request
+	for _, r := range s.Requests() {
+		if r.Method == method && strings.HasSuffix(r.Path, suffix) {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, errorf(http.StatusBadRequest, "INVALID_ARGUMENT", "reading body: %v", err))
+		return
+	}
+	req := &Request{
+		Method: r.Method,
+		Path:   strings.TrimLeft(r.URL.Path, "/"),
+		Query:  r.URL.Query(),
+		Header: r.Header.Clone(),
+		Body:   body,
+	}
+	s.mu.Lock()
+	s.requests = append(s.requests, req)
+	s.mu.Unlock()
+
+	if r.Header.Get("x-goog-api-key") == "" && req.Query.Get("key") == "" {
+		writeError(w, errorf(http.StatusForbidden, "PERMISSION_DENIED", "missing API key"))
+		return
+	}
+	req.fault = s.takeFault(req)
+	if serveFault(w, req) {
+		return
+	}
+	s.route(w, req)
+}
+
+func (s *Server) route(w http.ResponseWriter, req *Request) {
+	path := req.Path
+	if strings.HasPrefix(path, "upload/v1beta/files") {
+		s.serveUpload(w, req)
+		return
+	}
+	path, ok := strings.CutPrefix(path, "v1beta/")
+	if !ok {
+		writeError(w, errorf(http.StatusNotFound, "NOT_FOUND", "unknown path %q", req.Path))
+		return
+	}
+	resource, method, _ := strings.Cut(path, ":")
+	switch {
+	case strings.HasPrefix(resource, "models/") && method != "":
+		s.serveModelMethod(w, req, resource, method)
+	case resource == "models" || strings.HasPrefix(resource, "models/"):
+		s.serveModels(w, req, resource)
+	case resource == "files" || strings.HasPrefix(resource, "files/"):
+		s.serveFiles(w, req, resource)
+	case resource == "cachedContents" || strings.HasPrefix(resource, "cachedContents/"):
+		s.serveCaches(w, req, resource)
+	default:
+		writeError(w, errorf(http.StatusNotFound, "NOT_FOUND", "unknown path %q", req.Path))
+	}
+}
+
+func (s *Server) newID(prefix string) string {
+	s.nextID++
+	return fmt.Sprintf("%s-%d", prefix, s.nextID)
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	apiErr, ok := err.(*Error)
+	if !ok {
+		apiErr = errorf(http.StatusInternalServerError, "INTERNAL", "%v", err)
+	}
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(apiErr.Code)
+	json.NewEncoder(w).Encode(map[string]any{"error": apiErr})
+}
+
+// page returns the slice of names selected by the pageSize and pageToken
+// query parameters, and the token for the next page.
+func page(names []string, q url.Values, defaultSize int) ([]string, string, error) {
+	size := defaultSize
+	if v := q.Get("pageSize"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return nil, "", errorf(http.StatusBadRequest, "INVALID_ARGUMENT", "invalid pageSize %q", v)
+		}
+		if n > 0 {
+			size = n
+		}
+	}
+	start := 0
+	if tok := q.Get("pageToken"); tok != "" {
+		n, err := strconv.Atoi(tok)
+		if err != nil || n < 0 || n > len(names) {
+			return nil, "", errorf(http.StatusBadRequest, "INVALID_ARGUMENT", "invalid pageToken %q", tok)
+		}
+		start = n
+	}
+	end := min(start+size, len(names))
+	next := ""
+	if end < len(names) {
+		next = strconv.Itoa(end)
+	}
+	return names[start:end], next, nil
+}
+
+func decode(body []byte, v any) error {
+	if len(body) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return errorf(http.StatusBadRequest, "INVALID_ARGUMENT", "invalid JSON payload: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,322 @@
+package fakegemini
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/genai"
+)
+
+func newClient(t *testing.T) (*Server, *genai.Client) {
+	t.Helper()
+	srv := New()
+	t.Cleanup(srv.Close)
+	client, err := genai.NewClient(context.Background(), &genai.ClientConfig{
+		APIKey:      "fake",
+		Backend:     genai.BackendGeminiAPI,
+		HTTPOptions: genai.HTTPOptions{BaseURL: srv.URL},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return srv, client
+}
+
+func TestGenerateContentDefault(t *testing.T) {
+	_, client := newClient(t)
+	resp, err := client.Models.GenerateContent(context.Background(), "gemini-3.5-flash", genai.Text("ping"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := resp.Text(), "You said: ping"; got != want {
+		t.Errorf("Text() = %q, want %q", got, want)
+	}
+	if resp.UsageMetadata == nil || resp.UsageMetadata.TotalTokenCount == 0 {
+		t.Errorf("UsageMetadata = %+v, want token counts", resp.UsageMetadata)
+	}
+}
+
+func TestGenerateContentScripted(t *testing.T) {
+	srv, client := newClient(t)
+	srv.OnGenerate(func(req *GenerateRequest) (*genai.GenerateContentResponse, error) {
+		if len(req.FunctionDeclarations()) == 0 {
+			return nil, &Error{Code: http.StatusBadRequest, Status: "INVALID_ARGUMENT", Message: "no tools"}
+		}
+		return FunctionCallResponse("addNumbers", map[string]any{"a": 1.0}), nil
+	})
+	ctx := context.Background()
+
+	if _, err := client.Models.GenerateContent(ctx, "gemini-3.5-flash", genai.Text("hi"), nil); err == nil {
+		t.Error("GenerateContent without tools succeeded, want scripted error")
+	}
+
+	resp, err := client.Models.GenerateContent(ctx, "gemini-3.5-flash", genai.Text("hi"), &genai.GenerateContentConfig{
+		Tools: []*genai.Tool{{FunctionDeclarations: []*genai.FunctionDeclaration{{Name: "addNumbers"}}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	calls := resp.FunctionCalls()
+	if len(calls) != 1 || calls[0].Name != "addNumbers" {
+		t.Errorf("FunctionCalls() = %+v, want one call to addNumbers", calls)
+	}
+	if n := len(srv.GenerateRequests()); n != 2 {
+		t.Errorf("GenerateRequests() has %d entries, want 2", n)
+	}
+}
+
+func TestGenerateContentStream(t *testing.T) {
+	srv, client := newClient(t)
+	srv.OnGenerate(func(*GenerateRequest) (*genai.GenerateContentResponse, error) {
+		return TextResponse("one two three four five six seven"), nil
+	})
+	var chunks []string
+	var last *genai.GenerateContentResponse
+	for resp, err := range client.Models.GenerateContentStream(context.Background(), "gemini-3.5-flash", genai.Text("count"), nil) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		chunks = append(chunks, resp.Text())
+		last = resp
+	}
+	if len(chunks) != 3 {
+		t.Errorf("got %d chunks, want 3: %q", len(chunks), chunks)
+	}
+	if got := strings.Join(chunks, ""); got != "one two three four five six seven" {
+		t.Errorf("joined chunks = %q", got)
+	}
+	if last.Candidates[0].FinishReason != genai.FinishReasonStop || last.UsageMetadata == nil {
+		t.Errorf("last chunk = %+v, want finish reason and usage", last)
+	}
+}
+
+func TestUnknownModel(t *testing.T) {
+	_, client := newClient(t)
+	_, err := client.Models.GenerateContent(context.Background(), "no-such-model", genai.Text("hi"), nil)
+	var apiErr genai.APIError
+	if !errors.As(err, &apiErr) || apiErr.Code != http.StatusNotFound {
+		t.Errorf("err = %v, want 404 APIError", err)
+	}
+}
+
+func TestCountTokensAndEmbed(t *testing.T) {
+	_, client := newClient(t)
+	ctx := context.Background()
+	count, err := client.Models.CountTokens(ctx, "gemini-3.5-flash", genai.Text("12345678"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count.TotalTokens != 2 {
+		t.Errorf("TotalTokens = %d, want 2", count.TotalTokens)
+	}
+
+	dim := int32(10)
+	contents := []*genai.Content{
+		genai.NewContentFromText("a", genai.RoleUser),
+		genai.NewContentFromText("b", genai.RoleUser),
+	}
+	emb, err := client.Models.EmbedContent(ctx, "gemini-embedding-001", contents, &genai.EmbedContentConfig{
+		OutputDimensionality: &dim,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(emb.Embeddings) != 2 || len(emb.Embeddings[0].Values) != 10 {
+		t.Fatalf("Embeddings = %+v, want 2 vectors of 10 values", emb.Embeddings)
+	}
+	again, err := client.Models.EmbedContent(ctx, "gemini-embedding-001", contents[:1], &genai.EmbedContentConfig{
+		OutputDimensionality: &dim,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.Embeddings[0].Values[3] != emb.Embeddings[0].Values[3] {
+		t.Error("embeddings are not deterministic")
+	}
+}
+
+func TestFilesLifecycle(t *testing.T) {
+	srv, client := newClient(t)
+	ctx := context.Background()
+	data := bytes.Repeat([]byte("x"), 100)
+
+	f, err := client.Files.Upload(ctx, bytes.NewReader(data), &genai.UploadFileConfig{
+		MIMEType:    "text/plain",
+		DisplayName: "notes",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.State != genai.FileStateActive || *f.SizeBytes != 100 || f.DisplayName != "notes" {
+		t.Errorf("uploaded file = %+v", f)
+	}
+	if got, _ := srv.FileData(f.Name); !bytes.Equal(got, data) {
+		t.Error("server did not store the uploaded bytes")
+	}
+
+	resp, err := client.Models.GenerateContent(ctx, "gemini-3.5-flash", []*genai.Content{
+		genai.NewContentFromURI(f.URI, f.MIMEType, genai.RoleUser),
+	}, nil)
+	if err != nil {
+		t.Fatalf("GenerateContent with file: %v", err)
+	}
+	if resp.UsageMetadata.PromptTokenCount != 25 {
+		t.Errorf("PromptTokenCount = %d, want 25", resp.UsageMetadata.PromptTokenCount)
+	}
+
+	if _, err := client.Files.Delete(ctx, f.Name, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Files.Get(ctx, f.Name, nil); err == nil {
+		t.Error("Get after Delete succeeded")
+	}
+	_, err = client.Models.GenerateContent(ctx, "gemini-3.5-flash", []*genai.Content{
+		genai.NewContentFromURI(f.URI, f.MIMEType, genai.RoleUser),
+	}, nil)
+	if err == nil {
+		t.Error("GenerateContent with a deleted file succeeded")
+	}
+}
+
+func TestFilesVideoProcessing(t *testing.T) {
+	srv, client := newClient(t)
+	srv.SetProcessingPolls(2)
+	ctx := context.Background()
+
+	f, err := client.Files.Upload(ctx, strings.NewReader("video"), &genai.UploadFileConfig{MIMEType: "video/mp4"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var states []genai.FileState
+	for f.State != genai.FileStateActive {
+		states = append(states, f.State)
+		if len(states) > 5 {
+			t.Fatalf("file never became ACTIVE: %v", states)
+		}
+		if f, err = client.Files.Get(ctx, f.Name, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(states) != 3 {
+		t.Errorf("saw states %v, want PROCESSING three times", states)
+	}
+}
+
+func TestFilesListPagination(t *testing.T) {
+	srv, client := newClient(t)
+	for range 25 {
+		srv.AddFile(&genai.File{MIMEType: "text/plain"}, []byte("x"))
+	}
+	ctx := context.Background()
+	var names []string
+	page, err := client.Files.List(ctx, &genai.ListFilesConfig{PageSize: 10})
+	for ; err == nil; page, err = page.Next(ctx) {
+		for _, f := range page.Items {
+			names = append(names, f.Name)
+		}
+	}
+	if !errors.Is(err, genai.ErrPageDone) {
+		t.Fatal(err)
+	}
+	if len(names) != 25 {
+		t.Errorf("listed %d files, want 25", len(names))
+	}
+}
+
+func TestCachesLifecycle(t *testing.T) {
+	srv, client := newClient(t)
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	srv.SetClock(func() time.Time { return now })
+	ctx := context.Background()
+
+	cache, err := client.Caches.Create(ctx, "gemini-3.5-flash", &genai.CreateCachedContentConfig{
+		Contents:    genai.Text("a long document"),
+		DisplayName: "doc",
+		TTL:         10 * time.Minute,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cache.Model != "models/gemini-3.5-flash" || !cache.ExpireTime.Equal(now.Add(10*time.Minute)) {
+		t.Errorf("created cache = %+v", cache)
+	}
+
+	updated, err := client.Caches.Update(ctx, cache.Name, &genai.UpdateCachedContentConfig{TTL: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !updated.ExpireTime.Equal(now.Add(time.Hour)) {
+		t.Errorf("ExpireTime after update = %v", updated.ExpireTime)
+	}
+
+	resp, err := client.Models.GenerateContent(ctx, "gemini-3.5-flash", genai.Text("summarize"), &genai.GenerateContentConfig{
+		CachedContent: cache.Name,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.UsageMetadata.CachedContentTokenCount == 0 {
+		t.Error("CachedContentTokenCount = 0, want the cached tokens")
+	}
+	// The system instruction belongs in the cache.
+	_, err = client.Models.GenerateContent(ctx, "gemini-3.5-flash", genai.Text("summarize"), &genai.GenerateContentConfig{
+		CachedContent:     cache.Name,
+		SystemInstruction: genai.NewContentFromText("Be brief.", genai.RoleUser),
+	})
+	if err == nil {
+		t.Error("GenerateContent with a cache and a system instruction succeeded")
+	}
+
+	now = now.Add(2 * time.Hour)
+	if _, err := client.Caches.Get(ctx, cache.Name, nil); err == nil {
+		t.Error("Get of an expired cache succeeded")
+	}
+	if n := len(srv.Caches()); n != 0 {
+		t.Errorf("%d caches left after expiry", n)
+	}
+}
+
+func TestCachesListPagination(t *testing.T) {
+	srv, client := newClient(t)
+	for range 5 {
+		srv.AddCache(&genai.CachedContent{Model: "models/gemini-3.5-flash"})
+	}
+	ctx := context.Background()
+	pages := 0
+	total := 0
+	page, err := client.Caches.List(ctx, &genai.ListCachedContentsConfig{PageSize: 2})
+	for ; err == nil; page, err = page.Next(ctx) {
+		pages++
+		total += len(page.Items)
+	}
+	if !errors.Is(err, genai.ErrPageDone) {
+		t.Fatal(err)
+	}
+	if pages != 3 || total != 5 {
+		t.Errorf("got %d caches on %d pages, want 5 on 3", total, pages)
+	}
+}
+
+func TestModels(t *testing.T) {
+	_, client := newClient(t)
+	ctx := context.Background()
+	m, err := client.Models.Get(ctx, "gemini-3.5-flash", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.InputTokenLimit == 0 || len(m.SupportedActions) == 0 {
+		t.Errorf("model = %+v, want limits and supported actions", m)
+	}
+	page, err := client.Models.List(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != 2 {
+		t.Errorf("listed %d models, want 2", len(page.Items))
+	}
+}
@@ -0,0 +1,171 @@
+// Package filewait waits for uploaded files to be processed by the Files
+// API, as videos and audio files must be before they can be used in a
+// prompt.
+//
+// Wait polls one file with exponential backoff and jitter until it is
+// ACTIVE, fails with a *FailedError if it becomes FAILED, and gives up when
+// ctx is done:
+//
+//	ctx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+//	defer cancel()
+//	file, err = filewait.Wait(ctx, client, file, nil)
+//
+// WaitAll does the same for many files at once.
+package filewait
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"google.golang.org/genai"
+)
+
+// A FailedError reports a file whose processing failed.
+type FailedError struct {
+	// File is the file as last fetched, with the server's error details
+	// in File.Error, if any.
+	File *genai.File
+}
+
+func (e *FailedError) Error() string {
+	msg := fmt.Sprintf("processing of %s failed", e.File.Name)
+	if status := e.File.Error; status != nil {
+		if status.Message != "" {
+			msg += ": " + status.Message
+		}
+		if status.Code != nil {
+			msg += fmt.Sprintf(" (code %d)", *status.Code)
+		}
+	}
+	return msg
+}
+
+// Status returns the server's error details, or nil if there are none.
+func (e *FailedError) Status() *genai.FileStatus {
+	return e.File.Error
+}
+
+// Options controls the polling. The zero value, like a nil *Options, polls
+// after 1s, then doubles the delay up to 30s, varying each delay by up to
+// 20% either way.
+type Options struct {
+	// Initial is the delay before the first poll.
+	Initial time.Duration
+	// Max caps the delay between polls.
+	Max time.Duration
+	// Multiplier is the growth factor of the delay, at least 1.
+	Multiplier float64
+	// Jitter is the fraction by which each delay is randomly shortened or
+	// lengthened, between 0 and 1. Set it negative for no jitter.
+	Jitter float64
+	// Progress, if not nil, is called each time a file is not ready yet,
+	// with the file as last fetched and the delay before the next poll.
+	// WaitAll never makes concurrent calls to it.
+	Progress func(file *genai.File, next time.Duration)
+}
+
+func (o *Options) withDefaults() Options {
+	var opts Options
+	if o != nil {
+		opts = *o
+	}
+	if opts.Initial <= 0 {
+		opts.Initial = time.Second
+	}
+	if opts.Max <= 0 {
+		opts.Max = 30 * time.Second
+	}
+	if opts.Multiplier < 1 {
+		opts.Multiplier = 2
+	}
+	switch {
+	case opts.Jitter == 0:
+		opts.Jitter = 0.2
+	case opts.Jitter < 0:
+		opts.Jitter = 0
+	case opts.Jitter > 1:
+		opts.Jitter = 1
+	}
+	return opts
+}
+
+// delay returns the delay before poll number attempt, counting from 0,
+// given a random number r in [0, 1).
+func (o *Options) delay(attempt int, r float64) time.Duration {
+	d := float64(o.Initial)
+	for range attempt {
+		d *= o.Multiplier
+		if d >= float64(o.Max) {
+			break
+		}
+	}
+	d = min(d, float64(o.Max))
+	return time.Duration(d * (1 + o.Jitter*(2*r-1)))
+}
+
+// Wait returns file once it is ACTIVE, polling Files.Get while it is
+// PROCESSING. It returns a *FailedError if the file becomes FAILED, and an
+// error wrapping ctx.Err() if ctx is done first.
+func Wait(ctx context.Context, client *genai.Client, file *genai.File, opts *Options) (*genai.File, error) {
+	o := opts.withDefaults()
+	return wait(ctx, client, file, &o)
+}
+
+func wait(ctx context.Context, client *genai.Client, file *genai.File, o *Options) (*genai.File, error) {
+	for attempt := 0; ; attempt++ {
+		switch file.State {
+		case genai.FileStateActive:
+			return file, nil
+		case genai.FileStateFailed:
+			return file, &FailedError{File: file}
+		}
+		next := o.delay(attempt, rand.Float64())
+		if o.Progress != nil {
+			o.Progress(file, next)
+		}
+		t := time.NewTimer(next)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return file, fmt.Errorf("%s is still %s: %w", file.Name, file.State, ctx.Err())
+		case <-t.C:
+		}
+		f, err := client.Files.Get(ctx, file.Name, nil)
+		if err != nil {
+			return file, fmt.Errorf("checking %s: %w", file.Name, err)
+		}
+		file = f
+	}
+}
+
+// WaitAll waits for all the files at once, as Wait does for each, and
+// returns them as last fetched, in the same order. The error joins those of
+// the files that did not become ACTIVE; a failure does not stop the wait
+// for the others.
+func WaitAll(ctx context.Context, client *genai.Client, files []*genai.File, opts *Options) ([]*genai.File, error) {
+	o := opts.withDefaults()
+	if progress := o.Progress; progress != nil {
+		var mu sync.Mutex
+		o.Progress = func(file *genai.File, next time.Duration) {
+			mu.Lock()
+			defer mu.Unlock()
+			progress(file, next)
+		}
+	}
+	out := make([]*genai.File, len(files))
+	errs := make([]error, len(files))
+	var wg sync.WaitGroup
+	for i, f := range files {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			out[i], errs[i] = wait(ctx, client, f, &o)
+		}()
+	}
+	wg.Wait()
+	return out, errors.Join(errs...)
+}
@@ -0,0 +1,153 @@
+package filewait
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"gemini-api-examples/internal/config"
+	"gemini-api-examples/internal/fakegemini"
+
+	"google.golang.org/genai"
+)
+
+func newFake(t *testing.T) (*fakegemini.Server, *genai.Client) {
+	t.Helper()
+	srv := fakegemini.New()
+	t.Cleanup(srv.Close)
+	cfg := &config.Config{APIKey: "fake", BaseURL: srv.URL}
+	client, err := cfg.NewClient(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return srv, client
+}
+
+func upload(t *testing.T, client *genai.Client) *genai.File {
+	t.Helper()
+	f, err := client.Files.Upload(t.Context(), bytes.NewReader([]byte("video")), &genai.UploadFileConfig{MIMEType: "video/mp4"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+// fast polls every millisecond.
+var fast = &Options{Initial: time.Millisecond, Max: time.Millisecond, Jitter: -1}
+
+func TestDelay(t *testing.T) {
+	o := (&Options{Initial: time.Second, Max: 10 * time.Second, Jitter: -1}).withDefaults()
+	var got []time.Duration
+	for attempt := range 6 {
+		got = append(got, o.delay(attempt, 0.5))
+	}
+	want := []time.Duration{1, 2, 4, 8, 10, 10}
+	for i := range want {
+		if got[i] != want[i]*time.Second {
+			t.Errorf("delays = %v, want %v seconds", got, want)
+			break
+		}
+	}
+	// Jitter varies the delay by up to 20% by default.
+	o = (*Options)(nil).withDefaults()
+	if lo, hi := o.delay(0, 0), o.delay(0, 0.999999); lo != 800*time.Millisecond || hi < 1199*time.Millisecond || hi > 1200*time.Millisecond {
+		t.Errorf("jittered delays span [%v, %v], want [800ms, 1.2s]", lo, hi)
+	}
+	// Large attempt counts do not overflow.
+	if d := o.delay(1000, 0.5); d != 30*time.Second {
+		t.Errorf("delay(1000) = %v, want the 30s cap", d)
+	}
+}
+
+func TestWait(t *testing.T) {
+	srv, client := newFake(t)
+	srv.SetProcessingPolls(3)
+	f := upload(t, client)
+	if f.State != genai.FileStateProcessing {
+		t.Fatalf("uploaded file is %s, want PROCESSING", f.State)
+	}
+	var polls []genai.FileState
+	opts := *fast
+	opts.Progress = func(f *genai.File, next time.Duration) { polls = append(polls, f.State) }
+	got, err := Wait(t.Context(), client, f, &opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The upload and the first three polls find the file PROCESSING.
+	if got.State != genai.FileStateActive || len(polls) != 4 {
+		t.Errorf("state %s after %d progress calls, want ACTIVE after 4", got.State, len(polls))
+	}
+}
+
+func TestWaitFailed(t *testing.T) {
+	srv, client := newFake(t)
+	srv.Inject("files", 1, fakegemini.ProcessingFailed("unsupported codec"))
+	f := upload(t, client)
+	_, err := Wait(t.Context(), client, f, fast)
+	var failed *FailedError
+	if !errors.As(err, &failed) {
+		t.Fatalf("err = %v, want a *FailedError", err)
+	}
+	if failed.Status() == nil || failed.Status().Message != "unsupported codec" {
+		t.Errorf("status = %+v", failed.Status())
+	}
+	if want := "processing of " + f.Name + " failed: unsupported codec (code 3)"; err.Error() != want {
+		t.Errorf("err = %q, want %q", err, want)
+	}
+}
+
+func TestWaitDeadline(t *testing.T) {
+	srv, client := newFake(t)
+	srv.SetProcessingPolls(-1)
+	f := upload(t, client)
+	ctx, cancel := context.WithTimeout(t.Context(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	// The default backoff would sleep for a second: the deadline cuts it short.
+	_, err := Wait(ctx, client, f, nil)
+	if !errors.Is(err, context.DeadlineExceeded) || !strings.Contains(err.Error(), "is still PROCESSING") {
+		t.Errorf("err = %v, want the deadline with the file state", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Wait returned after %v, long after the deadline", elapsed)
+	}
+}
+
+func TestWaitAll(t *testing.T) {
+	srv, client := newFake(t)
+	srv.SetProcessingPolls(2)
+	var files []*genai.File
+	for range 20 {
+		files = append(files, upload(t, client))
+	}
+	srv.Inject("files", 1, fakegemini.ProcessingFailed("corrupt"))
+	files = append(files, upload(t, client))
+
+	var calls, inside int
+	opts := *fast
+	opts.Progress = func(f *genai.File, next time.Duration) {
+		inside++
+		if inside > 1 {
+			t.Error("concurrent progress calls")
+		}
+		calls++
+		time.Sleep(100 * time.Microsecond)
+		inside--
+	}
+	got, err := WaitAll(t.Context(), client, files, &opts)
+	var failed *FailedError
+	if !errors.As(err, &failed) || failed.File.Name != files[20].Name {
+		t.Errorf("err = %v, want the failure of %s", err, files[20].Name)
+	}
+	for i, f := range got[:20] {
+		if f.Name != files[i].Name || f.State != genai.FileStateActive {
+			t.Errorf("file %d: %s is %s, want %s ACTIVE", i, f.Name, f.State, files[i].Name)
+		}
+	}
+	if calls != 60 {
+		t.Errorf("%d progress calls, want 3 per file that became ACTIVE", calls)
+	}
+}
@@ -0,0 +1,199 @@
+// Package index records remote resources, such as the files of an upload
+// index and the caches of a cache manager, by a key derived from their
+// content, in memory or in a JSON file kept across runs and shared between
+// programs.
+//
+// An Index also serializes the work on each key, so that concurrent calls
+// for the same content create one resource:
+//
+//	key := index.Key(client, hash)
+//	entry, ok, done, err := ix.Acquire(ctx, key)
+//	if err != nil {
+//		return err
+//	}
+//	defer done()
+//	if ok && index.Fresh(entry.ExpireTime, nil, 0, time.Hour) {
+//		// Reuse the resource of entry.
+//	}
+//	// Create the resource, then:
+//	err = ix.Record(key, Entry{...}, nil)
+package index
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"gemini-api-examples/internal/config"
+
+	"google.golang.org/genai"
+)
+
+// An Index holds entries of type E by key. It is safe for concurrent use.
+type Index[E any] struct {
+	path    string
+	field   string
+	expires func(E) time.Time
+
+	mu       sync.Mutex
+	entries  map[string]E
+	inflight map[string]chan struct{}
+}
+
+// Open returns the index stored in the JSON file at path, which holds the
+// entries in an object under field and is created when the first entry is
+// recorded. With an empty path, the index only lives in memory. expires
+// returns the expiry of an entry, past which it is dropped from the file.
+func Open[E any](path, field string, expires func(E) time.Time) (*Index[E], error) {
+	ix := &Index[E]{path: path, field: field, expires: expires, entries: map[string]E{}, inflight: map[string]chan struct{}{}}
+	if path == "" {
+		return ix, nil
+	}
+	stored, err := ix.read()
+	if err != nil {
+		return nil, err
+	}
+	ix.entries = stored
+	return ix, nil
+}
+
+func (ix *Index[E]) read() (map[string]E, error) {
+	data, err := os.ReadFile(ix.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return map[string]E{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var f map[string]map[string]E
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("reading index %s: %w", ix.path, err)
+	}
+	if f[ix.field] == nil {
+		return map[string]E{}, nil
+	}
+	return f[ix.field], nil
+}
+
+// Entries returns a copy of the entries, by key.
+func (ix *Index[E]) Entries() map[string]E {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	out := make(map[string]E, len(ix.entries))
+	for k, e := range ix.entries {
+		out[k] = e
+	}
+	return out
+}
+
+// Acquire waits until no other call handles key and returns its entry, if
+// any, and a function to call when done with the key.
+func (ix *Index[E]) Acquire(ctx context.Context, key string) (E, bool, func(), error) {
+	for {
+		ix.mu.Lock()
+		busy, ok := ix.inflight[key]
+		if !ok {
+			break
+		}
+		ix.mu.Unlock()
+		select {
+		case <-busy:
+		case <-ctx.Done():
+			var zero E
+			return zero, false, nil, ctx.Err()
+		}
+	}
+	defer ix.mu.Unlock()
+	busy := make(chan struct{})
+	ix.inflight[key] = busy
+	entry, ok := ix.entries[key]
+	done := func() {
+		ix.mu.Lock()
+		defer ix.mu.Unlock()
+		delete(ix.inflight, key)
+		close(busy)
+	}
+	return entry, ok, done, nil
+}
+
+// Record stores e under key and saves the index file, merging in the
+// entries other programs may have added since it was read and dropping
+// those expired at the time now returns, or time.Now if now is nil.
+func (ix *Index[E]) Record(key string, e E, now func() time.Time) error {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.entries[key] = e
+	if ix.path == "" {
+		return nil
+	}
+	stored, err := ix.read()
+	if err != nil {
+		return err
+	}
+	for k, e := range ix.entries {
+		stored[k] = e
+	}
+	t := clock(now)
+	for k, e := range stored {
+		if !ix.expires(e).After(t) {
+			delete(stored, k)
+		}
+	}
+	ix.entries = stored
+	data, err := json.MarshalIndent(map[string]map[string]E{ix.field: stored}, "", "  ")
+	if err != nil {
+		return err
+	}
+	// Write a temporary file and rename it, so that readers never see a
+	// partial index.
+	if err := os.MkdirAll(filepath.Dir(ix.path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(ix.path), filepath.Base(ix.path)+".*")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(append(data, '\n'))
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), ix.path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("saving index %s: %w", ix.path, err)
+	}
+	return nil
+}
+
+// Key returns the key of a resource of client identified by parts, scoped
+// to the account and endpoint of client, so that one index can serve
+// several API keys or fake servers.
+func Key(client *genai.Client, parts ...string) string {
+	return strings.Join(append([]string{config.Scope(client)}, parts...), "/")
+}
+
+// Fresh reports whether a resource expiring at expire has at least
+// minRemaining left, or def if minRemaining is not positive, at the time now
+// returns, or time.Now if now is nil.
+func Fresh(expire time.Time, now func() time.Time, minRemaining, def time.Duration) bool {
+	if minRemaining <= 0 {
+		minRemaining = def
+	}
+	return expire.Sub(clock(now)) >= minRemaining
+}
+
+func clock(now func() time.Time) time.Time {
+	if now != nil {
+		return now()
+	}
+	return time.Now()
+}
@@ -0,0 +1,94 @@
+package index
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type entry struct {
+	Name   string    `json:"name"`
+	Expire time.Time `json:"expire"`
+}
+
+func open(t *testing.T, path string) *Index[entry] {
+	t.Helper()
+	ix, err := Open(path, "things", func(e entry) time.Time { return e.Expire })
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ix
+}
+
+func TestRecordMerges(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+	path := filepath.Join(t.TempDir(), "sub", "index.json")
+	a, b := open(t, path), open(t, path)
+	if err := a.Record("a", entry{"a", now.Add(time.Hour)}, clock); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Record("old", entry{"old", now.Add(time.Minute)}, clock); err != nil {
+		t.Fatal(err)
+	}
+	// b, opened before, keeps what a recorded and drops what expired.
+	now = now.Add(time.Minute)
+	if err := b.Record("b", entry{"b", now.Add(time.Hour)}, clock); err != nil {
+		t.Fatal(err)
+	}
+	got := open(t, path).Entries()
+	if len(got) != 2 || got["a"].Name != "a" || got["b"].Name != "b" {
+		t.Errorf("entries = %v, want a and b", got)
+	}
+
+	if err := os.WriteFile(path, []byte("{"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Open(path, "things", func(e entry) time.Time { return e.Expire }); err == nil {
+		t.Error("Open succeeded on a malformed file")
+	}
+}
+
+func TestAcquire(t *testing.T) {
+	ix := open(t, "")
+	_, ok, done, err := ix.Acquire(t.Context(), "k")
+	if err != nil || ok {
+		t.Fatalf("Acquire = %v, %v, want no entry", ok, err)
+	}
+	// Another call for the key waits for the first to be done.
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Millisecond)
+	defer cancel()
+	if _, _, _, err := ix.Acquire(ctx, "k"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Acquire of a busy key = %v, want it to wait", err)
+	}
+	if err := ix.Record("k", entry{Name: "k"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	done()
+	e, ok, done, err := ix.Acquire(t.Context(), "k")
+	if err != nil || !ok || e.Name != "k" {
+		t.Fatalf("Acquire = %v, %v, %v, want the recorded entry", e, ok, err)
+	}
+	done()
+}
+
+func TestFresh(t *testing.T) {
+	now := time.Now()
+	clock := func() time.Time { return now }
+	for _, tt := range []struct {
+		left, min time.Duration
+		want      bool
+	}{
+		{2 * time.Hour, 0, true},
+		{30 * time.Minute, 0, false},
+		{30 * time.Minute, 10 * time.Minute, true},
+		{5 * time.Minute, 10 * time.Minute, false},
+	} {
+		if got := Fresh(now.Add(tt.left), clock, tt.min, time.Hour); got != tt.want {
+			t.Errorf("Fresh with %v left and minimum %v = %v, want %v", tt.left, tt.min, got, tt.want)
+		}
+	}
+}
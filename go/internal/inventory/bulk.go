@@ -0,0 +1,110 @@
+package inventory
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"gemini-api-examples/internal/tracker"
+
+	"google.golang.org/genai"
+)
+
+// BulkOptions controls ExtendCaches and DeleteCaches, and Each.
+type BulkOptions struct {
+	// DryRun only reports what would be done.
+	DryRun bool
+	// Parallel is the number of concurrent requests, 1 if not positive.
+	Parallel int
+	// Rate is the maximum number of requests started per second, without
+	// limit if not positive.
+	Rate float64
+}
+
+// Each calls op on each of items with opts.Parallel workers, starting no
+// more than opts.Rate calls per second, and returns the error of each call,
+// or that of ctx for the items it did not get to. It ignores opts.DryRun.
+func Each[T any](ctx context.Context, items []T, opts BulkOptions, op func(*T) error) []error {
+	errs := make([]error, len(items))
+	limit := newLimiter(opts.Rate)
+	defer limit.stop()
+	work := make(chan int)
+	var wg sync.WaitGroup
+	for range min(max(opts.Parallel, 1), len(items)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range work {
+				if errs[i] = limit.wait(ctx); errs[i] == nil {
+					errs[i] = op(&items[i])
+				}
+			}
+		}()
+	}
+	for i := range items {
+		work <- i
+	}
+	close(work)
+	wg.Wait()
+	return errs
+}
+
+// A limiter spaces out the start of requests.
+type limiter struct {
+	ticker *time.Ticker
+}
+
+func newLimiter(rate float64) *limiter {
+	if rate <= 0 {
+		return &limiter{}
+	}
+	return &limiter{ticker: time.NewTicker(time.Duration(float64(time.Second) / rate))}
+}
+
+// wait blocks until the next request may start.
+func (l *limiter) wait(ctx context.Context) error {
+	if l.ticker == nil {
+		return ctx.Err()
+	}
+	select {
+	case <-l.ticker.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (l *limiter) stop() {
+	if l.ticker != nil {
+		l.ticker.Stop()
+	}
+}
+
+// DeleteFile deletes the file named name. A file that no longer exists
+// counts as deleted.
+func DeleteFile(ctx context.Context, client *genai.Client, name string) error {
+	if _, err := client.Files.Delete(ctx, name, nil); err != nil && !tracker.IsGone(tracker.KindFile, err) {
+		return err
+	}
+	return nil
+}
+
+// DeleteCache deletes the cache named name. A cache that no longer exists
+// counts as deleted; one the key may not delete does not.
+func DeleteCache(ctx context.Context, client *genai.Client, name string) error {
+	if _, err := client.Caches.Delete(ctx, name, nil); err != nil && !tracker.IsGone(tracker.KindCache, err) {
+		return err
+	}
+	return nil
+}
+
+// ModelID returns the model ID at the end of a model name such as
+// "models/gemini-3.5-flash" or, on Vertex AI,
+// "projects/p/locations/l/publishers/google/models/gemini-3.5-flash".
+func ModelID(name string) string {
+	if i := strings.LastIndex(name, "models/"); i >= 0 {
+		return name[i+len("models/"):]
+	}
+	return name
+}
@@ -0,0 +1,276 @@
+package inventory
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"google.golang.org/genai"
+)
+
+// A Cache is the metadata of a cached content.
+type Cache struct {
+	// Name is the resource name, such as "cachedContents/abc".
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName,omitempty"`
+	// Model is the model of the cache, without "models/".
+	Model      string    `json:"model"`
+	CreateTime time.Time `json:"createTime"`
+	UpdateTime time.Time `json:"updateTime"`
+	ExpireTime time.Time `json:"expireTime"`
+	// Tokens is the size of the cache, 0 if the API does not report it.
+	Tokens int32 `json:"tokens"`
+}
+
+// Remaining returns the time left at now before c expires, never negative.
+func (c *Cache) Remaining(now time.Time) time.Duration {
+	return max(c.ExpireTime.Sub(now), 0)
+}
+
+// ListCaches returns the caches of the account, going through every page of
+// pageSize caches, or the API's default if pageSize is 0.
+func ListCaches(ctx context.Context, client *genai.Client, pageSize int32) ([]Cache, error) {
+	var caches []Cache
+	page, err := client.Caches.List(ctx, &genai.ListCachedContentsConfig{PageSize: pageSize})
+	for err == nil {
+		for _, c := range page.Items {
+			caches = append(caches, cacheOf(c))
+		}
+		if page.NextPageToken == "" {
+			break
+		}
+		page, err = page.Next(ctx)
+	}
+	if err != nil && err != genai.ErrPageDone {
+		return nil, fmt.Errorf("listing caches: %w", err)
+	}
+	return caches, nil
+}
+
+func cacheOf(c *genai.CachedContent) Cache {
+	out := Cache{
+		Name:        c.Name,
+		DisplayName: c.DisplayName,
+		Model:       ModelID(c.Model),
+		CreateTime:  c.CreateTime,
+		UpdateTime:  c.UpdateTime,
+		ExpireTime:  c.ExpireTime,
+	}
+	if c.UsageMetadata != nil {
+		out.Tokens = c.UsageMetadata.TotalTokenCount
+	}
+	return out
+}
+
+// A CacheFilter selects caches. A cache matches when it meets every
+// criterion set.
+type CacheFilter struct {
+	// Models lists patterns, as in path.Match, for the model, with or
+	// without "models/", for example "gemini-3.5-*".
+	Models []string
+	// DisplayName is a pattern, as in path.Match, for the display name.
+	DisplayName string
+	// CreatedAfter and CreatedBefore bound the creation time.
+	CreatedAfter, CreatedBefore time.Time
+	// ExpiresAfter and ExpiresBefore bound the expiry time.
+	ExpiresAfter, ExpiresBefore time.Time
+	// MinTokens and MaxTokens bound the size; MaxTokens 0 means no bound.
+	MinTokens, MaxTokens int32
+}
+
+// Validate reports malformed patterns and empty ranges.
+func (f *CacheFilter) Validate() error {
+	for _, p := range f.Models {
+		if _, err := path.Match(ModelID(p), ""); err != nil {
+			return fmt.Errorf("bad model pattern %q: %v", p, err)
+		}
+	}
+	if _, err := path.Match(f.DisplayName, ""); err != nil {
+		return fmt.Errorf("bad display name pattern %q: %v", f.DisplayName, err)
+	}
+	if f.MaxTokens != 0 && f.MaxTokens < f.MinTokens {
+		return fmt.Errorf("maximum of %d tokens is below minimum of %d", f.MaxTokens, f.MinTokens)
+	}
+	if err := checkRange(f.CreatedAfter, f.CreatedBefore); err != nil {
+		return fmt.Errorf("creation time: %v", err)
+	}
+	if err := checkRange(f.ExpiresAfter, f.ExpiresBefore); err != nil {
+		return fmt.Errorf("expiry time: %v", err)
+	}
+	return nil
+}
+
+// Empty reports whether f sets no criterion, selecting every cache.
+func (f *CacheFilter) Empty() bool {
+	return len(f.Models) == 0 && f.DisplayName == "" && f.MinTokens == 0 && f.MaxTokens == 0 &&
+		f.CreatedAfter.IsZero() && f.CreatedBefore.IsZero() && f.ExpiresAfter.IsZero() && f.ExpiresBefore.IsZero()
+}
+
+// Match reports whether c meets the criteria of f.
+func (f *CacheFilter) Match(c Cache) bool {
+	switch {
+	case len(f.Models) > 0 && !slices.ContainsFunc(f.Models, func(p string) bool {
+		ok, _ := path.Match(ModelID(p), c.Model)
+		return ok
+	}),
+		c.Tokens < f.MinTokens,
+		f.MaxTokens != 0 && c.Tokens > f.MaxTokens,
+		!f.CreatedAfter.IsZero() && !c.CreateTime.After(f.CreatedAfter),
+		!f.CreatedBefore.IsZero() && !c.CreateTime.Before(f.CreatedBefore),
+		!f.ExpiresAfter.IsZero() && !c.ExpireTime.After(f.ExpiresAfter),
+		!f.ExpiresBefore.IsZero() && !c.ExpireTime.Before(f.ExpiresBefore):
+		return false
+	}
+	if f.DisplayName != "" {
+		ok, _ := path.Match(f.DisplayName, c.DisplayName)
+		return ok
+	}
+	return true
+}
+
+// CacheSortKeys lists the keys SortCaches accepts.
+var CacheSortKeys = []string{"name", "display-name", "model", "created", "expires", "tokens"}
+
+// SortCaches sorts caches by key, one of CacheSortKeys, optionally preceded
+// by "-" for descending order. Caches with equal keys stay in their order.
+func SortCaches(caches []Cache, key string) error {
+	key, desc := strings.CutPrefix(key, "-")
+	var compare func(a, b Cache) int
+	switch key {
+	case "name":
+		compare = func(a, b Cache) int { return strings.Compare(a.Name, b.Name) }
+	case "display-name":
+		compare = func(a, b Cache) int { return strings.Compare(a.DisplayName, b.DisplayName) }
+	case "model":
+		compare = func(a, b Cache) int { return strings.Compare(a.Model, b.Model) }
+	case "created":
+		compare = func(a, b Cache) int { return a.CreateTime.Compare(b.CreateTime) }
+	case "expires":
+		compare = func(a, b Cache) int { return a.ExpireTime.Compare(b.ExpireTime) }
+	case "tokens":
+		compare = func(a, b Cache) int { return cmp.Compare(a.Tokens, b.Tokens) }
+	default:
+		return fmt.Errorf("unknown sort key %q; use one of %s", key, strings.Join(CacheSortKeys, ", "))
+	}
+	if desc {
+		asc := compare
+		compare = func(a, b Cache) int { return asc(b, a) }
+	}
+	slices.SortStableFunc(caches, compare)
+	return nil
+}
+
+// CacheTotals sums up caches.
+type CacheTotals struct {
+	Count  int   `json:"count"`
+	Tokens int64 `json:"tokens"`
+	// ByModel counts the caches of each model.
+	ByModel map[string]int `json:"byModel"`
+}
+
+// TotalCaches sums up caches.
+func TotalCaches(caches []Cache) CacheTotals {
+	t := CacheTotals{ByModel: map[string]int{}}
+	for _, c := range caches {
+		t.Count++
+		t.Tokens += int64(c.Tokens)
+		t.ByModel[c.Model]++
+	}
+	return t
+}
+
+// A CacheReport is the outcome of Caches.
+type CacheReport struct {
+	// Listed is the number of caches listed.
+	Listed int `json:"listed"`
+	// Time is when the listing ended, against which the remaining TTLs
+	// are measured.
+	Time time.Time `json:"time"`
+	// Caches holds the caches matching the filter, in listing order.
+	Caches []Cache     `json:"caches"`
+	Totals CacheTotals `json:"totals"`
+}
+
+// Caches lists the caches of the account, as ListCaches does, and reports
+// those selected by f, with their totals.
+func Caches(ctx context.Context, client *genai.Client, f CacheFilter, pageSize int32) (*CacheReport, error) {
+	if err := f.Validate(); err != nil {
+		return nil, err
+	}
+	caches, err := ListCaches(ctx, client, pageSize)
+	if err != nil {
+		return nil, err
+	}
+	r := &CacheReport{Listed: len(caches), Time: time.Now(), Caches: []Cache{}}
+	for _, c := range caches {
+		if f.Match(c) {
+			r.Caches = append(r.Caches, c)
+		}
+	}
+	r.Totals = TotalCaches(r.Caches)
+	return r, nil
+}
+
+// A CacheFailure is a cache that a bulk operation could not update or
+// delete.
+type CacheFailure struct {
+	Cache
+	Err error `json:"-"`
+	// Error is the text of Err, for JSON.
+	Error string `json:"error"`
+}
+
+// A BulkReport is the outcome of ExtendCaches or DeleteCaches.
+type BulkReport struct {
+	// Done holds the caches updated or deleted, or that a dry run would
+	// update or delete, in the order given. Updated caches have their new
+	// expiry time.
+	Done   []Cache        `json:"done"`
+	Failed []CacheFailure `json:"failed"`
+	DryRun bool           `json:"dryRun"`
+}
+
+// ExtendCaches sets the TTL of caches to ttl from now, so that they expire
+// ttl later. Failures are in the report.
+func ExtendCaches(ctx context.Context, client *genai.Client, caches []Cache, ttl time.Duration, opts BulkOptions) *BulkReport {
+	return bulk(ctx, caches, opts, func(c *Cache) error {
+		updated, err := client.Caches.Update(ctx, c.Name, &genai.UpdateCachedContentConfig{TTL: ttl})
+		if err != nil {
+			return err
+		}
+		*c = cacheOf(updated)
+		return nil
+	})
+}
+
+// DeleteCaches deletes caches. Caches that no longer exist count as
+// deleted; other failures are in the report.
+func DeleteCaches(ctx context.Context, client *genai.Client, caches []Cache, opts BulkOptions) *BulkReport {
+	return bulk(ctx, caches, opts, func(c *Cache) error {
+		return DeleteCache(ctx, client, c.Name)
+	})
+}
+
+// bulk calls op on a copy of each of caches, as Each does, and reports the
+// outcome.
+func bulk(ctx context.Context, caches []Cache, opts BulkOptions, op func(*Cache) error) *BulkReport {
+	r := &BulkReport{Done: []Cache{}, Failed: []CacheFailure{}, DryRun: opts.DryRun}
+	if opts.DryRun {
+		r.Done = append(r.Done, caches...)
+		return r
+	}
+	out := slices.Clone(caches)
+	errs := Each(ctx, out, opts, op)
+	for i, c := range out {
+		if errs[i] != nil {
+			r.Failed = append(r.Failed, CacheFailure{Cache: c, Err: errs[i], Error: errs[i].Error()})
+		} else {
+			r.Done = append(r.Done, c)
+		}
+	}
+	return r
+}
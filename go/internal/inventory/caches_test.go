@@ -0,0 +1,271 @@
+package inventory
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"gemini-api-examples/internal/fakegemini"
+
+	"google.golang.org/genai"
+)
+
+// seedCaches stores 12 caches created an hour apart from now less a day,
+// alternating between two models, with 1000 tokens times their index and
+// expiring 10 minutes times their index from now.
+func seedCaches(srv *fakegemini.Server, now time.Time) {
+	models := []string{"models/gemini-3.5-flash", "models/gemini-3.5-pro"}
+	for i := range 12 {
+		srv.AddCache(&genai.CachedContent{
+			Name:          fmt.Sprintf("cachedContents/c%02d", i),
+			DisplayName:   fmt.Sprintf("cache-%02d", i),
+			Model:         models[i%2],
+			CreateTime:    now.Add(time.Duration(i-24) * time.Hour),
+			ExpireTime:    now.Add(time.Duration(i+1) * 10 * time.Minute),
+			UsageMetadata: &genai.CachedContentUsageMetadata{TotalTokenCount: int32(1000 * i)},
+		})
+	}
+}
+
+func cacheNames(caches []Cache) string {
+	var s []string
+	for _, c := range caches {
+		s = append(s, strings.TrimPrefix(c.Name, "cachedContents/c"))
+	}
+	return strings.Join(s, " ")
+}
+
+func TestListCachesPages(t *testing.T) {
+	for _, tc := range []struct {
+		pageSize int32
+		requests int
+	}{
+		{0, 2}, // the fake's default of 10
+		{2, 6},
+		{5, 3},
+		{12, 1},
+	} {
+		t.Run(fmt.Sprint(tc.pageSize), func(t *testing.T) {
+			srv, client := newFake(t)
+			seedCaches(srv, time.Now())
+			caches, err := ListCaches(t.Context(), client, tc.pageSize)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := cacheNames(caches), "00 01 02 03 04 05 06 07 08 09 10 11"; got != want {
+				t.Errorf("caches = %s, want %s", got, want)
+			}
+			if got := len(srv.RequestsTo("GET", "/cachedContents")); got != tc.requests {
+				t.Errorf("made %d list requests, want %d", got, tc.requests)
+			}
+		})
+	}
+}
+
+func TestListCachesMetadata(t *testing.T) {
+	srv, client := newFake(t)
+	now := time.Now().UTC().Truncate(time.Second)
+	seedCaches(srv, now)
+	caches, err := ListCaches(t.Context(), client, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := caches[3]
+	if c.Model != "gemini-3.5-pro" || c.DisplayName != "cache-03" || c.Tokens != 3000 {
+		t.Errorf("caches[3] = %+v", c)
+	}
+	if !c.CreateTime.Equal(now.Add(-21*time.Hour)) || !c.ExpireTime.Equal(now.Add(40*time.Minute)) {
+		t.Errorf("caches[3] times = %v, %v", c.CreateTime, c.ExpireTime)
+	}
+	if got := c.Remaining(now.Add(10 * time.Minute)); got != 30*time.Minute {
+		t.Errorf("Remaining = %v, want 30m", got)
+	}
+	if got := c.Remaining(now.Add(time.Hour)); got != 0 {
+		t.Errorf("Remaining after expiry = %v, want 0", got)
+	}
+}
+
+func TestListCachesError(t *testing.T) {
+	srv, client := newFake(t)
+	seedCaches(srv, time.Now())
+	srv.Inject("/cachedContents", 0, fakegemini.ServerError(500))
+	_, err := ListCaches(t.Context(), client, 5)
+	if err == nil || !strings.Contains(err.Error(), "listing caches") {
+		t.Errorf("err = %v, want a listing error", err)
+	}
+}
+
+func TestCacheFilter(t *testing.T) {
+	srv, client := newFake(t)
+	now := time.Now()
+	seedCaches(srv, now)
+	for _, tc := range []struct {
+		name   string
+		filter CacheFilter
+		want   string
+	}{
+		{"all", CacheFilter{}, "00 01 02 03 04 05 06 07 08 09 10 11"},
+		{"model", CacheFilter{Models: []string{"gemini-3.5-pro"}}, "01 03 05 07 09 11"},
+		{"model prefix", CacheFilter{Models: []string{"models/gemini-3.5-flash"}}, "00 02 04 06 08 10"},
+		{"model pattern", CacheFilter{Models: []string{"gemini-*"}, MinTokens: 9000}, "09 10 11"},
+		{"expires within", CacheFilter{ExpiresBefore: now.Add(35 * time.Minute)}, "00 01 02"},
+		{"expires after", CacheFilter{ExpiresAfter: now.Add(100 * time.Minute)}, "10 11"},
+		{"created", CacheFilter{CreatedAfter: now.Add(-20 * time.Hour), CreatedBefore: now.Add(-17 * time.Hour)}, "05 06"},
+		{"tokens", CacheFilter{MinTokens: 2000, MaxTokens: 4000}, "02 03 04"},
+		{"name", CacheFilter{DisplayName: "cache-1?"}, "10 11"},
+		{"none", CacheFilter{Models: []string{"gemini-2.0-flash"}}, ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r, err := Caches(t.Context(), client, tc.filter, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := cacheNames(r.Caches); got != tc.want {
+				t.Errorf("caches = %s, want %s", got, tc.want)
+			}
+			if r.Listed != 12 {
+				t.Errorf("Listed = %d, want 12", r.Listed)
+			}
+			if r.Totals.Count != len(r.Caches) {
+				t.Errorf("Totals.Count = %d, want %d", r.Totals.Count, len(r.Caches))
+			}
+		})
+	}
+}
+
+func TestSortCaches(t *testing.T) {
+	caches := []Cache{
+		{Name: "cachedContents/b", Model: "gemini-3.5-pro", Tokens: 10, ExpireTime: epoch.Add(2 * time.Hour)},
+		{Name: "cachedContents/c", Model: "gemini-3.5-flash", Tokens: 30, ExpireTime: epoch},
+		{Name: "cachedContents/a", Model: "gemini-3.5-pro", Tokens: 20, ExpireTime: epoch.Add(time.Hour)},
+	}
+	for key, want := range map[string]string{
+		"name":    "a b c",
+		"-name":   "c b a",
+		"model":   "c b a",
+		"-model":  "b a c",
+		"expires": "c a b",
+		"tokens":  "b a c",
+		"-tokens": "c a b",
+	} {
+		sorted := append([]Cache(nil), caches...)
+		if err := SortCaches(sorted, key); err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, c := range sorted {
+			got = append(got, strings.TrimPrefix(c.Name, "cachedContents/"))
+		}
+		if strings.Join(got, " ") != want {
+			t.Errorf("sorted by %s: %s, want %s", key, strings.Join(got, " "), want)
+		}
+	}
+	if err := SortCaches(caches, "size"); err == nil {
+		t.Error("SortCaches with an unknown key succeeded")
+	}
+}
+
+func TestTotalCaches(t *testing.T) {
+	tot := TotalCaches([]Cache{
+		{Model: "gemini-3.5-flash", Tokens: 100},
+		{Model: "gemini-3.5-flash", Tokens: 200},
+		{Model: "gemini-3.5-pro", Tokens: 300},
+	})
+	if tot.Count != 3 || tot.Tokens != 600 {
+		t.Errorf("totals = %d caches, %d tokens, want 3 caches, 600 tokens", tot.Count, tot.Tokens)
+	}
+	if tot.ByModel["gemini-3.5-flash"] != 2 || tot.ByModel["gemini-3.5-pro"] != 1 {
+		t.Errorf("ByModel = %v", tot.ByModel)
+	}
+}
+
+func TestValidateCacheFilter(t *testing.T) {
+	for _, tc := range []struct {
+		filter CacheFilter
+		want   string
+	}{
+		{CacheFilter{Models: []string{"gemini-["}}, "bad model pattern"},
+		{CacheFilter{DisplayName: "["}, "bad display name pattern"},
+		{CacheFilter{MinTokens: 10, MaxTokens: 5}, "below minimum"},
+		{CacheFilter{CreatedAfter: epoch, CreatedBefore: epoch}, "creation time"},
+		{CacheFilter{ExpiresAfter: epoch.Add(time.Hour), ExpiresBefore: epoch}, "expiry time"},
+	} {
+		err := tc.filter.Validate()
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("Validate(%+v) = %v, want an error containing %q", tc.filter, err, tc.want)
+		}
+	}
+}
+
+func TestExtendCaches(t *testing.T) {
+	srv, client := newFake(t)
+	now := time.Now().UTC().Truncate(time.Second)
+	srv.SetClock(func() time.Time { return now })
+	seedCaches(srv, now)
+	r, err := Caches(t.Context(), client, CacheFilter{ExpiresBefore: now.Add(35 * time.Minute)}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dry := ExtendCaches(t.Context(), client, r.Caches, 3*time.Hour, BulkOptions{DryRun: true})
+	if len(dry.Done) != 3 || len(srv.RequestsTo("PATCH", "")) != 0 {
+		t.Errorf("dry run: %d done, %d updates made, want 3 and none", len(dry.Done), len(srv.RequestsTo("PATCH", "")))
+	}
+
+	srv.Inject("/cachedContents/c01", 1, fakegemini.ServerError(500))
+	ext := ExtendCaches(t.Context(), client, r.Caches, 3*time.Hour, BulkOptions{Parallel: 3})
+	if got := cacheNames(ext.Done); got != "00 02" {
+		t.Errorf("extended %s, want 00 02", got)
+	}
+	if len(ext.Failed) != 1 || ext.Failed[0].Name != "cachedContents/c01" || ext.Failed[0].Error == "" {
+		t.Errorf("failed = %+v, want c01", ext.Failed)
+	}
+	for _, c := range ext.Done {
+		if !c.ExpireTime.Equal(now.Add(3 * time.Hour)) {
+			t.Errorf("%s expires at %v, want in 3h", c.Name, c.ExpireTime)
+		}
+	}
+	if !r.Caches[0].ExpireTime.Equal(now.Add(10 * time.Minute)) {
+		t.Error("ExtendCaches changed the caches it was given")
+	}
+	for _, c := range srv.Caches() {
+		if (c.Name == "cachedContents/c00" || c.Name == "cachedContents/c02") != c.ExpireTime.Equal(now.Add(3*time.Hour)) {
+			t.Errorf("server has %s expiring at %v", c.Name, c.ExpireTime)
+		}
+	}
+}
+
+func TestDeleteCaches(t *testing.T) {
+	srv, client := newFake(t)
+	seedCaches(srv, time.Now())
+	r, err := Caches(t.Context(), client, CacheFilter{Models: []string{"gemini-3.5-pro"}}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.Inject("/cachedContents/c03", 1, fakegemini.ServerError(503))
+	srv.Inject("/cachedContents/c07", 1, fakegemini.ServerError(http.StatusForbidden))
+	if _, err := client.Caches.Delete(t.Context(), "cachedContents/c05", nil); err != nil {
+		t.Fatal(err)
+	}
+	del := DeleteCaches(t.Context(), client, r.Caches, BulkOptions{Parallel: 4})
+	// c05, already gone, counts as deleted; c07, forbidden, does not.
+	if got := cacheNames(del.Done); got != "01 05 09 11" {
+		t.Errorf("deleted %s, want 01 05 09 11", got)
+	}
+	var failed []Cache
+	for _, f := range del.Failed {
+		failed = append(failed, f.Cache)
+	}
+	if got := cacheNames(failed); got != "03 07" {
+		t.Errorf("failed = %+v, want c03 and c07", del.Failed)
+	}
+	var left []Cache
+	for _, c := range srv.Caches() {
+		left = append(left, cacheOf(c))
+	}
+	if got := cacheNames(left); got != "00 02 03 04 06 07 08 10" {
+		t.Errorf("caches left = %s", got)
+	}
+}
@@ -0,0 +1,202 @@
+// Package inventory reports on the files and caches of an account: it
+// lists them page by page, filters them by their metadata and totals them.
+// It can also extend the TTL of caches or delete them in bulk.
+//
+//	r, err := inventory.Files(ctx, client, inventory.FileFilter{
+//		MIMETypes: []string{"video/*"},
+//		MinSize:   10 << 20,
+//	}, 0)
+//
+//	r, err := inventory.Caches(ctx, client, inventory.CacheFilter{
+//		Models:        []string{"gemini-3.5-flash"},
+//		ExpiresBefore: time.Now().Add(10 * time.Minute),
+//	}, 0)
+//	if err == nil {
+//		ext := inventory.ExtendCaches(ctx, client, r.Caches, time.Hour, inventory.BulkOptions{Parallel: 4})
+//	}
+package inventory
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"slices"
+	"time"
+
+	"google.golang.org/genai"
+)
+
+// A File is the metadata of an uploaded file.
+type File struct {
+	// Name is the resource name, such as "files/abc".
+	Name           string          `json:"name"`
+	DisplayName    string          `json:"displayName,omitempty"`
+	MIMEType       string          `json:"mimeType"`
+	SizeBytes      int64           `json:"sizeBytes"`
+	State          genai.FileState `json:"state"`
+	CreateTime     time.Time       `json:"createTime"`
+	ExpirationTime time.Time       `json:"expirationTime"`
+	URI            string          `json:"uri"`
+}
+
+// ListFiles returns the files of the account, going through every page of
+// pageSize files, or the API's default if pageSize is 0.
+func ListFiles(ctx context.Context, client *genai.Client, pageSize int32) ([]File, error) {
+	var files []File
+	page, err := client.Files.List(ctx, &genai.ListFilesConfig{PageSize: pageSize})
+	for err == nil {
+		for _, f := range page.Items {
+			files = append(files, fileOf(f))
+		}
+		if page.NextPageToken == "" {
+			break
+		}
+		page, err = page.Next(ctx)
+	}
+	if err != nil && err != genai.ErrPageDone {
+		return nil, fmt.Errorf("listing files: %w", err)
+	}
+	return files, nil
+}
+
+func fileOf(f *genai.File) File {
+	out := File{
+		Name:           f.Name,
+		DisplayName:    f.DisplayName,
+		MIMEType:       f.MIMEType,
+		State:          f.State,
+		CreateTime:     f.CreateTime,
+		ExpirationTime: f.ExpirationTime,
+		URI:            f.URI,
+	}
+	if f.SizeBytes != nil {
+		out.SizeBytes = *f.SizeBytes
+	}
+	return out
+}
+
+// A FileFilter selects files. A file matches when it meets every criterion
+// set.
+type FileFilter struct {
+	// States lists the states of the files to select.
+	States []genai.FileState
+	// MIMETypes lists patterns, as in path.Match, for the MIME type, for
+	// example "image/*".
+	MIMETypes []string
+	// MinSize and MaxSize bound the size in bytes; MaxSize 0 means no
+	// bound.
+	MinSize, MaxSize int64
+	// CreatedAfter and CreatedBefore bound the creation time.
+	CreatedAfter, CreatedBefore time.Time
+	// ExpiresAfter and ExpiresBefore bound the expiration time.
+	ExpiresAfter, ExpiresBefore time.Time
+	// DisplayName is a pattern, as in path.Match, for the display name.
+	DisplayName string
+}
+
+// Validate reports malformed patterns and empty ranges.
+func (f *FileFilter) Validate() error {
+	for _, p := range f.MIMETypes {
+		if _, err := path.Match(p, ""); err != nil {
+			return fmt.Errorf("bad MIME type pattern %q: %v", p, err)
+		}
+	}
+	if _, err := path.Match(f.DisplayName, ""); err != nil {
+		return fmt.Errorf("bad display name pattern %q: %v", f.DisplayName, err)
+	}
+	if f.MaxSize != 0 && f.MaxSize < f.MinSize {
+		return fmt.Errorf("maximum size %d is below minimum size %d", f.MaxSize, f.MinSize)
+	}
+	if err := checkRange(f.CreatedAfter, f.CreatedBefore); err != nil {
+		return fmt.Errorf("creation time: %v", err)
+	}
+	if err := checkRange(f.ExpiresAfter, f.ExpiresBefore); err != nil {
+		return fmt.Errorf("expiration time: %v", err)
+	}
+	return nil
+}
+
+// checkRange reports an empty time range.
+func checkRange(after, before time.Time) error {
+	if !after.IsZero() && !before.IsZero() && !after.Before(before) {
+		return fmt.Errorf("no time is after %s and before %s", after.Format(time.RFC3339), before.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// Match reports whether file meets the criteria of f.
+func (f *FileFilter) Match(file File) bool {
+	switch {
+	case len(f.States) > 0 && !slices.Contains(f.States, file.State),
+		len(f.MIMETypes) > 0 && !matchAny(f.MIMETypes, file.MIMEType),
+		file.SizeBytes < f.MinSize,
+		f.MaxSize != 0 && file.SizeBytes > f.MaxSize,
+		!f.CreatedAfter.IsZero() && !file.CreateTime.After(f.CreatedAfter),
+		!f.CreatedBefore.IsZero() && !file.CreateTime.Before(f.CreatedBefore),
+		!f.ExpiresAfter.IsZero() && !file.ExpirationTime.After(f.ExpiresAfter),
+		!f.ExpiresBefore.IsZero() && !file.ExpirationTime.Before(f.ExpiresBefore):
+		return false
+	}
+	if f.DisplayName != "" {
+		ok, _ := path.Match(f.DisplayName, file.DisplayName)
+		return ok
+	}
+	return true
+}
+
+func matchAny(patterns []string, s string) bool {
+	return slices.ContainsFunc(patterns, func(p string) bool {
+		ok, _ := path.Match(p, s)
+		return ok
+	})
+}
+
+// FileTotals sums up files.
+type FileTotals struct {
+	Count     int   `json:"count"`
+	SizeBytes int64 `json:"sizeBytes"`
+	// ByState and ByMIMEType count the files of each state and MIME type.
+	ByState    map[genai.FileState]int `json:"byState"`
+	ByMIMEType map[string]int          `json:"byMimeType"`
+}
+
+// TotalFiles sums up files.
+func TotalFiles(files []File) FileTotals {
+	t := FileTotals{ByState: map[genai.FileState]int{}, ByMIMEType: map[string]int{}}
+	for _, f := range files {
+		t.Count++
+		t.SizeBytes += f.SizeBytes
+		t.ByState[f.State]++
+		t.ByMIMEType[f.MIMEType]++
+	}
+	return t
+}
+
+// A FileReport is the outcome of Files.
+type FileReport struct {
+	// Listed is the number of files listed.
+	Listed int `json:"listed"`
+	// Files holds the files matching the filter, in listing order.
+	Files  []File     `json:"files"`
+	Totals FileTotals `json:"totals"`
+}
+
+// Files lists the files of the account, as ListFiles does, and reports those
+// selected by f, with their totals.
+func Files(ctx context.Context, client *genai.Client, f FileFilter, pageSize int32) (*FileReport, error) {
+	if err := f.Validate(); err != nil {
+		return nil, err
+	}
+	files, err := ListFiles(ctx, client, pageSize)
+	if err != nil {
+		return nil, err
+	}
+	r := &FileReport{Listed: len(files), Files: []File{}}
+	for _, file := range files {
+		if f.Match(file) {
+			r.Files = append(r.Files, file)
+		}
+	}
+	r.Totals = TotalFiles(r.Files)
+	return r, nil
+}
@@ -0,0 +1,183 @@
+package inventory
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"gemini-api-examples/internal/config"
+	"gemini-api-examples/internal/fakegemini"
+
+	"google.golang.org/genai"
+)
+
+func newFake(t *testing.T) (*fakegemini.Server, *genai.Client) {
+	t.Helper()
+	srv := fakegemini.New()
+	t.Cleanup(srv.Close)
+	cfg := &config.Config{APIKey: "fake", BaseURL: srv.URL}
+	client, err := cfg.NewClient(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return srv, client
+}
+
+var epoch = time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+
+// seedFiles stores 25 files created an hour apart, cycling through three
+// MIME types, with sizes of 100 bytes times their index and every fifth one
+// FAILED.
+func seedFiles(srv *fakegemini.Server) {
+	types := []string{"image/png", "video/mp4", "application/pdf"}
+	exts := []string{".png", ".mp4", ".pdf"}
+	for i := range 25 {
+		state := genai.FileStateActive
+		if i%5 == 0 {
+			state = genai.FileStateFailed
+		}
+		srv.AddFile(&genai.File{
+			Name:        fmt.Sprintf("files/f%02d", i),
+			DisplayName: fmt.Sprintf("file-%02d%s", i, exts[i%3]),
+			MIMEType:    types[i%3],
+			State:       state,
+			CreateTime:  epoch.Add(time.Duration(i) * time.Hour),
+		}, make([]byte, 100*i))
+	}
+}
+
+func TestListFilesPages(t *testing.T) {
+	for _, tc := range []struct {
+		pageSize int32
+		requests int
+	}{
+		{0, 3}, // the fake's default of 10
+		{7, 4},
+		{25, 1},
+		{100, 1},
+	} {
+		t.Run(fmt.Sprint(tc.pageSize), func(t *testing.T) {
+			srv, client := newFake(t)
+			seedFiles(srv)
+			files, err := ListFiles(t.Context(), client, tc.pageSize)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(files) != 25 {
+				t.Fatalf("listed %d files, want 25", len(files))
+			}
+			for i, f := range files {
+				if want := fmt.Sprintf("files/f%02d", i); f.Name != want {
+					t.Errorf("files[%d] = %s, want %s", i, f.Name, want)
+				}
+			}
+			if got := len(srv.RequestsTo("GET", "/files")); got != tc.requests {
+				t.Errorf("made %d list requests, want %d", got, tc.requests)
+			}
+		})
+	}
+}
+
+func TestListFilesEmpty(t *testing.T) {
+	_, client := newFake(t)
+	files, err := ListFiles(t.Context(), client, 0)
+	if err != nil || len(files) != 0 {
+		t.Errorf("ListFiles = %v, %v, want no files", files, err)
+	}
+}
+
+func TestListFilesError(t *testing.T) {
+	srv, client := newFake(t)
+	seedFiles(srv)
+	srv.Inject("/files", 0, fakegemini.ServerError(500))
+	_, err := ListFiles(t.Context(), client, 10)
+	if err == nil || !strings.Contains(err.Error(), "listing files") {
+		t.Errorf("err = %v, want a listing error", err)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	srv, client := newFake(t)
+	seedFiles(srv)
+	names := func(files []File) string {
+		var s []string
+		for _, f := range files {
+			s = append(s, strings.TrimPrefix(f.Name, "files/f"))
+		}
+		return strings.Join(s, " ")
+	}
+	for _, tc := range []struct {
+		name   string
+		filter FileFilter
+		want   string
+	}{
+		{"all", FileFilter{}, "00 01 02 03 04 05 06 07 08 09 10 11 12 13 14 15 16 17 18 19 20 21 22 23 24"},
+		{"state", FileFilter{States: []genai.FileState{genai.FileStateFailed}}, "00 05 10 15 20"},
+		{"mime", FileFilter{MIMETypes: []string{"video/*"}}, "01 04 07 10 13 16 19 22"},
+		{"mimes", FileFilter{MIMETypes: []string{"video/mp4", "application/pdf"}, MaxSize: 800}, "01 02 04 05 07 08"},
+		{"size", FileFilter{MinSize: 1000, MaxSize: 1200}, "10 11 12"},
+		{"created", FileFilter{CreatedAfter: epoch.Add(20 * time.Hour), CreatedBefore: epoch.Add(23 * time.Hour)}, "21 22"},
+		{"expires", FileFilter{ExpiresBefore: epoch.Add(51 * time.Hour)}, "00 01 02"},
+		{"expires after", FileFilter{ExpiresAfter: epoch.Add(70 * time.Hour)}, "23 24"},
+		{"name", FileFilter{DisplayName: "file-1?.png"}, "12 15 18"},
+		{"combined", FileFilter{States: []genai.FileState{genai.FileStateActive}, MIMETypes: []string{"image/*"}, MinSize: 1000}, "12 18 21 24"},
+		{"none", FileFilter{MIMETypes: []string{"audio/*"}}, ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r, err := Files(t.Context(), client, tc.filter, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := names(r.Files); got != tc.want {
+				t.Errorf("files = %s, want %s", got, tc.want)
+			}
+			if r.Listed != 25 {
+				t.Errorf("Listed = %d, want 25", r.Listed)
+			}
+			if r.Totals.Count != len(r.Files) {
+				t.Errorf("Totals.Count = %d, want %d", r.Totals.Count, len(r.Files))
+			}
+		})
+	}
+}
+
+func TestTotalFiles(t *testing.T) {
+	files := []File{
+		{MIMEType: "image/png", SizeBytes: 10, State: genai.FileStateActive},
+		{MIMEType: "image/png", SizeBytes: 20, State: genai.FileStateProcessing},
+		{MIMEType: "video/mp4", SizeBytes: 30, State: genai.FileStateActive},
+	}
+	tot := TotalFiles(files)
+	if tot.Count != 3 || tot.SizeBytes != 60 {
+		t.Errorf("totals = %d files, %d bytes, want 3 files, 60 bytes", tot.Count, tot.SizeBytes)
+	}
+	if tot.ByState[genai.FileStateActive] != 2 || tot.ByState[genai.FileStateProcessing] != 1 {
+		t.Errorf("ByState = %v", tot.ByState)
+	}
+	if tot.ByMIMEType["image/png"] != 2 || tot.ByMIMEType["video/mp4"] != 1 {
+		t.Errorf("ByMIMEType = %v", tot.ByMIMEType)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for _, tc := range []struct {
+		filter FileFilter
+		want   string
+	}{
+		{FileFilter{MIMETypes: []string{"image/["}}, "bad MIME type pattern"},
+		{FileFilter{DisplayName: "["}, "bad display name pattern"},
+		{FileFilter{MinSize: 10, MaxSize: 5}, "below minimum size"},
+		{FileFilter{CreatedAfter: epoch, CreatedBefore: epoch}, "creation time"},
+		{FileFilter{ExpiresAfter: epoch.Add(time.Hour), ExpiresBefore: epoch}, "expiration time"},
+	} {
+		err := tc.filter.Validate()
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("Validate(%+v) = %v, want an error containing %q", tc.filter, err, tc.want)
+		}
+	}
+	if err := (&FileFilter{MinSize: 10, MIMETypes: []string{"image/*"}}).Validate(); err != nil {
+		t.Errorf("Validate of a good filter = %v", err)
+	}
+}
@@ -0,0 +1,241 @@
+// Package janitor finds the files and caches of an account that match a
+// filter, such as those left behind by the samples, and deletes them.
+//
+// List pages through Files.List and Caches.List; Sweep lists, filters and
+// deletes in parallel under a rate limit:
+//
+//	report, err := janitor.Sweep(ctx, client, janitor.Filter{
+//		Prefix:    "sample-",
+//		OlderThan: 24 * time.Hour,
+//	}, janitor.Options{DryRun: true})
+package janitor
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"gemini-api-examples/internal/inventory"
+	"gemini-api-examples/internal/tracker"
+
+	"google.golang.org/genai"
+)
+
+// An Item is a file or cache of the account.
+type Item struct {
+	Kind tracker.Kind `json:"kind"`
+	// Name is the resource name, such as "files/abc".
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName,omitempty"`
+	// MIMEType is set for files.
+	MIMEType string `json:"mimeType,omitempty"`
+	// Model is set for caches, without "models/", for example
+	// "gemini-3.5-flash".
+	Model      string    `json:"model,omitempty"`
+	CreateTime time.Time `json:"createTime"`
+	SizeBytes  int64     `json:"sizeBytes,omitempty"`
+}
+
+// List returns the items of the given kinds, or of both kinds if kinds is
+// empty, listed as inventory.ListFiles and inventory.ListCaches do, going
+// through every page of pageSize items, or the API's default if pageSize
+// is 0. Files are not listed on Vertex AI, which has no Files API, unless
+// asked for explicitly.
+func List(ctx context.Context, client *genai.Client, kinds []tracker.Kind, pageSize int32) ([]Item, error) {
+	var items []Item
+	if wants(kinds, tracker.KindFile) && (len(kinds) > 0 || client.ClientConfig().Backend != genai.BackendVertexAI) {
+		files, err := inventory.ListFiles(ctx, client, pageSize)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range files {
+			items = append(items, Item{Kind: tracker.KindFile, Name: f.Name, DisplayName: f.DisplayName, MIMEType: f.MIMEType, CreateTime: f.CreateTime, SizeBytes: f.SizeBytes})
+		}
+	}
+	if wants(kinds, tracker.KindCache) {
+		caches, err := inventory.ListCaches(ctx, client, pageSize)
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range caches {
+			items = append(items, Item{Kind: tracker.KindCache, Name: c.Name, DisplayName: c.DisplayName, Model: c.Model, CreateTime: c.CreateTime})
+		}
+	}
+	return items, nil
+}
+
+func wants(kinds []tracker.Kind, k tracker.Kind) bool {
+	return len(kinds) == 0 || slices.Contains(kinds, k)
+}
+
+// A Filter selects items. An item matches when it meets every criterion
+// set. MIME types only match files and models only match caches, so
+// setting either leaves out the other kind.
+type Filter struct {
+	// Kinds lists the kinds of items to select; empty means both.
+	Kinds []tracker.Kind
+	// Prefix is a prefix of the display name.
+	Prefix string
+	// OlderThan is the minimum age of the items.
+	OlderThan time.Duration
+	// MIMETypes lists patterns, as in path.Match, for the MIME type of
+	// files, for example "image/*".
+	MIMETypes []string
+	// Models lists the models of caches, with or without the "models/"
+	// prefix.
+	Models []string
+	// Now returns the current time, against which ages are measured. It
+	// defaults to time.Now.
+	Now func() time.Time
+}
+
+// Validate reports malformed MIME type patterns.
+func (f *Filter) Validate() error {
+	for _, p := range f.MIMETypes {
+		if _, err := path.Match(p, ""); err != nil {
+			return fmt.Errorf("bad MIME type pattern %q: %v", p, err)
+		}
+	}
+	return nil
+}
+
+// Empty reports whether f selects items by kind only, which would sweep
+// everything of those kinds.
+func (f *Filter) Empty() bool {
+	return f.Prefix == "" && f.OlderThan == 0 && len(f.MIMETypes) == 0 && len(f.Models) == 0
+}
+
+// Match reports whether it meets the criteria of f.
+func (f *Filter) Match(it Item) bool {
+	if !wants(f.Kinds, it.Kind) || !strings.HasPrefix(it.DisplayName, f.Prefix) {
+		return false
+	}
+	if f.OlderThan > 0 {
+		now := time.Now
+		if f.Now != nil {
+			now = f.Now
+		}
+		if it.CreateTime.IsZero() || now().Sub(it.CreateTime) < f.OlderThan {
+			return false
+		}
+	}
+	if len(f.MIMETypes) > 0 {
+		if it.Kind != tracker.KindFile || !slices.ContainsFunc(f.MIMETypes, func(p string) bool {
+			ok, _ := path.Match(p, it.MIMEType)
+			return ok
+		}) {
+			return false
+		}
+	}
+	if len(f.Models) > 0 {
+		if it.Kind != tracker.KindCache || !slices.ContainsFunc(f.Models, func(m string) bool {
+			return inventory.ModelID(m) == inventory.ModelID(it.Model)
+		}) {
+			return false
+		}
+	}
+	return true
+}
+
+// Options controls how Sweep deletes.
+type Options struct {
+	// DryRun only reports what would be deleted.
+	DryRun bool
+	// Parallel is the number of concurrent deletions, 1 if not positive.
+	Parallel int
+	// Rate is the maximum number of deletions started per second, without
+	// limit if not positive.
+	Rate float64
+	// PageSize is the page size for listing, or 0 for the API's default.
+	PageSize int32
+	// Progress, if not nil, is called after each deletion, from the
+	// goroutine that did it, with its error, if any.
+	Progress func(it Item, err error)
+}
+
+// A Failure is an item that could not be deleted.
+type Failure struct {
+	Item
+	Err error `json:"-"`
+	// Error is the text of Err, for JSON.
+	Error string `json:"error"`
+}
+
+// A Report is the outcome of a sweep.
+type Report struct {
+	// Listed is the number of items listed.
+	Listed int `json:"listed"`
+	// Selected holds the items matching the filter, in listing order.
+	Selected []Item `json:"selected"`
+	// Deleted holds the items deleted, or that a dry run would delete, in
+	// listing order.
+	Deleted []Item `json:"deleted"`
+	// Failed holds the items that could not be deleted.
+	Failed []Failure `json:"failed"`
+	DryRun bool      `json:"dryRun"`
+}
+
+// Sweep lists the items selected by f and deletes them, caches first since
+// they may refer to files. Items that disappear in the meantime count as
+// deleted. The error is only about listing; deletion failures are in the
+// report.
+func Sweep(ctx context.Context, client *genai.Client, f Filter, opts Options) (*Report, error) {
+	if err := f.Validate(); err != nil {
+		return nil, err
+	}
+	items, err := List(ctx, client, f.Kinds, opts.PageSize)
+	if err != nil {
+		return nil, err
+	}
+	r := &Report{Listed: len(items), Selected: []Item{}, Deleted: []Item{}, Failed: []Failure{}, DryRun: opts.DryRun}
+	for _, it := range items {
+		if f.Match(it) {
+			r.Selected = append(r.Selected, it)
+		}
+	}
+	if opts.DryRun {
+		r.Deleted = append(r.Deleted, r.Selected...)
+		return r, nil
+	}
+
+	// Caches go first, since they may refer to files.
+	errs := make([]error, len(r.Selected))
+	bulk := inventory.BulkOptions{Parallel: opts.Parallel, Rate: opts.Rate}
+	for _, kind := range []tracker.Kind{tracker.KindCache, tracker.KindFile} {
+		var batch []int
+		for i, it := range r.Selected {
+			if it.Kind == kind {
+				batch = append(batch, i)
+			}
+		}
+		batchErrs := inventory.Each(ctx, batch, bulk, func(i *int) error {
+			it := r.Selected[*i]
+			err := deleteItem(ctx, client, it)
+			if opts.Progress != nil {
+				opts.Progress(it, err)
+			}
+			return err
+		})
+		for j, i := range batch {
+			errs[i] = batchErrs[j]
+		}
+	}
+	for i, it := range r.Selected {
+		if errs[i] != nil {
+			r.Failed = append(r.Failed, Failure{Item: it, Err: errs[i], Error: errs[i].Error()})
+		} else {
+			r.Deleted = append(r.Deleted, it)
+		}
+	}
+	return r, nil
+}
+
+func deleteItem(ctx context.Context, client *genai.Client, it Item) error {
+	if it.Kind == tracker.KindCache {
+		return inventory.DeleteCache(ctx, client, it.Name)
+	}
+	return inventory.DeleteFile(ctx, client, it.Name)
+}
@@ -0,0 +1,273 @@
+package janitor
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gemini-api-examples/internal/config"
+	"gemini-api-examples/internal/fakegemini"
+	"gemini-api-examples/internal/tracker"
+
+	"google.golang.org/genai"
+)
+
+var now = time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+
+var mimeTypes = []string{"text/plain", "image/jpeg", "application/pdf"}
+
+// newFake returns a fake server holding 300 files and 200 caches, and a
+// client talking to it through transport, if not nil. Even-numbered items
+// have display names starting with "sample-", the others with "keep-";
+// item i was created i hours before now.
+func newFake(t *testing.T, transport http.RoundTripper) (*fakegemini.Server, *genai.Client) {
+	t.Helper()
+	srv := fakegemini.New()
+	t.Cleanup(srv.Close)
+	srv.SetClock(func() time.Time { return now })
+	name := func(i int) string {
+		if i%2 == 0 {
+			return fmt.Sprintf("sample-%d", i)
+		}
+		return fmt.Sprintf("keep-%d", i)
+	}
+	for i := range 300 {
+		srv.AddFile(&genai.File{
+			DisplayName: name(i),
+			MIMEType:    mimeTypes[i%len(mimeTypes)],
+			CreateTime:  now.Add(-time.Duration(i) * time.Hour),
+		}, []byte("data"))
+	}
+	for i := range 200 {
+		model := "models/gemini-3.5-flash"
+		if i%4 == 3 {
+			model = "models/gemini-3.5-pro"
+		}
+		srv.AddCache(&genai.CachedContent{
+			DisplayName: name(i),
+			Model:       model,
+			CreateTime:  now.Add(-time.Duration(i) * time.Hour),
+			ExpireTime:  now.Add(time.Hour),
+		})
+	}
+	cfg := &config.Config{APIKey: "fake", BaseURL: srv.URL}
+	cc := cfg.ClientConfig()
+	if transport != nil {
+		cc.HTTPClient = &http.Client{Transport: transport}
+	}
+	client, err := genai.NewClient(context.Background(), cc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return srv, client
+}
+
+func TestList(t *testing.T) {
+	srv, client := newFake(t, nil)
+	items, err := List(t.Context(), client, nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var files, caches int
+	for _, it := range items {
+		switch it.Kind {
+		case tracker.KindFile:
+			files++
+		case tracker.KindCache:
+			caches++
+		}
+	}
+	if files != 300 || caches != 200 {
+		t.Errorf("listed %d files and %d caches, want 300 and 200", files, caches)
+	}
+	// The API's default page size is 10.
+	if n := len(srv.RequestsTo(http.MethodGet, "files")); n != 30 {
+		t.Errorf("%d pages of files, want 30", n)
+	}
+	if n := len(srv.RequestsTo(http.MethodGet, "cachedContents")); n != 20 {
+		t.Errorf("%d pages of caches, want 20", n)
+	}
+
+	items, err = List(t.Context(), client, []tracker.Kind{tracker.KindCache}, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 200 || items[0].Model != "gemini-3.5-flash" || items[0].DisplayName != "sample-0" {
+		t.Errorf("listed %d caches, the first being %+v", len(items), items[0])
+	}
+}
+
+func TestMatch(t *testing.T) {
+	file := Item{Kind: tracker.KindFile, DisplayName: "sample-1", MIMEType: "image/jpeg", CreateTime: now.Add(-2 * time.Hour)}
+	cache := Item{Kind: tracker.KindCache, DisplayName: "sample-2", Model: "models/gemini-3.5-flash", CreateTime: now.Add(-30 * time.Minute)}
+	clock := func() time.Time { return now }
+	for _, tt := range []struct {
+		name        string
+		f           Filter
+		file, cache bool
+	}{
+		{"empty", Filter{}, true, true},
+		{"kind", Filter{Kinds: []tracker.Kind{tracker.KindCache}}, false, true},
+		{"prefix", Filter{Prefix: "sample-1"}, true, false},
+		{"older", Filter{OlderThan: time.Hour, Now: clock}, true, false},
+		{"mime", Filter{MIMETypes: []string{"image/*"}}, true, false},
+		{"other mime", Filter{MIMETypes: []string{"text/plain"}}, false, false},
+		{"model", Filter{Models: []string{"gemini-3.5-flash"}}, false, true},
+		{"full model", Filter{Models: []string{"models/gemini-3.5-flash"}}, false, true},
+		{"vertex model", Filter{Models: []string{"projects/p/locations/l/publishers/google/models/gemini-3.5-flash"}}, false, true},
+		{"all criteria", Filter{Prefix: "sample-", OlderThan: time.Hour, MIMETypes: []string{"image/jpeg"}, Now: clock}, true, false},
+	} {
+		if got := tt.f.Match(file); got != tt.file {
+			t.Errorf("%s: Match(file) = %v, want %v", tt.name, got, tt.file)
+		}
+		if got := tt.f.Match(cache); got != tt.cache {
+			t.Errorf("%s: Match(cache) = %v, want %v", tt.name, got, tt.cache)
+		}
+	}
+	if err := (&Filter{MIMETypes: []string{"image/["}}).Validate(); err == nil {
+		t.Error("Validate accepted a malformed pattern")
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	srv, client := newFake(t, nil)
+	f := Filter{Prefix: "sample-", Now: func() time.Time { return now }, OlderThan: 100 * time.Hour}
+	r, err := Sweep(t.Context(), client, f, Options{DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Even items from 100 on: 100 files and 50 caches.
+	if r.Listed != 500 || len(r.Selected) != 150 || len(r.Deleted) != 150 || !r.DryRun {
+		t.Errorf("report: listed %d, selected %d, deleted %d", r.Listed, len(r.Selected), len(r.Deleted))
+	}
+	if n := len(srv.RequestsTo(http.MethodDelete, "")); n != 0 {
+		t.Errorf("dry run sent %d deletions", n)
+	}
+}
+
+func TestSweep(t *testing.T) {
+	srv, client := newFake(t, nil)
+	f := Filter{Prefix: "sample-", OlderThan: 100 * time.Hour, Now: func() time.Time { return now }}
+	r, err := Sweep(t.Context(), client, f, Options{Parallel: 8})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Deleted) != 150 || len(r.Failed) != 0 {
+		t.Errorf("deleted %d, failed %v; want 150 deleted", len(r.Deleted), r.Failed)
+	}
+	for _, file := range srv.Files() {
+		if strings.HasPrefix(file.DisplayName, "sample-") && now.Sub(file.CreateTime) >= 100*time.Hour {
+			t.Errorf("file %s (%s) was not deleted", file.Name, file.DisplayName)
+		}
+	}
+	if files, caches := len(srv.Files()), len(srv.Caches()); files != 200 || caches != 150 {
+		t.Errorf("%d files and %d caches left, want 200 and 150", files, caches)
+	}
+	// Caches go first, since they may refer to files.
+	deletes := srv.RequestsTo(http.MethodDelete, "")
+	lastCache := slices.IndexFunc(deletes, func(r *fakegemini.Request) bool { return strings.Contains(r.Path, "files/") })
+	if lastCache != 50 {
+		t.Errorf("first file deletion at %d, want 50, after every cache", lastCache)
+	}
+}
+
+func TestSweepModelAndMIME(t *testing.T) {
+	srv, client := newFake(t, nil)
+	r, err := Sweep(t.Context(), client, Filter{Models: []string{"gemini-3.5-pro"}}, Options{Parallel: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Deleted) != 50 || len(srv.Files()) != 300 {
+		t.Errorf("deleted %d caches and %d files, want 50 caches only", len(r.Deleted), 300-len(srv.Files()))
+	}
+	r, err = Sweep(t.Context(), client, Filter{MIMETypes: []string{"application/pdf", "text/*"}}, Options{Parallel: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Deleted) != 200 || len(srv.Caches()) != 150 {
+		t.Errorf("deleted %d files and %d caches, want 200 files only", len(r.Deleted), 150-len(srv.Caches()))
+	}
+	for _, f := range srv.Files() {
+		if f.MIMEType != "image/jpeg" {
+			t.Errorf("file %s of type %s left", f.Name, f.MIMEType)
+		}
+	}
+}
+
+// inflight counts the DELETE requests in flight, holding each a little so
+// that they overlap.
+type inflight struct {
+	mu       sync.Mutex
+	now, max int
+}
+
+func (c *inflight) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodDelete {
+		return http.DefaultTransport.RoundTrip(req)
+	}
+	c.mu.Lock()
+	c.now++
+	c.max = max(c.max, c.now)
+	c.mu.Unlock()
+	time.Sleep(20 * time.Millisecond)
+	defer func() {
+		c.mu.Lock()
+		c.now--
+		c.mu.Unlock()
+	}()
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestParallelRateLimit(t *testing.T) {
+	counter := &inflight{}
+	_, client := newFake(t, counter)
+	f := Filter{Kinds: []tracker.Kind{tracker.KindCache}, Prefix: "sample-1"}
+	var progress []string
+	var mu sync.Mutex
+	start := time.Now()
+	r, err := Sweep(t.Context(), client, f, Options{
+		Parallel: 4,
+		Rate:     500,
+		Progress: func(it Item, err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			progress = append(progress, it.Name)
+		},
+	})
+	elapsed := time.Since(start)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// sample-1, sample-10 to sample-18 and sample-100 to sample-198.
+	if n := len(r.Deleted); n != 55 || len(progress) != n {
+		t.Fatalf("deleted %d caches with %d progress calls, want 55", n, len(progress))
+	}
+	if counter.max < 2 || counter.max > 4 {
+		t.Errorf("%d deletions in flight at most, want 2 to 4", counter.max)
+	}
+	// At 500 per second, 55 deletions take at least 110ms.
+	if elapsed < 100*time.Millisecond {
+		t.Errorf("55 deletions took %v despite the rate limit", elapsed)
+	}
+}
+
+func TestFailures(t *testing.T) {
+	srv, client := newFake(t, nil)
+	victim := srv.Files()[4]
+	srv.Inject(victim.Name, 0, fakegemini.ServerError(http.StatusServiceUnavailable))
+	r, err := Sweep(t.Context(), client, Filter{Kinds: []tracker.Kind{tracker.KindFile}, Prefix: "sample-"}, Options{Parallel: 8})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Failed) != 1 || r.Failed[0].Name != victim.Name || r.Failed[0].Error == "" {
+		t.Errorf("failed = %+v, want %s", r.Failed, victim.Name)
+	}
+	if len(r.Deleted) != 149 {
+		t.Errorf("deleted %d, want 149", len(r.Deleted))
+	}
+}
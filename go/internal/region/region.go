@@ -0,0 +1,302 @@
+// Package region finds the region-tagged snippets in the samples of every
+// language in the repository.
+//
+// A region is the code between a START and an END marker comment naming the
+// same tag, each alone on its line, for example in Go:
+//
+//	// [START cache_create]
+//	...
+//	// [END cache_create]
+//
+// Python and the REST samples use # comments instead. Regions may nest;
+// marker lines are never part of a region's code.
+package region
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// A Language describes where the samples of one language live.
+type Language struct {
+	// Name is the key used for the language in output, for example "go".
+	Name string
+	// Title is the human-readable name, for example "JavaScript".
+	Title string
+	// Dir is the directory holding the samples, relative to the
+	// repository root.
+	Dir string
+	// Exts lists the extensions of the source files.
+	Exts []string
+	// Comment starts a line comment in the language.
+	Comment string
+	// Fence is the Markdown code fence language.
+	Fence string
+}
+
+// Languages lists the languages of the repository, in display order.
+var Languages = []Language{
+	{Name: "go", Title: "Go", Dir: "go", Exts: []string{".go"}, Comment: "//", Fence: "go"},
+	{Name: "python", Title: "Python", Dir: "python", Exts: []string{".py"}, Comment: "#", Fence: "python"},
+	{Name: "javascript", Title: "JavaScript", Dir: "javascript", Exts: []string{".js", ".mjs", ".ts"}, Comment: "//", Fence: "javascript"},
+	{Name: "java", Title: "Java", Dir: "java", Exts: []string{".java"}, Comment: "//", Fence: "java"},
+	{Name: "rest", Title: "REST", Dir: "rest", Exts: []string{".sh"}, Comment: "#", Fence: "bash"},
+}
+
+// LanguageByName returns the language with the given name.
+func LanguageByName(name string) (Language, bool) {
+	i := slices.IndexFunc(Languages, func(l Language) bool { return l.Name == name })
+	if i < 0 {
+		return Language{}, false
+	}
+	return Languages[i], true
+}
+
+// ParseLanguages returns the languages named in list, separated by commas,
+// or all of them if list is empty.
+func ParseLanguages(list string) ([]Language, error) {
+	if list == "" {
+		return Languages, nil
+	}
+	var langs []Language
+	for _, name := range strings.Split(list, ",") {
+		l, ok := LanguageByName(strings.TrimSpace(name))
+		if !ok {
+			return nil, fmt.Errorf("unknown language %q", name)
+		}
+		langs = append(langs, l)
+	}
+	return langs, nil
+}
+
+// FindRoot returns the repository root: the nearest directory at or above
+// the current one that holds the Go samples.
+func FindRoot() (string, error) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	for {
+		if _, err := os.Stat(filepath.Join(dir, "go", "go.mod")); err == nil {
+			return dir, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", errors.New("cannot find the repository root; use -root")
+		}
+		dir = parent
+	}
+}
+
+// A Region is one tagged snippet.
+type Region struct {
+	Tag      string `json:"-"`
+	Language string `json:"-"`
+	// File is the slash-separated path of the source file, relative to the
+	// repository root.
+	File string `json:"file"`
+	// StartLine and EndLine are the lines of the START and END markers.
+	StartLine int `json:"startLine"`
+	EndLine   int `json:"endLine"`
+	// Code is the region's code with its common indentation removed.
+	Code string `json:"code"`
+}
+
+// A Problem is a malformed marker or region.
+type Problem struct {
+	File string
+	Line int
+	// Tag is the tag of the region concerned.
+	Tag string
+	Msg string
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("%s:%d: %s", p.File, p.Line, p.Msg)
+}
+
+// Marker reports whether line is a region marker in a language whose line
+// comments start with comment, and if so returns its kind, START or END, and
+// its tag.
+func Marker(line, comment string) (kind, tag string, ok bool) {
+	rest, ok := strings.CutPrefix(strings.TrimSpace(line), comment)
+	if !ok {
+		return "", "", false
+	}
+	m := markerRE.FindStringSubmatch(rest)
+	if m == nil {
+		return "", "", false
+	}
+	return m[1], m[2], true
+}
+
+var markerRE = regexp.MustCompile(`^\s*\[(START|END)\s+([^\]\s]+)\s*\]$`)
+
+// Parse returns the regions of src, a file of the given language named file.
+// Regions that are not properly closed are reported as problems and left out.
+func Parse(file string, src []byte, lang Language) ([]Region, []Problem) {
+	type open struct {
+		tag   string
+		line  int
+		lines []string
+	}
+	var (
+		stack    []*open
+		regions  []Region
+		problems []Problem
+	)
+	sc := bufio.NewScanner(bytes.NewReader(src))
+	sc.Buffer(nil, 1<<20)
+	for n := 1; sc.Scan(); n++ {
+		line := sc.Text()
+		kind, tag, ok := Marker(line, lang.Comment)
+		if !ok {
+			for _, o := range stack {
+				o.lines = append(o.lines, line)
+			}
+			continue
+		}
+		i := slices.IndexFunc(stack, func(o *open) bool { return o.tag == tag })
+		switch {
+		case kind == "START" && i >= 0:
+			problems = append(problems, Problem{file, n, tag, fmt.Sprintf("START %s while the region started at line %d is open; should this be END?", tag, stack[i].line)})
+		case kind == "START":
+			stack = append(stack, &open{tag: tag, line: n})
+		case i < 0:
+			problems = append(problems, Problem{file, n, tag, fmt.Sprintf("END %s without a START", tag)})
+		default:
+			o := stack[i]
+			stack = slices.Delete(stack, i, i+1)
+			code := Dedent(o.lines)
+			if code == "" {
+				problems = append(problems, Problem{file, o.line, tag, fmt.Sprintf("region %s is empty", tag)})
+				continue
+			}
+			regions = append(regions, Region{
+				Tag:       tag,
+				Language:  lang.Name,
+				File:      file,
+				StartLine: o.line,
+				EndLine:   n,
+				Code:      code,
+			})
+		}
+	}
+	for _, o := range stack {
+		problems = append(problems, Problem{file, o.line, o.tag, fmt.Sprintf("region %s has no END", o.tag)})
+	}
+	slices.SortFunc(regions, func(a, b Region) int { return a.StartLine - b.StartLine })
+	return regions, problems
+}
+
+// Dedent joins lines after trimming leading and trailing blank lines and
+// removing the whitespace prefix the remaining non-blank lines share.
+func Dedent(lines []string) string {
+	for len(lines) > 0 && strings.TrimSpace(lines[0]) == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	prefix, first := "", true
+	for _, l := range lines {
+		if strings.TrimSpace(l) == "" {
+			continue
+		}
+		indent := l[:len(l)-len(strings.TrimLeft(l, " \t"))]
+		if first {
+			prefix, first = indent, false
+			continue
+		}
+		for !strings.HasPrefix(indent, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	var b strings.Builder
+	for _, l := range lines {
+		l = strings.TrimRight(l, " \t\r")
+		b.WriteString(strings.TrimPrefix(l, prefix))
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// Extract returns the regions of the given languages found under root, the
+// repository root, sorted by tag and then in the order of Languages, and
+// the problems found, sorted by position. A tag
+// is expected once per language: later regions with the same tag are
+// reported as problems and left out.
+//
+// Directories named testdata or node_modules, hidden directories and Go test
+// files are skipped.
+func Extract(root string, langs []Language) ([]Region, []Problem, error) {
+	var (
+		regions  []Region
+		problems []Problem
+	)
+	for _, lang := range langs {
+		seen := map[string]Region{}
+		dir := filepath.Join(root, lang.Dir)
+		err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			name := d.Name()
+			if d.IsDir() {
+				if path != dir && (name == "testdata" || name == "node_modules" || strings.HasPrefix(name, ".")) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !slices.Contains(lang.Exts, filepath.Ext(name)) || strings.HasSuffix(name, "_test.go") {
+				return nil
+			}
+			src, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			rel, err := filepath.Rel(root, path)
+			if err != nil {
+				return err
+			}
+			rs, ps := Parse(filepath.ToSlash(rel), src, lang)
+			problems = append(problems, ps...)
+			for _, r := range rs {
+				if prev, ok := seen[r.Tag]; ok {
+					problems = append(problems, Problem{r.File, r.StartLine, r.Tag, fmt.Sprintf("duplicate region %s; first defined at %s:%d", r.Tag, prev.File, prev.StartLine)})
+					continue
+				}
+				seen[r.Tag] = r
+				regions = append(regions, r)
+			}
+			return nil
+		})
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, nil, err
+		}
+	}
+	slices.SortStableFunc(problems, func(a, b Problem) int {
+		if c := strings.Compare(a.File, b.File); c != 0 {
+			return c
+		}
+		return a.Line - b.Line
+	})
+	order := func(name string) int {
+		return slices.IndexFunc(Languages, func(l Language) bool { return l.Name == name })
+	}
+	slices.SortStableFunc(regions, func(a, b Region) int {
+		if c := strings.Compare(a.Tag, b.Tag); c != 0 {
+			return c
+		}
+		return order(a.Language) - order(b.Language)
+	})
+	return regions, problems, nil
+}
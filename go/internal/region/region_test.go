@@ -0,0 +1,124 @@
+package region
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var goLang, _ = LanguageByName("go")
+
+func TestParse(t *testing.T) {
+	src := `package x
+
+func f() {
+	// [START outer]
+	a := 1
+	//[START inner]
+	b := 2
+	// [END inner]
+	_ = a + b // [END outer] is not a marker here
+	// [END outer]
+}
+`
+	regions, problems := Parse("x.go", []byte(src), goLang)
+	if len(problems) != 0 {
+		t.Errorf("problems = %v", problems)
+	}
+	want := []Region{
+		{Tag: "outer", Language: "go", File: "x.go", StartLine: 4, EndLine: 10,
+			Code: "a := 1\nb := 2\n_ = a + b // [END outer] is not a marker here\n"},
+		{Tag: "inner", Language: "go", File: "x.go", StartLine: 6, EndLine: 8, Code: "b := 2\n"},
+	}
+	if len(regions) != len(want) {
+		t.Fatalf("got %d regions, want %d", len(regions), len(want))
+	}
+	for i := range want {
+		if regions[i] != want[i] {
+			t.Errorf("region %d = %+v, want %+v", i, regions[i], want[i])
+		}
+	}
+}
+
+func TestParseProblems(t *testing.T) {
+	py, _ := LanguageByName("python")
+	src := `# [START a]
+x = 1
+# [START a]
+# [END b]
+# [START empty]
+
+# [END empty]
+`
+	regions, problems := Parse("x.py", []byte(src), py)
+	if len(regions) != 0 {
+		t.Errorf("regions = %+v, want none", regions)
+	}
+	var got []string
+	for _, p := range problems {
+		got = append(got, p.String())
+	}
+	want := []string{
+		"x.py:3: START a while the region started at line 1 is open; should this be END?",
+		"x.py:4: END b without a START",
+		"x.py:5: region empty is empty",
+		"x.py:1: region a has no END",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("problems:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestDedent(t *testing.T) {
+	tests := []struct {
+		lines []string
+		want  string
+	}{
+		{nil, ""},
+		{[]string{"", "\t\ta", "", "\t\t\tb  ", "\t\tc", " "}, "a\n\n\tb\nc\n"},
+		{[]string{"    x", "  y", "      z"}, "  x\ny\n    z\n"},
+		{[]string{"\tx", "    y"}, "\tx\n    y\n"},
+	}
+	for _, tt := range tests {
+		if got := Dedent(tt.lines); got != tt.want {
+			t.Errorf("Dedent(%q) = %q, want %q", tt.lines, got, tt.want)
+		}
+	}
+}
+
+func TestExtract(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"go/a.go":                 "// [START t]\nA\n// [END t]\n",
+		"go/b.go":                 "// [START t]\nB\n// [END t]\n// [START s]\nS\n// [END s]\n",
+		"go/a_test.go":            "// [START test]\nT\n// [END test]\n",
+		"go/testdata/x.go":        "// [START fixture]\nF\n// [END fixture]\n",
+		"python/a.py":             "# [START t]\nP\n# [END t]\n",
+		"python/notes.txt":        "# [START txt]\nN\n# [END txt]\n",
+		"javascript/.hidden/a.js": "// [START hidden]\nH\n// [END hidden]\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	regions, problems, err := Extract(root, Languages)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, r := range regions {
+		got = append(got, r.Tag+"/"+r.Language+"/"+strings.TrimSpace(r.Code))
+	}
+	if want := "s/go/S t/go/A t/python/P"; strings.Join(got, " ") != want {
+		t.Errorf("regions = %s, want %s", strings.Join(got, " "), want)
+	}
+	if len(problems) != 1 || problems[0].String() != "go/b.go:1: duplicate region t; first defined at go/a.go:1" {
+		t.Errorf("problems = %v", problems)
+	}
+}
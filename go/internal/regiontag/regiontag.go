@@ -0,0 +1,464 @@
+// Package regiontag defines an Analyzer that checks the region tags of the
+// Go samples.
+//
+// Documentation embeds the code between a START and an END marker comment,
+// such as
+//
+//	// [START files_delete]
+//	...
+//	// [END files_delete]
+//
+// so each region must be a well-formed, tested snippet that makes sense on
+// its own. The analyzer reports:
+//
+//   - START and END markers that do not pair up, overlap or repeat a tag
+//     already used in the package;
+//   - regions outside a function body, or whose markers are not in the same
+//     block, or fall inside a statement;
+//   - unreachable code in a region, and regions ending with a return
+//     statement, which leave the rest of the sample unreachable;
+//   - regions without a test: a Test function named after the function
+//     holding the region, such as TestFilesDelete for FilesDelete, or one
+//     calling it;
+//   - regions that do not compile as a standalone snippet importing only the
+//     packages the region uses.
+//
+// A snippet may use the package-level helpers listed in the -provide flag,
+// such as getMedia, without declaring them; anything else it uses, the
+// parameters of its function included, it must declare, as a reader
+// copying it would have to.
+package regiontag
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gemini-api-examples/internal/region"
+	"golang.org/x/tools/go/analysis"
+)
+
+const doc = `check the region tags of the samples
+
+The regiontag analyzer checks that the START and END markers delimiting
+documentation snippets pair up and nest properly, that tags are unique, that
+every region is tested, has no unreachable code and compiles on its own.`
+
+// Analyzer checks the region tags of a package.
+var Analyzer = &analysis.Analyzer{
+	Name: "regiontag",
+	Doc:  doc,
+	Run:  run,
+}
+
+var provide = "getMedia,printResponse,checkFinished"
+
+func init() {
+	Analyzer.Flags.StringVar(&provide, "provide", provide,
+		"comma-separated package-level identifiers a snippet may use without declaring them")
+}
+
+// A marker is a START or END comment.
+type marker struct {
+	kind, tag string
+	pos       token.Pos
+	line      int
+}
+
+// A span is a region with well-paired markers.
+type span struct {
+	tag        string
+	start, end marker
+	file       *ast.File
+	src        []byte
+}
+
+func run(pass *analysis.Pass) (any, error) {
+	var spans []*span
+	for _, f := range pass.Files {
+		name := pass.Fset.File(f.Pos()).Name()
+		if strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		src, err := pass.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+		spans = append(spans, pair(pass, f, src)...)
+	}
+	if len(spans) == 0 {
+		return nil, nil
+	}
+	tests, err := loadTests(pass)
+	if err != nil {
+		return nil, err
+	}
+	seen := map[string]*span{}
+	for _, sp := range spans {
+		if prev, ok := seen[sp.tag]; ok {
+			p := pass.Fset.Position(prev.start.pos)
+			pass.Reportf(sp.start.pos, "duplicate region %s; first defined at %s:%d", sp.tag, filepath.Base(p.Filename), p.Line)
+			continue
+		}
+		seen[sp.tag] = sp
+		check(pass, sp, tests)
+	}
+	return nil, nil
+}
+
+// pair finds the markers of f and pairs them into spans, reporting those
+// that do not pair up.
+func pair(pass *analysis.Pass, f *ast.File, src []byte) []*span {
+	tf := pass.Fset.File(f.Pos())
+	var (
+		open  []marker
+		spans []*span
+	)
+	for i, line := range bytes.Split(src, []byte("\n")) {
+		kind, tag, ok := region.Marker(string(line), "//")
+		if !ok {
+			continue
+		}
+		n := i + 1
+		m := marker{kind: kind, tag: tag, line: n, pos: tf.LineStart(n) + token.Pos(bytes.Index(line, []byte("//")))}
+		j := slices.IndexFunc(open, func(o marker) bool { return o.tag == tag })
+		switch {
+		case kind == "START" && j >= 0:
+			pass.Reportf(m.pos, "START %s while the region started at line %d is open; should this be END?", tag, open[j].line)
+		case kind == "START":
+			open = append(open, m)
+		case j < 0:
+			pass.Reportf(m.pos, "END %s without a START", tag)
+		default:
+			if inner := open[len(open)-1]; j != len(open)-1 {
+				pass.Reportf(m.pos, "region %s ends inside region %s started at line %d", tag, inner.tag, inner.line)
+			}
+			spans = append(spans, &span{tag: tag, start: open[j], end: m, file: f, src: src})
+			open = slices.Delete(open, j, j+1)
+		}
+	}
+	for _, o := range open {
+		pass.Reportf(o.pos, "region %s has no END", o.tag)
+	}
+	return spans
+}
+
+// check applies the per-region checks to sp.
+func check(pass *analysis.Pass, sp *span, tests *tests) {
+	fn := enclosingFunc(sp.file, sp.start.pos)
+	if fn == nil || fn != enclosingFunc(sp.file, sp.end.pos) {
+		pass.Reportf(sp.start.pos, "region %s is not inside a single function body", sp.tag)
+		return
+	}
+	list, ok := statements(pass, sp, fn)
+	if !ok {
+		return
+	}
+	for _, s := range list {
+		unreachable(pass, sp.tag, s)
+	}
+	if n := len(list); n > 0 {
+		if _, ok := list[n-1].(*ast.ReturnStmt); ok {
+			pass.Reportf(sp.end.pos, "region %s ends with a return statement; end the region before it so the rest of the sample stays reachable", sp.tag)
+		}
+	}
+	if name := fn.Name.Name; !tests.names["Test"+name] && !tests.calls[name] {
+		pass.Reportf(sp.start.pos, "region %s has no test: add Test%s or a test calling %s", sp.tag, name, name)
+	}
+	compile(pass, sp, fn)
+}
+
+func enclosingFunc(f *ast.File, pos token.Pos) *ast.FuncDecl {
+	for _, d := range f.Decls {
+		if fn, ok := d.(*ast.FuncDecl); ok && fn.Body != nil && fn.Body.Lbrace < pos && pos < fn.Body.Rbrace {
+			return fn
+		}
+	}
+	return nil
+}
+
+// statements returns the statements of the region, after checking that its
+// markers are in the same statement list and outside its statements.
+func statements(pass *analysis.Pass, sp *span, fn *ast.FuncDecl) ([]ast.Stmt, bool) {
+	block, stmts := innermostList(fn.Body, sp.start.pos)
+	if endBlock, _ := innermostList(fn.Body, sp.end.pos); endBlock != block {
+		pass.Reportf(sp.end.pos, "END %s is not in the block of its START at line %d", sp.tag, sp.start.line)
+		return nil, false
+	}
+	var list []ast.Stmt
+	ok := true
+	for _, s := range stmts {
+		for _, m := range []marker{sp.start, sp.end} {
+			if s.Pos() < m.pos && m.pos < s.End() {
+				pass.Reportf(m.pos, "%s %s is inside a statement", m.kind, sp.tag)
+				ok = false
+			}
+		}
+		if sp.start.pos < s.Pos() && s.End() < sp.end.pos {
+			list = append(list, s)
+		}
+	}
+	return list, ok
+}
+
+// innermostList returns the innermost block or case clause of body that
+// contains pos, and its statements.
+func innermostList(body *ast.BlockStmt, pos token.Pos) (ast.Node, []ast.Stmt) {
+	var (
+		block ast.Node
+		list  []ast.Stmt
+	)
+	ast.Inspect(body, func(n ast.Node) bool {
+		if n == nil || pos < n.Pos() || n.End() <= pos {
+			return false
+		}
+		if l, ok := stmtList(n); ok {
+			block, list = n, l
+		}
+		return true
+	})
+	return block, list
+}
+
+func stmtList(n ast.Node) ([]ast.Stmt, bool) {
+	switch n := n.(type) {
+	case *ast.BlockStmt:
+		return n.List, true
+	case *ast.CaseClause:
+		return n.Body, true
+	case *ast.CommClause:
+		return n.Body, true
+	}
+	return nil, false
+}
+
+// unreachable reports the statements of s and its nested blocks that follow
+// a return, a panic or a jump in the same statement list.
+func unreachable(pass *analysis.Pass, tag string, s ast.Stmt) {
+	ast.Inspect(s, func(n ast.Node) bool {
+		l, ok := stmtList(n)
+		if !ok {
+			return true
+		}
+		for i, s := range l[:max(len(l)-1, 0)] {
+			if _, labeled := l[i+1].(*ast.LabeledStmt); terminates(s) && !labeled {
+				pass.Reportf(l[i+1].Pos(), "unreachable code in region %s", tag)
+				break
+			}
+		}
+		return true
+	})
+}
+
+func terminates(s ast.Stmt) bool {
+	switch s := s.(type) {
+	case *ast.ReturnStmt:
+		return true
+	case *ast.BranchStmt:
+		return s.Tok != token.FALLTHROUGH
+	case *ast.ExprStmt:
+		call, ok := s.X.(*ast.CallExpr)
+		if !ok {
+			return false
+		}
+		id, ok := call.Fun.(*ast.Ident)
+		return ok && id.Name == "panic"
+	}
+	return false
+}
+
+// tests holds what the package's test functions are named and call.
+type tests struct {
+	names map[string]bool
+	calls map[string]bool
+}
+
+// loadTests parses the test files of the package. They are read from disk
+// rather than taken from the pass, so that the result does not depend on
+// whether the driver analyzes the package with its tests.
+func loadTests(pass *analysis.Pass) (*tests, error) {
+	t := &tests{names: map[string]bool{}, calls: map[string]bool{}}
+	dir := filepath.Dir(pass.Fset.File(pass.Files[0].Pos()).Name())
+	names, err := filepath.Glob(filepath.Join(dir, "*_test.go"))
+	if err != nil {
+		return nil, err
+	}
+	fset := token.NewFileSet()
+	for _, name := range names {
+		src, err := os.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+		f, err := parser.ParseFile(fset, name, src, parser.SkipObjectResolution)
+		if err != nil {
+			return nil, err
+		}
+		for _, d := range f.Decls {
+			fn, ok := d.(*ast.FuncDecl)
+			if !ok || fn.Recv != nil || fn.Body == nil || !strings.HasPrefix(fn.Name.Name, "Test") {
+				continue
+			}
+			t.names[fn.Name.Name] = true
+			ast.Inspect(fn.Body, func(n ast.Node) bool {
+				if call, ok := n.(*ast.CallExpr); ok {
+					switch fun := call.Fun.(type) {
+					case *ast.Ident:
+						t.calls[fun.Name] = true
+					case *ast.SelectorExpr:
+						t.calls[fun.Sel.Name] = true
+					}
+				}
+				return true
+			})
+		}
+	}
+	return t, nil
+}
+
+// compile type-checks the region as a package of its own, holding the
+// region's lines in a function with the results of fn, and the identifiers
+// to provide declared in a second file.
+func compile(pass *analysis.Pass, sp *span, fn *ast.FuncDecl) {
+	obj, ok := pass.TypesInfo.Defs[fn.Name].(*types.Func)
+	if !ok {
+		return
+	}
+	sig := obj.Type().(*types.Signature)
+
+	// The provided identifiers and the result types are declared with
+	// their own imports, so that the snippet file imports exactly what the
+	// region uses.
+	aliases := map[*types.Package]string{}
+	qualifier := func(p *types.Package) string {
+		if _, ok := aliases[p]; !ok {
+			aliases[p] = fmt.Sprintf("p%d", len(aliases))
+		}
+		return aliases[p]
+	}
+	var decls strings.Builder
+	for _, name := range strings.Split(provide, ",") {
+		if typ := providedType(pass, name); typ != nil {
+			fmt.Fprintf(&decls, "var %s %s\n", name, types.TypeString(typ, qualifier))
+		}
+	}
+	var results []string
+	for i := range sig.Results().Len() {
+		fmt.Fprintf(&decls, "type result%d = %s\n", i, types.TypeString(sig.Results().At(i).Type(), qualifier))
+		results = append(results, fmt.Sprintf("result%d", i))
+	}
+	var provided strings.Builder
+	provided.WriteString("package snippet\n\n")
+	for p, alias := range aliases {
+		fmt.Fprintf(&provided, "import %s %q\n", alias, p.Path())
+	}
+	provided.WriteString(decls.String())
+
+	var snippet strings.Builder
+	snippet.WriteString("package snippet\n\n")
+	for _, spec := range usedImports(pass, sp) {
+		fmt.Fprintf(&snippet, "import %s\n", spec)
+	}
+	fmt.Fprintf(&snippet, "func snippet() (%s) {\n", strings.Join(results, ", "))
+	header := strings.Count(snippet.String(), "\n")
+	lines := bytes.Split(sp.src, []byte("\n"))[sp.start.line : sp.end.line-1]
+	for _, l := range lines {
+		snippet.Write(l)
+		snippet.WriteByte('\n')
+	}
+	snippet.WriteString("panic(0)\n}\n")
+
+	fset := token.NewFileSet()
+	var files []*ast.File
+	for _, src := range []struct{ name, text string }{{"snippet.go", snippet.String()}, {"provided.go", provided.String()}} {
+		f, err := parser.ParseFile(fset, src.name, src.text, parser.SkipObjectResolution)
+		if err != nil {
+			pass.Reportf(sp.start.pos, "region %s does not parse on its own: %v", sp.tag, err)
+			return
+		}
+		files = append(files, f)
+	}
+	conf := types.Config{
+		GoVersion: pass.Pkg.GoVersion(),
+		Importer:  importer(pass.Pkg),
+		Error: func(err error) {
+			terr := err.(types.Error)
+			p := fset.Position(terr.Pos)
+			pos := sp.start.pos
+			if line := sp.start.line + p.Line - header; p.Filename == "snippet.go" && line > sp.start.line && line < sp.end.line {
+				pos = pass.Fset.File(sp.file.Pos()).LineStart(line) + token.Pos(p.Column-1)
+			}
+			pass.Reportf(pos, "region %s does not compile on its own: %s", sp.tag, terr.Msg)
+		},
+	}
+	conf.Check("snippet", fset, files, nil)
+}
+
+// providedType returns the type of the package-level variable or function
+// name, as provided to the snippets, or nil if there is none.
+func providedType(pass *analysis.Pass, name string) types.Type {
+	switch obj := pass.Pkg.Scope().Lookup(name).(type) {
+	case *types.Var, *types.Func:
+		return obj.Type()
+	}
+	return nil
+}
+
+// usedImports returns the import specs of the packages referred to in the
+// region.
+func usedImports(pass *analysis.Pass, sp *span) []string {
+	var specs []string
+	ast.Inspect(sp.file, func(n ast.Node) bool {
+		if n == nil || n.End() < sp.start.pos || sp.end.pos < n.Pos() {
+			return false
+		}
+		id, ok := n.(*ast.Ident)
+		if !ok || id.Pos() < sp.start.pos {
+			return true
+		}
+		if pn, ok := pass.TypesInfo.Uses[id].(*types.PkgName); ok {
+			spec := fmt.Sprintf("%q", pn.Imported().Path())
+			if pn.Name() != pn.Imported().Name() {
+				spec = pn.Name() + " " + spec
+			}
+			if !slices.Contains(specs, spec) {
+				specs = append(specs, spec)
+			}
+		}
+		return true
+	})
+	return specs
+}
+
+// importer returns a types.Importer resolving the packages imported,
+// directly or not, by pkg.
+func importer(pkg *types.Package) types.Importer {
+	pkgs := map[string]*types.Package{}
+	var add func(*types.Package)
+	add = func(p *types.Package) {
+		if _, ok := pkgs[p.Path()]; ok {
+			return
+		}
+		pkgs[p.Path()] = p
+		for _, q := range p.Imports() {
+			add(q)
+		}
+	}
+	add(pkg)
+	return importerFunc(func(path string) (*types.Package, error) {
+		if p, ok := pkgs[path]; ok {
+			return p, nil
+		}
+		return nil, fmt.Errorf("package %s is not imported by %s", path, pkg.Path())
+	})
+}
+
+type importerFunc func(path string) (*types.Package, error)
+
+func (f importerFunc) Import(path string) (*types.Package, error) { return f(path) }
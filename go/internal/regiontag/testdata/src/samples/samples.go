@@ -0,0 +1,148 @@
+package samples
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	str "strings"
+)
+
+func getMedia() string { return "media" }
+
+func secret() string { return "s3cret" }
+
+func Hello() (string, error) {
+	// [START hello]
+	ctx := context.Background()
+	client := &http.Client{}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://example.com", nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	f, err := os.Open(filepath.Join(getMedia(), "hello.txt"))
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	fmt.Println(str.ToUpper(resp.Status))
+	// [END hello]
+	return resp.Status, nil
+}
+
+func Called() error {
+	// [START called]
+	fmt.Println("called")
+	// [END called]
+	return nil
+}
+
+func Untested() error {
+	// want +1 `region untested has no test: add TestUntested or a test calling Untested`
+	// [START untested]
+	fmt.Println("untested")
+	// [END untested]
+	return nil
+}
+
+func Delete() error {
+	// [START delete]
+	if _, err := fmt.Println("deleting"); err != nil {
+		return nil
+	}
+	return fmt.Errorf("expected an error")
+	// want +1 `region delete ends with a return statement; end the region before it`
+	// [END delete]
+}
+
+func Unreachable() error {
+	// [START unreachable]
+	for i := range 3 {
+		if i == 1 {
+			continue
+			fmt.Println(i) // want `unreachable code in region unreachable`
+		}
+	}
+	// [END unreachable]
+	return nil
+}
+
+func Markers() error {
+	// want +1 `region open has no END`
+	// [START open]
+	// want +1 `START open while the region started at line 79 is open; should this be END\?`
+	// [START open]
+	// want +1 `END stray without a START`
+	// [END stray]
+	// [START outer]
+	// [START inner]
+	fmt.Println("overlap")
+	// want +1 `region outer ends inside region inner started at line 85`
+	// [END outer]
+	// [END inner]
+	return nil
+}
+
+func Duplicate() error {
+	// want +1 `duplicate region hello; first defined at samples.go:18`
+	// [START hello]
+	fmt.Println("again")
+	// [END hello]
+	return nil
+}
+
+// want +1 `region package_level is not inside a single function body`
+// [START package_level]
+var greeting = "hello"
+
+// [END package_level]
+
+func Blocks() error {
+	// [START blocks]
+	ctx := context.Background()
+	if ctx.Err() == nil {
+		fmt.Println("blocks")
+		// want +1 `END blocks is not in the block of its START at line 108`
+		// [END blocks]
+	}
+	fmt.Println(
+		// want +1 `START inside is inside a statement`
+		// [START inside]
+		"inside",
+	)
+	// [END inside]
+	return nil
+}
+
+func Standalone() error {
+	name := secret()
+	// [START standalone]
+	fmt.Println(name, secret()) // want `region standalone does not compile on its own: undefined: name` `region standalone does not compile on its own: undefined: secret`
+	unused := 1 // want `region standalone does not compile on its own: declared and not used: unused`
+	// [END standalone]
+	_ = unused
+	return nil
+}
+
+func ParamsWithClient(ctx context.Context, client *http.Client, w io.Writer) error {
+	// [START params]
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://example.com", nil) // want `region params does not compile on its own: undefined: ctx`
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req) // want `region params does not compile on its own: undefined: client`
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	fmt.Fprintln(w, resp.Status) // want `region params does not compile on its own: undefined: w`
+	// [END params]
+	return nil
+}
@@ -0,0 +1,21 @@
+package samples
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"testing"
+)
+
+func TestHello(t *testing.T)       {}
+func TestDelete(t *testing.T)      {}
+func TestUnreachable(t *testing.T) {}
+func TestMarkers(t *testing.T)     {}
+func TestDuplicate(t *testing.T)   {}
+func TestBlocks(t *testing.T)      {}
+func TestStandalone(t *testing.T)  {}
+
+func TestCalls(t *testing.T) {
+	Called()
+	ParamsWithClient(context.Background(), http.DefaultClient, io.Discard)
+}
@@ -0,0 +1,415 @@
+// Package replay records HTTP interactions with the Gemini API into cassette
+// files and replays them later, so that tests can run without network access
+// or an API key.
+//
+// A Recorder is an http.RoundTripper. Plug it into a client through
+// genai.ClientConfig.HTTPClient:
+//
+//	rec, err := replay.New("testdata/cassettes/TestFoo.json", replay.ModeReplay, nil)
+//	client, err := genai.NewClient(ctx, &genai.ClientConfig{
+//		APIKey:     "replay",
+//		Backend:    genai.BackendGeminiAPI,
+//		HTTPClient: rec.Client(),
+//	})
+package replay
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// Mode selects how a Recorder handles requests.
+type Mode int
+
+const (
+	// ModeReplay serves responses from the cassette and never touches the
+	// network.
+	ModeReplay Mode = iota
+	// ModeRecord forwards requests to the real API and records them.
+	ModeRecord
+	// ModeLive forwards requests to the real API without recording.
+	ModeLive
+)
+
+// String returns the name accepted by ParseMode.
+func (m Mode) String() string {
+	switch m {
+	case ModeRecord:
+		return "record"
+	case ModeLive:
+		return "live"
+	default:
+		return "replay"
+	}
+}
+
+// ParseMode parses a mode name. The empty string selects ModeReplay.
+func ParseMode(s string) (Mode, error) {
+	switch strings.ToLower(s) {
+	case "", "replay":
+		return ModeReplay, nil
+	case "record":
+		return ModeRecord, nil
+	case "live":
+		return ModeLive, nil
+	}
+	return ModeReplay, fmt.Errorf("replay: unknown mode %q (want replay, record or live)", s)
+}
+
+// ErrNoCassette is returned by New in replay mode when the cassette file does
+// not exist.
+var ErrNoCassette = errors.New("replay: cassette not found")
+
+// Cassette is the on-disk format of a recorded session.
+type Cassette struct {
+	// Source names what answered the requests when it was not the Gemini
+	// API, such as "fakegemini" for the fake server of internal/fakegemini.
+	Source       string         `json:"source,omitempty"`
+	Interactions []*Interaction `json:"interactions"`
+}
+
+// Interaction is a single recorded request and its response.
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// Request is the recorded part of an outgoing request. API keys are never
+// recorded.
+type Request struct {
+	Method  string            `json:"method"`
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers,omitempty"`
+	// Body holds JSON request bodies verbatim.
+	Body json.RawMessage `json:"body,omitempty"`
+	// Binary bodies, such as upload chunks, are recorded by digest only.
+	BodySHA256 string `json:"bodySha256,omitempty"`
+	BodyLength int    `json:"bodyLength,omitempty"`
+}
+
+// Response is a recorded response. Exactly one of Body, Events, Text and
+// Base64 is set.
+type Response struct {
+	StatusCode int               `json:"statusCode"`
+	Headers    map[string]string `json:"headers,omitempty"`
+	Body       json.RawMessage   `json:"body,omitempty"`
+	// Events holds the server-sent events of a streaming response.
+	Events []string `json:"events,omitempty"`
+	Text   string   `json:"text,omitempty"`
+	Base64 string   `json:"base64,omitempty"`
+}
+
+// Request and response headers worth keeping. Everything else, including
+// x-goog-api-key, is dropped.
+var (
+	recordedRequestHeaders = []string{
+		"Content-Type",
+		"X-Goog-Upload-Command",
+		"X-Goog-Upload-Offset",
+		"X-Goog-Upload-Protocol",
+	}
+	recordedResponseHeaders = []string{
+		"Content-Type",
+		"X-Goog-Upload-Chunk-Granularity",
+		"X-Goog-Upload-Size-Received",
+		"X-Goog-Upload-Status",
+		"X-Goog-Upload-Url",
+	}
+)
+
+// Recorder records or replays HTTP interactions.
+type Recorder struct {
+	mode      Mode
+	path      string
+	transport http.RoundTripper
+
+	mu       sync.Mutex
+	cassette *Cassette
+	used     []bool
+}
+
+// New returns a Recorder for the cassette at path. In ModeRecord and ModeLive
+// requests are sent through transport, or http.DefaultTransport if it is nil.
+// In ModeReplay the cassette is loaded immediately; if it does not exist the
+// returned error wraps ErrNoCassette.
+func New(path string, mode Mode, transport http.RoundTripper) (*Recorder, error) {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	r := &Recorder{
+		mode:      mode,
+		path:      path,
+		transport: transport,
+		cassette:  &Cassette{},
+	}
+	if mode != ModeReplay {
+		return r, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrNoCassette, path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, r.cassette); err != nil {
+		return nil, fmt.Errorf("replay: parsing %s: %w", path, err)
+	}
+	r.used = make([]bool, len(r.cassette.Interactions))
+	return r, nil
+}
+
+// Source returns the source of the cassette; see Cassette.Source.
+func (r *Recorder) Source() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.cassette.Source
+}
+
+// SetSource sets the source recorded in the cassette, when something other
+// than the Gemini API answers the requests; see Cassette.Source.
+func (r *Recorder) SetSource(source string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cassette.Source = source
+}
+
+// Mode returns the mode the Recorder was created with.
+func (r *Recorder) Mode() Mode {
+	return r.mode
+}
+
+// Client returns an HTTP client that sends its requests through r.
+func (r *Recorder) Client() *http.Client {
+	return &http.Client{Transport: r}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	recorded := newRequest(req, body)
+
+	switch r.mode {
+	case ModeReplay:
+		return r.replay(req, recorded)
+	case ModeRecord:
+		return r.record(req, body, recorded)
+	default:
+		req = req.Clone(req.Context())
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		return r.transport.RoundTrip(req)
+	}
+}
+
+func (r *Recorder) replay(req *http.Request, recorded Request) (*http.Response, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Interactions are consumed in order. Prefer an exact match, but fall
+	// back to the first unused request for the same method and URL, since
+	// some bodies contain timestamps.
+	match := -1
+	for i, in := range r.cassette.Interactions {
+		if r.used[i] || in.Request.Method != recorded.Method || in.Request.URL != recorded.URL {
+			continue
+		}
+		if in.Request.sameBody(recorded) {
+			match = i
+			break
+		}
+		if match < 0 {
+			match = i
+		}
+	}
+	if match < 0 {
+		return nil, fmt.Errorf("replay: no recorded interaction for %s %s in %s", recorded.Method, recorded.URL, r.path)
+	}
+	r.used[match] = true
+	return r.cassette.Interactions[match].Response.toHTTP(req)
+}
+
+func (r *Recorder) record(req *http.Request, body []byte, recorded Request) (*http.Response, error) {
+	out := req.Clone(req.Context())
+	out.Body = io.NopCloser(bytes.NewReader(body))
+	resp, err := r.transport.RoundTrip(out)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cassette.Interactions = append(r.cassette.Interactions, &Interaction{
+		Request:  recorded,
+		Response: newResponse(resp, respBody),
+	})
+	return resp, nil
+}
+
+// Save writes the recorded interactions to the cassette file. It does nothing
+// unless the Recorder is in ModeRecord.
+func (r *Recorder) Save() error {
+	if r.mode != ModeRecord {
+		return nil
+	}
+	r.mu.Lock()
+	data, err := json.MarshalIndent(r.cassette, "", "  ")
+	r.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(r.path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(r.path, append(data, '\n'), 0o644)
+}
+
+// Unused returns the recorded interactions that were not replayed. A non-empty
+// result usually means the code under test changed since recording.
+func (r *Recorder) Unused() []*Interaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var unused []*Interaction
+	for i, in := range r.cassette.Interactions {
+		if i < len(r.used) && !r.used[i] {
+			unused = append(unused, in)
+		}
+	}
+	return unused
+}
+
+func newRequest(req *http.Request, body []byte) Request {
+	rr := Request{
+		Method:  req.Method,
+		URL:     sanitizeURL(req.URL),
+		Headers: pickHeaders(req.Header, recordedRequestHeaders),
+	}
+	if len(body) == 0 {
+		return rr
+	}
+	if json.Valid(body) {
+		rr.Body = compact(body)
+	} else {
+		sum := sha256.Sum256(body)
+		rr.BodySHA256 = hex.EncodeToString(sum[:])
+		rr.BodyLength = len(body)
+	}
+	return rr
+}
+
+func (r Request) sameBody(other Request) bool {
+	return bytes.Equal(r.Body, other.Body) && r.BodySHA256 == other.BodySHA256
+}
+
+func newResponse(resp *http.Response, body []byte) Response {
+	rr := Response{
+		StatusCode: resp.StatusCode,
+		Headers:    pickHeaders(resp.Header, recordedResponseHeaders),
+	}
+	switch {
+	case len(body) == 0:
+	case strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream"):
+		for _, ev := range strings.Split(strings.ReplaceAll(string(body), "\r\n", "\n"), "\n\n") {
+			if ev != "" {
+				rr.Events = append(rr.Events, ev)
+			}
+		}
+	case json.Valid(body):
+		rr.Body = compact(body)
+	case utf8.Valid(body):
+		rr.Text = string(body)
+	default:
+		rr.Base64 = base64.StdEncoding.EncodeToString(body)
+	}
+	return rr
+}
+
+func (r Response) toHTTP(req *http.Request) (*http.Response, error) {
+	var body []byte
+	switch {
+	case len(r.Events) > 0:
+		body = []byte(strings.Join(r.Events, "\n\n") + "\n\n")
+	case len(r.Body) > 0:
+		body = r.Body
+	case r.Text != "":
+		body = []byte(r.Text)
+	case r.Base64 != "":
+		var err error
+		if body, err = base64.StdEncoding.DecodeString(r.Base64); err != nil {
+			return nil, fmt.Errorf("replay: decoding response body: %w", err)
+		}
+	}
+	header := make(http.Header)
+	for k, v := range r.Headers {
+		header.Set(k, v)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", r.StatusCode, http.StatusText(r.StatusCode)),
+		StatusCode:    r.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+// sanitizeURL drops the API key, which the Gemini API also accepts as a query
+// parameter.
+func sanitizeURL(u *url.URL) string {
+	c := *u
+	q := c.Query()
+	q.Del("key")
+	c.RawQuery = q.Encode()
+	return c.String()
+}
+
+func pickHeaders(h http.Header, keys []string) map[string]string {
+	var out map[string]string
+	for _, k := range keys {
+		if v := h.Get(k); v != "" {
+			if out == nil {
+				out = make(map[string]string)
+			}
+			out[k] = v
+		}
+	}
+	return out
+}
+
+func compact(data []byte) json.RawMessage {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, data); err != nil {
+		return json.RawMessage(data)
+	}
+	return json.RawMessage(buf.Bytes())
+}
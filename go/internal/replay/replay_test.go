@@ -0,0 +1,216 @@
+package replay
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"google.golang.org/genai"
+)
+
+// newTestServer returns a minimal stand-in for the endpoints exercised by
+// the tests below: unary and streaming generation, and a two-step resumable
+// upload.
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := strings.TrimLeft(r.URL.Path, "/")
+		switch {
+		case r.Header.Get("x-goog-api-key") != "secret":
+			http.Error(w, `{"error":{"code":401,"message":"bad key"}}`, http.StatusUnauthorized)
+		case strings.HasSuffix(path, ":generateContent"):
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{"candidates":[{"content":{"role":"model","parts":[{"text":"hello"}]}}]}`)
+		case strings.HasSuffix(path, ":streamGenerateContent"):
+			w.Header().Set("Content-Type", "text/event-stream")
+			for _, s := range []string{"one ", "two ", "three"} {
+				fmt.Fprintf(w, "data: {\"candidates\":[{\"content\":{\"role\":\"model\",\"parts\":[{\"text\":%q}]}}]}\r\n\r\n", s)
+			}
+		case path == "upload/v1beta/files":
+			w.Header().Set("X-Goog-Upload-Url", srv.URL+"/upload-session?upload_id=1")
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{}`)
+		case path == "upload-session":
+			data, _ := io.ReadAll(r.Body)
+			w.Header().Set("X-Goog-Upload-Status", "final")
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprintf(w, `{"file":{"name":"files/abc","mimeType":"text/plain","sizeBytes":"%d","uri":"%s/files/abc"}}`, len(data), srv.URL)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func newClient(t *testing.T, baseURL string, rec *Recorder) *genai.Client {
+	t.Helper()
+	client, err := genai.NewClient(context.Background(), &genai.ClientConfig{
+		APIKey:      "secret",
+		Backend:     genai.BackendGeminiAPI,
+		HTTPClient:  rec.Client(),
+		HTTPOptions: genai.HTTPOptions{BaseURL: baseURL},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+// exercise runs a fixed sequence of calls and returns a transcript of the
+// results.
+func exercise(t *testing.T, client *genai.Client) string {
+	t.Helper()
+	ctx := context.Background()
+	var out strings.Builder
+
+	resp, err := client.Models.GenerateContent(ctx, "m", genai.Text("hi"), nil)
+	if err != nil {
+		t.Fatalf("GenerateContent: %v", err)
+	}
+	fmt.Fprintln(&out, resp.Text())
+
+	for chunk, err := range client.Models.GenerateContentStream(ctx, "m", genai.Text("hi"), nil) {
+		if err != nil {
+			t.Fatalf("GenerateContentStream: %v", err)
+		}
+		fmt.Fprint(&out, chunk.Text())
+	}
+	fmt.Fprintln(&out)
+
+	file, err := client.Files.Upload(ctx, bytes.NewReader([]byte("binary\x00data")), &genai.UploadFileConfig{
+		MIMEType: "text/plain",
+	})
+	if err != nil {
+		t.Fatalf("Upload: %v", err)
+	}
+	fmt.Fprintln(&out, file.Name, *file.SizeBytes)
+	return out.String()
+}
+
+func TestRecordThenReplay(t *testing.T) {
+	srv := newTestServer(t)
+	path := filepath.Join(t.TempDir(), "cassettes", "session.json")
+
+	rec, err := New(path, ModeRecord, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := exercise(t, newClient(t, srv.URL, rec))
+	if err := rec.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, []byte("secret")) {
+		t.Errorf("cassette contains the API key:\n%s", data)
+	}
+
+	// Replay against a server that is no longer running.
+	srv.Close()
+	rep, err := New(path, ModeReplay, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := exercise(t, newClient(t, srv.URL, rep))
+	if got != want {
+		t.Errorf("replayed transcript = %q, want %q", got, want)
+	}
+	if unused := rep.Unused(); len(unused) != 0 {
+		t.Errorf("%d interactions were not replayed", len(unused))
+	}
+}
+
+func TestRecordStreamEvents(t *testing.T) {
+	srv := newTestServer(t)
+	rec, err := New(filepath.Join(t.TempDir(), "c.json"), ModeRecord, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := newClient(t, srv.URL, rec)
+	for _, err := range client.Models.GenerateContentStream(context.Background(), "m", genai.Text("hi"), nil) {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	in := rec.cassette.Interactions
+	if len(in) != 1 {
+		t.Fatalf("recorded %d interactions, want 1", len(in))
+	}
+	if got := len(in[0].Response.Events); got != 3 {
+		t.Errorf("recorded %d events, want 3", got)
+	}
+}
+
+func TestReplayMissingCassette(t *testing.T) {
+	_, err := New(filepath.Join(t.TempDir(), "missing.json"), ModeReplay, nil)
+	if !errors.Is(err, ErrNoCassette) {
+		t.Errorf("New() error = %v, want ErrNoCassette", err)
+	}
+}
+
+func TestReplayUnmatchedRequest(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "empty.json")
+	if err := os.WriteFile(path, []byte(`{"interactions":[]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	rec, err := New(path, ModeReplay, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := newClient(t, "http://example.invalid", rec)
+	if _, err := client.Models.GenerateContent(context.Background(), "m", genai.Text("hi"), nil); err == nil {
+		t.Error("GenerateContent succeeded with an empty cassette")
+	}
+}
+
+func TestReplayPrefersMatchingBody(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "c.json")
+	cassette := `{"interactions":[
+		{"request":{"method":"POST","url":"http://x/a","body":{"n":1}},"response":{"statusCode":200,"text":"first"}},
+		{"request":{"method":"POST","url":"http://x/a","body":{"n":2}},"response":{"statusCode":200,"text":"second"}}
+	]}`
+	if err := os.WriteFile(path, []byte(cassette), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	rec, err := New(path, ModeReplay, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct{ body, want string }{
+		{`{"n":2}`, "second"},
+		{`{"n":3}`, "first"},
+	} {
+		resp, err := rec.Client().Post("http://x/a?key=secret", "application/json", strings.NewReader(tc.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, _ := io.ReadAll(resp.Body)
+		if string(got) != tc.want {
+			t.Errorf("body %s replayed %q, want %q", tc.body, got, tc.want)
+		}
+	}
+}
+
+func TestParseMode(t *testing.T) {
+	for _, s := range []string{"", "replay", "record", "live", "RECORD"} {
+		if _, err := ParseMode(s); err != nil {
+			t.Errorf("ParseMode(%q): %v", s, err)
+		}
+	}
+	if _, err := ParseMode("bogus"); err == nil {
+		t.Error("ParseMode(bogus) succeeded")
+	}
+}
@@ -0,0 +1,245 @@
+// Package tracker records the files and caches created through a Gemini API
+// client, so that they can be deleted once the samples are done with them,
+// even when a sample fails before deleting them itself.
+//
+// A Tracker watches the HTTP exchanges of the clients it wraps:
+//
+//	tr := tracker.New()
+//	client, err = tr.Client(ctx, client)
+//	...
+//	if err := tr.Cleanup(ctx, client); err != nil {
+//		// err is a *LeakError listing what was left behind.
+//	}
+package tracker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+
+	"google.golang.org/genai"
+)
+
+// Kind is a kind of remote resource.
+type Kind string
+
+const (
+	KindFile  Kind = "file"
+	KindCache Kind = "cache"
+)
+
+// A Resource is a remote resource created through a tracked client.
+type Resource struct {
+	Kind Kind
+	// Name is the resource name, such as "files/abc" or
+	// "cachedContents/xyz".
+	Name string
+}
+
+func (r Resource) String() string {
+	return r.Name
+}
+
+// A Leak is a resource Cleanup could not delete.
+type Leak struct {
+	Resource
+	Err error
+}
+
+// A LeakError is returned by Cleanup when resources are left behind.
+type LeakError struct {
+	Leaks []Leak
+}
+
+func (e *LeakError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "could not delete %d resources:", len(e.Leaks))
+	for _, l := range e.Leaks {
+		fmt.Fprintf(&b, "\n\t%s %s: %v", l.Kind, l.Name, l.Err)
+	}
+	return b.String()
+}
+
+// A Tracker records the resources created through the clients it wraps and
+// forgets them once they are deleted. It is safe for concurrent use.
+type Tracker struct {
+	mu   sync.Mutex
+	live []Resource
+}
+
+// New returns an empty Tracker.
+func New() *Tracker {
+	return &Tracker{}
+}
+
+// Live returns the resources created and not deleted yet, in creation
+// order.
+func (t *Tracker) Live() []Resource {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return slices.Clone(t.live)
+}
+
+// Add records a resource created by other means, so that Cleanup deletes
+// it.
+func (t *Tracker) Add(r Resource) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !slices.Contains(t.live, r) {
+		t.live = append(t.live, r)
+	}
+}
+
+// forget drops the resource named name.
+func (t *Tracker) forget(name string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.live = slices.DeleteFunc(t.live, func(r Resource) bool { return r.Name == name })
+}
+
+// Transport returns a transport sending requests through base, or
+// http.DefaultTransport if base is nil, and recording the resources they
+// create and delete.
+func (t *Tracker) Transport(base http.RoundTripper) http.RoundTripper {
+	return &transport{t: t, base: base}
+}
+
+// Client returns a client like client whose requests go through
+// t.Transport.
+func (t *Tracker) Client(ctx context.Context, client *genai.Client) (*genai.Client, error) {
+	cc := client.ClientConfig()
+	hc := &http.Client{}
+	if cc.HTTPClient != nil {
+		*hc = *cc.HTTPClient
+	}
+	hc.Transport = t.Transport(hc.Transport)
+	cc.HTTPClient = hc
+	return genai.NewClient(ctx, &cc)
+}
+
+// Cleanup deletes the live resources with client, caches first since they
+// may refer to files. Resources already gone count as deleted. It returns a
+// *LeakError listing those it could not delete, which stay live.
+func (t *Tracker) Cleanup(ctx context.Context, client *genai.Client) error {
+	live := t.Live()
+	slices.SortStableFunc(live, func(a, b Resource) int {
+		return cmpKind(a.Kind) - cmpKind(b.Kind)
+	})
+	var leaks []Leak
+	for _, r := range live {
+		var err error
+		switch r.Kind {
+		case KindCache:
+			_, err = client.Caches.Delete(ctx, r.Name, nil)
+		case KindFile:
+			_, err = client.Files.Delete(ctx, r.Name, nil)
+		}
+		if err != nil && !IsGone(r.Kind, err) {
+			leaks = append(leaks, Leak{r, err})
+			continue
+		}
+		t.forget(r.Name)
+	}
+	if leaks != nil {
+		return &LeakError{Leaks: leaks}
+	}
+	return nil
+}
+
+func cmpKind(k Kind) int {
+	if k == KindCache {
+		return 0
+	}
+	return 1
+}
+
+// IsGone reports whether err, from deleting a resource of kind k, means the
+// resource no longer exists. The Files API answers PERMISSION_DENIED rather
+// than NOT_FOUND for files that do not exist.
+func IsGone(k Kind, err error) bool {
+	var apiErr genai.APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	return apiErr.Code == http.StatusNotFound || k == KindFile && apiErr.Code == http.StatusForbidden
+}
+
+type untrackedKey struct{}
+
+// Untracked returns a context whose requests create resources that trackers
+// do not record, for resources meant to outlive the program, such as the
+// shared uploads of an upload index. Deletions are still recorded.
+func Untracked(ctx context.Context) context.Context {
+	return context.WithValue(ctx, untrackedKey{}, true)
+}
+
+type transport struct {
+	t    *Tracker
+	base http.RoundTripper
+}
+
+func (tr *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := tr.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	resp, err := base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	path := strings.TrimSuffix(req.URL.Path, "/")
+	switch {
+	case req.Method == http.MethodDelete:
+		// The path ends with the resource name, after the API version
+		// and, on Vertex AI, the project and location.
+		for _, r := range tr.t.Live() {
+			if !strings.HasSuffix(path, "/"+r.Name) {
+				continue
+			}
+			code := resp.StatusCode
+			if code/100 == 2 || IsGone(r.Kind, genai.APIError{Code: code}) {
+				tr.t.forget(r.Name)
+			}
+		}
+	case resp.StatusCode/100 != 2, req.Context().Value(untrackedKey{}) != nil:
+	case req.Method == http.MethodPost && resp.Header.Get("X-Goog-Upload-Status") == "final":
+		// The last request of a resumable upload returns the file.
+		var body struct {
+			File struct{ Name string }
+		}
+		if peek(resp, &body) && body.File.Name != "" {
+			tr.t.Add(Resource{KindFile, body.File.Name})
+		}
+	case req.Method == http.MethodPost && strings.HasSuffix(path, "/cachedContents"):
+		var body struct{ Name string }
+		if peek(resp, &body) && body.Name != "" {
+			tr.t.Add(Resource{KindCache, body.Name})
+		}
+	}
+	return resp, nil
+}
+
+// peek decodes the JSON body of resp into v and leaves the body readable by
+// the caller. It reports whether the body could be decoded.
+func peek(resp *http.Response, v any) bool {
+	data, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		resp.Body = io.NopCloser(io.MultiReader(bytes.NewReader(data), errReader{err}))
+		return false
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(data))
+	return json.Unmarshal(data, v) == nil
+}
+
+// errReader fails reads with err, to pass on a failure to read a body.
+type errReader struct{ err error }
+
+func (r errReader) Read([]byte) (int, error) { return 0, r.err }
@@ -0,0 +1,146 @@
+package tracker
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+
+	"gemini-api-examples/internal/config"
+	"gemini-api-examples/internal/fakegemini"
+
+	"google.golang.org/genai"
+)
+
+// newFake returns a fake server and a client talking to it, tracked by the
+// returned Tracker.
+func newFake(t *testing.T) (*fakegemini.Server, *Tracker, *genai.Client) {
+	t.Helper()
+	srv := fakegemini.New()
+	t.Cleanup(srv.Close)
+	cfg := &config.Config{APIKey: "fake", BaseURL: srv.URL}
+	client, err := cfg.NewClient(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := New()
+	client, err = tr.Client(context.Background(), client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return srv, tr, client
+}
+
+// create uploads a file and creates a cache of it through client.
+func create(t *testing.T, client *genai.Client) (*genai.File, *genai.CachedContent) {
+	t.Helper()
+	ctx := t.Context()
+	file, err := client.Files.Upload(ctx, bytes.NewReader([]byte("hello")), &genai.UploadFileConfig{MIMEType: "text/plain"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache, err := client.Caches.Create(ctx, "gemini-3.5-flash", &genai.CreateCachedContentConfig{
+		Contents: []*genai.Content{genai.NewContentFromURI(file.URI, file.MIMEType, genai.RoleUser)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return file, cache
+}
+
+func TestCleanup(t *testing.T) {
+	srv, tr, client := newFake(t)
+	file, cache := create(t, client)
+	want := []Resource{{KindFile, file.Name}, {KindCache, cache.Name}}
+	if got := tr.Live(); !slices.Equal(got, want) {
+		t.Fatalf("Live() = %v, want %v", got, want)
+	}
+	if err := tr.Cleanup(t.Context(), client); err != nil {
+		t.Fatal(err)
+	}
+	if got := tr.Live(); len(got) != 0 {
+		t.Errorf("Live() after Cleanup = %v, want none", got)
+	}
+	if len(srv.Files()) != 0 || len(srv.Caches()) != 0 {
+		t.Errorf("left on the server: %d files, %d caches", len(srv.Files()), len(srv.Caches()))
+	}
+	// Caches go first, since they may refer to files.
+	deletes := srv.RequestsTo(http.MethodDelete, "")
+	if len(deletes) != 2 || !strings.HasSuffix(deletes[0].Path, cache.Name) {
+		t.Errorf("deletions: %v, want the cache, then the file", deletes)
+	}
+}
+
+func TestDeletedBySample(t *testing.T) {
+	srv, tr, client := newFake(t)
+	file, cache := create(t, client)
+	if _, err := client.Caches.Delete(t.Context(), cache.Name, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := tr.Live(), []Resource{{KindFile, file.Name}}; !slices.Equal(got, want) {
+		t.Errorf("Live() after deleting the cache = %v, want %v", got, want)
+	}
+	// A resource deleted behind the tracker's back counts as deleted.
+	cfg := &config.Config{APIKey: "fake", BaseURL: srv.URL}
+	untracked, err := cfg.NewClient(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := untracked.Files.Delete(t.Context(), file.Name, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.Cleanup(t.Context(), client); err != nil {
+		t.Fatal(err)
+	}
+	if got := tr.Live(); len(got) != 0 {
+		t.Errorf("Live() after Cleanup = %v, want none", got)
+	}
+}
+
+func TestUntracked(t *testing.T) {
+	srv, tr, client := newFake(t)
+	ctx := Untracked(t.Context())
+	file, err := client.Files.Upload(ctx, bytes.NewReader([]byte("shared")), &genai.UploadFileConfig{MIMEType: "text/plain"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := tr.Live(); len(got) != 0 {
+		t.Errorf("Live() after an untracked upload = %v, want none", got)
+	}
+	if err := tr.Cleanup(t.Context(), client); err != nil {
+		t.Fatal(err)
+	}
+	if files := srv.Files(); len(files) != 1 || files[0].Name != file.Name {
+		t.Errorf("files after Cleanup = %v, want the untracked upload kept", files)
+	}
+}
+
+func TestLeaks(t *testing.T) {
+	srv, tr, client := newFake(t)
+	file, cache := create(t, client)
+	srv.Inject(file.Name, 1, fakegemini.ServerError(http.StatusServiceUnavailable))
+	err := tr.Cleanup(t.Context(), client)
+	var leakErr *LeakError
+	if !errors.As(err, &leakErr) {
+		t.Fatalf("Cleanup returned %v, want a *LeakError", err)
+	}
+	if len(leakErr.Leaks) != 1 || leakErr.Leaks[0].Resource != (Resource{KindFile, file.Name}) {
+		t.Errorf("leaks = %v, want the file", leakErr.Leaks)
+	}
+	if !strings.Contains(err.Error(), "could not delete 1 resources:\n\tfile "+file.Name+": ") {
+		t.Errorf("error = %q", err)
+	}
+	if got, want := tr.Live(), []Resource{{KindFile, file.Name}}; !slices.Equal(got, want) {
+		t.Errorf("Live() = %v, want the leaked file only, not %s", got, cache.Name)
+	}
+	// The leaked file is retried by the next Cleanup.
+	if err := tr.Cleanup(t.Context(), client); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(srv.Files()); n != 0 {
+		t.Errorf("%d files left on the server", n)
+	}
+}
@@ -0,0 +1,165 @@
+package upload
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"gemini-api-examples/internal/filewait"
+
+	"google.golang.org/genai"
+)
+
+// BatchOptions controls UploadAll.
+type BatchOptions struct {
+	// Parallel is the number of concurrent uploads, 4 if not positive.
+	// Waiting for files to be processed does not count against it.
+	Parallel int
+	// Wait controls the wait for files still being processed; nil means
+	// filewait's defaults. Bound it with the context given to UploadAll.
+	Wait *filewait.Options
+	// Progress, if not nil, is called once per file when it is ready or
+	// has failed, with its error. Calls are never concurrent.
+	Progress func(path string, err error)
+}
+
+// A Result is the outcome of one file of a batch.
+type Result struct {
+	Path string
+	// File is the uploaded file, ACTIVE unless Err is set, and nil if the
+	// upload itself failed.
+	File *genai.File
+	Err  error
+}
+
+// A BatchError reports the files of a batch that could not be uploaded or
+// failed processing.
+type BatchError struct {
+	// Total is the number of files in the batch.
+	Total  int
+	Failed []Result
+}
+
+func (e *BatchError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "could not upload %d of %d files:", len(e.Failed), e.Total)
+	for _, r := range e.Failed {
+		fmt.Fprintf(&b, "\n\t%s: %v", r.Path, r.Err)
+	}
+	return b.String()
+}
+
+// Unwrap returns the errors of the failed files, for errors.Is and
+// errors.As.
+func (e *BatchError) Unwrap() []error {
+	errs := make([]error, len(e.Failed))
+	for i, r := range e.Failed {
+		errs[i] = r.Err
+	}
+	return errs
+}
+
+// UploadAll uploads the files at paths with FromPath, opts.Parallel at a
+// time, and waits for each to be ACTIVE. It returns one result per path, in
+// the order of paths, and a *BatchError if any file failed; the others are
+// uploaded regardless.
+func UploadAll(ctx context.Context, client *genai.Client, paths []string, opts BatchOptions) ([]Result, error) {
+	results := make([]Result, len(paths))
+	var mu sync.Mutex
+	finish := func(i int, f *genai.File, err error) {
+		results[i] = Result{Path: paths[i], File: f, Err: err}
+		if opts.Progress != nil {
+			mu.Lock()
+			defer mu.Unlock()
+			opts.Progress(paths[i], err)
+		}
+	}
+
+	work := make(chan int)
+	var uploads, waits sync.WaitGroup
+	parallel := opts.Parallel
+	if parallel <= 0 {
+		parallel = 4
+	}
+	for range min(parallel, len(paths)) {
+		uploads.Add(1)
+		go func() {
+			defer uploads.Done()
+			for i := range work {
+				f, err := FromPath(ctx, client, paths[i], nil)
+				if err != nil {
+					finish(i, nil, err)
+					continue
+				}
+				waits.Add(1)
+				go func() {
+					defer waits.Done()
+					f, err := filewait.Wait(ctx, client, f, opts.Wait)
+					finish(i, f, err)
+				}()
+			}
+		}()
+	}
+	for i := range paths {
+		work <- i
+	}
+	close(work)
+	uploads.Wait()
+	waits.Wait()
+
+	var failed []Result
+	for _, r := range results {
+		if r.Err != nil {
+			failed = append(failed, r)
+		}
+	}
+	if failed != nil {
+		return results, &BatchError{Total: len(paths), Failed: failed}
+	}
+	return results, nil
+}
+
+// UploadGlob uploads the regular files matching pattern, as in
+// filepath.Glob, in lexical order, like UploadAll. It fails if nothing
+// matches.
+func UploadGlob(ctx context.Context, client *genai.Client, pattern string, opts BatchOptions) ([]Result, error) {
+	paths, err := Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+	return UploadAll(ctx, client, paths, opts)
+}
+
+// Glob returns the regular files matching pattern, as in filepath.Glob, in
+// lexical order. It fails if nothing matches.
+func Glob(pattern string) ([]string, error) {
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for _, m := range matches {
+		if info, err := os.Stat(m); err == nil && info.Mode().IsRegular() {
+			paths = append(paths, m)
+		}
+	}
+	if paths == nil {
+		return nil, fmt.Errorf("no files match %s", pattern)
+	}
+	return paths, nil
+}
+
+// Parts returns parts referring to the files of results that are ready, in
+// order.
+func Parts(results []Result) []*genai.Part {
+	var parts []*genai.Part
+	for _, r := range results {
+		if r.Err == nil {
+			parts = append(parts, genai.NewPartFromURI(r.File.URI, r.File.MIMEType))
+		}
+	}
+	return parts
+}
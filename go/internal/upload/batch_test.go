@@ -0,0 +1,177 @@
+package upload
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gemini-api-examples/internal/fakegemini"
+	"gemini-api-examples/internal/filewait"
+
+	"google.golang.org/genai"
+)
+
+// writeFiles writes files named name-0.txt, name-1.txt, ... with distinct
+// contents in dir and returns their paths.
+func writeFiles(t *testing.T, dir, name string, n int) []string {
+	t.Helper()
+	var paths []string
+	for i := range n {
+		path := filepath.Join(dir, fmt.Sprintf("%s-%02d.txt", name, i))
+		if err := os.WriteFile(path, []byte(fmt.Sprintf("document %d\n", i)), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+// concurrency records the largest number of uploads in flight at once.
+type concurrency struct {
+	base      http.RoundTripper
+	mu        sync.Mutex
+	now, peak int
+}
+
+func (c *concurrency) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !strings.HasPrefix(req.URL.Path, "/upload/") {
+		return c.base.RoundTrip(req)
+	}
+	c.mu.Lock()
+	c.now++
+	c.peak = max(c.peak, c.now)
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		c.now--
+		c.mu.Unlock()
+	}()
+	time.Sleep(5 * time.Millisecond)
+	return c.base.RoundTrip(req)
+}
+
+func TestUploadAll(t *testing.T) {
+	srv, client := newFake(t)
+	c := &concurrency{base: http.DefaultTransport}
+	cc := client.ClientConfig()
+	cc.HTTPClient = &http.Client{Transport: c}
+	client, err := genai.NewClient(t.Context(), &cc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	paths := writeFiles(t, t.TempDir(), "doc", 24)
+	var progress []string
+	results, err := UploadAll(t.Context(), client, paths, BatchOptions{
+		Parallel: 3,
+		Progress: func(path string, err error) { progress = append(progress, path) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, r := range results {
+		if r.Path != paths[i] || r.File == nil || r.File.State != genai.FileStateActive {
+			t.Fatalf("result %d = %+v, want %s uploaded", i, r, paths[i])
+		}
+		data, _ := srv.FileData(r.File.Name)
+		if want := fmt.Sprintf("document %d\n", i); string(data) != want {
+			t.Errorf("result %d holds %q, want %q", i, data, want)
+		}
+	}
+	if len(progress) != len(paths) {
+		t.Errorf("%d progress calls, want %d", len(progress), len(paths))
+	}
+	if c.peak > 3 || c.peak < 2 {
+		t.Errorf("%d uploads at once, want up to 3", c.peak)
+	}
+	if parts := Parts(results); len(parts) != len(paths) || parts[5].FileData.FileURI != results[5].File.URI {
+		t.Errorf("Parts(results) does not follow the input order")
+	}
+}
+
+func TestUploadAllPartialFailure(t *testing.T) {
+	_, client := newFake(t)
+	dir := t.TempDir()
+	paths := writeFiles(t, dir, "doc", 2)
+	gif := filepath.Join(dir, "anim.gif")
+	if err := os.WriteFile(gif, []byte("GIF89a"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	paths = []string{paths[0], gif, filepath.Join(dir, "missing.txt"), paths[1]}
+	results, err := UploadAll(t.Context(), client, paths, BatchOptions{})
+	var batchErr *BatchError
+	if !errors.As(err, &batchErr) || batchErr.Total != 4 || len(batchErr.Failed) != 2 {
+		t.Fatalf("err = %v, want a *BatchError with 2 of 4 failed", err)
+	}
+	if batchErr.Failed[0].Path != gif || batchErr.Failed[1].Path != paths[2] {
+		t.Errorf("failed = %+v, want the GIF then the missing file", batchErr.Failed)
+	}
+	var unsupported *UnsupportedError
+	if !errors.As(err, &unsupported) || !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("err = %v, want the errors of the files wrapped", err)
+	}
+	if results[0].Err != nil || results[3].Err != nil || len(Parts(results)) != 2 {
+		t.Errorf("the files that could be uploaded were not: %+v", results)
+	}
+}
+
+func TestUploadAllWaits(t *testing.T) {
+	srv, client := newFake(t)
+	srv.SetProcessingPolls(2)
+	video, err := os.ReadFile(filepath.Join(thirdParty, "Big_Buck_Bunny.mp4"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	var paths []string
+	for i := range 6 {
+		path := filepath.Join(dir, fmt.Sprintf("clip-%d.mp4", i))
+		// Distinct contents, in case an upload index is in use.
+		if err := os.WriteFile(path, append(video, byte(i)), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, path)
+	}
+	// Uploaded one at a time, the first file fails processing.
+	srv.Inject("files", 1, fakegemini.ProcessingFailed("corrupt"))
+	results, err := UploadAll(t.Context(), client, paths, BatchOptions{
+		Parallel: 1,
+		Wait:     &filewait.Options{Initial: time.Millisecond, Max: time.Millisecond},
+	})
+	var failed *filewait.FailedError
+	if !errors.As(err, &failed) || results[0].Err == nil || results[0].File == nil {
+		t.Fatalf("err = %v, want the first file FAILED", err)
+	}
+	for _, r := range results[1:] {
+		if r.Err != nil || r.File.State != genai.FileStateActive {
+			t.Errorf("%s is %s (%v), want ACTIVE", r.Path, r.File.State, r.Err)
+		}
+	}
+}
+
+func TestGlob(t *testing.T) {
+	dir := t.TempDir()
+	paths := writeFiles(t, dir, "doc", 3)
+	if err := os.Mkdir(filepath.Join(dir, "doc-sub.txt"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	got, err := Glob(filepath.Join(dir, "doc-*.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(got, " ") != strings.Join(paths, " ") {
+		t.Errorf("Glob = %q, want the regular files %q", got, paths)
+	}
+	if _, err := Glob(filepath.Join(dir, "*.pdf")); err == nil {
+		t.Error("Glob succeeded with no match")
+	}
+	if _, err := UploadGlob(t.Context(), nil, filepath.Join(dir, "*.pdf"), BatchOptions{}); err == nil {
+		t.Error("UploadGlob succeeded with no match")
+	}
+}
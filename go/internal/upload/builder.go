@@ -0,0 +1,223 @@
+package upload
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"io"
+	"os"
+	"path/filepath"
+
+	"gemini-api-examples/internal/filewait"
+
+	"google.golang.org/genai"
+)
+
+// DefaultLimit is the size of the largest request the Gemini API accepts,
+// 20 MB, counting the text of the prompt and the base64 encoding of inline
+// data.
+const DefaultLimit = 20 << 20
+
+// A Builder builds the parts of one request from text and local media.
+// Media goes inline while the request stays within a size limit, which
+// saves an upload, and through the Files API once it would not:
+//
+//	b := upload.NewBuilder(client, upload.DefaultLimit)
+//	b.AddText("Describe this image")
+//	if err := b.AddPath(ctx, "organ.jpg"); err != nil {
+//		...
+//	}
+//	contents := []*genai.Content{genai.NewContentFromParts(b.Parts(), genai.RoleUser)}
+type Builder struct {
+	// Wait controls the wait for uploaded files still being processed,
+	// such as videos; nil means filewait's defaults. Bound it with the
+	// context given to the Add methods.
+	Wait *filewait.Options
+	// Parallel is the number of concurrent uploads of AddPaths, 4 if not
+	// positive.
+	Parallel int
+
+	client *genai.Client
+	limit  int64
+	size   int64
+	parts  []*genai.Part
+	files  []*genai.File
+}
+
+// NewBuilder returns a Builder uploading through client and keeping the
+// request within limit bytes, or DefaultLimit if limit is not positive.
+func NewBuilder(client *genai.Client, limit int64) *Builder {
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	return &Builder{client: client, limit: limit}
+}
+
+// Parts returns the parts added so far, in order.
+func (b *Builder) Parts() []*genai.Part {
+	return b.parts
+}
+
+// Files returns the files uploaded for the parts that are not inline,
+// including those that failed processing, which the caller may delete once
+// done with the request.
+func (b *Builder) Files() []*genai.File {
+	return b.files
+}
+
+// Size returns the size of the request so far, as counted against the
+// limit.
+func (b *Builder) Size() int64 {
+	return b.size
+}
+
+// AddText adds a text part. Text is always inline, even past the limit.
+func (b *Builder) AddText(text string) {
+	b.size += int64(len(text))
+	b.parts = append(b.parts, genai.NewPartFromText(text))
+}
+
+// AddPath adds the file at path, inline if it fits within the limit with
+// the parts added so far, otherwise uploaded with FromPath. The MIME type is
+// detected with DetectFile.
+func (b *Builder) AddPath(ctx context.Context, path string) error {
+	mimeType, err := DetectFile(path)
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if info.Size() <= b.room() {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		b.addInline(data, mimeType)
+		return nil
+	}
+	f, err := FromPath(ctx, b.client, path, &genai.UploadFileConfig{MIMEType: mimeType})
+	if err != nil {
+		return err
+	}
+	return b.addFile(ctx, f)
+}
+
+// AddPaths adds the files at paths, in order, like AddPath, except that the
+// files that do not fit inline are uploaded in parallel with UploadAll. The
+// files that cannot be added are left out and listed in a *BatchError.
+func (b *Builder) AddPaths(ctx context.Context, paths ...string) error {
+	// Each path gets an inline part, an error, or the index of its upload.
+	type item struct {
+		part   *genai.Part
+		err    error
+		upload int
+	}
+	items := make([]item, len(paths))
+	var uploads []string
+	for i, path := range paths {
+		it := &items[i]
+		it.upload = -1
+		var mimeType string
+		var info os.FileInfo
+		mimeType, it.err = DetectFile(path)
+		if it.err == nil {
+			info, it.err = os.Stat(path)
+		}
+		switch {
+		case it.err != nil:
+		case info.Size() > b.room():
+			it.upload = len(uploads)
+			uploads = append(uploads, path)
+		default:
+			var data []byte
+			if data, it.err = os.ReadFile(path); it.err == nil {
+				b.size += int64(base64.StdEncoding.EncodedLen(len(data)))
+				it.part = genai.NewPartFromBytes(data, mimeType)
+			}
+		}
+	}
+
+	var results []Result
+	if len(uploads) > 0 {
+		// The failures are in the results.
+		results, _ = UploadAll(ctx, b.client, uploads, BatchOptions{Parallel: b.Parallel, Wait: b.Wait})
+	}
+	var failed []Result
+	for i, it := range items {
+		if it.upload >= 0 {
+			r := results[it.upload]
+			if r.File != nil {
+				b.files = append(b.files, r.File)
+			}
+			if r.Err != nil {
+				failed = append(failed, r)
+				continue
+			}
+			b.size += int64(len(r.File.URI))
+			it.part = genai.NewPartFromURI(r.File.URI, r.File.MIMEType)
+		}
+		if it.err != nil {
+			failed = append(failed, Result{Path: paths[i], Err: it.err})
+			continue
+		}
+		b.parts = append(b.parts, it.part)
+	}
+	if failed != nil {
+		return &BatchError{Total: len(paths), Failed: failed}
+	}
+	return nil
+}
+
+// AddReader adds the content read from r, inline if it fits within the
+// limit with the parts added so far, otherwise uploaded with Files.Upload.
+// The MIME type is detected with Detect, from name, which may be empty, and
+// the head of the content, which is read in full even when little or no
+// room is left inline.
+func (b *Builder) AddReader(ctx context.Context, name string, r io.Reader) error {
+	room := b.room()
+	data, err := io.ReadAll(io.LimitReader(r, max(room+1, sniffLen)))
+	if err != nil {
+		return err
+	}
+	mimeType, err := Detect(name, data)
+	if err != nil {
+		return err
+	}
+	if int64(len(data)) <= room {
+		b.addInline(data, mimeType)
+		return nil
+	}
+	cfg := &genai.UploadFileConfig{MIMEType: mimeType}
+	if name != "" {
+		cfg.DisplayName = filepath.Base(name)
+	}
+	f, err := b.client.Files.Upload(ctx, io.MultiReader(bytes.NewReader(data), r), cfg)
+	if err != nil {
+		return err
+	}
+	return b.addFile(ctx, f)
+}
+
+// room returns the largest number of bytes that still fit inline.
+func (b *Builder) room() int64 {
+	return max(b.limit-b.size, 0) / 4 * 3
+}
+
+func (b *Builder) addInline(data []byte, mimeType string) {
+	b.size += int64(base64.StdEncoding.EncodedLen(len(data)))
+	b.parts = append(b.parts, genai.NewPartFromBytes(data, mimeType))
+}
+
+// addFile waits for f to be processed and adds a part referring to it.
+func (b *Builder) addFile(ctx context.Context, f *genai.File) error {
+	b.files = append(b.files, f)
+	f, err := filewait.Wait(ctx, b.client, f, b.Wait)
+	if err != nil {
+		return err
+	}
+	b.size += int64(len(f.URI))
+	b.parts = append(b.parts, genai.NewPartFromURI(f.URI, f.MIMEType))
+	return nil
+}
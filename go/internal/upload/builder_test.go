@@ -0,0 +1,172 @@
+package upload
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gemini-api-examples/internal/filewait"
+)
+
+func TestBuilderInline(t *testing.T) {
+	srv, client := newFake(t)
+	b := NewBuilder(client, 0)
+	b.AddText("Describe these")
+	for _, name := range []string{"organ.jpg", "Cajun_instruments.jpg", "test.pdf"} {
+		if err := b.AddPath(t.Context(), filepath.Join(thirdParty, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	parts := b.Parts()
+	if len(parts) != 4 || parts[0].Text != "Describe these" {
+		t.Fatalf("parts = %v", parts)
+	}
+	want, err := os.ReadFile(organ)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := parts[1].InlineData; d == nil || d.MIMEType != "image/jpeg" || !bytes.Equal(d.Data, want) {
+		t.Errorf("organ.jpg not inline")
+	}
+	if d := parts[3].InlineData; d == nil || d.MIMEType != "application/pdf" {
+		t.Errorf("test.pdf not inline")
+	}
+	if len(srv.Files()) != 0 || len(b.Files()) != 0 {
+		t.Errorf("files uploaded below the default limit")
+	}
+}
+
+func TestBuilderLimit(t *testing.T) {
+	srv, client := newFake(t)
+	// organ.jpg takes 512,000 bytes in base64, leaving room for poem.txt
+	// but not a11.txt.
+	b := NewBuilder(client, 600_000)
+	b.AddText("Compare")
+	for _, name := range []string{"organ.jpg", "a11.txt", "poem.txt", "Cajun_instruments.jpg"} {
+		if err := b.AddPath(t.Context(), filepath.Join(thirdParty, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var kinds []string
+	for _, p := range b.Parts()[1:] {
+		switch {
+		case p.InlineData != nil:
+			kinds = append(kinds, "inline")
+		case p.FileData != nil:
+			kinds = append(kinds, "file")
+		}
+	}
+	if got := strings.Join(kinds, " "); got != "inline file inline file" {
+		t.Errorf("parts are %s, want inline file inline file", got)
+	}
+	if len(b.Files()) != 2 || len(srv.Files()) != 2 {
+		t.Errorf("%d files recorded, %d uploaded, want 2", len(b.Files()), len(srv.Files()))
+	}
+	if b.Size() > 600_000 {
+		t.Errorf("size %d is over the limit", b.Size())
+	}
+}
+
+func TestBuilderAddPaths(t *testing.T) {
+	srv, client := newFake(t)
+	b := NewBuilder(client, 600_000)
+	b.Parallel = 2
+	paths := []string{organ, filepath.Join(thirdParty, "a11.txt"), filepath.Join(thirdParty, "missing.jpg"),
+		filepath.Join(thirdParty, "poem.txt"), filepath.Join(thirdParty, "test.pdf")}
+	err := b.AddPaths(t.Context(), paths...)
+	var batchErr *BatchError
+	if !errors.As(err, &batchErr) || len(batchErr.Failed) != 1 || batchErr.Failed[0].Path != paths[2] {
+		t.Fatalf("err = %v, want missing.jpg to fail", err)
+	}
+	var kinds []string
+	for _, p := range b.Parts() {
+		switch {
+		case p.InlineData != nil:
+			kinds = append(kinds, "inline "+p.InlineData.MIMEType)
+		case p.FileData != nil:
+			kinds = append(kinds, "file "+p.FileData.MIMEType)
+		}
+	}
+	want := "inline image/jpeg, file text/plain, inline text/plain, file application/pdf"
+	if got := strings.Join(kinds, ", "); got != want {
+		t.Errorf("parts are %s, want %s", got, want)
+	}
+	if len(b.Files()) != 2 || len(srv.Files()) != 2 {
+		t.Errorf("%d files recorded, %d uploaded, want 2", len(b.Files()), len(srv.Files()))
+	}
+}
+
+func TestBuilderReader(t *testing.T) {
+	srv, client := newFake(t)
+	b := NewBuilder(client, 1000)
+	small := strings.Repeat("a", 100)
+	if err := b.AddReader(t.Context(), "small.txt", strings.NewReader(small)); err != nil {
+		t.Fatal(err)
+	}
+	large := strings.Repeat("b", 5000)
+	if err := b.AddReader(t.Context(), "large.md", strings.NewReader(large)); err != nil {
+		t.Fatal(err)
+	}
+	parts := b.Parts()
+	if parts[0].InlineData == nil || string(parts[0].InlineData.Data) != small {
+		t.Errorf("small reader not inline")
+	}
+	if parts[1].FileData == nil || parts[1].FileData.MIMEType != "text/markdown" {
+		t.Fatalf("large reader not uploaded: %+v", parts[1])
+	}
+	data, _ := srv.FileData(b.Files()[0].Name)
+	if string(data) != large || b.Files()[0].DisplayName != "large.md" {
+		t.Errorf("uploaded %d bytes as %q, want the whole reader", len(data), b.Files()[0].DisplayName)
+	}
+}
+
+func TestBuilderWaits(t *testing.T) {
+	srv, client := newFake(t)
+	srv.SetProcessingPolls(2)
+	b := NewBuilder(client, 1000)
+	b.Wait = &filewait.Options{Initial: time.Millisecond}
+	if err := b.AddPath(t.Context(), filepath.Join(thirdParty, "Big_Buck_Bunny.mp4")); err != nil {
+		t.Fatal(err)
+	}
+	if f := srv.Files()[0]; f.State != "ACTIVE" {
+		t.Errorf("file is %s after AddPath, want ACTIVE", f.State)
+	}
+}
+
+func TestBuilderUnsupported(t *testing.T) {
+	_, client := newFake(t)
+	b := NewBuilder(client, 0)
+	var unsupported *UnsupportedError
+	if err := b.AddReader(t.Context(), "anim.gif", strings.NewReader("GIF89a")); !errors.As(err, &unsupported) {
+		t.Errorf("err = %v, want an *UnsupportedError", err)
+	}
+	if len(b.Parts()) != 0 {
+		t.Errorf("parts = %v, want none", b.Parts())
+	}
+}
+
+func TestBuilderReaderNoRoom(t *testing.T) {
+	srv, client := newFake(t)
+	b := NewBuilder(client, 100)
+	b.AddText(strings.Repeat("x", 100))
+	// Without an extension, only the content tells the type, and the
+	// inline budget is spent: the head is still read to detect it.
+	image, err := os.ReadFile(filepath.Join(thirdParty, "organ.jpg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := b.AddReader(t.Context(), "organ", bytes.NewReader(image)); err != nil {
+		t.Fatal(err)
+	}
+	parts := b.Parts()
+	if len(parts) != 2 || parts[1].FileData == nil || parts[1].FileData.MIMEType != "image/jpeg" {
+		t.Fatalf("parts = %+v, want the image uploaded as image/jpeg", parts)
+	}
+	if data, _ := srv.FileData(b.Files()[0].Name); !bytes.Equal(data, image) {
+		t.Errorf("uploaded %d bytes, want the %d of the image", len(data), len(image))
+	}
+}
@@ -0,0 +1,117 @@
+package upload
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"time"
+
+	"gemini-api-examples/internal/index"
+	"gemini-api-examples/internal/tracker"
+
+	"google.golang.org/genai"
+)
+
+// An Index remembers the remote files holding local content, by SHA-256
+// hash, so that content is uploaded once and reused until it expires. The
+// Files API deletes uploads after 48 hours.
+//
+// Entries are scoped to the account and endpoint of the client, so one
+// index can serve several API keys or fake servers. The files an Index
+// uploads are shared: they are not recorded by trackers, and a reused file
+// keeps the display name of its first upload. It is safe for concurrent
+// use; concurrent uploads of the same content upload it once.
+type Index struct {
+	// MinRemaining is the minimum time a file must have left before it
+	// expires to be reused, so that it does not expire while in use. It
+	// defaults to one hour.
+	MinRemaining time.Duration
+	// Now returns the current time, against which expiry is checked. It
+	// defaults to time.Now.
+	Now func() time.Time
+
+	index *index.Index[Entry]
+}
+
+// An Entry is a remote file recorded in an Index.
+type Entry struct {
+	// Name is the resource name, such as "files/abc".
+	Name           string    `json:"name"`
+	MIMEType       string    `json:"mimeType"`
+	SizeBytes      int64     `json:"sizeBytes"`
+	ExpirationTime time.Time `json:"expirationTime"`
+}
+
+// OpenIndex returns the index stored in the JSON file at path, which is
+// created when the first entry is recorded. With an empty path, the index
+// only lives in memory.
+func OpenIndex(path string) (*Index, error) {
+	ix, err := index.Open(path, "files", func(e Entry) time.Time { return e.ExpirationTime })
+	if err != nil {
+		return nil, err
+	}
+	return &Index{index: ix}, nil
+}
+
+// Entries returns a copy of the entries, keyed by scope, content hash and
+// MIME type.
+func (ix *Index) Entries() map[string]Entry {
+	return ix.index.Entries()
+}
+
+// FromPath returns a remote file holding the content of the file at path,
+// as uploaded by FromPath with config. If the index records such a file
+// that is not about to expire and Files.Get finds it still ACTIVE, it is
+// returned as is; otherwise the file is uploaded and recorded.
+func (ix *Index) FromPath(ctx context.Context, client *genai.Client, path string, config *genai.UploadFileConfig) (*genai.File, error) {
+	cfg, err := resolve(path, config)
+	if err != nil {
+		return nil, err
+	}
+	sum, size, err := hashFile(path)
+	if err != nil {
+		return nil, err
+	}
+	key := index.Key(client, sum, cfg.MIMEType)
+
+	entry, ok, done, err := ix.index.Acquire(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	if ok && entry.SizeBytes == size && index.Fresh(entry.ExpirationTime, ix.Now, ix.MinRemaining, time.Hour) {
+		f, err := client.Files.Get(ctx, entry.Name, nil)
+		switch {
+		case err == nil && f.State == genai.FileStateActive:
+			return f, nil
+		case err != nil && ctx.Err() != nil:
+			return nil, err
+		}
+		// Gone, failed or not ready: upload again.
+	}
+	f, err := client.Files.UploadFromPath(tracker.Untracked(ctx), path, cfg)
+	if err != nil {
+		return nil, err
+	}
+	if err := ix.index.Record(key, Entry{Name: f.Name, MIMEType: cfg.MIMEType, SizeBytes: size, ExpirationTime: f.ExpirationTime}, ix.Now); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// hashFile returns the hex SHA-256 hash and the size of the file at path.
+func hashFile(path string) (string, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(h.Sum(nil)), n, nil
+}
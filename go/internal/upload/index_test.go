@@ -0,0 +1,240 @@
+package upload
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"gemini-api-examples/internal/tracker"
+
+	"google.golang.org/genai"
+)
+
+var organ = filepath.Join(thirdParty, "organ.jpg")
+
+func TestIndexReuse(t *testing.T) {
+	srv, client := newFake(t)
+	ix, err := OpenIndex("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, err := ix.FromPath(t.Context(), client, organ, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := ix.FromPath(t.Context(), client, organ, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.Name != first.Name || len(srv.Files()) != 1 {
+		t.Errorf("uploads %s then %s, %d files, want one file reused", first.Name, second.Name, len(srv.Files()))
+	}
+	if n := len(srv.RequestsTo(http.MethodGet, first.Name)); n != 1 {
+		t.Errorf("%d Files.Get calls, want 1 to check the reused file", n)
+	}
+
+	// The same content under another name is reused too.
+	copied := filepath.Join(t.TempDir(), "copy.jpg")
+	data, err := os.ReadFile(organ)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(copied, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if f, err := ix.FromPath(t.Context(), client, copied, nil); err != nil || f.Name != first.Name {
+		t.Errorf("copy uploaded as %v, %v, want %s reused", f, err, first.Name)
+	}
+	// Other content, or the same content with another MIME type, is not.
+	for _, tt := range []struct{ path, mime string }{
+		{filepath.Join(thirdParty, "poem.txt"), ""},
+		{filepath.Join(thirdParty, "poem.txt"), "text/markdown"},
+	} {
+		if _, err := ix.FromPath(t.Context(), client, tt.path, &genai.UploadFileConfig{MIMEType: tt.mime}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := len(srv.Files()); n != 3 {
+		t.Errorf("%d files, want 3", n)
+	}
+}
+
+func TestIndexReuploads(t *testing.T) {
+	srv, client := newFake(t)
+	ix, err := OpenIndex("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	ix.Now = func() time.Time { return now }
+	first, err := ix.FromPath(t.Context(), client, organ, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A file deleted since is uploaded again.
+	if _, err := client.Files.Delete(t.Context(), first.Name, nil); err != nil {
+		t.Fatal(err)
+	}
+	second, err := ix.FromPath(t.Context(), client, organ, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.Name == first.Name {
+		t.Fatalf("deleted file %s reused", first.Name)
+	}
+
+	// So is a file about to expire, without checking it.
+	now = second.ExpirationTime.Add(-30 * time.Minute)
+	gets := len(srv.RequestsTo(http.MethodGet, second.Name))
+	third, err := ix.FromPath(t.Context(), client, organ, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if third.Name == second.Name || len(srv.RequestsTo(http.MethodGet, second.Name)) != gets {
+		t.Errorf("file expiring in 30 minutes reused or checked")
+	}
+
+	// And a file that failed processing.
+	now = time.Now()
+	srv.SetFileState(third.Name, "FAILED")
+	if fourth, err := ix.FromPath(t.Context(), client, organ, nil); err != nil || fourth.Name == third.Name {
+		t.Errorf("FAILED file reused: %v, %v", fourth, err)
+	}
+}
+
+func TestIndexFile(t *testing.T) {
+	srv, client := newFake(t)
+	path := filepath.Join(t.TempDir(), "cache", "uploads.json")
+	ix, err := OpenIndex(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, err := ix.FromPath(t.Context(), client, organ, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Another program reading the index reuses the file.
+	reopened, err := OpenIndex(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := reopened.FromPath(t.Context(), client, organ, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.Name != first.Name || len(srv.Files()) != 1 {
+		t.Errorf("reopened index uploaded %s, want %s reused", second.Name, first.Name)
+	}
+	// Each keeps the entries of the other when saving.
+	if _, err := ix.FromPath(t.Context(), client, filepath.Join(thirdParty, "poem.txt"), nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := reopened.FromPath(t.Context(), client, filepath.Join(thirdParty, "a11.txt"), nil); err != nil {
+		t.Fatal(err)
+	}
+	final, err := OpenIndex(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(final.Entries()); n != 3 {
+		t.Errorf("index file has %d entries, want 3", n)
+	}
+
+	// Other accounts and endpoints do not see the entries.
+	other, client2 := newFake(t)
+	if _, err := final.FromPath(t.Context(), client2, organ, nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(other.Files()) != 1 || len(other.RequestsTo(http.MethodGet, first.Name)) != 0 {
+		t.Errorf("entry of another server checked instead of uploading")
+	}
+}
+
+func TestIndexBadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "uploads.json")
+	if err := os.WriteFile(path, []byte("{"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenIndex(path); err == nil {
+		t.Error("OpenIndex succeeded on a malformed file")
+	}
+}
+
+func TestIndexConcurrent(t *testing.T) {
+	srv, client := newFake(t)
+	ix, err := OpenIndex(filepath.Join(t.TempDir(), "uploads.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	names := make([]string, 10)
+	for i := range names {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			f, err := ix.FromPath(t.Context(), client, organ, nil)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			names[i] = f.Name
+		}()
+	}
+	wg.Wait()
+	if n := len(srv.Files()); n != 1 {
+		t.Errorf("%d uploads, want 1", n)
+	}
+	for _, name := range names {
+		if name != names[0] {
+			t.Errorf("names = %q, want the same file", names)
+			break
+		}
+	}
+}
+
+func TestIndexUntracked(t *testing.T) {
+	srv, client := newFake(t)
+	tr := tracker.New()
+	tracked, err := tr.Client(t.Context(), client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ix, err := OpenIndex("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ix.FromPath(t.Context(), tracked, organ, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.Cleanup(t.Context(), tracked); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(srv.Files()); n != 1 {
+		t.Errorf("%d files after Cleanup, want the shared upload kept", n)
+	}
+}
+
+func TestFromPathEnvIndex(t *testing.T) {
+	srv, client := newFake(t)
+	path := filepath.Join(t.TempDir(), "uploads.json")
+	t.Setenv("GEMINI_UPLOAD_INDEX", path)
+	// envIndex reads the variable once per process.
+	saved := envIndex
+	t.Cleanup(func() { envIndex = saved })
+	envIndex = sync.OnceValues(openEnvIndex)
+	for range 2 {
+		if _, err := FromPath(t.Context(), client, organ, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := len(srv.Files()); n != 1 {
+		t.Errorf("%d uploads, want 1", n)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Error(err)
+	}
+}
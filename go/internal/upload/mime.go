@@ -0,0 +1,227 @@
+package upload
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// supported lists the MIME types the Gemini API accepts in prompts, by
+// family.
+var supported = map[string][]string{
+	"image": {"image/png", "image/jpeg", "image/webp", "image/heic", "image/heif"},
+	"audio": {"audio/wav", "audio/mpeg", "audio/mp3", "audio/aiff", "audio/aac", "audio/ogg", "audio/flac"},
+	"video": {"video/mp4", "video/mpeg", "video/mov", "video/avi", "video/x-flv", "video/mpg", "video/webm", "video/wmv", "video/3gpp"},
+	"text": {"text/plain", "text/html", "text/css", "text/javascript", "text/x-typescript", "text/csv",
+		"text/markdown", "text/x-python", "text/xml", "text/rtf"},
+	"application": {"application/pdf", "application/json", "application/x-javascript",
+		"application/x-typescript", "application/x-python-code", "application/rtf"},
+}
+
+// closest maps each family to the supported type files of an unsupported
+// type of that family are most easily converted to.
+var closest = map[string]string{
+	"image": "image/png",
+	"audio": "audio/wav",
+	"video": "video/mp4",
+	"text":  "text/plain",
+}
+
+// documents lists unsupported application types that convert to PDF.
+var documents = []string{
+	"application/msword",
+	"application/vnd.ms-excel",
+	"application/vnd.ms-powerpoint",
+	"application/vnd.oasis.opendocument.presentation",
+	"application/vnd.oasis.opendocument.spreadsheet",
+	"application/vnd.oasis.opendocument.text",
+	"application/vnd.openxmlformats-officedocument.presentationml.presentation",
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	"application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+	"application/epub+zip",
+	"application/postscript",
+}
+
+// aliases maps other names of supported formats, as returned by sniffing or
+// other tools, to the names the API uses.
+var aliases = map[string]string{
+	"application/ogg":   "audio/ogg",
+	"audio/wave":        "audio/wav",
+	"audio/x-wav":       "audio/wav",
+	"audio/vnd.wave":    "audio/wav",
+	"audio/x-aiff":      "audio/aiff",
+	"audio/x-flac":      "audio/flac",
+	"audio/x-aac":       "audio/aac",
+	"video/quicktime":   "video/mov",
+	"video/x-msvideo":   "video/avi",
+	"video/x-ms-wmv":    "video/wmv",
+	"text/x-markdown":   "text/markdown",
+	"application/xml":   "text/xml",
+	"text/x-javascript": "text/javascript",
+}
+
+// extensions maps lowercase file extensions to MIME types, supported or
+// not, so that detection does not depend on the system's MIME database.
+var extensions = map[string]string{
+	".png":  "image/png",
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".webp": "image/webp",
+	".heic": "image/heic",
+	".heif": "image/heif",
+	".gif":  "image/gif",
+	".bmp":  "image/bmp",
+	".tif":  "image/tiff",
+	".tiff": "image/tiff",
+	".svg":  "image/svg+xml",
+	".wav":  "audio/wav",
+	".mp3":  "audio/mpeg",
+	".aif":  "audio/aiff",
+	".aiff": "audio/aiff",
+	".aac":  "audio/aac",
+	".ogg":  "audio/ogg",
+	".flac": "audio/flac",
+	".m4a":  "audio/mp4",
+	".mid":  "audio/midi",
+	".midi": "audio/midi",
+	".mp4":  "video/mp4",
+	".mpeg": "video/mpeg",
+	".mpg":  "video/mpg",
+	".mov":  "video/mov",
+	".avi":  "video/avi",
+	".flv":  "video/x-flv",
+	".webm": "video/webm",
+	".wmv":  "video/wmv",
+	".3gp":  "video/3gpp",
+	".mkv":  "video/x-matroska",
+	".pdf":  "application/pdf",
+	".txt":  "text/plain",
+	".html": "text/html",
+	".htm":  "text/html",
+	".css":  "text/css",
+	".js":   "text/javascript",
+	".ts":   "text/x-typescript",
+	".csv":  "text/csv",
+	".md":   "text/markdown",
+	".py":   "text/x-python",
+	".json": "application/json",
+	".xml":  "text/xml",
+	".rtf":  "text/rtf",
+	".doc":  "application/msword",
+	".docx": "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+	".xls":  "application/vnd.ms-excel",
+	".xlsx": "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	".ppt":  "application/vnd.ms-powerpoint",
+	".pptx": "application/vnd.openxmlformats-officedocument.presentationml.presentation",
+	".odt":  "application/vnd.oasis.opendocument.text",
+	".epub": "application/epub+zip",
+	".zip":  "application/zip",
+}
+
+// containers lists the types sniffing returns for container formats that
+// hold other formats, which the extension tells apart: WebM is a kind of
+// Matroska, and office documents are ZIP archives.
+var containers = []string{"video/webm", "application/zip"}
+
+// An UnsupportedError reports a file whose MIME type the Gemini API does
+// not accept.
+type UnsupportedError struct {
+	// Name is the name of the file.
+	Name string
+	// MIMEType is the type of the file, application/octet-stream if it
+	// could not be determined.
+	MIMEType string
+	// Closest is the supported type the file is most easily converted to,
+	// or "" if there is none.
+	Closest string
+}
+
+func (e *UnsupportedError) Error() string {
+	msg := fmt.Sprintf("%s: MIME type %s is not supported by the Gemini API", e.Name, e.MIMEType)
+	if e.Closest != "" {
+		msg += "; the closest supported type is " + e.Closest
+	}
+	return msg
+}
+
+// Supported reports whether the Gemini API accepts files of MIME type t.
+// Parameters such as charset are ignored.
+func Supported(t string) bool {
+	t = canonical(t)
+	family, _, _ := strings.Cut(t, "/")
+	return slices.Contains(supported[family], t)
+}
+
+// Closest returns the supported MIME type closest to t: t itself, under the
+// name the API uses, if it is supported, otherwise the supported type files
+// of type t are most easily converted to, or "" if there is none.
+func Closest(t string) string {
+	t = canonical(t)
+	if Supported(t) {
+		return t
+	}
+	if slices.Contains(documents, t) {
+		return "application/pdf"
+	}
+	family, _, _ := strings.Cut(t, "/")
+	return closest[family]
+}
+
+// canonical strips the parameters of MIME type t and returns the name the
+// API uses for it.
+func canonical(t string) string {
+	if mt, _, err := mime.ParseMediaType(t); err == nil {
+		t = mt
+	}
+	t = strings.ToLower(strings.TrimSpace(t))
+	if a, ok := aliases[t]; ok {
+		return a
+	}
+	return t
+}
+
+// sniffLen is the length of the head of a file that Detect needs.
+const sniffLen = 512
+
+// Detect returns the MIME type of a file named name whose content starts
+// with head, of which the first sniffLen bytes are enough. The content decides
+// for the formats it identifies; the extension decides otherwise, and
+// refines plain text, such as Python source, and containers, such as ZIP
+// archives that are office documents. It returns an
+// *UnsupportedError if the type is not supported.
+func Detect(name string, head []byte) (string, error) {
+	byExt := extensions[strings.ToLower(filepath.Ext(name))]
+	sniffed := canonical(http.DetectContentType(head))
+	t := sniffed
+	switch {
+	case byExt == "":
+	case sniffed == "application/octet-stream", sniffed == "text/plain", slices.Contains(containers, sniffed):
+		t = byExt
+	case strings.HasPrefix(sniffed, "text/") && strings.HasPrefix(byExt, "text/"):
+		t = byExt
+	}
+	if !Supported(t) {
+		return "", &UnsupportedError{Name: name, MIMEType: t, Closest: Closest(t)}
+	}
+	return t, nil
+}
+
+// DetectFile returns the MIME type of the file at path, as Detect does.
+func DetectFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	head := make([]byte, sniffLen)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", err
+	}
+	return Detect(path, head[:n])
+}
@@ -0,0 +1,126 @@
+package upload
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// thirdParty is the directory of the samples' media.
+const thirdParty = "../../../third_party"
+
+func TestDetectFileThirdParty(t *testing.T) {
+	want := map[string]string{
+		"Big_Buck_Bunny.mp4":    "video/mp4",
+		"Cajun_instruments.jpg": "image/jpeg",
+		"LICENSE.txt":           "text/plain",
+		"a11.txt":               "text/plain",
+		"organ.jpg":             "image/jpeg",
+		"poem.txt":              "text/plain",
+		"test.pdf":              "application/pdf",
+	}
+	entries, err := os.ReadDir(thirdParty)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		w, ok := want[e.Name()]
+		if !ok {
+			t.Errorf("%s: no expected MIME type; add one to the test", e.Name())
+			continue
+		}
+		got, err := DetectFile(filepath.Join(thirdParty, e.Name()))
+		if err != nil || got != w {
+			t.Errorf("DetectFile(%s) = %q, %v, want %q", e.Name(), got, err, w)
+		}
+	}
+}
+
+func TestDetect(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	tests := []struct {
+		name string
+		head string
+		want string
+	}{
+		// The content decides for the formats it identifies.
+		{"photo.jpg", string(png), "image/png"},
+		{"photo", string(png), "image/png"},
+		{"sound.bin", "RIFF\x00\x00\x00\x00WAVEfmt ", "audio/wav"},
+		{"clip.ogg", "OggS\x00", "audio/ogg"},
+		// The extension decides otherwise.
+		{"clip.mov", "\x00\x00\x00\x14ftypqt  ", "video/mov"},
+		{"photo.HEIC", "\x00\x00\x00\x18ftypheic", "image/heic"},
+		{"clip.mp4", "video", "video/mp4"},
+		{"clip.webm", "\x1a\x45\xdf\xa3\x01", "video/webm"},
+		// and refines text.
+		{"script.py", "print('hello')\n", "text/x-python"},
+		{"data.json", `{"a": 1}`, "application/json"},
+		{"notes.md", "<p>Hello</p>", "text/markdown"},
+		{"notes", "Hello, world.\n", "text/plain"},
+		{"page", "<!DOCTYPE html><html></html>", "text/html"},
+	}
+	for _, tt := range tests {
+		got, err := Detect(tt.name, []byte(tt.head))
+		if err != nil || got != tt.want {
+			t.Errorf("Detect(%q, %q) = %q, %v, want %q", tt.name, tt.head, got, err, tt.want)
+		}
+	}
+}
+
+func TestDetectUnsupported(t *testing.T) {
+	tests := []struct {
+		name    string
+		head    string
+		mime    string
+		closest string
+	}{
+		{"anim.gif", "GIF89a", "image/gif", "image/png"},
+		{"anim.png", "GIF89a", "image/gif", "image/png"},
+		{"scan.tiff", "II*\x00", "image/tiff", "image/png"},
+		{"tune.mid", "MThd\x00\x00\x00\x06", "audio/midi", "audio/wav"},
+		{"film.mkv", "\x1a\x45\xdf\xa3\x01", "video/x-matroska", "video/mp4"},
+		{"report.docx", "PK\x03\x04", "application/vnd.openxmlformats-officedocument.wordprocessingml.document", "application/pdf"},
+		{"archive.zip", "PK\x03\x04", "application/zip", ""},
+		{"blob", "\x00\x01\x02\x03", "application/octet-stream", ""},
+	}
+	for _, tt := range tests {
+		_, err := Detect(tt.name, []byte(tt.head))
+		var unsupported *UnsupportedError
+		if !errors.As(err, &unsupported) {
+			t.Errorf("Detect(%q) error = %v, want an *UnsupportedError", tt.name, err)
+			continue
+		}
+		if unsupported.MIMEType != tt.mime || unsupported.Closest != tt.closest {
+			t.Errorf("Detect(%q) = %+v, want type %q and closest %q", tt.name, unsupported, tt.mime, tt.closest)
+		}
+		if tt.closest != "" && !strings.HasSuffix(err.Error(), "the closest supported type is "+tt.closest) {
+			t.Errorf("error %q does not name the closest type", err)
+		}
+	}
+}
+
+func TestSupported(t *testing.T) {
+	for _, tt := range []struct {
+		mime string
+		want bool
+	}{
+		{"text/plain; charset=utf-8", true},
+		{"Image/JPEG", true},
+		{"audio/x-wav", true},
+		{"video/quicktime", true},
+		{"application/pdf", true},
+		{"image/gif", false},
+		{"application/zip", false},
+		{"", false},
+	} {
+		if got := Supported(tt.mime); got != tt.want {
+			t.Errorf("Supported(%q) = %v, want %v", tt.mime, got, tt.want)
+		}
+	}
+	if got := Closest("audio/x-wav"); got != "audio/wav" {
+		t.Errorf("Closest(audio/x-wav) = %q, want the API's name audio/wav", got)
+	}
+}
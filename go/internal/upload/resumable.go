@@ -0,0 +1,355 @@
+package upload
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/genai"
+)
+
+// DefaultChunkSize is the number of bytes a resumable upload sends per
+// request by default, the same as Files.Upload.
+const DefaultChunkSize = 8 << 20
+
+// ResumableOptions controls resumable uploads.
+type ResumableOptions struct {
+	// ChunkSize is the number of bytes sent per request, DefaultChunkSize if
+	// not positive. It is rounded up to the granularity the server
+	// requires, if any.
+	ChunkSize int64
+	// Retries is the number of failures in a row after which the upload
+	// gives up, 5 if zero; a negative value disables retries. Only network
+	// errors and 408, 429 and 5xx responses are retried.
+	Retries int
+	// RetryDelay is the wait before the first retry, doubled after each
+	// failure in a row up to 30 seconds. It defaults to one second.
+	RetryDelay time.Duration
+	// Progress, if not nil, is called with the number of bytes the server
+	// has received and the size of the file, after each chunk and when the
+	// upload resumes.
+	Progress func(sent, total int64)
+}
+
+// A Session is a resumable upload through the Files API: the file is sent
+// in chunks, and after a failure the session asks the server how much it
+// received and resumes from there. The URL identifies the session, so that
+// another program can resume it with OpenSession; the server keeps it for
+// about a week.
+type Session struct {
+	URL  string
+	Size int64
+
+	hc          *http.Client
+	header      http.Header
+	granularity int64
+	// offset is the number of bytes the server has, or -1 if unknown.
+	offset int64
+}
+
+// StartSession starts a resumable upload of size bytes. The MIME type of
+// config is required.
+func StartSession(ctx context.Context, client *genai.Client, size int64, config *genai.UploadFileConfig) (*Session, error) {
+	s, err := newSession(client, "", size)
+	if err != nil {
+		return nil, err
+	}
+	if config == nil || config.MIMEType == "" {
+		return nil, errors.New("resumable upload: missing MIME type")
+	}
+	var meta struct {
+		File struct {
+			Name        string `json:"name,omitempty"`
+			DisplayName string `json:"displayName,omitempty"`
+			MIMEType    string `json:"mimeType"`
+		} `json:"file"`
+	}
+	meta.File.Name = config.Name
+	if meta.File.Name != "" && !strings.HasPrefix(meta.File.Name, "files/") {
+		meta.File.Name = "files/" + meta.File.Name
+	}
+	meta.File.DisplayName = config.DisplayName
+	meta.File.MIMEType = config.MIMEType
+	body, err := json.Marshal(meta)
+	if err != nil {
+		return nil, err
+	}
+	cc := client.ClientConfig()
+	endpoint := strings.TrimSuffix(cc.HTTPOptions.BaseURL, "/") + "/upload/" + cc.HTTPOptions.APIVersion + "/files"
+	resp, err := s.do(ctx, endpoint, body, map[string]string{
+		"Content-Type":                        "application/json",
+		"X-Goog-Upload-Protocol":              "resumable",
+		"X-Goog-Upload-Command":               "start",
+		"X-Goog-Upload-Header-Content-Length": strconv.FormatInt(size, 10),
+		"X-Goog-Upload-Header-Content-Type":   config.MIMEType,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("starting resumable upload: %w", err)
+	}
+	s.URL = resp.header.Get("X-Goog-Upload-Url")
+	if s.URL == "" {
+		return nil, errors.New("starting resumable upload: no upload URL returned")
+	}
+	if g, err := strconv.ParseInt(resp.header.Get("X-Goog-Upload-Chunk-Granularity"), 10, 64); err == nil && g > 0 {
+		s.granularity = g
+	}
+	s.offset = 0
+	return s, nil
+}
+
+// OpenSession returns the session of a resumable upload of size bytes
+// started before, whose upload URL is uploadURL. Upload asks the server
+// where to resume.
+func OpenSession(client *genai.Client, uploadURL string, size int64) (*Session, error) {
+	return newSession(client, uploadURL, size)
+}
+
+func newSession(client *genai.Client, uploadURL string, size int64) (*Session, error) {
+	cc := client.ClientConfig()
+	if cc.Backend == genai.BackendVertexAI {
+		return nil, errors.New("resumable uploads are only supported by the Gemini API")
+	}
+	if size < 0 {
+		return nil, fmt.Errorf("resumable upload: negative size %d", size)
+	}
+	header := cc.HTTPOptions.Headers.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	if cc.APIKey != "" {
+		header.Set("x-goog-api-key", cc.APIKey)
+	}
+	hc := cc.HTTPClient
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	return &Session{URL: uploadURL, Size: size, hc: hc, header: header, offset: -1}, nil
+}
+
+// Query returns the number of bytes the server has received, and the file
+// if the upload is complete.
+func (s *Session) Query(ctx context.Context) (int64, *genai.File, error) {
+	resp, err := s.do(ctx, s.URL, nil, map[string]string{"X-Goog-Upload-Command": "query"})
+	if err != nil {
+		return 0, nil, fmt.Errorf("querying resumable upload: %w", err)
+	}
+	f, err := s.parse(resp)
+	if err != nil {
+		return 0, nil, err
+	}
+	received, err := strconv.ParseInt(resp.header.Get("X-Goog-Upload-Size-Received"), 10, 64)
+	if f == nil && (err != nil || received < 0 || received > s.Size) {
+		return 0, nil, fmt.Errorf("querying resumable upload: bad received size %q", resp.header.Get("X-Goog-Upload-Size-Received"))
+	}
+	return received, f, nil
+}
+
+// Upload sends the Size bytes of r, from where the server is, and returns
+// the file once they are all received. Failed requests are retried as set
+// by opts, which may be nil, after asking the server how much it has
+// received.
+func (s *Session) Upload(ctx context.Context, r io.ReaderAt, opts *ResumableOptions) (*genai.File, error) {
+	var o ResumableOptions
+	if opts != nil {
+		o = *opts
+	}
+	chunk := o.ChunkSize
+	if chunk <= 0 {
+		chunk = DefaultChunkSize
+	}
+	if g := s.granularity; g > 0 {
+		chunk = (chunk + g - 1) / g * g
+	}
+	retries := o.Retries
+	if retries == 0 {
+		retries = 5
+	}
+	delay := o.RetryDelay
+	if delay <= 0 {
+		delay = time.Second
+	}
+	progress := func() {
+		if o.Progress != nil {
+			o.Progress(s.offset, s.Size)
+		}
+	}
+
+	buf := make([]byte, min(chunk, s.Size))
+	failures := 0
+	for {
+		var f *genai.File
+		var err error
+		if s.offset < 0 {
+			var received int64
+			if received, f, err = s.Query(ctx); err == nil && f == nil {
+				s.offset = received
+				progress()
+			}
+		} else {
+			n := min(chunk, s.Size-s.offset)
+			if n > 0 {
+				if m, err := r.ReadAt(buf[:n], s.offset); int64(m) < n {
+					if err == io.EOF {
+						err = io.ErrUnexpectedEOF
+					}
+					return nil, fmt.Errorf("reading at offset %d: %w", s.offset+int64(m), err)
+				}
+			}
+			command := "upload"
+			if s.offset+n == s.Size {
+				command = "upload, finalize"
+			}
+			f, err = s.send(ctx, command, buf[:n])
+			if err == nil {
+				s.offset += n
+				failures = 0
+				progress()
+				if f == nil && s.offset == s.Size {
+					return nil, errors.New("resumable upload: not finalized after the last chunk")
+				}
+			}
+		}
+		switch {
+		case f != nil:
+			return f, nil
+		case err == nil:
+			continue
+		case !retryable(err) || ctx.Err() != nil:
+			return nil, err
+		}
+		failures++
+		if retries < 0 || failures > retries {
+			return nil, fmt.Errorf("resumable upload gave up after %d failures: %w", failures, err)
+		}
+		// The server may have received part of the request.
+		s.offset = -1
+		wait := delay
+		for range failures - 1 {
+			wait = min(wait*2, 30*time.Second)
+		}
+		t := time.NewTimer(min(wait, 30*time.Second))
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// send sends data at the current offset with command and returns the file
+// if the upload is complete.
+func (s *Session) send(ctx context.Context, command string, data []byte) (*genai.File, error) {
+	resp, err := s.do(ctx, s.URL, data, map[string]string{
+		"X-Goog-Upload-Command": command,
+		"X-Goog-Upload-Offset":  strconv.FormatInt(s.offset, 10),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("uploading at offset %d: %w", s.offset, err)
+	}
+	return s.parse(resp)
+}
+
+// do posts body to endpoint with the session's headers and extra, and returns
+// the response, with its body read, or a genai.APIError for an error status.
+func (s *Session) do(ctx context.Context, endpoint string, body []byte, extra map[string]string) (*response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header = s.header.Clone()
+	for k, v := range extra {
+		req.Header.Set(k, v)
+	}
+	resp, err := s.hc.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		var e struct{ Error *genai.APIError }
+		if json.Unmarshal(data, &e) != nil || e.Error == nil {
+			e.Error = &genai.APIError{Code: resp.StatusCode, Message: string(data)}
+		}
+		return nil, *e.Error
+	}
+	return &response{header: resp.Header, body: data}, nil
+}
+
+// A response is an HTTP response with its body read.
+type response struct {
+	header http.Header
+	body   []byte
+}
+
+// parse returns the file of a response whose upload status is final, or nil
+// if the upload is still active.
+func (s *Session) parse(resp *response) (*genai.File, error) {
+	switch status := resp.header.Get("X-Goog-Upload-Status"); status {
+	case "active":
+		return nil, nil
+	case "final":
+		var body struct{ File *genai.File }
+		if err := json.Unmarshal(resp.body, &body); err != nil || body.File == nil {
+			return nil, fmt.Errorf("resumable upload: bad final response: %s", resp.body)
+		}
+		return body.File, nil
+	default:
+		return nil, fmt.Errorf("resumable upload: upload status %q", status)
+	}
+}
+
+// retryable reports whether a request that failed with err may succeed if
+// sent again: the connection failed, or the server asked for a retry.
+func retryable(err error) bool {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	var apiErr genai.APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	return apiErr.Code == http.StatusRequestTimeout || apiErr.Code == http.StatusTooManyRequests || apiErr.Code/100 == 5
+}
+
+// Resumable uploads size bytes read from r through a new Session.
+func Resumable(ctx context.Context, client *genai.Client, r io.ReaderAt, size int64, config *genai.UploadFileConfig, opts *ResumableOptions) (*genai.File, error) {
+	s, err := StartSession(ctx, client, size, config)
+	if err != nil {
+		return nil, err
+	}
+	return s.Upload(ctx, r, opts)
+}
+
+// ResumableFromPath uploads the file at path like FromPath, through a new
+// Session.
+func ResumableFromPath(ctx context.Context, client *genai.Client, path string, config *genai.UploadFileConfig, opts *ResumableOptions) (*genai.File, error) {
+	cfg, err := resolve(path, config)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return Resumable(ctx, client, f, info.Size(), cfg, opts)
+}
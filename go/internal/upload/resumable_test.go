@@ -0,0 +1,249 @@
+package upload
+
+import (
+	"bytes"
+	"errors"
+	"math/rand/v2"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"gemini-api-examples/internal/fakegemini"
+	"gemini-api-examples/internal/tracker"
+
+	"google.golang.org/genai"
+)
+
+// video returns size bytes of random content.
+func video(size int) []byte {
+	data := make([]byte, size)
+	r := rand.NewChaCha8([32]byte{})
+	r.Read(data)
+	return data
+}
+
+// chunkCommands returns the commands of the requests carrying bytes of
+// uploads, in order.
+func chunkCommands(srv *fakegemini.Server) []string {
+	var commands []string
+	for _, r := range srv.RequestsTo(http.MethodPost, "upload/v1beta/files") {
+		if c := r.Header.Get("X-Goog-Upload-Command"); c != "start" {
+			commands = append(commands, c)
+		}
+	}
+	return commands
+}
+
+func checkUploaded(t *testing.T, srv *fakegemini.Server, f *genai.File, want []byte) {
+	t.Helper()
+	got, ok := srv.FileData(f.Name)
+	if !ok {
+		t.Fatalf("file %s not found", f.Name)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("uploaded %d bytes differing from the %d bytes of the file", len(got), len(want))
+	}
+}
+
+func TestResumable(t *testing.T) {
+	srv, client := newFake(t)
+	data := video(1 << 20)
+	var progress []int64
+	opts := &ResumableOptions{
+		ChunkSize: 300 << 10,
+		Progress: func(sent, total int64) {
+			if total != int64(len(data)) {
+				t.Errorf("Progress total = %d, want %d", total, len(data))
+			}
+			progress = append(progress, sent)
+		},
+	}
+	f, err := Resumable(t.Context(), client, bytes.NewReader(data), int64(len(data)), &genai.UploadFileConfig{MIMEType: "video/mp4", DisplayName: "clip"}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.DisplayName != "clip" || f.MIMEType != "video/mp4" {
+		t.Errorf("file = %+v, want the display name and MIME type given", f)
+	}
+	checkUploaded(t, srv, f, data)
+	if got, want := strings.Join(chunkCommands(srv), "|"), "upload|upload|upload|upload, finalize"; got != want {
+		t.Errorf("commands = %s, want %s", got, want)
+	}
+	if want := []int64{300 << 10, 600 << 10, 900 << 10, 1 << 20}; !slices.Equal(progress, want) {
+		t.Errorf("progress = %v, want %v", progress, want)
+	}
+}
+
+func TestResumableEmpty(t *testing.T) {
+	srv, client := newFake(t)
+	f, err := Resumable(t.Context(), client, bytes.NewReader(nil), 0, &genai.UploadFileConfig{MIMEType: "text/plain"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkUploaded(t, srv, f, nil)
+}
+
+func TestResumableDroppedConnections(t *testing.T) {
+	srv, client := newFake(t)
+	data := video(1 << 20)
+	// The first chunk breaks after 100000 bytes, and the one resuming it
+	// after 5 more.
+	srv.Inject("files", 1, fakegemini.DropUpload(100000))
+	srv.Inject("files", 1, fakegemini.DropUpload(5))
+	var progress []int64
+	opts := &ResumableOptions{
+		ChunkSize:  256 << 10,
+		RetryDelay: time.Millisecond,
+		Progress:   func(sent, _ int64) { progress = append(progress, sent) },
+	}
+	f, err := Resumable(t.Context(), client, bytes.NewReader(data), int64(len(data)), &genai.UploadFileConfig{MIMEType: "video/mp4"}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkUploaded(t, srv, f, data)
+	want := "upload|query|upload|query|upload|upload|upload|upload, finalize"
+	if got := strings.Join(chunkCommands(srv), "|"); got != want {
+		t.Errorf("commands = %s, want %s", got, want)
+	}
+	if len(progress) < 3 || progress[0] != 100000 || progress[1] != 100005 || progress[len(progress)-1] != 1<<20 {
+		t.Errorf("progress = %v, want to resume at 100000 and 100005 and end at %d", progress, 1<<20)
+	}
+}
+
+func TestResumableLostFinalResponse(t *testing.T) {
+	srv, client := newFake(t)
+	data := video(10 << 10)
+	// All the bytes arrive, but the connection breaks before the upload
+	// is finalized.
+	srv.Inject("files", 1, fakegemini.DropUpload(len(data)))
+	f, err := Resumable(t.Context(), client, bytes.NewReader(data), int64(len(data)), &genai.UploadFileConfig{MIMEType: "video/mp4"}, &ResumableOptions{RetryDelay: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkUploaded(t, srv, f, data)
+	if got, want := strings.Join(chunkCommands(srv), "|"), "upload, finalize|query|upload, finalize"; got != want {
+		t.Errorf("commands = %s, want %s", got, want)
+	}
+}
+
+func TestResumableGivesUp(t *testing.T) {
+	srv, client := newFake(t)
+	srv.Inject("files", 0, fakegemini.DropUpload(0))
+	opts := &ResumableOptions{ChunkSize: 1 << 10, Retries: 2, RetryDelay: time.Millisecond}
+	_, err := Resumable(t.Context(), client, bytes.NewReader(video(4<<10)), 4<<10, &genai.UploadFileConfig{MIMEType: "video/mp4"}, opts)
+	if err == nil || !strings.Contains(err.Error(), "gave up after 3 failures") {
+		t.Errorf("err = %v, want giving up after 3 failures", err)
+	}
+	if n := len(chunkCommands(srv)); n != 5 {
+		t.Errorf("made %d upload and query requests, want 3 uploads and 2 queries", n)
+	}
+	if len(srv.Files()) != 0 {
+		t.Error("a file was created")
+	}
+}
+
+func TestResumableServerErrors(t *testing.T) {
+	srv, client := newFake(t)
+	data := video(8 << 10)
+	s, err := StartSession(t.Context(), client, int64(len(data)), &genai.UploadFileConfig{MIMEType: "video/mp4"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.Inject("files", 2, fakegemini.ServerError(http.StatusServiceUnavailable))
+	f, err := s.Upload(t.Context(), bytes.NewReader(data), &ResumableOptions{ChunkSize: 4 << 10, RetryDelay: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkUploaded(t, srv, f, data)
+
+	// Client errors are not retried.
+	s, err = StartSession(t.Context(), client, int64(len(data)), &genai.UploadFileConfig{MIMEType: "video/mp4"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.Inject("files", 1, fakegemini.ServerError(http.StatusForbidden))
+	_, err = s.Upload(t.Context(), bytes.NewReader(data), &ResumableOptions{RetryDelay: time.Millisecond})
+	var apiErr genai.APIError
+	if !errors.As(err, &apiErr) || apiErr.Code != http.StatusForbidden {
+		t.Errorf("err = %v, want the 403 error", err)
+	}
+}
+
+func TestOpenSession(t *testing.T) {
+	srv, client := newFake(t)
+	data := video(64 << 10)
+	s, err := StartSession(t.Context(), client, int64(len(data)), &genai.UploadFileConfig{MIMEType: "video/mp4"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The first program stops when the connection breaks.
+	srv.Inject("files", 1, fakegemini.DropUpload(20000))
+	if _, err := s.Upload(t.Context(), bytes.NewReader(data), &ResumableOptions{ChunkSize: 32 << 10, Retries: -1}); err == nil {
+		t.Fatal("upload succeeded despite the dropped connection")
+	}
+
+	// Another one resumes from the upload URL.
+	resumed, err := OpenSession(client, s.URL, int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var progress []int64
+	f, err := resumed.Upload(t.Context(), bytes.NewReader(data), &ResumableOptions{
+		ChunkSize: 32 << 10,
+		Progress:  func(sent, _ int64) { progress = append(progress, sent) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkUploaded(t, srv, f, data)
+	if len(progress) == 0 || progress[0] != 20000 {
+		t.Errorf("progress = %v, want to resume at 20000", progress)
+	}
+
+	// Once finalized, the session only reports the file.
+	n, again, err := resumed.Query(t.Context())
+	if err != nil || again == nil || again.Name != f.Name || n != int64(len(data)) {
+		t.Errorf("Query = %d, %v, %v, want the size and the file", n, again, err)
+	}
+}
+
+func TestResumableTracked(t *testing.T) {
+	srv, client := newFake(t)
+	tr := tracker.New()
+	tracked, err := tr.Client(t.Context(), client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Resumable(t.Context(), tracked, bytes.NewReader(video(100)), 100, &genai.UploadFileConfig{MIMEType: "video/mp4"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.Cleanup(t.Context(), tracked); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(srv.Files()); n != 0 {
+		t.Errorf("%d files after Cleanup, want the upload deleted", n)
+	}
+}
+
+func TestResumableFromPath(t *testing.T) {
+	srv, client := newFake(t)
+	f, err := ResumableFromPath(t.Context(), client, filepath.Join(thirdParty, "test.pdf"), nil, &ResumableOptions{ChunkSize: 64 << 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.MIMEType != "application/pdf" {
+		t.Errorf("MIME type = %q, want application/pdf", f.MIMEType)
+	}
+	if n := len(chunkCommands(srv)); n < 2 {
+		t.Errorf("sent %d chunks, want several", n)
+	}
+
+	_, err = ResumableFromPath(t.Context(), client, filepath.Join(thirdParty, "test.pdf"), &genai.UploadFileConfig{MIMEType: "image/gif"}, nil)
+	var unsupported *UnsupportedError
+	if !errors.As(err, &unsupported) {
+		t.Errorf("err = %v, want an *UnsupportedError", err)
+	}
+}
@@ -0,0 +1,78 @@
+// Package upload uploads local files to the Files API.
+//
+// FromPath works like Files.UploadFromPath but detects the MIME type of the
+// file when none is given, and rejects files whose type the Gemini API does
+// not accept before sending them:
+//
+//	file, err := upload.FromPath(ctx, client, "organ.jpg", nil)
+//
+// A Builder builds the parts of a request from local media, inline while
+// the request stays small and through the Files API otherwise.
+//
+// Resumable sends large files in chunks and resumes uploads that break
+// partway from where the server left off.
+//
+// An Index reuses the remote copy of content uploaded before instead of
+// uploading it again. FromPath uses the index file named by the
+// GEMINI_UPLOAD_INDEX environment variable, if set.
+package upload
+
+import (
+	"context"
+	"os"
+	"sync"
+
+	"google.golang.org/genai"
+)
+
+// FromPath uploads the file at path like client.Files.UploadFromPath. If
+// config has no MIME type, it is detected with DetectFile. Files whose type
+// is not supported are rejected with an *UnsupportedError before anything
+// is sent. When GEMINI_UPLOAD_INDEX names an index file, uploads go through
+// that Index.
+func FromPath(ctx context.Context, client *genai.Client, path string, config *genai.UploadFileConfig) (*genai.File, error) {
+	ix, err := envIndex()
+	if err != nil {
+		return nil, err
+	}
+	if ix != nil {
+		return ix.FromPath(ctx, client, path, config)
+	}
+	cfg, err := resolve(path, config)
+	if err != nil {
+		return nil, err
+	}
+	return client.Files.UploadFromPath(ctx, path, cfg)
+}
+
+// envIndex returns the index named by GEMINI_UPLOAD_INDEX, or nil, opened
+// once.
+var envIndex = sync.OnceValues(openEnvIndex)
+
+func openEnvIndex() (*Index, error) {
+	path := os.Getenv("GEMINI_UPLOAD_INDEX")
+	if path == "" {
+		return nil, nil
+	}
+	return OpenIndex(path)
+}
+
+// resolve returns a copy of config, which may be nil, with the MIME type of
+// the file at path detected if not set, after checking that the type is
+// supported.
+func resolve(path string, config *genai.UploadFileConfig) (*genai.UploadFileConfig, error) {
+	var cfg genai.UploadFileConfig
+	if config != nil {
+		cfg = *config
+	}
+	if cfg.MIMEType == "" {
+		t, err := DetectFile(path)
+		if err != nil {
+			return nil, err
+		}
+		cfg.MIMEType = t
+	} else if !Supported(cfg.MIMEType) {
+		return nil, &UnsupportedError{Name: path, MIMEType: canonical(cfg.MIMEType), Closest: Closest(cfg.MIMEType)}
+	}
+	return &cfg, nil
+}
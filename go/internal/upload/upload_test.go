@@ -0,0 +1,74 @@
+package upload
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gemini-api-examples/internal/config"
+	"gemini-api-examples/internal/fakegemini"
+
+	"google.golang.org/genai"
+)
+
+func newFake(t *testing.T) (*fakegemini.Server, *genai.Client) {
+	t.Helper()
+	srv := fakegemini.New()
+	t.Cleanup(srv.Close)
+	cfg := &config.Config{APIKey: "fake", BaseURL: srv.URL}
+	client, err := cfg.NewClient(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return srv, client
+}
+
+func TestFromPath(t *testing.T) {
+	_, client := newFake(t)
+	for name, want := range map[string]string{
+		"test.pdf":  "application/pdf",
+		"organ.jpg": "image/jpeg",
+		"poem.txt":  "text/plain",
+	} {
+		f, err := FromPath(t.Context(), client, filepath.Join(thirdParty, name), &genai.UploadFileConfig{DisplayName: name})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if f.MIMEType != want || f.DisplayName != name {
+			t.Errorf("%s uploaded as %q with type %q, want type %q", name, f.DisplayName, f.MIMEType, want)
+		}
+	}
+}
+
+func TestFromPathExplicitType(t *testing.T) {
+	_, client := newFake(t)
+	f, err := FromPath(t.Context(), client, filepath.Join(thirdParty, "a11.txt"), &genai.UploadFileConfig{MIMEType: "text/markdown"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.MIMEType != "text/markdown" {
+		t.Errorf("MIME type = %q, want the one given", f.MIMEType)
+	}
+}
+
+func TestFromPathUnsupported(t *testing.T) {
+	srv, client := newFake(t)
+	gif := filepath.Join(t.TempDir(), "anim.gif")
+	if err := os.WriteFile(gif, []byte("GIF89a\x01\x00\x01\x00"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	_, err := FromPath(t.Context(), client, gif, nil)
+	var unsupported *UnsupportedError
+	if !errors.As(err, &unsupported) || unsupported.Closest != "image/png" {
+		t.Errorf("err = %v, want an *UnsupportedError suggesting image/png", err)
+	}
+	_, err = FromPath(t.Context(), client, filepath.Join(thirdParty, "organ.jpg"), &genai.UploadFileConfig{MIMEType: "image/bmp"})
+	if !errors.As(err, &unsupported) || unsupported.MIMEType != "image/bmp" {
+		t.Errorf("err = %v, want an *UnsupportedError for the type given", err)
+	}
+	if n := len(srv.Requests()); n != 0 {
+		t.Errorf("got %d requests, want none for unsupported files", n)
+	}
+}
@@ -3,8 +3,8 @@ package examples
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
-	"log"
 
 	"google.golang.org/genai"
 )
@@ -17,14 +17,13 @@ func ModelsList() error {
 		Backend: genai.BackendGeminiAPI,
 	})
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
-
 	// Retrieve the list of models.
 	models, err := client.Models.List(ctx, &genai.ListModelsConfig{})
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	fmt.Println("List of models that support generateContent:")
@@ -50,6 +49,10 @@ func ModelsList() error {
 	return err
 }
 
+func ModelsListWithClient(ctx context.Context, client *genai.Client, w io.Writer) error {
+	return runWith(ctx, client, w, ModelsList)
+}
+
 func ModelsGet() error {
 	// [START models_get]
 	ctx := context.Background()
@@ -58,15 +61,18 @@ func ModelsGet() error {
 		Backend: genai.BackendGeminiAPI,
 	})
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
-
 	modelInfo, err := client.Models.Get(ctx, "gemini-3.5-flash", nil)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	fmt.Println(modelInfo)
 	// [END models_get]
 	return err
 }
+
+func ModelsGetWithClient(ctx context.Context, client *genai.Client, w io.Writer) error {
+	return runWith(ctx, client, w, ModelsGet)
+}
@@ -1,10 +1,13 @@
 package examples
 
 import (
+	"bytes"
+	"context"
 	"testing"
 )
 
 func TestModelsList(t *testing.T) {
+	useCassette(t)
 	err := ModelsList()
 	if err != nil {
 		t.Errorf("ModelsList returned an error.")
@@ -12,8 +15,22 @@ func TestModelsList(t *testing.T) {
 }
 
 func TestModelsGet(t *testing.T) {
+	useCassette(t)
 	err := ModelsGet()
 	if err != nil {
 		t.Errorf("ModelsGet returned an error.")
 	}
 }
+
+func TestFakeModelsListWithClient(t *testing.T) {
+	_, client := fakeClient(t)
+	var out bytes.Buffer
+	if err := ModelsListWithClient(context.Background(), client, &out); err != nil {
+		t.Fatalf("ModelsListWithClient returned an error: %v", err)
+	}
+	want := "List of models that support generateContent:\nmodels/gemini-3.5-flash\n\n" +
+		"List of models that support embedContent:\nmodels/gemini-embedding-001\n"
+	if got := out.String(); got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,772 @@
+package examples
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"google.golang.org/genai"
+)
+
+// A Modality is a kind of content a sample sends to the model.
+type Modality string
+
+const (
+	ModalityText  Modality = "text"
+	ModalityImage Modality = "image"
+	ModalityAudio Modality = "audio"
+	ModalityVideo Modality = "video"
+	ModalityPDF   Modality = "pdf"
+)
+
+// A Tool is a tool a sample lets the model use.
+type Tool string
+
+const (
+	ToolCodeExecution   Tool = "code-execution"
+	ToolGoogleSearch    Tool = "google-search"
+	ToolFunctionCalling Tool = "function-calling"
+)
+
+// A Resource is a kind of remote resource a sample creates.
+type Resource string
+
+const (
+	ResourceFile  Resource = "file"
+	ResourceCache Resource = "cache"
+)
+
+// A Sample describes one of the samples: what it needs and what it
+// exercises, so that tests and tools can select and run subsets of them.
+type Sample struct {
+	// Name is the name of the sample's function, such as "CacheCreate".
+	Name string
+	// Tag is the region tag of the sample's snippet.
+	Tag string
+	// File is the Go file defining the sample.
+	File string
+	// Modalities lists the kinds of content the sample sends to the
+	// model, from its prompts and media.
+	Modalities []Modality
+	// Media lists the files of third_party the sample reads.
+	Media []string
+	// Tools lists the tools the sample enables.
+	Tools []Tool
+	// Creates lists the kinds of remote resources the sample creates, such
+	// as uploaded files or caches.
+	Creates []Resource
+	// Streams reports whether the sample streams responses.
+	Streams bool
+	// Features lists the other parts of the API the sample exercises, such
+	// as "chat", "tokens" or "cache".
+	Features []string
+	// Run runs the WithClient variant of the sample.
+	Run func(ctx context.Context, client *genai.Client, w io.Writer) error
+}
+
+// CreatesResources reports whether the sample creates remote resources,
+// which cost quota and may be left behind if it fails.
+func (s *Sample) CreatesResources() bool {
+	return len(s.Creates) > 0
+}
+
+// Labels returns the labels describing the sample, by which it can be
+// selected: its modalities, tools and features, "streaming" if it
+// streams, and "creates" and "creates-" followed by the kind of each
+// resource it creates, such as "creates-file".
+func (s *Sample) Labels() []string {
+	var labels []string
+	for _, m := range s.Modalities {
+		labels = append(labels, string(m))
+	}
+	for _, t := range s.Tools {
+		labels = append(labels, string(t))
+	}
+	if s.Streams {
+		labels = append(labels, "streaming")
+	}
+	if s.CreatesResources() {
+		labels = append(labels, "creates")
+	}
+	for _, r := range s.Creates {
+		labels = append(labels, "creates-"+string(r))
+	}
+	return append(labels, s.Features...)
+}
+
+// HasLabel reports whether label is one of the sample's labels.
+func (s *Sample) HasLabel(label string) bool {
+	return slices.Contains(s.Labels(), label)
+}
+
+// MissingMedia returns the files of Media that are not in third_party.
+func (s *Sample) MissingMedia() []string {
+	var missing []string
+	for _, name := range s.Media {
+		if _, err := os.Stat(filepath.Join(getMedia(), name)); err != nil {
+			missing = append(missing, name)
+		}
+	}
+	return missing
+}
+
+// SampleByTag returns the sample with the given region tag, or nil.
+func SampleByTag(tag string) *Sample {
+	for i := range Samples {
+		if Samples[i].Tag == tag {
+			return &Samples[i]
+		}
+	}
+	return nil
+}
+
+// SelectFunc returns the samples for which keep returns true.
+func SelectFunc(keep func(*Sample) bool) []*Sample {
+	var samples []*Sample
+	for i := range Samples {
+		if keep(&Samples[i]) {
+			samples = append(samples, &Samples[i])
+		}
+	}
+	return samples
+}
+
+// Select returns the samples having all the given labels, for example
+// Select("streaming") or Select("creates-file"). A label may list
+// alternatives separated by "|", as in "image|video". Select() returns
+// every sample.
+func Select(labels ...string) []*Sample {
+	return SelectFunc(func(s *Sample) bool {
+		have := s.Labels()
+		for _, l := range labels {
+			if !slices.ContainsFunc(strings.Split(l, "|"), func(alt string) bool { return slices.Contains(have, alt) }) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// discard adapts a sample returning a result to the signature of
+// Sample.Run.
+func discard[T any](f func(context.Context, *genai.Client, io.Writer) (T, error)) func(context.Context, *genai.Client, io.Writer) error {
+	return func(ctx context.Context, client *genai.Client, w io.Writer) error {
+		_, err := f(ctx, client, w)
+		return err
+	}
+}
+
+// Samples lists the samples, in the order of their files.
+var Samples = []Sample{
+	{
+		Name:       "CacheCreate",
+		Tag:        "cache_create",
+		File:       "cache.go",
+		Modalities: []Modality{ModalityText},
+		Media:      []string{"a11.txt"},
+		Creates:    []Resource{ResourceFile, ResourceCache},
+		Features:   []string{"files", "cache"},
+		Run:        discard(CacheCreateWithClient),
+	},
+	{
+		Name:       "CacheCreateFromName",
+		Tag:        "cache_create_from_name",
+		File:       "cache.go",
+		Modalities: []Modality{ModalityText},
+		Media:      []string{"a11.txt"},
+		Creates:    []Resource{ResourceFile, ResourceCache},
+		Features:   []string{"files", "cache"},
+		Run:        discard(CacheCreateFromNameWithClient),
+	},
+	{
+		Name:       "CacheCreateFromChat",
+		Tag:        "cache_create_from_chat",
+		File:       "cache.go",
+		Modalities: []Modality{ModalityText},
+		Media:      []string{"a11.txt"},
+		Creates:    []Resource{ResourceFile, ResourceCache},
+		Features:   []string{"files", "cache", "chat"},
+		Run:        discard(CacheCreateFromChatWithClient),
+	},
+	{
+		Name:       "CacheDelete",
+		Tag:        "cache_delete",
+		File:       "cache.go",
+		Modalities: []Modality{ModalityText},
+		Media:      []string{"a11.txt"},
+		Creates:    []Resource{ResourceFile, ResourceCache},
+		Features:   []string{"files", "cache"},
+		Run:        CacheDeleteWithClient,
+	},
+	{
+		Name:       "CacheGet",
+		Tag:        "cache_get",
+		File:       "cache.go",
+		Modalities: []Modality{ModalityText},
+		Media:      []string{"a11.txt"},
+		Creates:    []Resource{ResourceFile, ResourceCache},
+		Features:   []string{"files", "cache"},
+		Run:        CacheGetWithClient,
+	},
+	{
+		Name:       "CacheList",
+		Tag:        "cache_list",
+		File:       "cache.go",
+		Modalities: []Modality{ModalityText},
+		Media:      []string{"a11.txt"},
+		Creates:    []Resource{ResourceFile, ResourceCache},
+		Features:   []string{"files", "cache"},
+		Run:        CacheListWithClient,
+	},
+	{
+		Name:       "CacheUpdate",
+		Tag:        "cache_update",
+		File:       "cache.go",
+		Modalities: []Modality{ModalityText},
+		Media:      []string{"a11.txt"},
+		Creates:    []Resource{ResourceFile, ResourceCache},
+		Features:   []string{"files", "cache"},
+		Run:        CacheUpdateWithClient,
+	},
+	{
+		Name:       "Chat",
+		Tag:        "chat",
+		File:       "chat.go",
+		Modalities: []Modality{ModalityText},
+		Features:   []string{"chat"},
+		Run:        ChatWithClient,
+	},
+	{
+		Name:       "ChatStreaming",
+		Tag:        "chat_streaming",
+		File:       "chat.go",
+		Modalities: []Modality{ModalityText},
+		Streams:    true,
+		Features:   []string{"chat"},
+		Run:        ChatStreamingWithClient,
+	},
+	{
+		Name:       "ChatStreamingWithImages",
+		Tag:        "chat_streaming_with_images",
+		File:       "chat.go",
+		Modalities: []Modality{ModalityText, ModalityImage},
+		Media:      []string{"organ.jpg"},
+		Creates:    []Resource{ResourceFile},
+		Streams:    true,
+		Features:   []string{"files", "chat"},
+		Run:        ChatStreamingWithImagesWithClient,
+	},
+	{
+		Name:       "CodeExecutionBasic",
+		Tag:        "code_execution_basic",
+		File:       "code_execution.go",
+		Modalities: []Modality{ModalityText},
+		Run:        discard(CodeExecutionBasicWithClient),
+	},
+	{
+		Name:       "CodeExecutionRequestOverride",
+		Tag:        "code_execution_request_override",
+		File:       "code_execution.go",
+		Modalities: []Modality{ModalityText},
+		Tools:      []Tool{ToolCodeExecution},
+		Run:        discard(CodeExecutionRequestOverrideWithClient),
+	},
+	{
+		Name:       "ConfigureModelParameters",
+		Tag:        "configure_model_parameters",
+		File:       "configure_model_parameters.go",
+		Modalities: []Modality{ModalityText},
+		Run:        discard(ConfigureModelParametersWithClient),
+	},
+	{
+		Name:       "JsonControlledGeneration",
+		Tag:        "json_controlled_generation",
+		File:       "controlled_generation.go",
+		Modalities: []Modality{ModalityText},
+		Features:   []string{"json"},
+		Run:        discard(JsonControlledGenerationWithClient),
+	},
+	{
+		Name:       "JsonNoSchema",
+		Tag:        "json_no_schema",
+		File:       "controlled_generation.go",
+		Modalities: []Modality{ModalityText},
+		Run:        discard(JsonNoSchemaWithClient),
+	},
+	{
+		Name:       "JsonEnum",
+		Tag:        "json_enum",
+		File:       "controlled_generation.go",
+		Modalities: []Modality{ModalityText, ModalityImage},
+		Media:      []string{"organ.jpg"},
+		Creates:    []Resource{ResourceFile},
+		Features:   []string{"files", "json"},
+		Run:        discard(JsonEnumWithClient),
+	},
+	{
+		Name:       "EnumInJson",
+		Tag:        "enum_in_json",
+		File:       "controlled_generation.go",
+		Modalities: []Modality{ModalityText},
+		Features:   []string{"json"},
+		Run:        discard(EnumInJsonWithClient),
+	},
+	{
+		Name:       "JsonEnumRaw",
+		Tag:        "json_enum_raw",
+		File:       "controlled_generation.go",
+		Modalities: []Modality{ModalityText, ModalityImage},
+		Media:      []string{"organ.jpg"},
+		Creates:    []Resource{ResourceFile},
+		Features:   []string{"files", "json"},
+		Run:        discard(JsonEnumRawWithClient),
+	},
+	{
+		Name:       "XEnum",
+		Tag:        "x_enum",
+		File:       "controlled_generation.go",
+		Modalities: []Modality{ModalityText, ModalityImage},
+		Media:      []string{"organ.jpg"},
+		Creates:    []Resource{ResourceFile},
+		Features:   []string{"files", "json"},
+		Run:        discard(XEnumWithClient),
+	},
+	{
+		Name:       "XEnumRaw",
+		Tag:        "x_enum_raw",
+		File:       "controlled_generation.go",
+		Modalities: []Modality{ModalityText, ModalityImage},
+		Media:      []string{"organ.jpg"},
+		Creates:    []Resource{ResourceFile},
+		Features:   []string{"files", "json"},
+		Run:        discard(XEnumRawWithClient),
+	},
+	{
+		Name:     "TokensContextWindow",
+		Tag:      "tokens_context_window",
+		File:     "count_tokens.go",
+		Features: []string{"tokens"},
+		Run:      TokensContextWindowWithClient,
+	},
+	{
+		Name:       "TokensTextOnly",
+		Tag:        "tokens_text_only",
+		File:       "count_tokens.go",
+		Modalities: []Modality{ModalityText},
+		Features:   []string{"tokens"},
+		Run:        TokensTextOnlyWithClient,
+	},
+	{
+		Name:       "TokensChat",
+		Tag:        "tokens_chat",
+		File:       "count_tokens.go",
+		Modalities: []Modality{ModalityText},
+		Features:   []string{"chat", "tokens"},
+		Run:        TokensChatWithClient,
+	},
+	{
+		Name:       "TokensMultimodalImageInline",
+		Tag:        "tokens_multimodal_image_inline",
+		File:       "count_tokens.go",
+		Modalities: []Modality{ModalityText, ModalityImage},
+		Media:      []string{"organ.jpg"},
+		Features:   []string{"tokens"},
+		Run:        TokensMultimodalImageInlineWithClient,
+	},
+	{
+		Name:       "TokensMultimodalImageFileApi",
+		Tag:        "tokens_multimodal_image_file_api",
+		File:       "count_tokens.go",
+		Modalities: []Modality{ModalityText, ModalityImage},
+		Media:      []string{"organ.jpg"},
+		Creates:    []Resource{ResourceFile},
+		Features:   []string{"files", "tokens"},
+		Run:        TokensMultimodalImageFileApiWithClient,
+	},
+	{
+		Name:       "TokensMultimodalVideoAudioFileApi",
+		Tag:        "tokens_multimodal_video_audio_file_api",
+		File:       "count_tokens.go",
+		Modalities: []Modality{ModalityText, ModalityVideo},
+		Media:      []string{"Big_Buck_Bunny.mp4"},
+		Creates:    []Resource{ResourceFile},
+		Features:   []string{"files", "tokens"},
+		Run:        TokensMultimodalVideoAudioFileApiWithClient,
+	},
+	{
+		Name:       "TokensMultimodalPdfFileApi",
+		Tag:        "tokens_multimodal_pdf_file_api",
+		File:       "count_tokens.go",
+		Modalities: []Modality{ModalityText, ModalityPDF},
+		Media:      []string{"test.pdf"},
+		Creates:    []Resource{ResourceFile},
+		Features:   []string{"files", "tokens"},
+		Run:        TokensMultimodalPdfFileApiWithClient,
+	},
+	{
+		Name:       "TokensCachedContent",
+		Tag:        "tokens_cached_content",
+		File:       "count_tokens.go",
+		Modalities: []Modality{ModalityText},
+		Media:      []string{"a11.txt"},
+		Creates:    []Resource{ResourceFile, ResourceCache},
+		Features:   []string{"files", "cache", "tokens"},
+		Run:        TokensCachedContentWithClient,
+	},
+	{
+		Name:       "EmbedContent",
+		Tag:        "embed_content",
+		File:       "embed.go",
+		Modalities: []Modality{ModalityText},
+		Features:   []string{"embeddings"},
+		Run:        EmbedContentWithClient,
+	},
+	{
+		Name:       "BatchEmbedContents",
+		Tag:        "batch_embed_contents",
+		File:       "embed.go",
+		Modalities: []Modality{ModalityText},
+		Features:   []string{"embeddings"},
+		Run:        BatchEmbedContentsWithClient,
+	},
+	{
+		Name:       "FilesCreateText",
+		Tag:        "files_create_text",
+		File:       "files.go",
+		Modalities: []Modality{ModalityText},
+		Media:      []string{"poem.txt"},
+		Creates:    []Resource{ResourceFile},
+		Features:   []string{"files"},
+		Run:        discard(FilesCreateTextWithClient),
+	},
+	{
+		Name:       "FilesCreateImage",
+		Tag:        "files_create_image",
+		File:       "files.go",
+		Modalities: []Modality{ModalityText, ModalityImage},
+		Media:      []string{"Cajun_instruments.jpg"},
+		Creates:    []Resource{ResourceFile},
+		Features:   []string{"files"},
+		Run:        discard(FilesCreateImageWithClient),
+	},
+	{
+		Name:       "FilesCreateAudio",
+		Tag:        "files_create_audio",
+		File:       "files.go",
+		Modalities: []Modality{ModalityText, ModalityAudio},
+		Media:      []string{"sample.mp3"},
+		Creates:    []Resource{ResourceFile},
+		Features:   []string{"files"},
+		Run:        discard(FilesCreateAudioWithClient),
+	},
+	{
+		Name:       "FilesCreateVideo",
+		Tag:        "files_create_video",
+		File:       "files.go",
+		Modalities: []Modality{ModalityText, ModalityVideo},
+		Media:      []string{"Big_Buck_Bunny.mp4"},
+		Creates:    []Resource{ResourceFile},
+		Features:   []string{"files"},
+		Run:        discard(FilesCreateVideoWithClient),
+	},
+	{
+		Name:       "FilesCreatePdf",
+		Tag:        "files_create_pdf",
+		File:       "files.go",
+		Modalities: []Modality{ModalityText, ModalityPDF},
+		Media:      []string{"test.pdf"},
+		Creates:    []Resource{ResourceFile},
+		Features:   []string{"files"},
+		Run:        discard(FilesCreatePdfWithClient),
+	},
+	{
+		Name:       "FilesCreateFromIO",
+		Tag:        "files_create_io",
+		File:       "files.go",
+		Modalities: []Modality{ModalityText, ModalityPDF},
+		Media:      []string{"test.pdf"},
+		Creates:    []Resource{ResourceFile},
+		Features:   []string{"files"},
+		Run:        discard(FilesCreateFromIOWithClient),
+	},
+	{
+		Name:     "FilesList",
+		Tag:      "files_list",
+		File:     "files.go",
+		Features: []string{"files"},
+		Run:      FilesListWithClient,
+	},
+	{
+		Name:       "FilesGet",
+		Tag:        "files_get",
+		File:       "files.go",
+		Modalities: []Modality{ModalityText},
+		Media:      []string{"poem.txt"},
+		Creates:    []Resource{ResourceFile},
+		Features:   []string{"files"},
+		Run:        discard(FilesGetWithClient),
+	},
+	{
+		Name:       "FilesDelete",
+		Tag:        "files_delete",
+		File:       "files.go",
+		Modalities: []Modality{ModalityText},
+		Media:      []string{"poem.txt"},
+		Creates:    []Resource{ResourceFile},
+		Features:   []string{"files"},
+		Run:        FilesDeleteWithClient,
+	},
+	{
+		Name:       "FunctionCalling",
+		Tag:        "function_calling",
+		File:       "function_calling.go",
+		Modalities: []Modality{ModalityText},
+		Tools:      []Tool{ToolFunctionCalling},
+		Run:        FunctionCallingWithClient,
+	},
+	{
+		Name:     "ModelsList",
+		Tag:      "models_list",
+		File:     "models.go",
+		Features: []string{"models"},
+		Run:      ModelsListWithClient,
+	},
+	{
+		Name:     "ModelsGet",
+		Tag:      "models_get",
+		File:     "models.go",
+		Features: []string{"models"},
+		Run:      ModelsGetWithClient,
+	},
+	{
+		Name:       "SafetySettings",
+		Tag:        "safety_settings",
+		File:       "safety_settings.go",
+		Modalities: []Modality{ModalityText},
+		Features:   []string{"safety"},
+		Run:        SafetySettingsWithClient,
+	},
+	{
+		Name:       "SafetySettingsMulti",
+		Tag:        "safety_settings_multi",
+		File:       "safety_settings.go",
+		Modalities: []Modality{ModalityText},
+		Features:   []string{"safety"},
+		Run:        SafetySettingsMultiWithClient,
+	},
+	{
+		Name:       "SystemInstruction",
+		Tag:        "system_instruction",
+		File:       "system_instruction.go",
+		Modalities: []Modality{ModalityText},
+		Run:        SystemInstructionWithClient,
+	},
+	{
+		Name:       "TextGenTextOnlyPrompt",
+		Tag:        "text_gen_text_only_prompt",
+		File:       "text_generation.go",
+		Modalities: []Modality{ModalityText},
+		Run:        discard(TextGenTextOnlyPromptWithClient),
+	},
+	{
+		Name:       "TextGenTextOnlyPromptStreaming",
+		Tag:        "text_gen_text_only_prompt_streaming",
+		File:       "text_generation.go",
+		Modalities: []Modality{ModalityText},
+		Streams:    true,
+		Run:        TextGenTextOnlyPromptStreamingWithClient,
+	},
+	{
+		Name:       "TextGenMultimodalOneImagePrompt",
+		Tag:        "text_gen_multimodal_one_image_prompt",
+		File:       "text_generation.go",
+		Modalities: []Modality{ModalityText, ModalityImage},
+		Media:      []string{"organ.jpg"},
+		Creates:    []Resource{ResourceFile},
+		Features:   []string{"files"},
+		Run:        discard(TextGenMultimodalOneImagePromptWithClient),
+	},
+	{
+		Name:       "TextGenMultimodalOneImagePromptStreaming",
+		Tag:        "text_gen_multimodal_one_image_prompt_streaming",
+		File:       "text_generation.go",
+		Modalities: []Modality{ModalityText, ModalityImage},
+		Media:      []string{"organ.jpg"},
+		Creates:    []Resource{ResourceFile},
+		Streams:    true,
+		Features:   []string{"files"},
+		Run:        TextGenMultimodalOneImagePromptStreamingWithClient,
+	},
+	{
+		Name:       "TextGenMultimodalImageInline",
+		Tag:        "text_gen_multimodal_image_inline",
+		File:       "text_generation.go",
+		Modalities: []Modality{ModalityText, ModalityImage},
+		Media:      []string{"organ.jpg"},
+		Run:        discard(TextGenMultimodalImageInlineWithClient),
+	},
+	{
+		Name:       "TextGenMultimodalMultiImagePrompt",
+		Tag:        "text_gen_multimodal_multi_image_prompt",
+		File:       "text_generation.go",
+		Modalities: []Modality{ModalityText, ModalityImage},
+		Media:      []string{"organ.jpg", "Cajun_instruments.jpg"},
+		Creates:    []Resource{ResourceFile},
+		Features:   []string{"files"},
+		Run:        discard(TextGenMultimodalMultiImagePromptWithClient),
+	},
+	{
+		Name:       "TextGenMultimodalMultiImagePromptStreaming",
+		Tag:        "text_gen_multimodal_multi_image_prompt_streaming",
+		File:       "text_generation.go",
+		Modalities: []Modality{ModalityText, ModalityImage},
+		Media:      []string{"organ.jpg", "Cajun_instruments.jpg"},
+		Creates:    []Resource{ResourceFile},
+		Streams:    true,
+		Features:   []string{"files"},
+		Run:        TextGenMultimodalMultiImagePromptStreamingWithClient,
+	},
+	{
+		Name:       "TextGenMultimodalAudio",
+		Tag:        "text_gen_multimodal_audio",
+		File:       "text_generation.go",
+		Modalities: []Modality{ModalityText, ModalityAudio},
+		Media:      []string{"sample.mp3"},
+		Creates:    []Resource{ResourceFile},
+		Features:   []string{"files"},
+		Run:        discard(TextGenMultimodalAudioWithClient),
+	},
+	{
+		Name:       "TextGenMultimodalAudioStreaming",
+		Tag:        "text_gen_multimodal_audio_streaming",
+		File:       "text_generation.go",
+		Modalities: []Modality{ModalityText, ModalityAudio},
+		Media:      []string{"sample.mp3"},
+		Creates:    []Resource{ResourceFile},
+		Streams:    true,
+		Features:   []string{"files"},
+		Run:        TextGenMultimodalAudioStreamingWithClient,
+	},
+	{
+		Name:       "TextGenMultimodalVideoPrompt",
+		Tag:        "text_gen_multimodal_video_prompt",
+		File:       "text_generation.go",
+		Modalities: []Modality{ModalityText, ModalityVideo},
+		Media:      []string{"Big_Buck_Bunny.mp4"},
+		Creates:    []Resource{ResourceFile},
+		Features:   []string{"files"},
+		Run:        discard(TextGenMultimodalVideoPromptWithClient),
+	},
+	{
+		Name:       "TextGenMultimodalVideoPromptStreaming",
+		Tag:        "text_gen_multimodal_video_prompt_streaming",
+		File:       "text_generation.go",
+		Modalities: []Modality{ModalityText, ModalityVideo},
+		Media:      []string{"Big_Buck_Bunny.mp4"},
+		Creates:    []Resource{ResourceFile},
+		Streams:    true,
+		Features:   []string{"files"},
+		Run:        TextGenMultimodalVideoPromptStreamingWithClient,
+	},
+	{
+		Name:       "TextGenMultimodalPdf",
+		Tag:        "text_gen_multimodal_pdf",
+		File:       "text_generation.go",
+		Modalities: []Modality{ModalityText, ModalityPDF},
+		Media:      []string{"test.pdf"},
+		Creates:    []Resource{ResourceFile},
+		Features:   []string{"files"},
+		Run:        discard(TextGenMultimodalPdfWithClient),
+	},
+	{
+		Name:       "TextGenMultimodalPdfStreaming",
+		Tag:        "text_gen_multimodal_pdf_streaming",
+		File:       "text_generation.go",
+		Modalities: []Modality{ModalityText, ModalityPDF},
+		Media:      []string{"test.pdf"},
+		Creates:    []Resource{ResourceFile},
+		Streams:    true,
+		Features:   []string{"files"},
+		Run:        TextGenMultimodalPdfStreamingWithClient,
+	},
+	{
+		Name:       "ThinkingTextOnlyPrompt",
+		Tag:        "thinking_text_only_prompt",
+		File:       "thinking_generation.go",
+		Modalities: []Modality{ModalityText},
+		Features:   []string{"thinking"},
+		Run:        discard(ThinkingTextOnlyPromptWithClient),
+	},
+	{
+		Name:       "ThinkingTextOnlyPromptStreaming",
+		Tag:        "thinking_text_only_prompt_streaming",
+		File:       "thinking_generation.go",
+		Modalities: []Modality{ModalityText},
+		Streams:    true,
+		Features:   []string{"thinking"},
+		Run:        discard(ThinkingTextOnlyPromptStreamingWithClient),
+	},
+	{
+		Name:       "ThinkingLogicPuzzle",
+		Tag:        "thinking_logic_puzzle",
+		File:       "thinking_generation.go",
+		Modalities: []Modality{ModalityText},
+		Features:   []string{"thinking"},
+		Run:        discard(ThinkingLogicPuzzleWithClient),
+	},
+	{
+		Name:       "ThinkingCodeExplanation",
+		Tag:        "thinking_code_explanation",
+		File:       "thinking_generation.go",
+		Modalities: []Modality{ModalityText},
+		Features:   []string{"thinking"},
+		Run:        discard(ThinkingCodeExplanationWithClient),
+	},
+	{
+		Name:       "ThinkingCreativeWritingConstraints",
+		Tag:        "thinking_creative_writing_constraints",
+		File:       "thinking_generation.go",
+		Modalities: []Modality{ModalityText},
+		Features:   []string{"thinking"},
+		Run:        discard(ThinkingCreativeWritingConstraintsWithClient),
+	},
+	{
+		Name:       "ThinkingWithSearchTool",
+		Tag:        "thinking_with_search_tool",
+		File:       "thinking_generation.go",
+		Modalities: []Modality{ModalityText},
+		Tools:      []Tool{ToolGoogleSearch},
+		Features:   []string{"thinking"},
+		Run:        discard(ThinkingWithSearchToolWithClient),
+	},
+	{
+		Name:       "ThinkingWithSearchToolStreaming",
+		Tag:        "thinking_with_search_tool_streaming",
+		File:       "thinking_generation.go",
+		Modalities: []Modality{ModalityText},
+		Tools:      []Tool{ToolGoogleSearch},
+		Streams:    true,
+		Features:   []string{"thinking"},
+		Run:        discard(ThinkingWithSearchToolStreamingWithClient),
+	},
+	{
+		Name:       "ThinkingCodeExecution",
+		Tag:        "thinking_code_execution",
+		File:       "thinking_generation.go",
+		Modalities: []Modality{ModalityText},
+		Tools:      []Tool{ToolCodeExecution},
+		Features:   []string{"thinking"},
+		Run:        discard(ThinkingCodeExecutionWithClient),
+	},
+	{
+		Name:       "ThinkingStructuredOutputJson",
+		Tag:        "thinking_structured_output_json",
+		File:       "thinking_generation.go",
+		Modalities: []Modality{ModalityText},
+		Features:   []string{"json", "thinking"},
+		Run:        discard(ThinkingStructuredOutputJsonWithClient),
+	},
+}
@@ -0,0 +1,249 @@
+package examples
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"gemini-api-examples/internal/region"
+)
+
+// mediaModalities maps the extensions of the files in third_party to their
+// modality.
+var mediaModalities = map[string]Modality{
+	".txt": ModalityText,
+	".jpg": ModalityImage,
+	".mp3": ModalityAudio,
+	".mp4": ModalityVideo,
+	".pdf": ModalityPDF,
+}
+
+// toolFields maps the fields of genai.Tool to the tool they enable.
+var toolFields = map[string]Tool{
+	"CodeExecution":        ToolCodeExecution,
+	"GoogleSearch":         ToolGoogleSearch,
+	"FunctionDeclarations": ToolFunctionCalling,
+}
+
+// TestSamplesRegistry checks the registry against the sources: every
+// sample with a WithClient variant is registered once, with the region tag,
+// media, tools, created resources and streaming found in its Foo form.
+func TestSamplesRegistry(t *testing.T) {
+	goLang, _ := region.LanguageByName("go")
+	fset := token.NewFileSet()
+	names, err := filepath.Glob("*.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defined := map[string]bool{}
+	for _, name := range names {
+		if strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		src, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f, err := parser.ParseFile(fset, name, src, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		regions, _ := region.Parse(name, src, goLang)
+		funcs := map[string]*ast.FuncDecl{}
+		for _, d := range f.Decls {
+			if fn, ok := d.(*ast.FuncDecl); ok {
+				funcs[fn.Name.Name] = fn
+			}
+		}
+		for _, d := range f.Decls {
+			withClient, ok := d.(*ast.FuncDecl)
+			if !ok || !strings.HasSuffix(withClient.Name.Name, "WithClient") {
+				continue
+			}
+			sampleName := strings.TrimSuffix(withClient.Name.Name, "WithClient")
+			defined[sampleName] = true
+			fn := funcs[sampleName]
+			if fn == nil {
+				t.Errorf("%s has no Foo form %s", withClient.Name.Name, sampleName)
+				continue
+			}
+			var s *Sample
+			for i := range Samples {
+				if Samples[i].Name == sampleName {
+					s = &Samples[i]
+				}
+			}
+			if s == nil {
+				t.Errorf("%s is not registered", sampleName)
+				continue
+			}
+			if s.File != name {
+				t.Errorf("%s: File = %q, want %q", s.Name, s.File, name)
+			}
+			start, end := fset.Position(fn.Pos()).Line, fset.Position(fn.End()).Line
+			if !slices.ContainsFunc(regions, func(r region.Region) bool {
+				return r.Tag == s.Tag && start < r.StartLine && r.EndLine < end
+			}) {
+				t.Errorf("%s: Tag %q is not a region of %s", s.Name, s.Tag, fn.Name.Name)
+			}
+			var (
+				media   []string
+				tools   []Tool
+				creates []Resource
+				streams bool
+			)
+			ast.Inspect(fn.Body, func(n ast.Node) bool {
+				switch n := n.(type) {
+				case *ast.KeyValueExpr:
+					if key, ok := n.Key.(*ast.Ident); ok {
+						if tool, ok := toolFields[key.Name]; ok && !slices.Contains(tools, tool) {
+							tools = append(tools, tool)
+						}
+					}
+				case *ast.CallExpr:
+					fun, ok := n.Fun.(*ast.SelectorExpr)
+					if !ok {
+						return true
+					}
+					if strings.HasSuffix(fun.Sel.Name, "Stream") {
+						streams = true
+					}
+					// Only the calls of the Files and Caches services of the
+					// client create resources.
+					if recv, ok := fun.X.(*ast.SelectorExpr); ok {
+						var r Resource
+						switch {
+						case recv.Sel.Name == "Files" && strings.HasPrefix(fun.Sel.Name, "Upload"):
+							r = ResourceFile
+						case recv.Sel.Name == "Caches" && fun.Sel.Name == "Create":
+							r = ResourceCache
+						}
+						if r != "" && !slices.Contains(creates, r) {
+							creates = append(creates, r)
+						}
+					}
+					if fun.Sel.Name == "Join" && len(n.Args) == 2 {
+						if c, ok := n.Args[0].(*ast.CallExpr); ok {
+							if id, ok := c.Fun.(*ast.Ident); ok && id.Name == "getMedia" {
+								lit, ok := n.Args[1].(*ast.BasicLit)
+								if !ok || lit.Kind != token.STRING {
+									t.Errorf("%s: %s: media file is not a string literal", s.Name, fset.Position(n.Args[1].Pos()))
+									return true
+								}
+								file, _ := strconv.Unquote(lit.Value)
+								media = append(media, file)
+							}
+						}
+					}
+				}
+				return true
+			})
+			if !slices.Equal(s.Media, media) {
+				t.Errorf("%s: Media = %q, want %q", s.Name, s.Media, media)
+			}
+			for _, file := range media {
+				if m := mediaModalities[filepath.Ext(file)]; !slices.Contains(s.Modalities, m) {
+					t.Errorf("%s: Modalities = %q, want %q for %s", s.Name, s.Modalities, m, file)
+				}
+			}
+			for _, m := range s.Modalities {
+				if m != ModalityText && !slices.ContainsFunc(media, func(file string) bool { return mediaModalities[filepath.Ext(file)] == m }) {
+					t.Errorf("%s: modality %q without media", s.Name, m)
+				}
+			}
+			if !slices.Equal(s.Tools, tools) {
+				t.Errorf("%s: Tools = %q, want %q", s.Name, s.Tools, tools)
+			}
+			if !slices.Equal(s.Creates, creates) {
+				t.Errorf("%s: Creates = %q, want %q", s.Name, s.Creates, creates)
+			}
+			if s.Streams != streams {
+				t.Errorf("%s: Streams = %v, want %v", s.Name, s.Streams, streams)
+			}
+		}
+	}
+	tags := map[string]bool{}
+	for _, s := range Samples {
+		if !defined[s.Name] {
+			t.Errorf("registered sample %s has no WithClient variant", s.Name)
+		}
+		if tags[s.Tag] {
+			t.Errorf("tag %s is registered twice", s.Tag)
+		}
+		tags[s.Tag] = true
+		if s.Run == nil {
+			t.Errorf("%s has no Run function", s.Name)
+		}
+	}
+}
+
+func TestSampleByTag(t *testing.T) {
+	s := SampleByTag("cache_update")
+	if s == nil || s.Name != "CacheUpdate" || !s.HasLabel("cache") {
+		t.Errorf("SampleByTag(cache_update) = %+v", s)
+	}
+	if s := SampleByTag("no_such_tag"); s != nil {
+		t.Errorf("SampleByTag(no_such_tag) = %+v, want nil", s)
+	}
+}
+
+func TestSelect(t *testing.T) {
+	names := func(samples []*Sample) []string {
+		var names []string
+		for _, s := range samples {
+			names = append(names, s.Name)
+		}
+		return names
+	}
+	if got := Select(); len(got) != len(Samples) {
+		t.Errorf("Select() returned %d samples, want all %d", len(got), len(Samples))
+	}
+	got := names(Select("streaming", "google-search"))
+	if want := []string{"ThinkingWithSearchToolStreaming"}; !slices.Equal(got, want) {
+		t.Errorf("Select(streaming, google-search) = %q, want %q", got, want)
+	}
+	got = names(Select("pdf", "creates-file", "tokens"))
+	if want := []string{"TokensMultimodalPdfFileApi"}; !slices.Equal(got, want) {
+		t.Errorf("Select(pdf, creates-file, tokens) = %q, want %q", got, want)
+	}
+	for _, s := range Select("creates") {
+		if !s.CreatesResources() {
+			t.Errorf("Select(creates) returned %s, which creates nothing", s.Name)
+		}
+	}
+	for _, s := range Select("image|video") {
+		if !slices.Contains(s.Modalities, ModalityImage) && !slices.Contains(s.Modalities, ModalityVideo) {
+			t.Errorf("Select(image|video) returned %s with modalities %q", s.Name, s.Modalities)
+		}
+	}
+	if got := Select("no-such-label"); got != nil {
+		t.Errorf("Select(no-such-label) = %q, want none", names(got))
+	}
+}
+
+func TestFakeSampleRun(t *testing.T) {
+	srv, client := fakeClient(t)
+	var out strings.Builder
+	if err := SampleByTag("text_gen_text_only_prompt").Run(t.Context(), client, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "You said:") || len(srv.GenerateRequests()) != 1 {
+		t.Errorf("output = %q", out.String())
+	}
+}
+
+func TestMissingMedia(t *testing.T) {
+	if m := SampleByTag("cache_create").MissingMedia(); m != nil {
+		t.Errorf("cache_create: MissingMedia() = %q, want none", m)
+	}
+	s := Sample{Media: []string{"a11.txt", "no_such_file.mp3"}}
+	if m := s.MissingMedia(); !slices.Equal(m, []string{"no_such_file.mp3"}) {
+		t.Errorf("MissingMedia() = %q, want [no_such_file.mp3]", m)
+	}
+}
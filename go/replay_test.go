@@ -0,0 +1,174 @@
+package examples
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"gemini-api-examples/internal/fakegemini"
+	"gemini-api-examples/internal/replay"
+	"gemini-api-examples/internal/tracker"
+	"google.golang.org/genai"
+)
+
+// testMode is read from GEMINI_TEST_MODE: "replay" (the default) serves
+// recorded responses from testdata/cassettes, "record" calls the API and
+// rewrites the cassettes, and "live" calls the API without recording.
+//
+// With GEMINI_RECORD_FAKE=1, "record" and "live" call the fake server of
+// internal/fakegemini instead of the API, so that cassettes can be recorded
+// without an API key. Such cassettes have "fakegemini" as their source.
+var testMode replay.Mode
+
+// recordFake is the fake server standing in for the API with
+// GEMINI_RECORD_FAKE=1, or nil.
+var recordFake *fakegemini.Server
+
+// The samples create their own clients with a nil HTTPClient, which makes
+// the SDK use http.DefaultTransport. TestMain points it at testTransport so
+// each test can route its requests through its own recorder, and records
+// the files and caches created through it in resources.
+var testTransport = &switchTransport{}
+
+var resources = tracker.New()
+
+type switchTransport struct {
+	mu   sync.Mutex
+	next http.RoundTripper
+	base http.RoundTripper
+}
+
+func (s *switchTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	s.mu.Lock()
+	next := s.next
+	s.mu.Unlock()
+	if next == nil {
+		next = s.base
+	}
+	return next.RoundTrip(req)
+}
+
+func (s *switchTransport) set(rt http.RoundTripper) {
+	s.mu.Lock()
+	s.next = rt
+	s.mu.Unlock()
+}
+
+func TestMain(m *testing.M) {
+	mode, err := replay.ParseMode(os.Getenv("GEMINI_TEST_MODE"))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	testMode = mode
+	testTransport.base = http.DefaultTransport
+	if testMode != replay.ModeReplay && os.Getenv("GEMINI_RECORD_FAKE") == "1" {
+		recordFake = fakegemini.New()
+		// Files are active at once, so that replays do not wait on the
+		// samples' polling.
+		recordFake.SetProcessingPolls(0)
+		testTransport.base = &toFake{fake: recordFake.Transport(), base: http.DefaultTransport}
+		os.Setenv("GEMINI_API_KEY", "fake")
+	}
+	if testMode != replay.ModeReplay && os.Getenv("GEMINI_API_KEY") == "" {
+		fmt.Fprintf(os.Stderr, "GEMINI_TEST_MODE=%s requires GEMINI_API_KEY\n", testMode)
+		os.Exit(2)
+	}
+	http.DefaultTransport = resources.Transport(testTransport)
+	code := m.Run()
+	// Every test running samples in their Foo form must delete what they
+	// leave behind with cleanupResources; fail the run if one did not.
+	if live := resources.Live(); len(live) > 0 {
+		fmt.Fprintf(os.Stderr, "tests left %d files and caches created by the samples without calling cleanupResources: %v\n", len(live), live)
+		code = 1
+	}
+	os.Exit(code)
+}
+
+// toFake sends the requests for the Gemini API, and for the URLs the fake
+// server recording the cassettes hands out, to that server, and the others,
+// such as those of the tests' own fake servers, through base.
+type toFake struct {
+	fake, base http.RoundTripper
+}
+
+func (f *toFake) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Host == apiHost || strings.HasPrefix(req.URL.String(), recordFake.URL+"/") {
+		return f.fake.RoundTrip(req)
+	}
+	return f.base.RoundTrip(req)
+}
+
+// cassettePath returns the cassette file for the named test.
+func cassettePath(name string) string {
+	return filepath.Join("testdata", "cassettes", strings.ReplaceAll(name, "/", "__")+".json")
+}
+
+// useCassette routes the API requests made by the current test through a
+// recorder for its cassette. In replay mode the test fails when no cassette
+// has been recorded for it.
+func useCassette(t *testing.T) {
+	t.Helper()
+	for _, s := range Samples {
+		if "Test"+s.Name != t.Name() {
+			continue
+		}
+		// The sample reads its media before making any request.
+		if missing := s.MissingMedia(); len(missing) > 0 {
+			t.Skipf("missing media in third_party: %s", strings.Join(missing, ", "))
+		}
+	}
+	rec, err := replay.New(cassettePath(t.Name()), testMode, testTransport.base)
+	if err != nil {
+		t.Fatalf("%v; record it with GEMINI_TEST_MODE=record", err)
+	}
+	if recordFake != nil {
+		rec.SetSource("fakegemini")
+	}
+	if testMode == replay.ModeReplay && os.Getenv("GEMINI_API_KEY") == "" {
+		// The key is never recorded, but the SDK refuses to start without one.
+		t.Setenv("GEMINI_API_KEY", "replay")
+	}
+	testTransport.set(rec)
+	t.Cleanup(func() {
+		testTransport.set(nil)
+		if t.Failed() {
+			return
+		}
+		if err := rec.Save(); err != nil {
+			t.Errorf("saving cassette: %v", err)
+		}
+		if unused := rec.Unused(); len(unused) > 0 {
+			t.Logf("%d recorded interactions were not replayed; consider re-recording", len(unused))
+		}
+	})
+	// Registered last so that it runs first, while the recorder is in place:
+	// the deletions are recorded with the rest.
+	cleanupResources(t)
+}
+
+// cleanupResources deletes the files and caches left behind by the samples
+// the test runs in their Foo form, once it ends.
+func cleanupResources(t *testing.T) {
+	t.Cleanup(func() {
+		if len(resources.Live()) == 0 {
+			return
+		}
+		ctx := context.Background()
+		client, err := genai.NewClient(ctx, &genai.ClientConfig{
+			APIKey:  os.Getenv("GEMINI_API_KEY"),
+			Backend: genai.BackendGeminiAPI,
+		})
+		if err == nil {
+			err = resources.Cleanup(ctx, client)
+		}
+		if err != nil {
+			t.Error(err)
+		}
+	})
+}
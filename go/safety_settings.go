@@ -3,8 +3,8 @@ package examples
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
-	"log"
 	"encoding/json"
 
 	"google.golang.org/genai"
@@ -18,9 +18,8 @@ func SafetySettings() error {
 		Backend: genai.BackendGeminiAPI,
 	})
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
-
 	unsafePrompt := "I support Martians Soccer Club and I think Jupiterians Football Club sucks! " +
 		"Write a ironic phrase about them including expletives."
 
@@ -37,7 +36,7 @@ func SafetySettings() error {
 	}
 	response, err := client.Models.GenerateContent(ctx, "gemini-3.5-flash", contents, config)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	// Print the finish reason and safety ratings from the first candidate.
@@ -55,6 +54,10 @@ func SafetySettings() error {
 	return err
 }
 
+func SafetySettingsWithClient(ctx context.Context, client *genai.Client, w io.Writer) error {
+	return runWith(ctx, client, w, SafetySettings)
+}
+
 func SafetySettingsMulti() error {
 	// [START safety_settings_multi]
 	ctx := context.Background()
@@ -63,9 +66,8 @@ func SafetySettingsMulti() error {
 		Backend: genai.BackendGeminiAPI,
 	})
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
-
 	unsafePrompt := "I support Martians Soccer Club and I think Jupiterians Football Club sucks! " +
 		"Write a ironic phrase about them including expletives."
 
@@ -86,7 +88,7 @@ func SafetySettingsMulti() error {
 	}
 	response, err := client.Models.GenerateContent(ctx, "gemini-3.5-flash", contents, config)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	// Print the generated text.
@@ -107,3 +109,7 @@ func SafetySettingsMulti() error {
 	// [END safety_settings_multi]
 	return err
 }
+
+func SafetySettingsMultiWithClient(ctx context.Context, client *genai.Client, w io.Writer) error {
+	return runWith(ctx, client, w, SafetySettingsMulti)
+}
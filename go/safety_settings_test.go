@@ -5,6 +5,7 @@ import (
 )
 
 func TestSafetySettings(t *testing.T) {
+	useCassette(t)
 	err := SafetySettings()
 	if err != nil {
 		t.Errorf("SafetySettings returned an error.")
@@ -12,6 +13,7 @@ func TestSafetySettings(t *testing.T) {
 }
 
 func TestSafetySettingsMulti(t *testing.T) {
+	useCassette(t)
 	err := SafetySettingsMulti()
 	if err != nil {
 		t.Errorf("SafetySettingsMulti returned an error.")
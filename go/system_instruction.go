@@ -3,7 +3,7 @@ package examples
 import (
 	"context"
 	"os"
-	"log"
+	"io"
 
 	"google.golang.org/genai"
 )
@@ -16,9 +16,8 @@ func SystemInstruction() error {
 		Backend: genai.BackendGeminiAPI,
 	})
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
-
 	// Construct the user message contents.
 	contents := []*genai.Content{
 		genai.NewContentFromText("Good morning! How are you?", genai.RoleUser),
@@ -31,9 +30,13 @@ func SystemInstruction() error {
 
 	response, err := client.Models.GenerateContent(ctx, "gemini-3.5-flash", contents, config)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 	printResponse(response)
 	// [END system_instruction]
 	return err
 }
+
+func SystemInstructionWithClient(ctx context.Context, client *genai.Client, w io.Writer) error {
+	return runWith(ctx, client, w, SystemInstruction)
+}
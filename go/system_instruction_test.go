@@ -5,6 +5,7 @@ import (
 )
 
 func TestSystemInstruction(t *testing.T) {
+	useCassette(t)
 	err := SystemInstruction()
 	if err != nil {
 		t.Errorf("SystemInstruction returned an error.")
@@ -1,6 +1,7 @@
 package examples
 
 import (
+	"errors"
 	"log"
 	"fmt"
 	"path/filepath"
@@ -33,3 +34,16 @@ func printResponse(resp *genai.GenerateContentResponse) {
 		}
 	}
 }
+
+// Helper for checking that a streamed response is complete. A stream cut
+// short ends without an error, but its last chunk lacks a finish reason; a
+// blocked prompt ends it with feedback giving the reason instead.
+func checkFinished(last *genai.GenerateContentResponse) error {
+	if last != nil && last.PromptFeedback != nil && last.PromptFeedback.BlockReason != "" {
+		return fmt.Errorf("prompt blocked: %s", last.PromptFeedback.BlockReason)
+	}
+	if last == nil || len(last.Candidates) == 0 || last.Candidates[0].FinishReason == "" {
+		return errors.New("stream ended before the model finished its response")
+	}
+	return nil
+}
@@ -3,11 +3,12 @@ package examples
 import (
 	"context"
 	"fmt"
-	"log"
+	"io"
 	"time"
 	"os"
 	"path/filepath"
 
+	"gemini-api-examples/internal/filewait"
 	"google.golang.org/genai"
 )
 
@@ -19,20 +20,24 @@ func TextGenTextOnlyPrompt() (*genai.GenerateContentResponse, error) {
 		Backend: genai.BackendGeminiAPI,
 	})
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 	contents := []*genai.Content{
 		genai.NewContentFromText("Write a story about a magic backpack.", genai.RoleUser),
 	}
 	response, err := client.Models.GenerateContent(ctx, "gemini-3.5-flash", contents, nil)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 	printResponse(response)
 	// [END text_gen_text_only_prompt]
 	return response, err
 }
 
+func TextGenTextOnlyPromptWithClient(ctx context.Context, client *genai.Client, w io.Writer) (*genai.GenerateContentResponse, error) {
+	return withClient(ctx, client, w, TextGenTextOnlyPrompt)
+}
+
 func TextGenTextOnlyPromptStreaming() error {
 	// [START text_gen_text_only_prompt_streaming]
 	ctx := context.Background()
@@ -41,11 +46,12 @@ func TextGenTextOnlyPromptStreaming() error {
 		Backend: genai.BackendGeminiAPI,
 	})
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 	contents := []*genai.Content{
 		genai.NewContentFromText("Write a story about a magic backpack.", genai.RoleUser),
 	}
+	var last *genai.GenerateContentResponse
 	for response, err := range client.Models.GenerateContentStream(
 		ctx,
 		"gemini-3.5-flash",
@@ -53,12 +59,20 @@ func TextGenTextOnlyPromptStreaming() error {
 		nil,
 	) {
 		if err != nil {
-			log.Fatal(err)
+			return err
 		}
-		fmt.Print(response.Candidates[0].Content.Parts[0].Text)
+		fmt.Print(response.Text())
+		last = response
+	}
+	if err := checkFinished(last); err != nil {
+		return err
 	}
 	// [END text_gen_text_only_prompt_streaming]
-	return err
+	return nil
+}
+
+func TextGenTextOnlyPromptStreamingWithClient(ctx context.Context, client *genai.Client, w io.Writer) error {
+	return runWith(ctx, client, w, TextGenTextOnlyPromptStreaming)
 }
 
 func TextGenMultimodalOneImagePrompt() (*genai.GenerateContentResponse, error) {
@@ -69,18 +83,17 @@ func TextGenMultimodalOneImagePrompt() (*genai.GenerateContentResponse, error) {
 		Backend: genai.BackendGeminiAPI,
 	})
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
-
 	file, err := client.Files.UploadFromPath(
-		ctx, 
-		filepath.Join(getMedia(), "organ.jpg"), 
+		ctx,
+		filepath.Join(getMedia(), "organ.jpg"),
 		&genai.UploadFileConfig{
-			MIMEType : "image/jpeg",
+			MIMEType: "image/jpeg",
 		},
 	)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 	parts := []*genai.Part{
 		genai.NewPartFromText("Tell me about this instrument"),
@@ -92,13 +105,17 @@ func TextGenMultimodalOneImagePrompt() (*genai.GenerateContentResponse, error) {
 
 	response, err := client.Models.GenerateContent(ctx, "gemini-3.5-flash", contents, nil)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 	printResponse(response)
 	// [END text_gen_multimodal_one_image_prompt]
 	return response, err
 }
 
+func TextGenMultimodalOneImagePromptWithClient(ctx context.Context, client *genai.Client, w io.Writer) (*genai.GenerateContentResponse, error) {
+	return withClient(ctx, client, w, TextGenMultimodalOneImagePrompt)
+}
+
 func TextGenMultimodalOneImagePromptStreaming() error {
 	// [START text_gen_multimodal_one_image_prompt_streaming]
 	ctx := context.Background()
@@ -107,17 +124,17 @@ func TextGenMultimodalOneImagePromptStreaming() error {
 		Backend: genai.BackendGeminiAPI,
 	})
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 	file, err := client.Files.UploadFromPath(
-		ctx, 
-		filepath.Join(getMedia(), "organ.jpg"), 
+		ctx,
+		filepath.Join(getMedia(), "organ.jpg"),
 		&genai.UploadFileConfig{
-			MIMEType : "image/jpeg",
+			MIMEType: "image/jpeg",
 		},
 	)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 	parts := []*genai.Part{
 		genai.NewPartFromText("Tell me about this instrument"),
@@ -126,6 +143,7 @@ func TextGenMultimodalOneImagePromptStreaming() error {
 	contents := []*genai.Content{
 		genai.NewContentFromParts(parts, genai.RoleUser),
 	}
+	var last *genai.GenerateContentResponse
 	for response, err := range client.Models.GenerateContentStream(
 		ctx,
 		"gemini-3.5-flash",
@@ -133,12 +151,57 @@ func TextGenMultimodalOneImagePromptStreaming() error {
 		nil,
 	) {
 		if err != nil {
-			log.Fatal(err)
+			return err
 		}
-		fmt.Print(response.Candidates[0].Content.Parts[0].Text)
+		fmt.Print(response.Text())
+		last = response
+	}
+	if err := checkFinished(last); err != nil {
+		return err
 	}
 	// [END text_gen_multimodal_one_image_prompt_streaming]
-	return err
+	return nil
+}
+
+func TextGenMultimodalOneImagePromptStreamingWithClient(ctx context.Context, client *genai.Client, w io.Writer) error {
+	return runWith(ctx, client, w, TextGenMultimodalOneImagePromptStreaming)
+}
+
+func TextGenMultimodalImageInline() (*genai.GenerateContentResponse, error) {
+	// [START text_gen_multimodal_image_inline]
+	ctx := context.Background()
+	client, err := genai.NewClient(ctx, &genai.ClientConfig{
+		APIKey:  os.Getenv("GEMINI_API_KEY"),
+		Backend: genai.BackendGeminiAPI,
+	})
+	if err != nil {
+		return nil, err
+	}
+	// Small files can be sent inline with the prompt instead of being
+	// uploaded, as long as the whole request stays under 20 MB.
+	data, err := os.ReadFile(filepath.Join(getMedia(), "organ.jpg"))
+	if err != nil {
+		return nil, err
+	}
+	parts := []*genai.Part{
+		genai.NewPartFromText("Tell me about this instrument"),
+		genai.NewPartFromBytes(data, "image/jpeg"),
+	}
+	contents := []*genai.Content{
+		genai.NewContentFromParts(parts, genai.RoleUser),
+	}
+
+	response, err := client.Models.GenerateContent(ctx, "gemini-3.5-flash", contents, nil)
+	if err != nil {
+		return nil, err
+	}
+	printResponse(response)
+	// [END text_gen_multimodal_image_inline]
+	return response, err
+}
+
+func TextGenMultimodalImageInlineWithClient(ctx context.Context, client *genai.Client, w io.Writer) (*genai.GenerateContentResponse, error) {
+	return withClient(ctx, client, w, TextGenMultimodalImageInline)
 }
 
 func TextGenMultimodalMultiImagePrompt() (*genai.GenerateContentResponse, error) {
@@ -149,29 +212,28 @@ func TextGenMultimodalMultiImagePrompt() (*genai.GenerateContentResponse, error)
 		Backend: genai.BackendGeminiAPI,
 	})
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
-
 	organ, err := client.Files.UploadFromPath(
-		ctx, 
-		filepath.Join(getMedia(), "organ.jpg"), 
+		ctx,
+		filepath.Join(getMedia(), "organ.jpg"),
 		&genai.UploadFileConfig{
-			MIMEType : "image/jpeg",
+			MIMEType: "image/jpeg",
 		},
 	)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
 	cajun, err := client.Files.UploadFromPath(
-		ctx, 
-		filepath.Join(getMedia(), "Cajun_instruments.jpg"), 
+		ctx,
+		filepath.Join(getMedia(), "Cajun_instruments.jpg"),
 		&genai.UploadFileConfig{
-			MIMEType : "image/jpeg",
+			MIMEType: "image/jpeg",
 		},
 	)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
 	parts := []*genai.Part{
@@ -186,13 +248,17 @@ func TextGenMultimodalMultiImagePrompt() (*genai.GenerateContentResponse, error)
 
 	response, err := client.Models.GenerateContent(ctx, "gemini-3.5-flash", contents, nil)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 	printResponse(response)
 	// [END text_gen_multimodal_multi_image_prompt]
 	return response, err
 }
 
+func TextGenMultimodalMultiImagePromptWithClient(ctx context.Context, client *genai.Client, w io.Writer) (*genai.GenerateContentResponse, error) {
+	return withClient(ctx, client, w, TextGenMultimodalMultiImagePrompt)
+}
+
 func TextGenMultimodalMultiImagePromptStreaming() error {
 	// [START text_gen_multimodal_multi_image_prompt_streaming]
 	ctx := context.Background()
@@ -201,29 +267,28 @@ func TextGenMultimodalMultiImagePromptStreaming() error {
 		Backend: genai.BackendGeminiAPI,
 	})
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
-
 	organ, err := client.Files.UploadFromPath(
-		ctx, 
-		filepath.Join(getMedia(), "organ.jpg"), 
+		ctx,
+		filepath.Join(getMedia(), "organ.jpg"),
 		&genai.UploadFileConfig{
-			MIMEType : "image/jpeg",
+			MIMEType: "image/jpeg",
 		},
 	)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	cajun, err := client.Files.UploadFromPath(
-		ctx, 
-		filepath.Join(getMedia(), "Cajun_instruments.jpg"), 
+		ctx,
+		filepath.Join(getMedia(), "Cajun_instruments.jpg"),
 		&genai.UploadFileConfig{
-			MIMEType : "image/jpeg",
+			MIMEType: "image/jpeg",
 		},
 	)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	parts := []*genai.Part{
@@ -236,6 +301,7 @@ func TextGenMultimodalMultiImagePromptStreaming() error {
 		genai.NewContentFromParts(parts, genai.RoleUser),
 	}
 
+	var last *genai.GenerateContentResponse
 	for result, err := range client.Models.GenerateContentStream(
 		ctx,
 		"gemini-3.5-flash",
@@ -243,12 +309,20 @@ func TextGenMultimodalMultiImagePromptStreaming() error {
 		nil,
 	) {
 		if err != nil {
-			log.Fatal(err)
+			return err
 		}
-		fmt.Print(result.Candidates[0].Content.Parts[0].Text)
+		fmt.Print(result.Text())
+		last = result
+	}
+	if err := checkFinished(last); err != nil {
+		return err
 	}
 	// [END text_gen_multimodal_multi_image_prompt_streaming]
-	return err
+	return nil
+}
+
+func TextGenMultimodalMultiImagePromptStreamingWithClient(ctx context.Context, client *genai.Client, w io.Writer) error {
+	return runWith(ctx, client, w, TextGenMultimodalMultiImagePromptStreaming)
 }
 
 func TextGenMultimodalAudio() (*genai.GenerateContentResponse, error) {
@@ -259,18 +333,17 @@ func TextGenMultimodalAudio() (*genai.GenerateContentResponse, error) {
 		Backend: genai.BackendGeminiAPI,
 	})
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
-
 	file, err := client.Files.UploadFromPath(
-		ctx, 
-		filepath.Join(getMedia(), "sample.mp3"), 
+		ctx,
+		filepath.Join(getMedia(), "sample.mp3"),
 		&genai.UploadFileConfig{
-			MIMEType : "audio/mpeg",
+			MIMEType: "audio/mpeg",
 		},
 	)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
 	parts := []*genai.Part{
@@ -284,13 +357,17 @@ func TextGenMultimodalAudio() (*genai.GenerateContentResponse, error) {
 
 	response, err := client.Models.GenerateContent(ctx, "gemini-3.5-flash", contents, nil)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 	printResponse(response)
 	// [END text_gen_multimodal_audio]
 	return response, err
 }
 
+func TextGenMultimodalAudioWithClient(ctx context.Context, client *genai.Client, w io.Writer) (*genai.GenerateContentResponse, error) {
+	return withClient(ctx, client, w, TextGenMultimodalAudio)
+}
+
 func TextGenMultimodalAudioStreaming() error {
 	// [START text_gen_multimodal_audio_streaming]
 	ctx := context.Background()
@@ -299,18 +376,17 @@ func TextGenMultimodalAudioStreaming() error {
 		Backend: genai.BackendGeminiAPI,
 	})
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
-
 	file, err := client.Files.UploadFromPath(
-		ctx, 
-		filepath.Join(getMedia(), "sample.mp3"), 
+		ctx,
+		filepath.Join(getMedia(), "sample.mp3"),
 		&genai.UploadFileConfig{
-			MIMEType : "audio/mpeg",
+			MIMEType: "audio/mpeg",
 		},
 	)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	parts := []*genai.Part{
@@ -322,6 +398,7 @@ func TextGenMultimodalAudioStreaming() error {
 		genai.NewContentFromParts(parts, genai.RoleUser),
 	}
 
+	var last *genai.GenerateContentResponse
 	for result, err := range client.Models.GenerateContentStream(
 		ctx,
 		"gemini-3.5-flash",
@@ -329,12 +406,20 @@ func TextGenMultimodalAudioStreaming() error {
 		nil,
 	) {
 		if err != nil {
-			log.Fatal(err)
+			return err
 		}
-		fmt.Print(result.Candidates[0].Content.Parts[0].Text)
+		fmt.Print(result.Text())
+		last = result
+	}
+	if err := checkFinished(last); err != nil {
+		return err
 	}
 	// [END text_gen_multimodal_audio_streaming]
-	return err
+	return nil
+}
+
+func TextGenMultimodalAudioStreamingWithClient(ctx context.Context, client *genai.Client, w io.Writer) error {
+	return runWith(ctx, client, w, TextGenMultimodalAudioStreaming)
 }
 
 func TextGenMultimodalVideoPrompt() (*genai.GenerateContentResponse, error) {
@@ -345,30 +430,32 @@ func TextGenMultimodalVideoPrompt() (*genai.GenerateContentResponse, error) {
 		Backend: genai.BackendGeminiAPI,
 	})
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
-
 	file, err := client.Files.UploadFromPath(
-		ctx, 
-		filepath.Join(getMedia(), "Big_Buck_Bunny.mp4"), 
+		ctx,
+		filepath.Join(getMedia(), "Big_Buck_Bunny.mp4"),
 		&genai.UploadFileConfig{
-			MIMEType : "video/mp4",
+			MIMEType: "video/mp4",
 		},
 	)
 	if err != nil {
-		log.Fatal(err)
-	}
-
-	// Poll until the video file is completely processed (state becomes ACTIVE).
-	for file.State == genai.FileStateUnspecified || file.State != genai.FileStateActive {
-		fmt.Println("Processing video...")
-		fmt.Println("File state:", file.State)
-		time.Sleep(5 * time.Second)
-
-		file, err = client.Files.Get(ctx, file.Name, nil)
-		if err != nil {
-			log.Fatal(err)
-		}
+		return nil, err
+	}
+
+	// Wait until the video file is completely processed (state becomes
+	// ACTIVE), polling less and less often, and give up if processing fails
+	// or takes more than five minutes.
+	waitCtx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+	defer cancel()
+	file, err = filewait.Wait(waitCtx, client, file, &filewait.Options{
+		Progress: func(f *genai.File, next time.Duration) {
+			fmt.Println("Processing video...")
+			fmt.Println("File state:", f.State)
+		},
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	parts := []*genai.Part{
@@ -382,13 +469,17 @@ func TextGenMultimodalVideoPrompt() (*genai.GenerateContentResponse, error) {
 
 	response, err := client.Models.GenerateContent(ctx, "gemini-3.5-flash", contents, nil)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 	printResponse(response)
 	// [END text_gen_multimodal_video_prompt]
 	return response, err
 }
 
+func TextGenMultimodalVideoPromptWithClient(ctx context.Context, client *genai.Client, w io.Writer) (*genai.GenerateContentResponse, error) {
+	return withClient(ctx, client, w, TextGenMultimodalVideoPrompt)
+}
+
 func TextGenMultimodalVideoPromptStreaming() error {
 	// [START text_gen_multimodal_video_prompt_streaming]
 	ctx := context.Background()
@@ -397,30 +488,32 @@ func TextGenMultimodalVideoPromptStreaming() error {
 		Backend: genai.BackendGeminiAPI,
 	})
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
-
 	file, err := client.Files.UploadFromPath(
-		ctx, 
-		filepath.Join(getMedia(), "Big_Buck_Bunny.mp4"), 
+		ctx,
+		filepath.Join(getMedia(), "Big_Buck_Bunny.mp4"),
 		&genai.UploadFileConfig{
-			MIMEType : "video/mp4",
+			MIMEType: "video/mp4",
 		},
 	)
 	if err != nil {
-		log.Fatal(err)
-	}
-
-	// Poll until the video file is completely processed (state becomes ACTIVE).
-	for file.State == genai.FileStateUnspecified || file.State != genai.FileStateActive {
-		fmt.Println("Processing video...")
-		fmt.Println("File state:", file.State)
-		time.Sleep(5 * time.Second)
-
-		file, err = client.Files.Get(ctx, file.Name, nil)
-		if err != nil {
-			log.Fatal(err)
-		}
+		return err
+	}
+
+	// Wait until the video file is completely processed (state becomes
+	// ACTIVE), polling less and less often, and give up if processing fails
+	// or takes more than five minutes.
+	waitCtx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+	defer cancel()
+	file, err = filewait.Wait(waitCtx, client, file, &filewait.Options{
+		Progress: func(f *genai.File, next time.Duration) {
+			fmt.Println("Processing video...")
+			fmt.Println("File state:", f.State)
+		},
+	})
+	if err != nil {
+		return err
 	}
 
 	parts := []*genai.Part{
@@ -432,6 +525,7 @@ func TextGenMultimodalVideoPromptStreaming() error {
 		genai.NewContentFromParts(parts, genai.RoleUser),
 	}
 
+	var last *genai.GenerateContentResponse
 	for result, err := range client.Models.GenerateContentStream(
 		ctx,
 		"gemini-3.5-flash",
@@ -439,14 +533,22 @@ func TextGenMultimodalVideoPromptStreaming() error {
 		nil,
 	) {
 		if err != nil {
-			log.Fatal(err)
+			return err
 		}
-		fmt.Print(result.Candidates[0].Content.Parts[0].Text)
+		fmt.Print(result.Text())
+		last = result
+	}
+	if err := checkFinished(last); err != nil {
+		return err
 	}
 	// [END text_gen_multimodal_video_prompt_streaming]
 	return err
 }
 
+func TextGenMultimodalVideoPromptStreamingWithClient(ctx context.Context, client *genai.Client, w io.Writer) error {
+	return runWith(ctx, client, w, TextGenMultimodalVideoPromptStreaming)
+}
+
 func TextGenMultimodalPdf() (*genai.GenerateContentResponse, error) {
 	// [START text_gen_multimodal_pdf]
 	ctx := context.Background()
@@ -455,18 +557,17 @@ func TextGenMultimodalPdf() (*genai.GenerateContentResponse, error) {
 		Backend: genai.BackendGeminiAPI,
 	})
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
-
 	file, err := client.Files.UploadFromPath(
-		ctx, 
-		filepath.Join(getMedia(), "test.pdf"), 
+		ctx,
+		filepath.Join(getMedia(), "test.pdf"),
 		&genai.UploadFileConfig{
-			MIMEType : "application/pdf",
+			MIMEType: "application/pdf",
 		},
 	)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
 	parts := []*genai.Part{
@@ -480,13 +581,17 @@ func TextGenMultimodalPdf() (*genai.GenerateContentResponse, error) {
 
 	response, err := client.Models.GenerateContent(ctx, "gemini-3.5-flash", contents, nil)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 	printResponse(response)
 	// [END text_gen_multimodal_pdf]
 	return response, err
 }
 
+func TextGenMultimodalPdfWithClient(ctx context.Context, client *genai.Client, w io.Writer) (*genai.GenerateContentResponse, error) {
+	return withClient(ctx, client, w, TextGenMultimodalPdf)
+}
+
 func TextGenMultimodalPdfStreaming() error {
 	// [START text_gen_multimodal_pdf_streaming]
 	ctx := context.Background()
@@ -495,18 +600,17 @@ func TextGenMultimodalPdfStreaming() error {
 		Backend: genai.BackendGeminiAPI,
 	})
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
-
 	file, err := client.Files.UploadFromPath(
-		ctx, 
-		filepath.Join(getMedia(), "test.pdf"), 
+		ctx,
+		filepath.Join(getMedia(), "test.pdf"),
 		&genai.UploadFileConfig{
-			MIMEType : "application/pdf",
+			MIMEType: "application/pdf",
 		},
 	)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	parts := []*genai.Part{
@@ -518,6 +622,7 @@ func TextGenMultimodalPdfStreaming() error {
 		genai.NewContentFromParts(parts, genai.RoleUser),
 	}
 
+	var last *genai.GenerateContentResponse
 	for result, err := range client.Models.GenerateContentStream(
 		ctx,
 		"gemini-3.5-flash",
@@ -525,10 +630,18 @@ func TextGenMultimodalPdfStreaming() error {
 		nil,
 	) {
 		if err != nil {
-			log.Fatal(err)
+			return err
 		}
-		fmt.Print(result.Candidates[0].Content.Parts[0].Text)
+		fmt.Print(result.Text())
+		last = result
+	}
+	if err := checkFinished(last); err != nil {
+		return err
 	}
 	// [END text_gen_multimodal_pdf_streaming]
-	return err
+	return nil
+}
+
+func TextGenMultimodalPdfStreamingWithClient(ctx context.Context, client *genai.Client, w io.Writer) error {
+	return runWith(ctx, client, w, TextGenMultimodalPdfStreaming)
 }
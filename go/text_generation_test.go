@@ -1,10 +1,21 @@
 package examples
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
 	"testing"
+	"time"
+
+	"gemini-api-examples/internal/fakegemini"
+	"google.golang.org/genai"
 )
 
 func TestTextGenTextOnlyPrompt(t *testing.T) {
+	useCassette(t)
 	_, err := TextGenTextOnlyPrompt()
 	if err != nil {
 		t.Errorf("TextGenTextOnlyPrompt returned an error.")
@@ -12,6 +23,7 @@ func TestTextGenTextOnlyPrompt(t *testing.T) {
 }
 
 func TestTextGenTextOnlyPromptStreaming(t *testing.T) {
+	useCassette(t)
 	err := TextGenTextOnlyPromptStreaming()
 	if err != nil {
 		t.Errorf("TextGenTextOnlyPromptStreaming returned an error.")
@@ -19,6 +31,7 @@ func TestTextGenTextOnlyPromptStreaming(t *testing.T) {
 }
 
 func TestTextGenMultimodalOneImagePrompt(t *testing.T) {
+	useCassette(t)
 	_, err := TextGenMultimodalOneImagePrompt()
 	if err != nil {
 		t.Errorf("TextGenMultimodalOneImagePrompt returned an error.")
@@ -26,13 +39,23 @@ func TestTextGenMultimodalOneImagePrompt(t *testing.T) {
 }
 
 func TestTextGenMultimodalOneImagePromptStreaming(t *testing.T) {
+	useCassette(t)
 	err := TextGenMultimodalOneImagePromptStreaming()
 	if err != nil {
 		t.Errorf("TextGenMultimodalOneImagePromptStreaming returned an error.")
 	}
 }
 
+func TestTextGenMultimodalImageInline(t *testing.T) {
+	useCassette(t)
+	_, err := TextGenMultimodalImageInline()
+	if err != nil {
+		t.Errorf("TextGenMultimodalImageInline returned an error.")
+	}
+}
+
 func TestTextGenMultimodalMultiImagePrompt(t *testing.T) {
+	useCassette(t)
 	_, err := TextGenMultimodalMultiImagePrompt()
 	if err != nil {
 		t.Errorf("TextGenMultimodalMultiImagePrompt returned an error.")
@@ -40,6 +63,7 @@ func TestTextGenMultimodalMultiImagePrompt(t *testing.T) {
 }
 
 func TestTextGenMultimodalMultiImagePromptStreaming(t *testing.T) {
+	useCassette(t)
 	err := TextGenMultimodalMultiImagePromptStreaming()
 	if err != nil {
 		t.Errorf("TextGenMultimodalMultiImagePromptStreaming returned an error.")
@@ -47,6 +71,7 @@ func TestTextGenMultimodalMultiImagePromptStreaming(t *testing.T) {
 }
 
 func TestTextGenMultimodalAudio(t *testing.T) {
+	useCassette(t)
 	_, err := TextGenMultimodalAudio()
 	if err != nil {
 		t.Errorf("TextGenMultimodalAudio returned an error.")
@@ -54,6 +79,7 @@ func TestTextGenMultimodalAudio(t *testing.T) {
 }
 
 func TestTextGenMultimodalAudioStreaming(t *testing.T) {
+	useCassette(t)
 	err := TextGenMultimodalAudioStreaming()
 	if err != nil {
 		t.Errorf("TextGenMultimodalAudioStreaming returned an error.")
@@ -61,6 +87,7 @@ func TestTextGenMultimodalAudioStreaming(t *testing.T) {
 }
 
 func TestTextGenMultimodalVideoPrompt(t *testing.T) {
+	useCassette(t)
 	_, err := TextGenMultimodalVideoPrompt()
 	if err != nil {
 		t.Errorf("TextGenMultimodalVideoPrompt returned an error.")
@@ -68,6 +95,7 @@ func TestTextGenMultimodalVideoPrompt(t *testing.T) {
 }
 
 func TestTextGenMultimodalVideoPromptStreaming(t *testing.T) {
+	useCassette(t)
 	err := TextGenMultimodalVideoPromptStreaming()
 	if err != nil {
 		t.Errorf("TextGenMultimodalVideoPromptStreaming returned an error.")
@@ -75,6 +103,7 @@ func TestTextGenMultimodalVideoPromptStreaming(t *testing.T) {
 }
 
 func TestTextGenMultimodalPdf(t *testing.T) {
+	useCassette(t)
 	_, err := TextGenMultimodalPdf()
 	if err != nil {
 		t.Errorf("TextGenMultimodalPdf returned an error.")
@@ -82,8 +111,59 @@ func TestTextGenMultimodalPdf(t *testing.T) {
 }
 
 func TestTextGenMultimodalPdfStreaming(t *testing.T) {
+	useCassette(t)
 	err := TextGenMultimodalPdfStreaming()
 	if err != nil {
 		t.Errorf("TextGenMultimodalPdfStreaming returned an error.")
 	}
 }
+
+func TestFakeTextGenMultimodalImageInline(t *testing.T) {
+	srv, client := fakeClient(t)
+	if _, err := TextGenMultimodalImageInlineWithClient(t.Context(), client, io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	reqs := srv.GenerateRequests()
+	if len(reqs) != 1 || len(reqs[0].Contents) != 1 {
+		t.Fatalf("got %d generate requests, want 1", len(reqs))
+	}
+	if p := reqs[0].Contents[0].Parts[1]; p.InlineData == nil || p.InlineData.MIMEType != "image/jpeg" {
+		t.Errorf("part %+v is not an inline image", p)
+	}
+	if n := len(srv.Files()); n != 0 {
+		t.Errorf("%d files uploaded, want the image inline", n)
+	}
+}
+
+func TestFakeTextGenRateLimitDetails(t *testing.T) {
+	srv, client := fakeClient(t)
+	srv.Inject(":generateContent", 1, fakegemini.RateLimited(30*time.Second))
+	_, err := TextGenTextOnlyPromptWithClient(t.Context(), client, io.Discard)
+	var apiErr genai.APIError
+	if !errors.As(err, &apiErr) || apiErr.Code != http.StatusTooManyRequests {
+		t.Fatalf("err = %v, want a 429 APIError", err)
+	}
+	if len(apiErr.Details) == 0 || apiErr.Details[0]["retryDelay"] != "30s" {
+		t.Errorf("Details = %v, want the RetryInfo passed through", apiErr.Details)
+	}
+}
+
+func TestFakeTextGenTextOnlyPromptStreamingWithClient(t *testing.T) {
+	_, client := fakeClient(t)
+	var out bytes.Buffer
+	if err := TextGenTextOnlyPromptStreamingWithClient(context.Background(), client, &out); err != nil {
+		t.Fatalf("TextGenTextOnlyPromptStreamingWithClient returned an error: %v", err)
+	}
+	if got, want := out.String(), "You said: Write a story about a magic backpack."; got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
+
+func TestFakeTextGenTextOnlyPromptStreamingBlocked(t *testing.T) {
+	srv, client := fakeClient(t)
+	srv.Inject(":streamGenerateContent", 0, fakegemini.BlockedPrompt(genai.BlockedReasonSafety))
+	err := TextGenTextOnlyPromptStreamingWithClient(context.Background(), client, io.Discard)
+	if got, want := fmt.Sprint(err), "prompt blocked: SAFETY"; got != want {
+		t.Errorf("err = %q, want %q", got, want)
+	}
+}
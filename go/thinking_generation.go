@@ -3,46 +3,31 @@ package examples
 import (
 	"context"
 	"fmt"
-	"log"
+	"io"
 	"os"
 	"strings"
 
 	"google.golang.org/genai"
 )
 
-// Define the thinking model centrally
-const modelID = "gemini-3.5-flash"
-
-// Helper function to initialize the client
-func newGenAIClient(ctx context.Context) (*genai.Client, error) {
-	apiKey := os.Getenv("GEMINI_API_KEY")
-	if apiKey == "" {
-		return nil, fmt.Errorf("GEMINI_API_KEY environment variable not set")
-	}
-	return genai.NewClient(ctx, &genai.ClientConfig{
-		APIKey:  apiKey,
-		Backend: genai.BackendGeminiAPI, // Assuming Gemini API backend
-	})
-}
-
 func ThinkingTextOnlyPrompt() (*genai.GenerateContentResponse, error) {
 	// [START thinking_text_only_prompt]
 	ctx := context.Background()
-	client, err := newGenAIClient(ctx)
+	client, err := genai.NewClient(ctx, &genai.ClientConfig{
+		APIKey:  os.Getenv("GEMINI_API_KEY"),
+		Backend: genai.BackendGeminiAPI,
+	})
 	if err != nil {
-		log.Printf("Failed to create client: %v", err)
 		return nil, err
 	}
-
 	prompt := "Explain the concept of Occam's Razor and provide a simple, everyday example."
 	contents := []*genai.Content{
 		genai.NewContentFromText(prompt, genai.RoleUser),
 	}
 
-	resp, err := client.Models.GenerateContent(ctx, modelID, contents, nil)
+	resp, err := client.Models.GenerateContent(ctx, "gemini-3.5-flash", contents, nil)
 	if err != nil {
-		log.Printf("GenerateContent failed: %v", err)
-		return nil, err
+		return nil, fmt.Errorf("GenerateContent failed: %w", err)
 	}
 
 	fmt.Println(resp.Text())
@@ -50,48 +35,62 @@ func ThinkingTextOnlyPrompt() (*genai.GenerateContentResponse, error) {
 	return resp, nil
 }
 
+func ThinkingTextOnlyPromptWithClient(ctx context.Context, client *genai.Client, w io.Writer) (*genai.GenerateContentResponse, error) {
+	return withClient(ctx, client, w, ThinkingTextOnlyPrompt)
+}
+
 func ThinkingTextOnlyPromptStreaming() (string, error) {
 	// [START thinking_text_only_prompt_streaming]
 	ctx := context.Background()
-	client, err := newGenAIClient(ctx)
+	client, err := genai.NewClient(ctx, &genai.ClientConfig{
+		APIKey:  os.Getenv("GEMINI_API_KEY"),
+		Backend: genai.BackendGeminiAPI,
+	})
 	if err != nil {
-		log.Printf("Failed to create client: %v", err)
 		return "", err
 	}
-
 	prompt := "Explain the concept of Occam's Razor and provide a simple, everyday example."
 	contents := []*genai.Content{
 		genai.NewContentFromText(prompt, genai.RoleUser),
 	}
 
 	var fullResponse strings.Builder
-	stream := client.Models.GenerateContentStream(ctx, modelID, contents, nil)
+	var last *genai.GenerateContentResponse
+	stream := client.Models.GenerateContentStream(ctx, "gemini-3.5-flash", contents, nil)
 	for resp, err := range stream {
 		if err != nil {
-			log.Printf("Stream error: %v", err)
-			return fullResponse.String(), err
+			return fullResponse.String(), fmt.Errorf("stream error: %w", err)
 		}
 		// Check if there are candidates and parts before accessing
-		if len(resp.Candidates) > 0 && len(resp.Candidates[0].Content.Parts) > 0 {
+		if len(resp.Candidates) > 0 && resp.Candidates[0].Content != nil && len(resp.Candidates[0].Content.Parts) > 0 {
 			textPart := resp.Candidates[0].Content.Parts[0].Text
 			fmt.Print(textPart) // Print chunk directly
 			fullResponse.WriteString(textPart)
 		}
+		last = resp
+	}
+	if err := checkFinished(last); err != nil {
+		return fullResponse.String(), err
 	}
 	fmt.Println("\n" + strings.Repeat("_", 80))
 	// [END thinking_text_only_prompt_streaming]
 	return fullResponse.String(), nil
 }
 
+func ThinkingTextOnlyPromptStreamingWithClient(ctx context.Context, client *genai.Client, w io.Writer) (string, error) {
+	return withClient(ctx, client, w, ThinkingTextOnlyPromptStreaming)
+}
+
 func ThinkingLogicPuzzle() (*genai.GenerateContentResponse, error) {
 	// [START thinking_logic_puzzle]
 	ctx := context.Background()
-	client, err := newGenAIClient(ctx)
+	client, err := genai.NewClient(ctx, &genai.ClientConfig{
+		APIKey:  os.Getenv("GEMINI_API_KEY"),
+		Backend: genai.BackendGeminiAPI,
+	})
 	if err != nil {
-		log.Printf("Failed to create client: %v", err)
 		return nil, err
 	}
-
 	prompt := `
         Solve this logic puzzle and explain your reasoning step-by-step:
         There are three boxes. One contains only apples, one contains only oranges,
@@ -104,10 +103,9 @@ func ThinkingLogicPuzzle() (*genai.GenerateContentResponse, error) {
 		genai.NewContentFromText(prompt, genai.RoleUser),
 	}
 
-	resp, err := client.Models.GenerateContent(ctx, modelID, contents, nil)
+	resp, err := client.Models.GenerateContent(ctx, "gemini-3.5-flash", contents, nil)
 	if err != nil {
-		log.Printf("GenerateContent failed: %v", err)
-		return nil, err
+		return nil, fmt.Errorf("GenerateContent failed: %w", err)
 	}
 
 	fmt.Println(resp.Text())
@@ -115,15 +113,20 @@ func ThinkingLogicPuzzle() (*genai.GenerateContentResponse, error) {
 	return resp, nil
 }
 
+func ThinkingLogicPuzzleWithClient(ctx context.Context, client *genai.Client, w io.Writer) (*genai.GenerateContentResponse, error) {
+	return withClient(ctx, client, w, ThinkingLogicPuzzle)
+}
+
 func ThinkingCodeExplanation() (*genai.GenerateContentResponse, error) {
 	// [START thinking_code_explanation]
 	ctx := context.Background()
-	client, err := newGenAIClient(ctx)
+	client, err := genai.NewClient(ctx, &genai.ClientConfig{
+		APIKey:  os.Getenv("GEMINI_API_KEY"),
+		Backend: genai.BackendGeminiAPI,
+	})
 	if err != nil {
-		log.Printf("Failed to create client: %v", err)
 		return nil, err
 	}
-
 	prompt := `
         Explain this Python code snippet step-by-step, including what it does
         and why recursion is used here:
@@ -144,10 +147,9 @@ func ThinkingCodeExplanation() (*genai.GenerateContentResponse, error) {
 		genai.NewContentFromText(prompt, genai.RoleUser),
 	}
 
-	resp, err := client.Models.GenerateContent(ctx, modelID, contents, nil)
+	resp, err := client.Models.GenerateContent(ctx, "gemini-3.5-flash", contents, nil)
 	if err != nil {
-		log.Printf("GenerateContent failed: %v", err)
-		return nil, err
+		return nil, fmt.Errorf("GenerateContent failed: %w", err)
 	}
 
 	fmt.Println(resp.Text())
@@ -155,15 +157,20 @@ func ThinkingCodeExplanation() (*genai.GenerateContentResponse, error) {
 	return resp, nil
 }
 
+func ThinkingCodeExplanationWithClient(ctx context.Context, client *genai.Client, w io.Writer) (*genai.GenerateContentResponse, error) {
+	return withClient(ctx, client, w, ThinkingCodeExplanation)
+}
+
 func ThinkingCreativeWritingConstraints() (*genai.GenerateContentResponse, error) {
 	// [START thinking_creative_writing_constraints]
 	ctx := context.Background()
-	client, err := newGenAIClient(ctx)
+	client, err := genai.NewClient(ctx, &genai.ClientConfig{
+		APIKey:  os.Getenv("GEMINI_API_KEY"),
+		Backend: genai.BackendGeminiAPI,
+	})
 	if err != nil {
-		log.Printf("Failed to create client: %v", err)
 		return nil, err
 	}
-
 	prompt := `
         Write a short story (max 150 words) about a detective investigating a
         mystery in a library, but the story must not contain the letter 'e'.
@@ -172,10 +179,9 @@ func ThinkingCreativeWritingConstraints() (*genai.GenerateContentResponse, error
 		genai.NewContentFromText(prompt, genai.RoleUser),
 	}
 
-	resp, err := client.Models.GenerateContent(ctx, modelID, contents, nil)
+	resp, err := client.Models.GenerateContent(ctx, "gemini-3.5-flash", contents, nil)
 	if err != nil {
-		log.Printf("GenerateContent failed: %v", err)
-		return nil, err
+		return nil, fmt.Errorf("GenerateContent failed: %w", err)
 	}
 
 	fmt.Println(resp.Text())
@@ -183,15 +189,20 @@ func ThinkingCreativeWritingConstraints() (*genai.GenerateContentResponse, error
 	return resp, nil
 }
 
+func ThinkingCreativeWritingConstraintsWithClient(ctx context.Context, client *genai.Client, w io.Writer) (*genai.GenerateContentResponse, error) {
+	return withClient(ctx, client, w, ThinkingCreativeWritingConstraints)
+}
+
 func ThinkingWithSearchTool() (*genai.GenerateContentResponse, error) {
 	// [START thinking_with_search_tool]
 	ctx := context.Background()
-	client, err := newGenAIClient(ctx)
+	client, err := genai.NewClient(ctx, &genai.ClientConfig{
+		APIKey:  os.Getenv("GEMINI_API_KEY"),
+		Backend: genai.BackendGeminiAPI,
+	})
 	if err != nil {
-		log.Printf("Failed to create client: %v", err)
 		return nil, err
 	}
-
 	googleSearchTool := &genai.Tool{
 		GoogleSearch: &genai.GoogleSearch{}, // Empty struct for default search
 	}
@@ -204,10 +215,9 @@ func ThinkingWithSearchTool() (*genai.GenerateContentResponse, error) {
 		Tools: []*genai.Tool{googleSearchTool},
 	}
 
-	resp, err := client.Models.GenerateContent(ctx, modelID, contents, config)
+	resp, err := client.Models.GenerateContent(ctx, "gemini-3.5-flash", contents, config)
 	if err != nil {
-		log.Printf("GenerateContent with search tool failed: %v", err)
-		return nil, err
+		return nil, fmt.Errorf("GenerateContent with search tool failed: %w", err)
 	}
 
 	fmt.Println(resp)
@@ -216,15 +226,20 @@ func ThinkingWithSearchTool() (*genai.GenerateContentResponse, error) {
 	return resp, nil
 }
 
+func ThinkingWithSearchToolWithClient(ctx context.Context, client *genai.Client, w io.Writer) (*genai.GenerateContentResponse, error) {
+	return withClient(ctx, client, w, ThinkingWithSearchTool)
+}
+
 func ThinkingWithSearchToolStreaming() (string, error) {
 	// [START thinking_with_search_tool_streaming]
 	ctx := context.Background()
-	client, err := newGenAIClient(ctx)
+	client, err := genai.NewClient(ctx, &genai.ClientConfig{
+		APIKey:  os.Getenv("GEMINI_API_KEY"),
+		Backend: genai.BackendGeminiAPI,
+	})
 	if err != nil {
-		log.Printf("Failed to create client: %v", err)
 		return "", err
 	}
-
 	googleSearchTool := &genai.Tool{
 		GoogleSearch: &genai.GoogleSearch{},
 	}
@@ -239,11 +254,12 @@ func ThinkingWithSearchToolStreaming() (string, error) {
 
 	var fullResponseText strings.Builder
 	// var finalResponse *genai.GenerateContentResponse // Store the last response chunk
+	var last *genai.GenerateContentResponse
 
-	stream := client.Models.GenerateContentStream(ctx, modelID, contents, config)
+	stream := client.Models.GenerateContentStream(ctx, "gemini-3.5-flash", contents, config)
 	for resp, err := range stream {
 		if err != nil {
-			log.Printf("Stream error: %v", err)
+			fmt.Printf("Stream error: %v", err)
 			fmt.Println("\nCould not access grounding metadata from stream response likely due to error.")
 			return fullResponseText.String(), err
 		}
@@ -255,8 +271,12 @@ func ThinkingWithSearchToolStreaming() (string, error) {
 				fullResponseText.WriteString(textPart)
 			}
 		}
+		last = resp
 		// finalResponse = resp // Keep track of the latest response which might contain aggregated data
 	}
+	if err := checkFinished(last); err != nil {
+		return fullResponseText.String(), err
+	}
 
 	fmt.Println("\n" + strings.Repeat("_", 80)) // Separator
 
@@ -264,15 +284,20 @@ func ThinkingWithSearchToolStreaming() (string, error) {
 	return fullResponseText.String(), nil
 }
 
+func ThinkingWithSearchToolStreamingWithClient(ctx context.Context, client *genai.Client, w io.Writer) (string, error) {
+	return withClient(ctx, client, w, ThinkingWithSearchToolStreaming)
+}
+
 func ThinkingCodeExecution() (*genai.GenerateContentResponse, error) {
 	// [START thinking_code_execution]
 	ctx := context.Background()
-	client, err := newGenAIClient(ctx)
+	client, err := genai.NewClient(ctx, &genai.ClientConfig{
+		APIKey:  os.Getenv("GEMINI_API_KEY"),
+		Backend: genai.BackendGeminiAPI,
+	})
 	if err != nil {
-		log.Printf("Failed to create client: %v", err)
 		return nil, err
 	}
-
 	prompt := "What is the sum of the first 50 prime numbers? " +
 		"Generate and run Python code for the calculation, and make sure you get all 50. " +
 		"Provide the final sum clearly."
@@ -288,10 +313,9 @@ func ThinkingCodeExecution() (*genai.GenerateContentResponse, error) {
 		Tools: []*genai.Tool{codeExecutionTool}, // Provide the tool
 	}
 
-	resp, err := client.Models.GenerateContent(ctx, modelID, contents, config)
+	resp, err := client.Models.GenerateContent(ctx, "gemini-3.5-flash", contents, config)
 	if err != nil {
-		log.Printf("GenerateContent with code execution failed: %v", err)
-		return nil, err
+		return nil, fmt.Errorf("GenerateContent with code execution failed: %w", err)
 	}
 
 	fmt.Println(resp)
@@ -300,15 +324,20 @@ func ThinkingCodeExecution() (*genai.GenerateContentResponse, error) {
 	return resp, nil
 }
 
+func ThinkingCodeExecutionWithClient(ctx context.Context, client *genai.Client, w io.Writer) (*genai.GenerateContentResponse, error) {
+	return withClient(ctx, client, w, ThinkingCodeExecution)
+}
+
 func ThinkingStructuredOutputJson() (*genai.GenerateContentResponse, error) {
 	// [START thinking_structured_output_json]
 	ctx := context.Background()
-	client, err := newGenAIClient(ctx)
+	client, err := genai.NewClient(ctx, &genai.ClientConfig{
+		APIKey:  os.Getenv("GEMINI_API_KEY"),
+		Backend: genai.BackendGeminiAPI,
+	})
 	if err != nil {
-		log.Printf("Failed to create client: %v", err)
 		return nil, err
 	}
-
 	// Prompt clearly asks for JSON and provides the schema inline
 	prompt := `
         Provide a list of 3 famous physicists and their key contributions
@@ -324,21 +353,24 @@ func ThinkingStructuredOutputJson() (*genai.GenerateContentResponse, error) {
 		genai.NewContentFromText(prompt, genai.RoleUser),
 	}
 
-	resp, err := client.Models.GenerateContent(ctx, modelID, contents, nil)
+	resp, err := client.Models.GenerateContent(ctx, "gemini-3.5-flash", contents, nil)
 
 	// For stricter JSON mode (if structured output is supported):
 	// config := &genai.GenerateContentConfig{
 	// 	ResponseMIMEType: "application/json",
 	//  // ResponseSchema: &genai.Schema{...} // Define schema if needed
 	// }
-	// resp, err := client.Models.GenerateContent(ctx, modelID, contents, config)
+	// resp, err := client.Models.GenerateContent(ctx, "gemini-3.5-flash", contents, config)
 
 	if err != nil {
-		log.Printf("GenerateContent failed: %v", err)
-		return nil, err
+		return nil, fmt.Errorf("GenerateContent failed: %w", err)
 	}
 
 	fmt.Println(resp.Text())
 	// [END thinking_structured_output_json]
 	return resp, nil
 }
+
+func ThinkingStructuredOutputJsonWithClient(ctx context.Context, client *genai.Client, w io.Writer) (*genai.GenerateContentResponse, error) {
+	return withClient(ctx, client, w, ThinkingStructuredOutputJson)
+}